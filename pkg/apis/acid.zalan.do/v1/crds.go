@@ -111,6 +111,7 @@ var OperatorConfigCRDResourceColumns = []apiextv1.CustomResourceColumnDefinition
 var min0 = 0.0
 var min1 = 1.0
 var minDisable = -1.0
+var max100 = 100.0
 
 // PostgresCRDResourceValidation to check applied manifest parameters
 var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
@@ -211,14 +212,108 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 								Type:   "string",
 								Format: "uuid",
 							},
+							"volumeSnapshot": {
+								Type: "string",
+							},
 						},
 					},
 					"connectionPooler": {
 						Type: "object",
 						Properties: map[string]apiextv1.JSONSchemaProps{
+							"authMode": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"lookup"`),
+									},
+									{
+										Raw: []byte(`"file"`),
+									},
+								},
+							},
+							"autoscaling": {
+								Type:     "object",
+								Required: []string{"maxInstances"},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"maxInstances": {
+										Type:    "integer",
+										Minimum: &min1,
+									},
+									"minInstances": {
+										Type:    "integer",
+										Minimum: &min1,
+									},
+									"targetCPUUtilization": {
+										Type:    "integer",
+										Minimum: &min1,
+									},
+									"targetClientConnections": {
+										Type:    "integer",
+										Minimum: &min1,
+									},
+								},
+							},
+							"clientTLSMode": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"disable"`),
+									},
+									{
+										Raw: []byte(`"allow"`),
+									},
+									{
+										Raw: []byte(`"prefer"`),
+									},
+									{
+										Raw: []byte(`"require"`),
+									},
+									{
+										Raw: []byte(`"verify-ca"`),
+									},
+									{
+										Raw: []byte(`"verify-full"`),
+									},
+								},
+							},
+							"databasePoolModes": {
+								Type: "object",
+								AdditionalProperties: &apiextv1.JSONSchemaPropsOrBool{
+									Schema: &apiextv1.JSONSchemaProps{
+										Type: "string",
+										Enum: []apiextv1.JSON{
+											{
+												Raw: []byte(`"session"`),
+											},
+											{
+												Raw: []byte(`"transaction"`),
+											},
+											{
+												Raw: []byte(`"statement"`),
+											},
+										},
+									},
+								},
+							},
+							"defaultPoolSize": {
+								Type:    "integer",
+								Minimum: &min1,
+							},
 							"dockerImage": {
 								Type: "string",
 							},
+							"ignoreStartupParameters": {
+								Type: "array",
+								Items: &apiextv1.JSONSchemaPropsOrArray{
+									Schema: &apiextv1.JSONSchemaProps{
+										Type: "string",
+									},
+								},
+							},
+							"maxClientConnections": {
+								Type:    "integer",
+								Minimum: &min1,
+							},
 							"maxDBConnections": {
 								Type: "integer",
 							},
@@ -237,6 +332,10 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 								Type:    "integer",
 								Minimum: &min1,
 							},
+							"reservePoolSize": {
+								Type:    "integer",
+								Minimum: &min0,
+							},
 							"resources": {
 								Type: "object",
 								Properties: map[string]apiextv1.JSONSchemaProps{
@@ -271,6 +370,78 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"schema": {
 								Type: "string",
 							},
+							"serverIdleTimeout": {
+								Type:    "integer",
+								Minimum: &min0,
+							},
+							"serverLifetime": {
+								Type:    "integer",
+								Minimum: &min0,
+							},
+							"serverTLSMode": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"disable"`),
+									},
+									{
+										Raw: []byte(`"allow"`),
+									},
+									{
+										Raw: []byte(`"prefer"`),
+									},
+									{
+										Raw: []byte(`"require"`),
+									},
+									{
+										Raw: []byte(`"verify-ca"`),
+									},
+									{
+										Raw: []byte(`"verify-full"`),
+									},
+								},
+							},
+							"spreadAcrossNodes": {
+								Type: "boolean",
+							},
+							"spreadAcrossZones": {
+								Type: "boolean",
+							},
+							"tls": {
+								Type:     "object",
+								Required: []string{"secretName"},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"secretName": {
+										Type: "string",
+									},
+									"certificateFile": {
+										Type: "string",
+									},
+									"privateKeyFile": {
+										Type: "string",
+									},
+									"caFile": {
+										Type: "string",
+									},
+									"caSecretName": {
+										Type: "string",
+									},
+								},
+							},
+							"type": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"pgbouncer"`),
+									},
+									{
+										Raw: []byte(`"pgcat"`),
+									},
+									{
+										Raw: []byte(`"odyssey"`),
+									},
+								},
+							},
 							"user": {
 								Type: "string",
 							},
@@ -558,6 +729,126 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 							},
 						},
 					},
+					"persistentVolumeClaimRetentionPolicy": {
+						Type: "object",
+						Properties: map[string]apiextv1.JSONSchemaProps{
+							"whenDeleted": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"Delete"`),
+									},
+									{
+										Raw: []byte(`"Retain"`),
+									},
+									{
+										Raw: []byte(`"delete"`),
+									},
+									{
+										Raw: []byte(`"retain"`),
+									},
+								},
+							},
+							"whenScaled": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"Delete"`),
+									},
+									{
+										Raw: []byte(`"Retain"`),
+									},
+									{
+										Raw: []byte(`"delete"`),
+									},
+									{
+										Raw: []byte(`"retain"`),
+									},
+								},
+							},
+						},
+					},
+					"pgHbaRules": {
+						Type: "array",
+						Items: &apiextv1.JSONSchemaPropsOrArray{
+							Schema: &apiextv1.JSONSchemaProps{
+								Type:     "object",
+								Required: []string{"type", "method"},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"address": {
+										Type: "string",
+									},
+									"database": {
+										Type: "string",
+									},
+									"method": {
+										Type: "string",
+										Enum: []apiextv1.JSON{
+											{
+												Raw: []byte(`"trust"`),
+											},
+											{
+												Raw: []byte(`"reject"`),
+											},
+											{
+												Raw: []byte(`"scram-sha-256"`),
+											},
+											{
+												Raw: []byte(`"md5"`),
+											},
+											{
+												Raw: []byte(`"password"`),
+											},
+											{
+												Raw: []byte(`"gss"`),
+											},
+											{
+												Raw: []byte(`"sspi"`),
+											},
+											{
+												Raw: []byte(`"ident"`),
+											},
+											{
+												Raw: []byte(`"peer"`),
+											},
+											{
+												Raw: []byte(`"ldap"`),
+											},
+											{
+												Raw: []byte(`"radius"`),
+											},
+											{
+												Raw: []byte(`"cert"`),
+											},
+											{
+												Raw: []byte(`"pam"`),
+											},
+											{
+												Raw: []byte(`"bsd"`),
+											},
+										},
+									},
+									"type": {
+										Type: "string",
+										Enum: []apiextv1.JSON{
+											{
+												Raw: []byte(`"host"`),
+											},
+											{
+												Raw: []byte(`"hostssl"`),
+											},
+										},
+									},
+									"useAllowedSourceRanges": {
+										Type: "boolean",
+									},
+									"user": {
+										Type: "string",
+									},
+								},
+							},
+						},
+					},
 					"podAnnotations": {
 						Type: "object",
 						AdditionalProperties: &apiextv1.JSONSchemaPropsOrBool{
@@ -785,6 +1076,94 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 							},
 						},
 					},
+					"tablespaces": {
+						Type: "array",
+						Items: &apiextv1.JSONSchemaPropsOrArray{
+							Schema: &apiextv1.JSONSchemaProps{
+								Type:     "object",
+								Required: []string{"name", "volume"},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"name": {
+										Type:    "string",
+										Pattern: "^[a-z][a-z0-9_]*$",
+									},
+									"owner": {
+										Type: "string",
+									},
+									"volume": {
+										Type:     "object",
+										Required: []string{"size"},
+										Properties: map[string]apiextv1.JSONSchemaProps{
+											"iops": {
+												Type: "integer",
+											},
+											"selector": {
+												Type: "object",
+												Properties: map[string]apiextv1.JSONSchemaProps{
+													"matchExpressions": {
+														Type: "array",
+														Items: &apiextv1.JSONSchemaPropsOrArray{
+															Schema: &apiextv1.JSONSchemaProps{
+																Type:     "object",
+																Required: []string{"key", "operator"},
+																Properties: map[string]apiextv1.JSONSchemaProps{
+																	"key": {
+																		Type: "string",
+																	},
+																	"operator": {
+																		Type: "string",
+																		Enum: []apiextv1.JSON{
+																			{
+																				Raw: []byte(`"DoesNotExist"`),
+																			},
+																			{
+																				Raw: []byte(`"Exists"`),
+																			},
+																			{
+																				Raw: []byte(`"In"`),
+																			},
+																			{
+																				Raw: []byte(`"NotIn"`),
+																			},
+																		},
+																	},
+																	"values": {
+																		Type: "array",
+																		Items: &apiextv1.JSONSchemaPropsOrArray{
+																			Schema: &apiextv1.JSONSchemaProps{
+																				Type: "string",
+																			},
+																		},
+																	},
+																},
+															},
+														},
+													},
+													"matchLabels": {
+														Type:                   "object",
+														XPreserveUnknownFields: util.True(),
+													},
+												},
+											},
+											"size": {
+												Type:    "string",
+												Pattern: "^(\\d+(e\\d+)?|\\d+(\\.\\d+)?(e\\d+)?[EPTGMK]i?)$",
+											},
+											"storageClass": {
+												Type: "string",
+											},
+											"subPath": {
+												Type: "string",
+											},
+											"throughput": {
+												Type: "integer",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
 					"teamId": {
 						Type: "string",
 					},
@@ -957,6 +1336,15 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 							},
 						},
 					},
+					"usersWithCertificateAuthentication": {
+						Type:     "array",
+						Nullable: true,
+						Items: &apiextv1.JSONSchemaPropsOrArray{
+							Schema: &apiextv1.JSONSchemaProps{
+								Type: "string",
+							},
+						},
+					},
 					"usersWithInPlaceSecretRotation": {
 						Type:     "array",
 						Nullable: true,
@@ -966,6 +1354,23 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 							},
 						},
 					},
+					"usersWithPasswordFromSecret": {
+						Type: "object",
+						AdditionalProperties: &apiextv1.JSONSchemaPropsOrBool{
+							Schema: &apiextv1.JSONSchemaProps{
+								Type:     "object",
+								Required: []string{"secretName", "key"},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"key": {
+										Type: "string",
+									},
+									"secretName": {
+										Type: "string",
+									},
+								},
+							},
+						},
+					},
 					"usersWithSecretRotation": {
 						Type:     "array",
 						Nullable: true,
@@ -976,6 +1381,114 @@ var PostgresCRDResourceValidation = apiextv1.CustomResourceValidation{
 						},
 					},
 					"volume": {
+						Type:     "object",
+						Required: []string{"size"},
+						Properties: map[string]apiextv1.JSONSchemaProps{
+							"autogrow": {
+								Type:     "object",
+								Required: []string{"maxSize"},
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"maxSize": {
+										Type:    "string",
+										Pattern: "^(\\d+(e\\d+)?|\\d+(\\.\\d+)?(e\\d+)?[EPTGMK]i?)$",
+									},
+									"step": {
+										Type:    "string",
+										Pattern: "^(\\d+%|\\d+(e\\d+)?|\\d+(\\.\\d+)?(e\\d+)?[EPTGMK]i?)$",
+									},
+									"thresholdPercent": {
+										Type:    "integer",
+										Minimum: &min1,
+										Maximum: &max100,
+									},
+								},
+							},
+							"iops": {
+								Type: "integer",
+							},
+							"selector": {
+								Type: "object",
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"matchExpressions": {
+										Type: "array",
+										Items: &apiextv1.JSONSchemaPropsOrArray{
+											Schema: &apiextv1.JSONSchemaProps{
+												Type:     "object",
+												Required: []string{"key", "operator"},
+												Properties: map[string]apiextv1.JSONSchemaProps{
+													"key": {
+														Type: "string",
+													},
+													"operator": {
+														Type: "string",
+														Enum: []apiextv1.JSON{
+															{
+																Raw: []byte(`"DoesNotExist"`),
+															},
+															{
+																Raw: []byte(`"Exists"`),
+															},
+															{
+																Raw: []byte(`"In"`),
+															},
+															{
+																Raw: []byte(`"NotIn"`),
+															},
+														},
+													},
+													"values": {
+														Type: "array",
+														Items: &apiextv1.JSONSchemaPropsOrArray{
+															Schema: &apiextv1.JSONSchemaProps{
+																Type: "string",
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+									"matchLabels": {
+										Type:                   "object",
+										XPreserveUnknownFields: util.True(),
+									},
+								},
+							},
+							"size": {
+								Type:    "string",
+								Pattern: "^(\\d+(e\\d+)?|\\d+(\\.\\d+)?(e\\d+)?[EPTGMK]i?)$",
+							},
+							"storageClass": {
+								Type: "string",
+							},
+							"subPath": {
+								Type: "string",
+							},
+							"throughput": {
+								Type: "integer",
+							},
+						},
+					},
+					"volumeSnapshots": {
+						Type: "object",
+						Properties: map[string]apiextv1.JSONSchemaProps{
+							"bootstrapReplicas": {
+								Type: "boolean",
+							},
+							"interval": {
+								Type:    "string",
+								Pattern: "^([0-9]+(\\.[0-9]+)?(h|m|s))+$",
+							},
+							"retention": {
+								Type:    "integer",
+								Minimum: &min1,
+							},
+							"volumeSnapshotClass": {
+								Type: "string",
+							},
+						},
+					},
+					"walVolume": {
 						Type:     "object",
 						Required: []string{"size"},
 						Properties: map[string]apiextv1.JSONSchemaProps{
@@ -1177,6 +1690,12 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 									},
 								},
 							},
+							"client_certificate_renew_before": {
+								Type: "string",
+							},
+							"client_certificate_validity": {
+								Type: "string",
+							},
 							"enable_password_rotation": {
 								Type: "boolean",
 							},
@@ -1278,9 +1797,15 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"enable_readiness_probe": {
 								Type: "boolean",
 							},
+							"enable_secret_connection_keys": {
+								Type: "boolean",
+							},
 							"enable_sidecars": {
 								Type: "boolean",
 							},
+							"enable_volume_rebuild": {
+								Type: "boolean",
+							},
 							"ignored_annotations": {
 								Type: "array",
 								Items: &apiextv1.JSONSchemaPropsOrArray{
@@ -1372,6 +1897,45 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"pdb_name_format": {
 								Type: "string",
 							},
+							"persistent_volume_claim_retention_policy": {
+								Type: "object",
+								Properties: map[string]apiextv1.JSONSchemaProps{
+									"when_deleted": {
+										Type: "string",
+										Enum: []apiextv1.JSON{
+											{
+												Raw: []byte(`"Delete"`),
+											},
+											{
+												Raw: []byte(`"Retain"`),
+											},
+											{
+												Raw: []byte(`"delete"`),
+											},
+											{
+												Raw: []byte(`"retain"`),
+											},
+										},
+									},
+									"when_scaled": {
+										Type: "string",
+										Enum: []apiextv1.JSON{
+											{
+												Raw: []byte(`"Delete"`),
+											},
+											{
+												Raw: []byte(`"Retain"`),
+											},
+											{
+												Raw: []byte(`"delete"`),
+											},
+											{
+												Raw: []byte(`"retain"`),
+											},
+										},
+									},
+								},
+							},
 							"pod_antiaffinity_topology_key": {
 								Type: "string",
 							},
@@ -1410,6 +1974,15 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"pod_terminate_grace_period": {
 								Type: "string",
 							},
+							"client_ca_secret_name_template": {
+								Type: "string",
+							},
+							"client_certificate_secret_name_template": {
+								Type: "string",
+							},
+							"secret_connection_key_template": {
+								Type: "string",
+							},
 							"secret_name_template": {
 								Type: "string",
 							},
@@ -1428,6 +2001,17 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"spilo_allow_privilege_escalation": {
 								Type: "boolean",
 							},
+							"storage_autogrow_target": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"manifest"`),
+									},
+									{
+										Raw: []byte(`"status"`),
+									},
+								},
+							},
 							"storage_resize_mode": {
 								Type: "string",
 								Enum: []apiextv1.JSON{
@@ -1595,6 +2179,9 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"enable_ebs_gp3_migration_max_size": {
 								Type: "integer",
 							},
+							"enable_volume_migration": {
+								Type: "boolean",
+							},
 							"gcp_credentials": {
 								Type: "string",
 							},
@@ -1604,6 +2191,39 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"log_s3_bucket": {
 								Type: "string",
 							},
+							"storage_resize_provider": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"aws"`),
+									},
+									{
+										Raw: []byte(`"azure"`),
+									},
+									{
+										Raw: []byte(`"gcp"`),
+									},
+								},
+							},
+							"volume_migration_iops": {
+								Type: "integer",
+							},
+							"volume_migration_max_concurrent": {
+								Type:    "integer",
+								Minimum: &min1,
+							},
+							"volume_migration_max_size": {
+								Type: "integer",
+							},
+							"volume_migration_source_type": {
+								Type: "string",
+							},
+							"volume_migration_target_type": {
+								Type: "string",
+							},
+							"volume_migration_throughput": {
+								Type: "integer",
+							},
 							"wal_s3_bucket": {
 								Type: "string",
 							},
@@ -1660,6 +2280,23 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"enable_database_access": {
 								Type: "boolean",
 							},
+							"tracing_exporter": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`""`),
+									},
+									{
+										Raw: []byte(`"otlp"`),
+									},
+									{
+										Raw: []byte(`"stdout"`),
+									},
+								},
+							},
+							"tracing_otlp_endpoint": {
+								Type: "string",
+							},
 						},
 					},
 					"teams_api": {
@@ -1730,9 +2367,40 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 							"api_port": {
 								Type: "integer",
 							},
+							"api_token_review_users": {
+								Type: "array",
+								Items: &apiextv1.JSONSchemaPropsOrArray{
+									Schema: &apiextv1.JSONSchemaProps{
+										Type: "string",
+									},
+								},
+							},
+							"api_token_secret_name": {
+								Type: "string",
+							},
 							"cluster_history_entries": {
 								Type: "integer",
 							},
+							"cluster_history_retention": {
+								Type: "string",
+							},
+							"cluster_history_storage": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`""`),
+									},
+									{
+										Raw: []byte(`"configmap"`),
+									},
+									{
+										Raw: []byte(`"file"`),
+									},
+								},
+							},
+							"cluster_history_storage_path": {
+								Type: "string",
+							},
 							"ring_log_lines": {
 								Type: "integer",
 							},
@@ -1771,6 +2439,20 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 					"connection_pooler": {
 						Type: "object",
 						Properties: map[string]apiextv1.JSONSchemaProps{
+							"connection_pooler_auth_mode": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"lookup"`),
+									},
+									{
+										Raw: []byte(`"file"`),
+									},
+								},
+							},
+							"connection_pooler_autoscaling_metric": {
+								Type: "string",
+							},
 							"connection_pooler_default_cpu_limit": {
 								Type:    "string",
 								Pattern: "^(\\d+m|\\d+(\\.\\d{1,3})?)$",
@@ -1808,9 +2490,50 @@ var OperatorConfigCRDResourceValidation = apiextv1.CustomResourceValidation{
 								Type:    "integer",
 								Minimum: &min1,
 							},
+							"connection_pooler_odyssey_image": {
+								Type: "string",
+							},
+							"connection_pooler_pgbouncer_auth_file": {
+								Type: "string",
+							},
+							"connection_pooler_pgbouncer_stats_users_prefix": {
+								Type: "string",
+							},
+							"connection_pooler_pgbouncer_template_path": {
+								Type: "string",
+							},
+							"connection_pooler_pgbouncer_tls_certificate_file": {
+								Type: "string",
+							},
+							"connection_pooler_pgbouncer_tls_private_key_file": {
+								Type: "string",
+							},
+							"connection_pooler_pgcat_image": {
+								Type: "string",
+							},
 							"connection_pooler_schema": {
 								Type: "string",
 							},
+							"connection_pooler_spread_across_nodes": {
+								Type: "boolean",
+							},
+							"connection_pooler_spread_across_zones": {
+								Type: "boolean",
+							},
+							"connection_pooler_type": {
+								Type: "string",
+								Enum: []apiextv1.JSON{
+									{
+										Raw: []byte(`"pgbouncer"`),
+									},
+									{
+										Raw: []byte(`"pgcat"`),
+									},
+									{
+										Raw: []byte(`"odyssey"`),
+									},
+								},
+							},
 							"connection_pooler_user": {
 								Type: "string",
 							},
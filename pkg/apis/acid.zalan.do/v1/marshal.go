@@ -113,6 +113,12 @@ func (p *Postgresql) UnmarshalJSON(data []byte) error {
 	if err := validateCloneClusterDescription(tmp2.Spec.Clone); err != nil {
 		tmp2.Error = err.Error()
 		tmp2.Status.PostgresClusterStatus = ClusterStatusInvalid
+	} else if err := validatePgHbaRules(&tmp2.Spec); err != nil {
+		tmp2.Error = err.Error()
+		tmp2.Status.PostgresClusterStatus = ClusterStatusInvalid
+	} else if err := validateCertificateAuthentication(&tmp2.Spec); err != nil {
+		tmp2.Error = err.Error()
+		tmp2.Status.PostgresClusterStatus = ClusterStatusInvalid
 	}
 
 	*p = tmp2
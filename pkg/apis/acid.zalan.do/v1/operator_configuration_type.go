@@ -43,6 +43,8 @@ type PostgresUsersConfiguration struct {
 	EnablePasswordRotation        bool     `json:"enable_password_rotation,omitempty"`
 	PasswordRotationInterval      uint32   `json:"password_rotation_interval,omitempty"`
 	PasswordRotationUserRetention uint32   `json:"password_rotation_user_retention,omitempty"`
+	ClientCertificateValidity     Duration `json:"client_certificate_validity,omitempty"`
+	ClientCertificateRenewBefore  Duration `json:"client_certificate_renew_before,omitempty"`
 }
 
 // MajorVersionUpgradeConfiguration defines how to execute major version upgrades of Postgres.
@@ -70,9 +72,16 @@ type KubernetesMetaConfiguration struct {
 	PDBNameFormat                          config.StringTemplate        `json:"pdb_name_format,omitempty"`
 	EnablePodDisruptionBudget              *bool                        `json:"enable_pod_disruption_budget,omitempty"`
 	StorageResizeMode                      string                       `json:"storage_resize_mode,omitempty"`
+	StorageAutogrowTarget                  string                       `json:"storage_autogrow_target,omitempty"`
+	EnableVolumeRebuild                    bool                         `json:"enable_volume_rebuild,omitempty"`
+	PersistentVolumeClaimRetentionPolicy   map[string]string            `json:"persistent_volume_claim_retention_policy,omitempty"`
 	EnableInitContainers                   *bool                        `json:"enable_init_containers,omitempty"`
 	EnableSidecars                         *bool                        `json:"enable_sidecars,omitempty"`
 	SecretNameTemplate                     config.StringTemplate        `json:"secret_name_template,omitempty"`
+	ClientCertificateSecretNameTemplate    config.StringTemplate        `json:"client_certificate_secret_name_template,omitempty"`
+	ClientCASecretNameTemplate             config.StringTemplate        `json:"client_ca_secret_name_template,omitempty"`
+	EnableSecretConnectionKeys             bool                         `json:"enable_secret_connection_keys,omitempty"`
+	SecretConnectionKeyTemplate            config.StringTemplate        `json:"secret_connection_key_template,omitempty"`
 	ClusterDomain                          string                       `json:"cluster_domain,omitempty"`
 	OAuthTokenSecretName                   spec.NamespacedName          `json:"oauth_token_secret_name,omitempty"`
 	InfrastructureRolesSecretName          spec.NamespacedName          `json:"infrastructure_roles_secret_name,omitempty"`
@@ -153,12 +162,22 @@ type AWSGCPConfiguration struct {
 	AdditionalSecretMountPath    string `json:"additional_secret_mount_path" default:"/meta/credentials"`
 	EnableEBSGp3Migration        bool   `json:"enable_ebs_gp3_migration" default:"false"`
 	EnableEBSGp3MigrationMaxSize int64  `json:"enable_ebs_gp3_migration_max_size" default:"1000"`
+	EnableVolumeMigration        bool   `json:"enable_volume_migration,omitempty"`
+	VolumeMigrationSourceType    string `json:"volume_migration_source_type,omitempty"`
+	VolumeMigrationTargetType    string `json:"volume_migration_target_type,omitempty"`
+	VolumeMigrationIops          int64  `json:"volume_migration_iops,omitempty"`
+	VolumeMigrationThroughput    int64  `json:"volume_migration_throughput,omitempty"`
+	VolumeMigrationMaxSize       int64  `json:"volume_migration_max_size,omitempty"`
+	VolumeMigrationMaxConcurrent int    `json:"volume_migration_max_concurrent,omitempty"`
+	StorageResizeProvider        string `json:"storage_resize_provider,omitempty"`
 }
 
 // OperatorDebugConfiguration defines options for the debug mode
 type OperatorDebugConfiguration struct {
-	DebugLogging   bool `json:"debug_logging,omitempty"`
-	EnableDBAccess bool `json:"enable_database_access,omitempty"`
+	DebugLogging        bool   `json:"debug_logging,omitempty"`
+	EnableDBAccess      bool   `json:"enable_database_access,omitempty"`
+	TracingExporter     string `json:"tracing_exporter,omitempty"`
+	TracingOTLPEndpoint string `json:"tracing_otlp_endpoint,omitempty"`
 }
 
 // TeamsAPIConfiguration defines the configuration of TeamsAPI
@@ -181,9 +200,14 @@ type TeamsAPIConfiguration struct {
 
 // LoggingRESTAPIConfiguration defines Logging API conf
 type LoggingRESTAPIConfiguration struct {
-	APIPort               int `json:"api_port,omitempty"`
-	RingLogLines          int `json:"ring_log_lines,omitempty"`
-	ClusterHistoryEntries int `json:"cluster_history_entries,omitempty"`
+	APIPort                   int                 `json:"api_port,omitempty"`
+	RingLogLines              int                 `json:"ring_log_lines,omitempty"`
+	ClusterHistoryEntries     int                 `json:"cluster_history_entries,omitempty"`
+	ClusterHistoryStorage     string              `json:"cluster_history_storage,omitempty"`
+	ClusterHistoryStoragePath string              `json:"cluster_history_storage_path,omitempty"`
+	ClusterHistoryRetention   Duration            `json:"cluster_history_retention,omitempty"`
+	APITokenSecretName        spec.NamespacedName `json:"api_token_secret_name,omitempty"`
+	APITokenReviewUsers       []string            `json:"api_token_review_users,omitempty"`
 }
 
 // ScalyrConfiguration defines the configuration for ScalyrAPI
@@ -199,16 +223,28 @@ type ScalyrConfiguration struct {
 
 // ConnectionPoolerConfiguration defines default configuration for connection pooler
 type ConnectionPoolerConfiguration struct {
-	NumberOfInstances    *int32 `json:"connection_pooler_number_of_instances,omitempty"`
-	Schema               string `json:"connection_pooler_schema,omitempty"`
-	User                 string `json:"connection_pooler_user,omitempty"`
-	Image                string `json:"connection_pooler_image,omitempty"`
-	Mode                 string `json:"connection_pooler_mode,omitempty"`
-	MaxDBConnections     *int32 `json:"connection_pooler_max_db_connections,omitempty"`
-	DefaultCPURequest    string `json:"connection_pooler_default_cpu_request,omitempty"`
-	DefaultMemoryRequest string `json:"connection_pooler_default_memory_request,omitempty"`
-	DefaultCPULimit      string `json:"connection_pooler_default_cpu_limit,omitempty"`
-	DefaultMemoryLimit   string `json:"connection_pooler_default_memory_limit,omitempty"`
+	NumberOfInstances           *int32 `json:"connection_pooler_number_of_instances,omitempty"`
+	Schema                      string `json:"connection_pooler_schema,omitempty"`
+	User                        string `json:"connection_pooler_user,omitempty"`
+	Type                        string `json:"connection_pooler_type,omitempty"`
+	AuthMode                    string `json:"connection_pooler_auth_mode,omitempty"`
+	Image                       string `json:"connection_pooler_image,omitempty"`
+	PgcatImage                  string `json:"connection_pooler_pgcat_image,omitempty"`
+	OdysseyImage                string `json:"connection_pooler_odyssey_image,omitempty"`
+	PgbouncerTemplatePath       string `json:"connection_pooler_pgbouncer_template_path,omitempty"`
+	PgbouncerAuthFile           string `json:"connection_pooler_pgbouncer_auth_file,omitempty"`
+	PgbouncerStatsUsersPrefix   string `json:"connection_pooler_pgbouncer_stats_users_prefix,omitempty"`
+	PgbouncerTLSCertificateFile string `json:"connection_pooler_pgbouncer_tls_certificate_file,omitempty"`
+	PgbouncerTLSPrivateKeyFile  string `json:"connection_pooler_pgbouncer_tls_private_key_file,omitempty"`
+	Mode                        string `json:"connection_pooler_mode,omitempty"`
+	MaxDBConnections            *int32 `json:"connection_pooler_max_db_connections,omitempty"`
+	AutoscalingMetric           string `json:"connection_pooler_autoscaling_metric,omitempty"`
+	SpreadAcrossZones           bool   `json:"connection_pooler_spread_across_zones,omitempty"`
+	SpreadAcrossNodes           bool   `json:"connection_pooler_spread_across_nodes,omitempty"`
+	DefaultCPURequest           string `json:"connection_pooler_default_cpu_request,omitempty"`
+	DefaultMemoryRequest        string `json:"connection_pooler_default_memory_request,omitempty"`
+	DefaultCPULimit             string `json:"connection_pooler_default_cpu_limit,omitempty"`
+	DefaultMemoryLimit          string `json:"connection_pooler_default_memory_limit,omitempty"`
 }
 
 // OperatorLogicalBackupConfiguration defines configuration for logical backup
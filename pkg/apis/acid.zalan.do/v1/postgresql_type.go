@@ -58,9 +58,16 @@ type PostgresSpec struct {
 	// load balancers' source ranges are the same for master and replica services
 	AllowedSourceRanges []string `json:"allowedSourceRanges"`
 
-	Users                          map[string]UserFlags `json:"users,omitempty"`
-	UsersWithSecretRotation        []string             `json:"usersWithSecretRotation,omitempty"`
-	UsersWithInPlaceSecretRotation []string             `json:"usersWithInPlaceSecretRotation,omitempty"`
+	// structured pg_hba entries which are merged with the entries the operator requires
+	PgHbaRules []PgHbaRule `json:"pgHbaRules,omitempty"`
+
+	Users                              map[string]UserFlags `json:"users,omitempty"`
+	UsersWithSecretRotation            []string             `json:"usersWithSecretRotation,omitempty"`
+	UsersWithInPlaceSecretRotation     []string             `json:"usersWithInPlaceSecretRotation,omitempty"`
+	UsersWithCertificateAuthentication []string             `json:"usersWithCertificateAuthentication,omitempty"`
+
+	// passwords of manifest users taken from existing secrets instead of generated ones
+	UsersWithPasswordFromSecret map[string]PasswordSecretReference `json:"usersWithPasswordFromSecret,omitempty"`
 
 	NumberOfInstances     int32                       `json:"numberOfInstances"`
 	MaintenanceWindows    []MaintenanceWindow         `json:"maintenanceWindows,omitempty"`
@@ -81,9 +88,15 @@ type PostgresSpec struct {
 	ServiceAnnotations    map[string]string           `json:"serviceAnnotations,omitempty"`
 	TLS                   *TLSDescription             `json:"tls,omitempty"`
 	AdditionalVolumes     []AdditionalVolume          `json:"additionalVolumes,omitempty"`
+	WALVolume             *Volume                     `json:"walVolume,omitempty"`
+	Tablespaces           []Tablespace                `json:"tablespaces,omitempty"`
+	VolumeSnapshots       *VolumeSnapshotDescription  `json:"volumeSnapshots,omitempty"`
 	Streams               []Stream                    `json:"streams,omitempty"`
 	Env                   []v1.EnvVar                 `json:"env,omitempty"`
 
+	// keep or delete volume claims of deleted pods, the operator configuration applies to omitted fields
+	PersistentVolumeClaimRetentionPolicy *PersistentVolumeClaimRetentionPolicy `json:"persistentVolumeClaimRetentionPolicy,omitempty"`
+
 	// deprecated json tags
 	InitContainersOld       []v1.Container `json:"init_containers,omitempty"`
 	PodPriorityClassNameOld string         `json:"pod_priority_class_name,omitempty"`
@@ -130,6 +143,29 @@ type Volume struct {
 	Iops         *int64                `json:"iops,omitempty"`
 	Throughput   *int64                `json:"throughput,omitempty"`
 	VolumeType   string                `json:"type,omitempty"`
+	Autogrow     *VolumeAutogrow       `json:"autogrow,omitempty"`
+}
+
+// VolumeAutogrow describes when and by how much the operator grows the data volume on its own
+type VolumeAutogrow struct {
+	ThresholdPercent *int32 `json:"thresholdPercent,omitempty"`
+	Step             string `json:"step,omitempty"`
+	MaxSize          string `json:"maxSize"`
+}
+
+// VolumeSnapshotDescription describes periodic CSI snapshots of the data volume of a replica
+type VolumeSnapshotDescription struct {
+	VolumeSnapshotClass string `json:"volumeSnapshotClass,omitempty"`
+	Interval            string `json:"interval,omitempty"`
+	Retention           *int32 `json:"retention,omitempty"`
+	BootstrapReplicas   bool   `json:"bootstrapReplicas,omitempty"`
+}
+
+// PersistentVolumeClaimRetentionPolicy describes whether volume claims are retained or deleted
+// when the cluster is deleted or scaled down
+type PersistentVolumeClaimRetentionPolicy struct {
+	WhenDeleted string `json:"whenDeleted,omitempty"`
+	WhenScaled  string `json:"whenScaled,omitempty"`
 }
 
 // AdditionalVolume specs additional optional volumes for statefulset
@@ -141,6 +177,13 @@ type AdditionalVolume struct {
 	VolumeSource     v1.VolumeSource `json:"volumeSource"`
 }
 
+// Tablespace describes a tablespace located on a separate persistent volume of every pod
+type Tablespace struct {
+	Name   string `json:"name"`
+	Owner  string `json:"owner,omitempty"`
+	Volume Volume `json:"volume"`
+}
+
 // PostgresqlParam describes PostgreSQL version and pairs of configuration parameter name - values.
 type PostgresqlParam struct {
 	PgVersion  string            `json:"version"`
@@ -174,6 +217,24 @@ type Patroni struct {
 	FailsafeMode          *bool                        `json:"failsafe_mode,omitempty"`
 }
 
+// PgHbaRule describes a single pg_hba entry. Instead of an address the
+// allowedSourceRanges of the cluster can be referenced, which generates one
+// entry per range.
+type PgHbaRule struct {
+	Type                   string `json:"type"`
+	Database               string `json:"database,omitempty" defaults:"all"`
+	User                   string `json:"user,omitempty" defaults:"all"`
+	Address                string `json:"address,omitempty"`
+	UseAllowedSourceRanges bool   `json:"useAllowedSourceRanges,omitempty"`
+	Method                 string `json:"method"`
+}
+
+// PasswordSecretReference points to a key of an existing secret holding a password
+type PasswordSecretReference struct {
+	SecretName string `json:"secretName"`
+	Key        string `json:"key"`
+}
+
 // StandbyDescription contains remote primary config or s3/gs wal path
 type StandbyDescription struct {
 	S3WalPath   string `json:"s3_wal_path,omitempty"`
@@ -201,6 +262,7 @@ type CloneDescription struct {
 	S3AccessKeyId     string `json:"s3_access_key_id,omitempty"`
 	S3SecretAccessKey string `json:"s3_secret_access_key,omitempty"`
 	S3ForcePathStyle  *bool  `json:"s3_force_path_style,omitempty" defaults:"false"`
+	VolumeSnapshot    string `json:"volumeSnapshot,omitempty"`
 }
 
 // Sidecar defines a container to be run in the same pod as the Postgres container.
@@ -218,19 +280,13 @@ type UserFlags []string
 // PostgresStatus contains status of the PostgreSQL cluster (running, creation failed etc.)
 type PostgresStatus struct {
 	PostgresClusterStatus string `json:"PostgresClusterStatus"`
+	VolumeSize            string `json:"volumeSize,omitempty"`
 }
 
 // ConnectionPooler Options for connection pooler
-//
-// TODO: prepared snippets of configuration, one can choose via type, e.g.
-// pgbouncer-large (with higher resources) or odyssey-small (with smaller
-// resources)
-// Type              string `json:"type,omitempty"`
-//
-// TODO: figure out what other important parameters of the connection pooler it
-// makes sense to expose. E.g. pool size (min/max boundaries), max client
-// connections etc.
 type ConnectionPooler struct {
+	Type              string `json:"type,omitempty"`
+	AuthMode          string `json:"authMode,omitempty"`
 	NumberOfInstances *int32 `json:"numberOfInstances,omitempty"`
 	Schema            string `json:"schema,omitempty"`
 	User              string `json:"user,omitempty"`
@@ -238,9 +294,42 @@ type ConnectionPooler struct {
 	DockerImage       string `json:"dockerImage,omitempty"`
 	MaxDBConnections  *int32 `json:"maxDBConnections,omitempty"`
 
+	// pool settings, derived from maxDBConnections if not set
+	DefaultPoolSize      *int32 `json:"defaultPoolSize,omitempty"`
+	ReservePoolSize      *int32 `json:"reservePoolSize,omitempty"`
+	MaxClientConnections *int32 `json:"maxClientConnections,omitempty"`
+
+	// timeouts in seconds, the pooler defaults apply if not set
+	ServerIdleTimeout *int32 `json:"serverIdleTimeout,omitempty"`
+	ServerLifetime    *int32 `json:"serverLifetime,omitempty"`
+
+	IgnoreStartupParameters []string          `json:"ignoreStartupParameters,omitempty"`
+	DatabasePoolModes       map[string]string `json:"databasePoolModes,omitempty"`
+
+	Autoscaling *ConnectionPoolerAutoscaling `json:"autoscaling,omitempty"`
+
+	// spread pods across nodes and zones, the operator configuration applies if not set
+	SpreadAcrossNodes *bool `json:"spreadAcrossNodes,omitempty"`
+	SpreadAcrossZones *bool `json:"spreadAcrossZones,omitempty"`
+
+	// certificate for client connections, the cluster's tls section is used if not set
+	TLS *TLSDescription `json:"tls,omitempty"`
+	// sslmode of client and server connections, require if a certificate is used
+	ClientTLSMode string `json:"clientTLSMode,omitempty"`
+	ServerTLSMode string `json:"serverTLSMode,omitempty"`
+
 	*Resources `json:"resources,omitempty"`
 }
 
+// ConnectionPoolerAutoscaling describes the HorizontalPodAutoscaler of a
+// connection pooler. Without a target the average CPU utilization is used.
+type ConnectionPoolerAutoscaling struct {
+	MinInstances            *int32 `json:"minInstances,omitempty"`
+	MaxInstances            int32  `json:"maxInstances"`
+	TargetCPUUtilization    *int32 `json:"targetCPUUtilization,omitempty"`
+	TargetClientConnections *int32 `json:"targetClientConnections,omitempty"`
+}
+
 // Stream defines properties for creating FabricEventStream resources
 type Stream struct {
 	ApplicationId string                 `json:"applicationId"`
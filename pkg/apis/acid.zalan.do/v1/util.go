@@ -12,6 +12,9 @@ import (
 var (
 	weekdays         = map[string]int{"Sun": 0, "Mon": 1, "Tue": 2, "Wed": 3, "Thu": 4, "Fri": 5, "Sat": 6}
 	serviceNameRegex = regexp.MustCompile(serviceNameRegexString)
+	pgHbaMethods     = map[string]bool{"trust": true, "reject": true, "scram-sha-256": true, "md5": true,
+		"password": true, "gss": true, "sspi": true, "ident": true, "peer": true, "ldap": true,
+		"radius": true, "cert": true, "pam": true, "bsd": true}
 )
 
 // Clone convenience wrapper around DeepCopy
@@ -84,6 +87,55 @@ func validateCloneClusterDescription(clone *CloneDescription) error {
 	return nil
 }
 
+// validateCertificateAuthentication rejects client certificates together with a
+// custom CA file, since Postgres would not trust the CA issuing them
+func validateCertificateAuthentication(spec *PostgresSpec) error {
+	if len(spec.UsersWithCertificateAuthentication) > 0 && spec.TLS != nil && spec.TLS.CAFile != "" {
+		return fmt.Errorf("usersWithCertificateAuthentication cannot be combined with a custom tls.caFile")
+	}
+	return nil
+}
+
+func validatePgHbaRules(spec *PostgresSpec) error {
+	if len(spec.PgHbaRules) == 0 {
+		return nil
+	}
+	if len(spec.Patroni.PgHba) > 0 {
+		return fmt.Errorf("pgHbaRules cannot be combined with patroni.pg_hba")
+	}
+
+	for i, rule := range spec.PgHbaRules {
+		for _, field := range []string{rule.Type, rule.Database, rule.User, rule.Address, rule.Method} {
+			if strings.ContainsAny(field, " \t\n#") {
+				return fmt.Errorf("pg_hba rule %d must not contain whitespace or comments: %q", i, field)
+			}
+		}
+		switch rule.Type {
+		case "local":
+			if rule.Address != "" || rule.UseAllowedSourceRanges {
+				return fmt.Errorf("pg_hba rule %d of type local cannot have an address", i)
+			}
+		case "host", "hostssl", "hostnossl":
+			if rule.Address == "" && !rule.UseAllowedSourceRanges {
+				return fmt.Errorf("pg_hba rule %d of type %s requires an address or useAllowedSourceRanges", i, rule.Type)
+			}
+			if rule.Address != "" && rule.UseAllowedSourceRanges {
+				return fmt.Errorf("pg_hba rule %d cannot have an address and useAllowedSourceRanges at the same time", i)
+			}
+		default:
+			return fmt.Errorf("pg_hba rule %d has an unsupported type %q", i, rule.Type)
+		}
+		if !pgHbaMethods[rule.Method] {
+			return fmt.Errorf("pg_hba rule %d has an unsupported method %q", i, rule.Method)
+		}
+		if rule.Method == "cert" && rule.Type != "hostssl" {
+			return fmt.Errorf("pg_hba rule %d with method cert requires type hostssl", i)
+		}
+	}
+
+	return nil
+}
+
 // Success of the current Status
 func (postgresStatus PostgresStatus) Success() bool {
 	return postgresStatus.PostgresClusterStatus != ClusterStatusAddFailed &&
@@ -66,12 +66,58 @@ var cloneClusterDescriptions = []struct {
 	in    *CloneDescription
 	err   error
 }{
-	{"cluster name invalid but EndTimeSet is not empty", &CloneDescription{"foo+bar", "", "NotEmpty", "", "", "", "", nil}, nil},
-	{"expect error as cluster name does not match DNS-1035", &CloneDescription{"foo+bar", "", "", "", "", "", "", nil},
+	{"cluster name invalid but EndTimeSet is not empty", &CloneDescription{"foo+bar", "", "NotEmpty", "", "", "", "", nil, ""}, nil},
+	{"expect error as cluster name does not match DNS-1035", &CloneDescription{"foo+bar", "", "", "", "", "", "", nil, ""},
 		errors.New(`clone cluster name must confirm to DNS-1035, regex used for validation is "^[a-z]([-a-z0-9]*[a-z0-9])?$"`)},
-	{"expect error as cluster name is too long", &CloneDescription{"foobar123456789012345678901234567890123456789012345678901234567890", "", "", "", "", "", "", nil},
+	{"expect error as cluster name is too long", &CloneDescription{"foobar123456789012345678901234567890123456789012345678901234567890", "", "", "", "", "", "", nil, ""},
 		errors.New("clone cluster name must be no longer than 63 characters")},
-	{"common cluster name", &CloneDescription{"foobar", "", "", "", "", "", "", nil}, nil},
+	{"common cluster name", &CloneDescription{"foobar", "", "", "", "", "", "", nil, ""}, nil},
+}
+
+var pgHbaRules = []struct {
+	about string
+	in    PostgresSpec
+	err   error
+}{
+	{"no rules", PostgresSpec{}, nil},
+	{"valid rules", PostgresSpec{PgHbaRules: []PgHbaRule{
+		{Type: "local", Database: "all", User: "all", Method: "peer"},
+		{Type: "hostssl", Database: "app", User: "app_user", Address: "10.0.0.0/8", Method: "scram-sha-256"},
+		{Type: "hostssl", UseAllowedSourceRanges: true, Method: "md5"},
+	}}, nil},
+	{"expect error as rules and raw pg_hba are combined", PostgresSpec{
+		Patroni:    Patroni{PgHba: []string{"hostssl all all all md5"}},
+		PgHbaRules: []PgHbaRule{{Type: "local", Method: "trust"}}},
+		errors.New("pgHbaRules cannot be combined with patroni.pg_hba")},
+	{"expect error as local rule has an address", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "local", Address: "all", Method: "trust"}}},
+		errors.New("pg_hba rule 0 of type local cannot have an address")},
+	{"expect error as host rule has no address", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "host", Method: "md5"}}},
+		errors.New("pg_hba rule 0 of type host requires an address or useAllowedSourceRanges")},
+	{"expect error as host rule has address and source ranges", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "host", Address: "all", UseAllowedSourceRanges: true, Method: "md5"}}},
+		errors.New("pg_hba rule 0 cannot have an address and useAllowedSourceRanges at the same time")},
+	{"expect error as field contains whitespace", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "host", User: "foo all", Address: "all", Method: "md5"}}},
+		errors.New(`pg_hba rule 0 must not contain whitespace or comments: "foo all"`)},
+	{"expect error as type is unknown", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "hostgssenc", Address: "all", Method: "md5"}}},
+		errors.New(`pg_hba rule 0 has an unsupported type "hostgssenc"`)},
+	{"expect error as method is unknown", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "local", Method: "foo"}}},
+		errors.New(`pg_hba rule 0 has an unsupported method "foo"`)},
+	{"expect error as cert method is used without SSL", PostgresSpec{PgHbaRules: []PgHbaRule{{Type: "host", Address: "all", Method: "cert"}}},
+		errors.New("pg_hba rule 0 with method cert requires type hostssl")},
+}
+
+var certificateAuthentication = []struct {
+	about string
+	in    PostgresSpec
+	err   error
+}{
+	{"no certificate users", PostgresSpec{TLS: &TLSDescription{SecretName: "tls", CAFile: "ca.crt"}}, nil},
+	{"certificate users with the client CA", PostgresSpec{
+		UsersWithCertificateAuthentication: []string{"app_user"},
+		TLS:                                &TLSDescription{SecretName: "tls"}}, nil},
+	{"expect error as certificate users are combined with a custom CA file", PostgresSpec{
+		UsersWithCertificateAuthentication: []string{"app_user"},
+		TLS:                                &TLSDescription{SecretName: "tls", CAFile: "ca.crt"}},
+		errors.New("usersWithCertificateAuthentication cannot be combined with a custom tls.caFile")},
 }
 
 var maintenanceWindows = []struct {
@@ -638,6 +684,34 @@ func TestCloneClusterDescription(t *testing.T) {
 	}
 }
 
+func TestPgHbaRules(t *testing.T) {
+	for _, tt := range pgHbaRules {
+		t.Run(tt.about, func(t *testing.T) {
+			if err := validatePgHbaRules(&tt.in); err != nil {
+				if tt.err == nil || err.Error() != tt.err.Error() {
+					t.Errorf("testPgHbaRules expected error: %v, got: %v", tt.err, err)
+				}
+			} else if tt.err != nil {
+				t.Errorf("Expected error: %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestCertificateAuthentication(t *testing.T) {
+	for _, tt := range certificateAuthentication {
+		t.Run(tt.about, func(t *testing.T) {
+			if err := validateCertificateAuthentication(&tt.in); err != nil {
+				if tt.err == nil || err.Error() != tt.err.Error() {
+					t.Errorf("testCertificateAuthentication expected error: %v, got: %v", tt.err, err)
+				}
+			} else if tt.err != nil {
+				t.Errorf("Expected error: %v", tt.err)
+			}
+		})
+	}
+}
+
 func TestUnmarshalMaintenanceWindow(t *testing.T) {
 	for _, tt := range maintenanceWindows {
 		t.Run(tt.about, func(t *testing.T) {
@@ -106,6 +106,63 @@ func (in *ConnectionPooler) DeepCopyInto(out *ConnectionPooler) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.DefaultPoolSize != nil {
+		in, out := &in.DefaultPoolSize, &out.DefaultPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReservePoolSize != nil {
+		in, out := &in.ReservePoolSize, &out.ReservePoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxClientConnections != nil {
+		in, out := &in.MaxClientConnections, &out.MaxClientConnections
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerIdleTimeout != nil {
+		in, out := &in.ServerIdleTimeout, &out.ServerIdleTimeout
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerLifetime != nil {
+		in, out := &in.ServerLifetime, &out.ServerLifetime
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IgnoreStartupParameters != nil {
+		in, out := &in.IgnoreStartupParameters, &out.IgnoreStartupParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatabasePoolModes != nil {
+		in, out := &in.DatabasePoolModes, &out.DatabasePoolModes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(ConnectionPoolerAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SpreadAcrossNodes != nil {
+		in, out := &in.SpreadAcrossNodes, &out.SpreadAcrossNodes
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SpreadAcrossZones != nil {
+		in, out := &in.SpreadAcrossZones, &out.SpreadAcrossZones
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSDescription)
+		**out = **in
+	}
 	if in.Resources != nil {
 		in, out := &in.Resources, &out.Resources
 		*out = new(Resources)
@@ -124,6 +181,37 @@ func (in *ConnectionPooler) DeepCopy() *ConnectionPooler {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionPoolerAutoscaling) DeepCopyInto(out *ConnectionPoolerAutoscaling) {
+	*out = *in
+	if in.MinInstances != nil {
+		in, out := &in.MinInstances, &out.MinInstances
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetCPUUtilization != nil {
+		in, out := &in.TargetCPUUtilization, &out.TargetCPUUtilization
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TargetClientConnections != nil {
+		in, out := &in.TargetClientConnections, &out.TargetClientConnections
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionPoolerAutoscaling.
+func (in *ConnectionPoolerAutoscaling) DeepCopy() *ConnectionPoolerAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionPoolerAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConnectionPoolerConfiguration) DeepCopyInto(out *ConnectionPoolerConfiguration) {
 	*out = *in
@@ -183,6 +271,13 @@ func (in *KubernetesMetaConfiguration) DeepCopyInto(out *KubernetesMetaConfigura
 		*out = new(bool)
 		**out = **in
 	}
+	if in.PersistentVolumeClaimRetentionPolicy != nil {
+		in, out := &in.PersistentVolumeClaimRetentionPolicy, &out.PersistentVolumeClaimRetentionPolicy
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.EnableInitContainers != nil {
 		in, out := &in.EnableInitContainers, &out.EnableInitContainers
 		*out = new(bool)
@@ -294,6 +389,12 @@ func (in *LoadBalancerConfiguration) DeepCopy() *LoadBalancerConfiguration {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoggingRESTAPIConfiguration) DeepCopyInto(out *LoggingRESTAPIConfiguration) {
 	*out = *in
+	out.APITokenSecretName = in.APITokenSecretName
+	if in.APITokenReviewUsers != nil {
+		in, out := &in.APITokenReviewUsers, &out.APITokenReviewUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -419,7 +520,7 @@ func (in *OperatorConfigurationData) DeepCopyInto(out *OperatorConfigurationData
 	out.AWSGCP = in.AWSGCP
 	out.OperatorDebug = in.OperatorDebug
 	in.TeamsAPI.DeepCopyInto(&out.TeamsAPI)
-	out.LoggingRESTAPI = in.LoggingRESTAPI
+	in.LoggingRESTAPI.DeepCopyInto(&out.LoggingRESTAPI)
 	out.Scalyr = in.Scalyr
 	out.LogicalBackup = in.LogicalBackup
 	in.ConnectionPooler.DeepCopyInto(&out.ConnectionPooler)
@@ -518,6 +619,22 @@ func (in *OperatorTimeouts) DeepCopy() *OperatorTimeouts {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PasswordSecretReference) DeepCopyInto(out *PasswordSecretReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PasswordSecretReference.
+func (in *PasswordSecretReference) DeepCopy() *PasswordSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(PasswordSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Patroni) DeepCopyInto(out *Patroni) {
 	*out = *in
@@ -589,6 +706,38 @@ func (in *PatroniConfiguration) DeepCopy() *PatroniConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeClaimRetentionPolicy) DeepCopyInto(out *PersistentVolumeClaimRetentionPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimRetentionPolicy.
+func (in *PersistentVolumeClaimRetentionPolicy) DeepCopy() *PersistentVolumeClaimRetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeClaimRetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgHbaRule) DeepCopyInto(out *PgHbaRule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgHbaRule.
+func (in *PgHbaRule) DeepCopy() *PgHbaRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PgHbaRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PostgresPodResourcesDefaults) DeepCopyInto(out *PostgresPodResourcesDefaults) {
 	*out = *in
@@ -681,6 +830,11 @@ func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PgHbaRules != nil {
+		in, out := &in.PgHbaRules, &out.PgHbaRules
+		*out = make([]PgHbaRule, len(*in))
+		copy(*out, *in)
+	}
 	if in.Users != nil {
 		in, out := &in.Users, &out.Users
 		*out = make(map[string]UserFlags, len(*in))
@@ -706,6 +860,18 @@ func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UsersWithCertificateAuthentication != nil {
+		in, out := &in.UsersWithCertificateAuthentication, &out.UsersWithCertificateAuthentication
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UsersWithPasswordFromSecret != nil {
+		in, out := &in.UsersWithPasswordFromSecret, &out.UsersWithPasswordFromSecret
+		*out = make(map[string]PasswordSecretReference, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.MaintenanceWindows != nil {
 		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
 		*out = make([]MaintenanceWindow, len(*in))
@@ -799,6 +965,23 @@ func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.WALVolume != nil {
+		in, out := &in.WALVolume, &out.WALVolume
+		*out = new(Volume)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tablespaces != nil {
+		in, out := &in.Tablespaces, &out.Tablespaces
+		*out = make([]Tablespace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = new(VolumeSnapshotDescription)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Streams != nil {
 		in, out := &in.Streams, &out.Streams
 		*out = make([]Stream, len(*in))
@@ -813,6 +996,11 @@ func (in *PostgresSpec) DeepCopyInto(out *PostgresSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.PersistentVolumeClaimRetentionPolicy != nil {
+		in, out := &in.PersistentVolumeClaimRetentionPolicy, &out.PersistentVolumeClaimRetentionPolicy
+		*out = new(PersistentVolumeClaimRetentionPolicy)
+		**out = **in
+	}
 	if in.InitContainersOld != nil {
 		in, out := &in.InitContainersOld, &out.InitContainersOld
 		*out = make([]corev1.Container, len(*in))
@@ -1310,6 +1498,23 @@ func (in *TLSDescription) DeepCopy() *TLSDescription {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tablespace) DeepCopyInto(out *Tablespace) {
+	*out = *in
+	in.Volume.DeepCopyInto(&out.Volume)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tablespace.
+func (in *Tablespace) DeepCopy() *Tablespace {
+	if in == nil {
+		return nil
+	}
+	out := new(Tablespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TeamsAPIConfiguration) DeepCopyInto(out *TeamsAPIConfiguration) {
 	*out = *in
@@ -1381,6 +1586,11 @@ func (in *Volume) DeepCopyInto(out *Volume) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.Autogrow != nil {
+		in, out := &in.Autogrow, &out.Autogrow
+		*out = new(VolumeAutogrow)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1393,3 +1603,45 @@ func (in *Volume) DeepCopy() *Volume {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeAutogrow) DeepCopyInto(out *VolumeAutogrow) {
+	*out = *in
+	if in.ThresholdPercent != nil {
+		in, out := &in.ThresholdPercent, &out.ThresholdPercent
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeAutogrow.
+func (in *VolumeAutogrow) DeepCopy() *VolumeAutogrow {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeAutogrow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeSnapshotDescription) DeepCopyInto(out *VolumeSnapshotDescription) {
+	*out = *in
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSnapshotDescription.
+func (in *VolumeSnapshotDescription) DeepCopy() *VolumeSnapshotDescription {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshotDescription)
+	in.DeepCopyInto(out)
+	return out
+}
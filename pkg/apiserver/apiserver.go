@@ -3,11 +3,14 @@ package apiserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/pprof"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,9 +23,10 @@ import (
 )
 
 const (
-	httpAPITimeout  = time.Minute * 1
-	shutdownTimeout = time.Second * 10
-	httpReadTimeout = time.Millisecond * 100
+	httpAPITimeout     = time.Minute * 1
+	shutdownTimeout    = time.Second * 10
+	httpReadTimeout    = time.Millisecond * 100
+	watchKeepAliveTime = time.Second * 15
 )
 
 // ControllerInformer describes stats methods of a controller
@@ -32,6 +36,7 @@ type controllerInformer interface {
 	GetStatus() *spec.ControllerStatus
 	TeamClusterList() map[string][]spec.NamespacedName
 	ClusterStatus(namespace, cluster string) (*cluster.ClusterStatus, error)
+	ClusterMembers(namespace, cluster string) ([]cluster.MemberStatus, error)
 	ClusterLogs(namespace, cluster string) ([]*spec.LogEntry, error)
 	ClusterHistory(namespace, cluster string) ([]*spec.Diff, error)
 	ClusterDatabasesMap() map[string][]string
@@ -39,6 +44,9 @@ type controllerInformer interface {
 	ListQueue(workerID uint32) (*spec.QueueDump, error)
 	GetWorkersCnt() uint32
 	WorkerStatus(workerID uint32) (*cluster.WorkerStatus, error)
+	AuthenticateAPIToken(token string) (string, error)
+	QueueClusterAction(namespace, cluster, action, candidate, caller string) (uint32, error)
+	WatchClusters(namespace, cluster string) (<-chan spec.WatchEvent, func(), error)
 }
 
 // Server describes HTTP API server
@@ -46,30 +54,39 @@ type Server struct {
 	logger     *logrus.Entry
 	http       http.Server
 	controller controllerInformer
+	done       chan struct{} // closed on shutdown to end the watch streams
 }
 
 const (
 	teamRe      = `(?P<team>[a-zA-Z][a-zA-Z0-9\-_]*)`
 	namespaceRe = `(?P<namespace>[a-z0-9]([-a-z0-9\-_]*[a-z0-9])?)`
 	clusterRe   = `(?P<cluster>[a-zA-Z][a-zA-Z0-9\-_]*)`
+	actionRe    = `(?P<action>sync|switchover|restart|reload|rotate-passwords)`
 )
 
 var (
 	clusterStatusRe  = fmt.Sprintf(`^/clusters/%s/%s/?$`, namespaceRe, clusterRe)
 	clusterLogsRe    = fmt.Sprintf(`^/clusters/%s/%s/logs/?$`, namespaceRe, clusterRe)
 	clusterHistoryRe = fmt.Sprintf(`^/clusters/%s/%s/history/?$`, namespaceRe, clusterRe)
+	clusterMembersRe = fmt.Sprintf(`^/clusters/%s/%s/members/?$`, namespaceRe, clusterRe)
 	teamURLRe        = fmt.Sprintf(`^/clusters/%s/?$`, teamRe)
+	clusterActionRe  = fmt.Sprintf(`^/clusters/%s/%s/%s/?$`, namespaceRe, clusterRe, actionRe)
+	clusterWatchRe   = fmt.Sprintf(`^/watch/clusters/%s/%s/?$`, namespaceRe, clusterRe)
 
 	clusterStatusURL     = regexp.MustCompile(clusterStatusRe)
 	clusterLogsURL       = regexp.MustCompile(clusterLogsRe)
 	clusterHistoryURL    = regexp.MustCompile(clusterHistoryRe)
+	clusterMembersURL    = regexp.MustCompile(clusterMembersRe)
 	teamURL              = regexp.MustCompile(teamURLRe)
+	clusterActionURL     = regexp.MustCompile(clusterActionRe)
+	clusterWatchURL      = regexp.MustCompile(clusterWatchRe)
 	workerLogsURL        = regexp.MustCompile(`^/workers/(?P<id>\d+)/logs/?$`)
 	workerEventsQueueURL = regexp.MustCompile(`^/workers/(?P<id>\d+)/queue/?$`)
 	workerStatusURL      = regexp.MustCompile(`^/workers/(?P<id>\d+)/status/?$`)
 	workerAllQueue       = regexp.MustCompile(`^/workers/all/queue/?$`)
 	workerAllStatus      = regexp.MustCompile(`^/workers/all/status/?$`)
 	clustersURL          = "/clusters/"
+	clustersWatchURL     = "/watch/clusters/"
 )
 
 // New creates new HTTP API server
@@ -77,6 +94,7 @@ func New(controller controllerInformer, port int, logger *logrus.Logger) *Server
 	s := &Server{
 		logger:     logger.WithField("pkg", "apiserver"),
 		controller: controller,
+		done:       make(chan struct{}),
 	}
 	mux := http.NewServeMux()
 
@@ -93,11 +111,19 @@ func New(controller controllerInformer, port int, logger *logrus.Logger) *Server
 	mux.HandleFunc("/workers/", s.workers)
 	mux.HandleFunc("/databases/", s.databases)
 
+	// watch streams stay open, so they must not be cut off by the timeout of the other endpoints
+	handler := http.NewServeMux()
+	handler.HandleFunc("/watch/", s.watch)
+	handler.Handle("/", http.TimeoutHandler(mux, httpAPITimeout, ""))
+
 	s.http = http.Server{
 		Addr:        fmt.Sprintf(":%d", port),
-		Handler:     http.TimeoutHandler(mux, httpAPITimeout, ""),
+		Handler:     handler,
 		ReadTimeout: httpReadTimeout,
 	}
+	s.http.RegisterOnShutdown(func() {
+		close(s.done)
+	})
 
 	return s
 }
@@ -135,15 +161,12 @@ func (s *Server) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 }
 
 func (s *Server) respond(obj interface{}, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json")
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		if err2 := json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()}); err2 != nil {
-			s.logger.Errorf("could not encode error response %q: %v", err, err2)
-		}
+		s.respondError(http.StatusInternalServerError, err, w)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(obj)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -151,6 +174,14 @@ func (s *Server) respond(obj interface{}, err error, w http.ResponseWriter) {
 	}
 }
 
+func (s *Server) respondError(status int, err error, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err2 := json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()}); err2 != nil {
+		s.logger.Errorf("could not encode error response %q: %v", err, err2)
+	}
+}
+
 func (s *Server) controllerStatus(w http.ResponseWriter, req *http.Request) {
 	s.respond(s.controller.GetStatus(), nil, w)
 }
@@ -168,6 +199,11 @@ func (s *Server) clusters(w http.ResponseWriter, req *http.Request) {
 		err  error
 	)
 
+	if req.Method == http.MethodPost {
+		s.clusterAction(w, req)
+		return
+	}
+
 	if matches := util.FindNamedStringSubmatch(clusterStatusURL, req.URL.Path); matches != nil {
 		namespace := matches["namespace"]
 		resp, err = s.controller.ClusterStatus(namespace, matches["cluster"])
@@ -191,6 +227,9 @@ func (s *Server) clusters(w http.ResponseWriter, req *http.Request) {
 	} else if matches := util.FindNamedStringSubmatch(clusterHistoryURL, req.URL.Path); matches != nil {
 		namespace := matches["namespace"]
 		resp, err = s.controller.ClusterHistory(namespace, matches["cluster"])
+	} else if matches := util.FindNamedStringSubmatch(clusterMembersURL, req.URL.Path); matches != nil {
+		namespace := matches["namespace"]
+		resp, err = s.controller.ClusterMembers(namespace, matches["cluster"])
 	} else if req.URL.Path == clustersURL {
 		clusterNamesPerTeam := make(map[string][]string)
 		for team, clusters := range s.controller.TeamClusterList() {
@@ -206,6 +245,124 @@ func (s *Server) clusters(w http.ResponseWriter, req *http.Request) {
 	s.respond(resp, err, w)
 }
 
+// clusterAction queues an action for the cluster, e.g. a switchover, after authenticating the caller
+func (s *Server) clusterAction(w http.ResponseWriter, req *http.Request) {
+	matches := util.FindNamedStringSubmatch(clusterActionURL, req.URL.Path)
+	if matches == nil {
+		s.respondError(http.StatusNotFound, fmt.Errorf("page not found"), w)
+		return
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	caller, err := s.controller.AuthenticateAPIToken(token)
+	if err != nil {
+		s.logger.Warningf("rejected %s request for %s: %v", matches["action"], req.URL.Path, err)
+		s.respondError(http.StatusUnauthorized, err, w)
+		return
+	}
+
+	var body struct {
+		Candidate string `json:"candidate"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && err != io.EOF {
+		s.respondError(http.StatusBadRequest, fmt.Errorf("could not decode request body: %v", err), w)
+		return
+	}
+
+	workerID, err := s.controller.QueueClusterAction(matches["namespace"], matches["cluster"], matches["action"], body.Candidate, caller)
+	if errors.Is(err, spec.ErrClusterNotFound) {
+		s.respondError(http.StatusNotFound, err, w)
+		return
+	}
+	if err != nil {
+		s.respondError(http.StatusBadRequest, err, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err = json.NewEncoder(w).Encode(map[string]interface{}{
+		"action": matches["action"],
+		"worker": workerID,
+	}); err != nil {
+		s.logger.Errorf("Could not encode: %v", err)
+	}
+}
+
+// watch streams changes of the status, the current process and the history of clusters.
+// Clients asking for text/event-stream get server-sent events, all others get one JSON
+// object per line. Empty lines and comments are sent regularly to keep the connection open.
+func (s *Server) watch(w http.ResponseWriter, req *http.Request) {
+	var namespace, clusterName string
+	if matches := util.FindNamedStringSubmatch(clusterWatchURL, req.URL.Path); matches != nil {
+		namespace, clusterName = matches["namespace"], matches["cluster"]
+	} else if req.URL.Path != clustersWatchURL {
+		s.respondError(http.StatusNotFound, fmt.Errorf("page not found"), w)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.respondError(http.StatusInternalServerError, fmt.Errorf("streaming is not supported"), w)
+		return
+	}
+
+	events, stop, err := s.controller.WatchClusters(namespace, clusterName)
+	if err != nil {
+		s.respondError(http.StatusNotFound, err, w)
+		return
+	}
+	defer stop()
+
+	eventStream := strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+	if eventStream {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// the request context is canceled once the short read timeout of the server expires,
+	// so a client closing the connection is only noticed when writing to it fails
+	keepAlive := time.NewTicker(watchKeepAliveTime)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// the client did not keep up with the events and has to watch again
+				return
+			}
+			data, err2 := json.Marshal(event)
+			if err2 != nil {
+				s.logger.Errorf("could not encode watch event: %v", err2)
+				continue
+			}
+			if eventStream {
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", data)
+			}
+		case <-keepAlive.C:
+			if eventStream {
+				_, err = io.WriteString(w, ": keep-alive\n\n")
+			} else {
+				_, err = io.WriteString(w, "\n")
+			}
+		case <-s.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
 func mustConvertToUint32(s string) uint32 {
 	result, err := strconv.Atoi(s)
 	if err != nil {
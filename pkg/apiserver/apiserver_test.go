@@ -1,13 +1,23 @@
 package apiserver
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zalando/postgres-operator/pkg/spec"
 )
 
 const (
 	clusterStatusTest        = "/clusters/test-namespace/testcluster/"
 	clusterStatusNumericTest = "/clusters/test-namespace-1/testcluster/"
 	clusterLogsTest          = "/clusters/test-namespace/testcluster/logs/"
+	clusterMembersTest       = "/clusters/test-namespace/testcluster/members/"
 	teamTest                 = "/clusters/test-id/"
 )
 
@@ -24,7 +34,249 @@ func TestUrlRegexps(t *testing.T) {
 		t.Errorf("clusterLogsURL can't match %s", clusterLogsTest)
 	}
 
+	if clusterMembersURL.FindStringSubmatch(clusterMembersTest) == nil {
+		t.Errorf("clusterMembersURL can't match %s", clusterMembersTest)
+	}
+
 	if teamURL.FindStringSubmatch(teamTest) == nil {
 		t.Errorf("teamURL can't match %s", teamTest)
 	}
 }
+
+func TestClusterActionURL(t *testing.T) {
+	for _, path := range []string{
+		"/clusters/test-namespace/testcluster/sync",
+		"/clusters/test-namespace/testcluster/switchover/",
+		"/clusters/test-namespace/testcluster/rotate-passwords",
+	} {
+		if clusterActionURL.FindStringSubmatch(path) == nil {
+			t.Errorf("clusterActionURL can't match %s", path)
+		}
+	}
+
+	for _, path := range []string{
+		clusterStatusTest,
+		clusterLogsTest,
+		"/clusters/test-namespace/testcluster/delete",
+	} {
+		if clusterActionURL.FindStringSubmatch(path) != nil {
+			t.Errorf("clusterActionURL should not match %s", path)
+		}
+	}
+}
+
+func TestClusterWatchURL(t *testing.T) {
+	for path, expectMatch := range map[string]bool{
+		"/watch/clusters/test-namespace/testcluster":  true,
+		"/watch/clusters/test-namespace/testcluster/": true,
+		"/watch/clusters/":                            false,
+		"/watch/clusters/test-namespace/":             false,
+		clusterStatusTest:                             false,
+	} {
+		if matched := clusterWatchURL.MatchString(path); matched != expectMatch {
+			t.Errorf("clusterWatchURL match of %s: expected %t, got %t", path, expectMatch, matched)
+		}
+	}
+}
+
+type mockWatchController struct {
+	controllerInformer
+	events    chan spec.WatchEvent
+	namespace string
+	cluster   string
+	stopped   bool
+}
+
+func (c *mockWatchController) WatchClusters(namespace, cluster string) (<-chan spec.WatchEvent, func(), error) {
+	if cluster == "unknown" {
+		return nil, nil, fmt.Errorf("could not find cluster")
+	}
+	c.namespace, c.cluster = namespace, cluster
+	return c.events, func() { c.stopped = true }, nil
+}
+
+func TestWatch(t *testing.T) {
+	eventTime := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	clusterName := spec.NamespacedName{Namespace: "test-namespace", Name: "testcluster"}
+
+	tests := []struct {
+		subTest       string
+		path          string
+		accept        string
+		expectStatus  int
+		expectType    string
+		expectBody    string
+		expectWatched string
+	}{
+		{
+			subTest:       "chunked JSON of all clusters",
+			path:          "/watch/clusters/",
+			expectStatus:  http.StatusOK,
+			expectWatched: "/",
+			expectType:    "application/json",
+			expectBody: `{"Type":"process","Time":"2022-03-01T12:00:00Z","ClusterName":"test-namespace/testcluster","Process":"syncing cluster"}` + "\n" +
+				`{"Type":"status","Time":"2022-03-01T12:00:00Z","ClusterName":"test-namespace/testcluster","Status":"Running"}` + "\n",
+		},
+		{
+			subTest:       "server-sent events of a single cluster",
+			path:          "/watch/clusters/test-namespace/testcluster/",
+			accept:        "text/event-stream",
+			expectStatus:  http.StatusOK,
+			expectWatched: "test-namespace/testcluster",
+			expectType:    "text/event-stream",
+			expectBody: "event: process\n" +
+				`data: {"Type":"process","Time":"2022-03-01T12:00:00Z","ClusterName":"test-namespace/testcluster","Process":"syncing cluster"}` + "\n\n" +
+				"event: status\n" +
+				`data: {"Type":"status","Time":"2022-03-01T12:00:00Z","ClusterName":"test-namespace/testcluster","Status":"Running"}` + "\n\n",
+		},
+		{
+			subTest:      "unknown cluster",
+			path:         "/watch/clusters/test-namespace/unknown/",
+			expectStatus: http.StatusNotFound,
+			expectType:   "application/json",
+			expectBody:   `{"error":"could not find cluster"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		controller := &mockWatchController{events: make(chan spec.WatchEvent, 2)}
+		controller.events <- spec.WatchEvent{Type: spec.WatchEventProcess, Time: eventTime, ClusterName: clusterName, Process: "syncing cluster"}
+		controller.events <- spec.WatchEvent{Type: spec.WatchEventStatus, Time: eventTime, ClusterName: clusterName, Status: "Running"}
+		// ends the stream like a watcher dropped by the controller
+		close(controller.events)
+
+		s := &Server{
+			logger:     logrus.New().WithField("test", "apiserver"),
+			controller: controller,
+			done:       make(chan struct{}),
+		}
+		server := httptest.NewServer(http.HandlerFunc(s.watch))
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+		if err != nil {
+			t.Fatalf("%s: could not create request: %v", tt.subTest, err)
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.subTest, err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: could not read response: %v", tt.subTest, err)
+		}
+
+		if resp.StatusCode != tt.expectStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.subTest, tt.expectStatus, resp.StatusCode)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != tt.expectType {
+			t.Errorf("%s: expected content type %q, got %q", tt.subTest, tt.expectType, contentType)
+		}
+		if string(body) != tt.expectBody {
+			t.Errorf("%s: expected body %q, got %q", tt.subTest, tt.expectBody, string(body))
+		}
+		if tt.expectStatus == http.StatusOK && !controller.stopped {
+			t.Errorf("%s: expected the watch to be stopped", tt.subTest)
+		}
+		if watched := controller.namespace + "/" + controller.cluster; tt.expectStatus == http.StatusOK && watched != tt.expectWatched {
+			t.Errorf("%s: expected to watch %q, got %q", tt.subTest, tt.expectWatched, watched)
+		}
+	}
+}
+
+type mockActionController struct {
+	controllerInformer
+}
+
+func (c *mockActionController) AuthenticateAPIToken(token string) (string, error) {
+	if token != "secret" {
+		return "", fmt.Errorf("invalid bearer token")
+	}
+	return "tester", nil
+}
+
+func (c *mockActionController) QueueClusterAction(namespace, cluster, action, candidate, caller string) (uint32, error) {
+	if cluster == "unknown" {
+		return 0, spec.ErrClusterNotFound
+	}
+	if candidate != "" && action != "switchover" {
+		return 0, fmt.Errorf("a candidate can only be given for a switchover")
+	}
+	return 3, nil
+}
+
+func TestClusterAction(t *testing.T) {
+	tests := []struct {
+		subTest      string
+		path         string
+		token        string
+		body         string
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			subTest:      "queued action",
+			path:         "/clusters/test-namespace/testcluster/sync",
+			token:        "secret",
+			expectStatus: http.StatusAccepted,
+			expectBody:   `{"action":"sync","worker":3}` + "\n",
+		},
+		{
+			subTest:      "invalid token",
+			path:         "/clusters/test-namespace/testcluster/sync",
+			token:        "wrong",
+			expectStatus: http.StatusUnauthorized,
+			expectBody:   `{"error":"invalid bearer token"}` + "\n",
+		},
+		{
+			subTest:      "unknown cluster",
+			path:         "/clusters/test-namespace/unknown/sync",
+			token:        "secret",
+			expectStatus: http.StatusNotFound,
+			expectBody:   `{"error":"could not find cluster"}` + "\n",
+		},
+		{
+			subTest:      "bad request",
+			path:         "/clusters/test-namespace/testcluster/restart",
+			token:        "secret",
+			body:         `{"candidate":"testcluster-1"}`,
+			expectStatus: http.StatusBadRequest,
+			expectBody:   `{"error":"a candidate can only be given for a switchover"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		s := &Server{
+			logger:     logrus.New().WithField("test", "apiserver"),
+			controller: &mockActionController{},
+		}
+		server := httptest.NewServer(http.HandlerFunc(s.clusterAction))
+
+		req, err := http.NewRequest(http.MethodPost, server.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("%s: could not create request: %v", tt.subTest, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.subTest, err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: could not read response: %v", tt.subTest, err)
+		}
+
+		if resp.StatusCode != tt.expectStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.subTest, tt.expectStatus, resp.StatusCode)
+		}
+		if string(body) != tt.expectBody {
+			t.Errorf("%s: expected body %q, got %q", tt.subTest, tt.expectBody, string(body))
+		}
+	}
+}
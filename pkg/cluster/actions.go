@@ -0,0 +1,139 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManualSwitchover switches the master role over to the given candidate pod or,
+// if no candidate is given, to the replica with the lowest lag
+func (c *Cluster) ManualSwitchover(candidate string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setProcessName("switching over on request")
+
+	masterPods, err := c.getRolePods(Master)
+	if err != nil {
+		return fmt.Errorf("could not get master pod: %v", err)
+	}
+	if len(masterPods) == 0 {
+		return fmt.Errorf("no master pod found")
+	}
+	masterPod := &masterPods[0]
+
+	var candidateName spec.NamespacedName
+	if candidate == "" {
+		if candidateName, err = c.getSwitchoverCandidate(masterPod); err != nil {
+			return fmt.Errorf("could not find suitable switchover candidate: %v", err)
+		}
+	} else {
+		replicaPods, err := c.getRolePods(Replica)
+		if err != nil {
+			return fmt.Errorf("could not get replica pods: %v", err)
+		}
+		for _, pod := range replicaPods {
+			if pod.Name == candidate {
+				candidateName = util.NameFromMeta(pod.ObjectMeta)
+				break
+			}
+		}
+		if candidateName.Name == "" {
+			return fmt.Errorf("switchover candidate %q is not a replica of the cluster", candidate)
+		}
+	}
+
+	return c.Switchover(masterPod, candidateName)
+}
+
+// RestartPostgres restarts Postgres in all pods of the cluster through Patroni, replicas first
+func (c *Cluster) RestartPostgres() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.forEachInstance("restarting Postgres on request", func(pod *v1.Pod) error {
+		if err := c.patroni.RestartNow(pod); err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeNormal, "Restart", "Postgres server restart done for pod %s", pod.Name)
+		return nil
+	})
+}
+
+// ReloadPostgres reloads the Patroni and Postgres configuration in all pods of the cluster
+func (c *Cluster) ReloadPostgres() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.forEachInstance("reloading Postgres on request", c.patroni.Reload)
+}
+
+func (c *Cluster) forEachInstance(process string, action func(pod *v1.Pod) error) error {
+	c.setProcessName(process)
+
+	replicaPods, err := c.getRolePods(Replica)
+	if err != nil {
+		return fmt.Errorf("could not get replica pods: %v", err)
+	}
+	masterPods, err := c.getRolePods(Master)
+	if err != nil {
+		return fmt.Errorf("could not get master pod: %v", err)
+	}
+
+	for _, pod := range append(replicaPods, masterPods...) {
+		if err := action(&pod); err != nil {
+			return fmt.Errorf("could not run action on pod %s: %v", pod.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// RotatePasswords expires the passwords of all users with password rotation enabled and
+// syncs the cluster, so that new passwords are set right away. Without such users it does nothing.
+func (c *Cluster) RotatePasswords(newSpec *acidv1.Postgresql) error {
+	c.mu.Lock()
+	rotated, err := c.expirePasswordRotation(time.Now())
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if rotated == 0 {
+		c.logger.Infof("no secrets with password rotation found, nothing to rotate")
+		return nil
+	}
+	c.logger.Infof("password rotation brought forward for %d secrets", rotated)
+
+	return c.Sync(newSpec)
+}
+
+func (c *Cluster) expirePasswordRotation(currentTime time.Time) (int, error) {
+	c.setProcessName("expiring passwords on request")
+
+	rotated := 0
+	for _, cached := range c.Secrets {
+		// only secrets of users with password rotation have a rotation date
+		if len(cached.Data["nextRotation"]) == 0 {
+			continue
+		}
+		secret, err := c.KubeClient.Secrets(cached.Namespace).Get(context.TODO(), cached.Name, metav1.GetOptions{})
+		if err != nil {
+			return rotated, fmt.Errorf("could not get secret %s: %v", cached.Name, err)
+		}
+		secret.Data["nextRotation"] = []byte(currentTime.Format(time.RFC3339))
+		if secret, err = c.KubeClient.Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+			return rotated, fmt.Errorf("could not update secret %s: %v", cached.Name, err)
+		}
+		c.Secrets[secret.UID] = secret
+		rotated++
+	}
+
+	return rotated, nil
+}
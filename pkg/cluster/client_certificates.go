@@ -0,0 +1,451 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acidzalando "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do"
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util"
+	"github.com/zalando/postgres-operator/pkg/util/certs"
+)
+
+const (
+	clientCAMountPath   = "/tlsclientca"
+	clientCAVolumeName  = "client-ca"
+	clientCACertKey     = "ca.crt"
+	clientCAPrivateKey  = "ca.key"
+	clientCAValidityMul = 10
+	// set on the client CA secret while Postgres has not loaded the current bundle yet
+	clientCAReloadAnnotationKey = "zalando-postgres-operator-client-ca-reload"
+	// lists the namespace/username of every issued client certificate on the client CA secret
+	clientCertificateUsersAnnotationKey = "zalando-postgres-operator-client-certificate-users"
+)
+
+func (c *Cluster) clientCASecretName() string {
+	return c.OpConfig.ClientCASecretNameTemplate.Format(
+		"cluster", c.Name,
+		"tprkind", acidv1.PostgresCRDResourceKind,
+		"tprgroup", acidzalando.GroupName)
+}
+
+func (c *Cluster) clientCertificateSecretName(username string) string {
+	// same naming restrictions as for the credential secrets apply
+	return c.OpConfig.ClientCertificateSecretNameTemplate.Format(
+		"username", strings.Replace(username, "_", "-", -1),
+		"cluster", c.Name,
+		"tprkind", acidv1.PostgresCRDResourceKind,
+		"tprgroup", acidzalando.GroupName)
+}
+
+// certificateAuthenticationUsers returns the valid, deduplicated and sorted
+// list of users that authenticate with client certificates
+func (c *Cluster) certificateAuthenticationUsers(spec *acidv1.PostgresSpec) []string {
+	users := make([]string, 0)
+	for _, username := range spec.UsersWithCertificateAuthentication {
+		if !isValidUsername(username) {
+			c.logger.Warningf("invalid username %q for certificate authentication", username)
+			continue
+		}
+		if util.SliceContains(users, username) {
+			continue
+		}
+		users = append(users, username)
+	}
+	sort.Strings(users)
+
+	return users
+}
+
+// syncClientCertificates makes sure every user listed for certificate
+// authentication has a valid certificate signed by the cluster's client CA.
+// Certificates are re-issued before they expire or when the CA changed.
+// Postgres keeps trusting the previous CA until it expires, so that clients
+// still presenting a certificate issued by it are not locked out.
+func (c *Cluster) syncClientCertificates() error {
+	users := c.certificateAuthenticationUsers(&c.Spec)
+	if len(users) == 0 {
+		// certificates issued before the last user was removed have to go, too
+		caSecret, err := c.KubeClient.Secrets(c.Namespace).Get(context.TODO(), c.clientCASecretName(), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not get secret %q: %v", c.clientCASecretName(), err)
+		}
+		if _, err = c.deleteStaleClientCertificates(caSecret, users, nil); err != nil {
+			return fmt.Errorf("could not delete client certificates of removed users: %v", err)
+		}
+		return nil
+	}
+
+	c.logger.Info("syncing client certificates")
+	c.setProcessName("syncing client certificates")
+	now := time.Now()
+
+	caSecret, ca, err := c.syncClientCA(now)
+	if err != nil {
+		return fmt.Errorf("could not sync client CA: %v", err)
+	}
+
+	issued := make([]string, 0, len(users))
+	for _, username := range users {
+		pgUser, exists := c.pgUsers[username]
+		if !exists {
+			c.logger.Warningf("user %q for certificate authentication is not defined in the manifest", username)
+			continue
+		}
+		if err := c.syncClientCertificate(pgUser, ca, now); err != nil {
+			return fmt.Errorf("could not sync client certificate of user %q: %v", username, err)
+		}
+		issued = append(issued, c.clientCertificateNamespace(pgUser)+"/"+username)
+	}
+
+	if caSecret, err = c.deleteStaleClientCertificates(caSecret, users, issued); err != nil {
+		return fmt.Errorf("could not delete client certificates of removed users: %v", err)
+	}
+
+	// all client certificates are issued by the current CA now
+	if caSecret, err = c.pruneClientCA(caSecret, now); err != nil {
+		return fmt.Errorf("could not remove expired client CAs: %v", err)
+	}
+	if err = c.reloadClientCA(caSecret); err != nil {
+		return fmt.Errorf("could not reload client CA: %v", err)
+	}
+
+	return nil
+}
+
+// deleteStaleClientCertificates deletes the certificate secrets of users no
+// longer listed for certificate authentication. Otherwise their key pairs stay
+// usable until they expire, since the CA that signed them is still trusted.
+// The owners of issued certificates are recorded on the client CA secret, as
+// the operator is not allowed to list secrets.
+func (c *Cluster) deleteStaleClientCertificates(caSecret *v1.Secret, users []string, issued []string) (*v1.Secret, error) {
+	recorded := make([]string, 0)
+	if value := caSecret.Annotations[clientCertificateUsersAnnotationKey]; value != "" {
+		recorded = strings.Split(value, ",")
+	}
+
+	current := make([]string, 0, len(issued))
+	for _, entry := range recorded {
+		parts := strings.SplitN(entry, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		namespace, username := parts[0], parts[1]
+		if util.SliceContains(users, username) {
+			current = append(current, entry)
+			continue
+		}
+
+		secretName := c.clientCertificateSecretName(username)
+		secret, err := c.KubeClient.Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("could not get secret %q: %v", secretName, err)
+		}
+		if err == nil {
+			delete(c.Secrets, secret.UID)
+			err = c.KubeClient.Secrets(namespace).Delete(context.TODO(), secretName, c.deleteOptions)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return nil, fmt.Errorf("could not delete secret %q: %v", secretName, err)
+			}
+			c.logger.Infof("deleted client certificate secret %s/%s of user %q no longer using certificate authentication",
+				namespace, secretName, username)
+		}
+	}
+	for _, entry := range issued {
+		if !util.SliceContains(current, entry) {
+			current = append(current, entry)
+		}
+	}
+	sort.Strings(current)
+
+	value := strings.Join(current, ",")
+	if value == caSecret.Annotations[clientCertificateUsersAnnotationKey] {
+		return caSecret, nil
+	}
+	if caSecret.Annotations == nil {
+		caSecret.Annotations = make(map[string]string)
+	}
+	caSecret.Annotations[clientCertificateUsersAnnotationKey] = value
+	updatedSecret, err := c.KubeClient.Secrets(caSecret.Namespace).Update(context.TODO(), caSecret, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not update secret %q: %v", caSecret.Name, err)
+	}
+	c.Secrets[updatedSecret.UID] = updatedSecret
+
+	return updatedSecret, nil
+}
+
+// syncClientCA returns the secret of the client CA together with the CA signing the client certificates.
+// The certificate of the signing CA comes first in the bundle stored as ca.crt, followed by the previous
+// CAs Postgres still has to trust.
+func (c *Cluster) syncClientCA(now time.Time) (*v1.Secret, *certs.KeyPair, error) {
+	secretName := c.clientCASecretName()
+	secret, err := c.KubeClient.Secrets(c.Namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, nil, fmt.Errorf("could not get secret %q: %v", secretName, err)
+	}
+
+	secretExists := err == nil
+	trusted := make([][]byte, 0)
+	if secretExists {
+		c.Secrets[secret.UID] = secret
+		ca := &certs.KeyPair{PrivateKey: secret.Data[clientCAPrivateKey]}
+		if bundle := certs.SplitBundle(secret.Data[clientCACertKey]); len(bundle) > 0 {
+			ca.Certificate = bundle[0]
+			trusted = unexpiredCertificates(bundle, now)
+		}
+		if !certs.NeedsRenewal(ca.Certificate, c.OpConfig.ClientCertificateRenewBefore, now) && len(ca.PrivateKey) > 0 {
+			return secret, ca, nil
+		}
+		c.logger.Warningf("client CA in secret %q expires soon or is invalid - generating a new one and re-issuing all client certificates", secretName)
+	}
+
+	ca, err := certs.GenerateCA(c.Name, clientCAValidityMul*c.OpConfig.ClientCertificateValidity, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generatedSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secretName,
+			Namespace:   c.Namespace,
+			Labels:      c.labelsSet(true),
+			Annotations: c.annotationsSet(nil),
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			clientCACertKey:    certs.Bundle(append([][]byte{ca.Certificate}, trusted...)),
+			clientCAPrivateKey: ca.PrivateKey,
+		},
+	}
+
+	if !secretExists {
+		secret, err = c.KubeClient.Secrets(c.Namespace).Create(context.TODO(), generatedSecret, metav1.CreateOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create secret %q: %v", secretName, err)
+		}
+		c.logger.Debugf("created client CA secret %s", util.NameFromMeta(secret.ObjectMeta))
+	} else {
+		secret.Data = generatedSecret.Data
+		secret, err = c.updateClientCASecret(secret)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.logger.Infof("client CA in secret %s has been renewed", util.NameFromMeta(secret.ObjectMeta))
+	}
+
+	return secret, ca, nil
+}
+
+// pruneClientCA removes the expired previous CAs from the bundle. Certificates issued by them are
+// expired as well, since client certificates never outlive their CA.
+func (c *Cluster) pruneClientCA(secret *v1.Secret, now time.Time) (*v1.Secret, error) {
+	bundle := certs.SplitBundle(secret.Data[clientCACertKey])
+	if len(bundle) < 2 {
+		return secret, nil
+	}
+	trusted := append([][]byte{bundle[0]}, unexpiredCertificates(bundle[1:], now)...)
+	if len(trusted) == len(bundle) {
+		return secret, nil
+	}
+
+	secret.Data[clientCACertKey] = certs.Bundle(trusted)
+	secret, err := c.updateClientCASecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Infof("removed %d expired CAs from the client CA bundle in secret %s", len(bundle)-len(trusted), util.NameFromMeta(secret.ObjectMeta))
+
+	return secret, nil
+}
+
+// updateClientCASecret writes a changed bundle to the secret and marks it to be reloaded by Postgres
+func (c *Cluster) updateClientCASecret(secret *v1.Secret) (*v1.Secret, error) {
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[clientCAReloadAnnotationKey] = "true"
+
+	updatedSecret, err := c.KubeClient.Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not update secret %q: %v", secret.Name, err)
+	}
+	c.Secrets[updatedSecret.UID] = updatedSecret
+
+	return updatedSecret, nil
+}
+
+// reloadClientCA makes Postgres load the bundle from the secret once it changed. Postgres reads
+// ssl_ca_file only on start and reload, and the kubelet updates the mounted secret with a delay,
+// so every pod is checked once per sync and only reloaded once it sees the new bundle. The
+// reload stays pending for the next sync until all pods have it.
+func (c *Cluster) reloadClientCA(secret *v1.Secret) error {
+	if _, pending := secret.Annotations[clientCAReloadAnnotationKey]; !pending {
+		return nil
+	}
+
+	bundle := strings.TrimSpace(string(secret.Data[clientCACertKey]))
+	reloaded := true
+	err := c.forEachInstance("reloading client CA", func(pod *v1.Pod) error {
+		podName := util.NameFromMeta(pod.ObjectMeta)
+		mounted, err := c.ExecCommand(&podName, "cat", clientCAMountPath+"/"+clientCACertKey)
+		if err != nil {
+			return fmt.Errorf("could not read mounted client CA: %v", err)
+		}
+		if strings.TrimSpace(mounted) != bundle {
+			c.logger.Debugf("pod %s does not see the new client CA yet", podName)
+			reloaded = false
+			return nil
+		}
+		return c.patroni.Reload(pod)
+	})
+	if err != nil {
+		return err
+	}
+	if !reloaded {
+		return nil
+	}
+
+	delete(secret.Annotations, clientCAReloadAnnotationKey)
+	updatedSecret, err := c.KubeClient.Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("could not update secret %q: %v", secret.Name, err)
+	}
+	c.Secrets[updatedSecret.UID] = updatedSecret
+	c.logger.Infof("Postgres reloaded the client CA bundle from secret %s", util.NameFromMeta(updatedSecret.ObjectMeta))
+
+	return nil
+}
+
+func unexpiredCertificates(certificates [][]byte, now time.Time) [][]byte {
+	unexpired := make([][]byte, 0)
+	for _, certificate := range certificates {
+		if !certs.IsExpired(certificate, now) {
+			unexpired = append(unexpired, certificate)
+		}
+	}
+
+	return unexpired
+}
+
+// clientCertificateNamespace returns the namespace of the certificate secret, which is the one of the user's secret
+func (c *Cluster) clientCertificateNamespace(pgUser spec.PgUser) string {
+	if pgUser.Namespace == "" {
+		return c.Namespace
+	}
+	return pgUser.Namespace
+}
+
+func (c *Cluster) syncClientCertificate(pgUser spec.PgUser, ca *certs.KeyPair, now time.Time) error {
+	namespace := c.clientCertificateNamespace(pgUser)
+	secretName := c.clientCertificateSecretName(pgUser.Name)
+
+	secret, err := c.KubeClient.Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("could not get secret %q: %v", secretName, err)
+	}
+
+	secretExists := err == nil
+	if secretExists {
+		c.Secrets[secret.UID] = secret
+		if !c.clientCertificateNeedsRenewal(secret, pgUser.Name, ca, now) {
+			return nil
+		}
+	}
+
+	clientCert, err := certs.IssueClientCertificate(ca, pgUser.Name, c.OpConfig.ClientCertificateValidity, now)
+	if err != nil {
+		return err
+	}
+
+	data := map[string][]byte{
+		v1.TLSCertKey:       clientCert.Certificate,
+		v1.TLSPrivateKeyKey: clientCert.PrivateKey,
+		clientCACertKey:     ca.Certificate,
+	}
+
+	if !secretExists {
+		secret, err = c.KubeClient.Secrets(namespace).Create(context.TODO(), &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        secretName,
+				Namespace:   namespace,
+				Labels:      c.labelsSet(true),
+				Annotations: c.annotationsSet(nil),
+			},
+			Type: v1.SecretTypeTLS,
+			Data: data,
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create secret %q: %v", secretName, err)
+		}
+		c.logger.Debugf("created client certificate secret %s", util.NameFromMeta(secret.ObjectMeta))
+	} else {
+		secret.Data = data
+		secret, err = c.KubeClient.Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not update secret %q: %v", secretName, err)
+		}
+		c.logger.Infof("client certificate in secret %s has been re-issued", util.NameFromMeta(secret.ObjectMeta))
+	}
+	c.Secrets[secret.UID] = secret
+
+	return nil
+}
+
+func (c *Cluster) clientCertificateNeedsRenewal(secret *v1.Secret, username string, ca *certs.KeyPair, now time.Time) bool {
+	certPEM := secret.Data[v1.TLSCertKey]
+	if certs.NeedsRenewal(certPEM, c.OpConfig.ClientCertificateRenewBefore, now) {
+		return true
+	}
+	if !certs.IsSignedBy(certPEM, ca.Certificate) {
+		return true
+	}
+	cert, err := certs.ParseCertificate(certPEM)
+	if err != nil || cert.Subject.CommonName != username {
+		return true
+	}
+
+	return len(secret.Data[v1.TLSPrivateKeyKey]) == 0
+}
+
+// generatePgHba returns the pg_hba entries passed to Patroni. Certificate
+// authentication entries always come first. They are followed by either the
+// raw entries from the manifest, the entries the operator requires merged with
+// the structured rules from the manifest or Spilo's default entries.
+func (c *Cluster) generatePgHba(spec *acidv1.PostgresSpec) ([]string, error) {
+	pgHba := make([]string, 0)
+	for _, username := range c.certificateAuthenticationUsers(spec) {
+		pgHba = append(pgHba, fmt.Sprintf("hostssl all %s all cert", username))
+	}
+
+	if len(spec.PgHbaRules) > 0 {
+		if err := c.validatePgHbaRules(spec); err != nil {
+			return nil, err
+		}
+		pgHba = append(pgHba, c.operatorPgHba(spec)...)
+		for _, rule := range spec.PgHbaRules {
+			pgHba = append(pgHba, c.formatPgHbaRule(rule, spec.AllowedSourceRanges)...)
+		}
+		return pgHba, nil
+	}
+
+	if len(pgHba) == 0 {
+		return spec.Patroni.PgHba, nil
+	}
+	if len(spec.Patroni.PgHba) > 0 {
+		return append(pgHba, spec.Patroni.PgHba...), nil
+	}
+
+	return append(pgHba, c.defaultPgHba()...), nil
+}
@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util/certs"
+	"github.com/zalando/postgres-operator/pkg/util/config"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+)
+
+func newClientCertificatesTestCluster(client k8sutil.KubernetesClient, users []string) *Cluster {
+	pg := acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acid-test-cluster",
+			Namespace: "default",
+		},
+		Spec: acidv1.PostgresSpec{
+			Users:                              map[string]acidv1.UserFlags{"foo": {}, "bar_user": {}},
+			UsersWithCertificateAuthentication: users,
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				Auth: config.Auth{
+					PamRoleName:                         "zalandos",
+					ReplicationUsername:                 "standby",
+					ClientCASecretNameTemplate:          "{cluster}.client-ca",
+					ClientCertificateSecretNameTemplate: "{username}.{cluster}.client-certificate",
+					ClientCertificateValidity:           48 * time.Hour,
+					ClientCertificateRenewBefore:        24 * time.Hour,
+				},
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+					PodRoleLabel:     "spilo-role",
+				},
+			},
+		}, client, pg, logger, eventRecorder)
+	cluster.Name = pg.Name
+	cluster.Namespace = pg.Namespace
+	cluster.pgUsers = map[string]spec.PgUser{
+		"foo":      {Name: "foo", Namespace: pg.Namespace, Origin: spec.RoleOriginManifest},
+		"bar_user": {Name: "bar_user", Namespace: pg.Namespace, Origin: spec.RoleOriginManifest},
+	}
+
+	return cluster
+}
+
+func TestSyncClientCertificates(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{SecretsGetter: clientSet.CoreV1()}
+	cluster := newClientCertificatesTestCluster(client, []string{"bar_user", "unknown"})
+	now := time.Now()
+
+	if err := cluster.syncClientCertificates(); err != nil {
+		t.Fatalf("could not sync client certificates: %v", err)
+	}
+
+	caSecret, err := client.Secrets("default").Get(context.TODO(), "acid-test-cluster.client-ca", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("client CA secret was not created: %v", err)
+	}
+	certSecret, err := client.Secrets("default").Get(context.TODO(), "bar-user.acid-test-cluster.client-certificate", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("client certificate secret was not created: %v", err)
+	}
+	if certSecret.Type != v1.SecretTypeTLS {
+		t.Errorf("expected secret type %s, got %s", v1.SecretTypeTLS, certSecret.Type)
+	}
+	if _, err := client.Secrets("default").Get(context.TODO(), "foo.acid-test-cluster.client-certificate", metav1.GetOptions{}); err == nil {
+		t.Errorf("unexpected client certificate secret for user foo")
+	}
+
+	ca := &certs.KeyPair{Certificate: caSecret.Data["ca.crt"], PrivateKey: caSecret.Data["ca.key"]}
+	if cluster.clientCertificateNeedsRenewal(certSecret, "bar_user", ca, now) {
+		t.Errorf("freshly issued client certificate must not need renewal")
+	}
+	if !cluster.clientCertificateNeedsRenewal(certSecret, "bar_user", ca, now.Add(25*time.Hour)) {
+		t.Errorf("client certificate within the renewal interval must be re-issued")
+	}
+	if !cluster.clientCertificateNeedsRenewal(certSecret, "foo", ca, now) {
+		t.Errorf("client certificate of another user must be re-issued")
+	}
+
+	// a new CA invalidates all issued client certificates
+	newCA, _ := certs.GenerateCA("acid-test-cluster", time.Hour, now)
+	if !cluster.clientCertificateNeedsRenewal(certSecret, "bar_user", newCA, now) {
+		t.Errorf("client certificate not signed by the current CA must be re-issued")
+	}
+
+	// a second sync keeps the certificate
+	if err := cluster.syncClientCertificates(); err != nil {
+		t.Fatalf("could not sync client certificates: %v", err)
+	}
+	syncedSecret, _ := client.Secrets("default").Get(context.TODO(), certSecret.Name, metav1.GetOptions{})
+	if !reflect.DeepEqual(syncedSecret.Data, certSecret.Data) {
+		t.Errorf("client certificate must not be re-issued when still valid")
+	}
+}
+
+func TestDeleteStaleClientCertificates(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{SecretsGetter: clientSet.CoreV1()}
+	cluster := newClientCertificatesTestCluster(client, []string{"bar_user", "foo"})
+
+	secretExists := func(name string) bool {
+		_, err := client.Secrets("default").Get(context.TODO(), name, metav1.GetOptions{})
+		return err == nil
+	}
+
+	if err := cluster.syncClientCertificates(); err != nil {
+		t.Fatalf("could not sync client certificates: %v", err)
+	}
+	caSecret, _ := client.Secrets("default").Get(context.TODO(), "acid-test-cluster.client-ca", metav1.GetOptions{})
+	if users := caSecret.Annotations[clientCertificateUsersAnnotationKey]; users != "default/bar_user,default/foo" {
+		t.Errorf("expected issued client certificates to be recorded, got %q", users)
+	}
+
+	// a user taken off certificate authentication loses the certificate
+	cluster.Spec.UsersWithCertificateAuthentication = []string{"bar_user"}
+	if err := cluster.syncClientCertificates(); err != nil {
+		t.Fatalf("could not sync client certificates: %v", err)
+	}
+	if secretExists("foo.acid-test-cluster.client-certificate") {
+		t.Errorf("client certificate secret of user foo must be deleted")
+	}
+	if !secretExists("bar-user.acid-test-cluster.client-certificate") {
+		t.Errorf("client certificate secret of user bar_user must be kept")
+	}
+
+	// the last user as well
+	cluster.Spec.UsersWithCertificateAuthentication = nil
+	if err := cluster.syncClientCertificates(); err != nil {
+		t.Fatalf("could not sync client certificates: %v", err)
+	}
+	if secretExists("bar-user.acid-test-cluster.client-certificate") {
+		t.Errorf("client certificate secret of user bar_user must be deleted")
+	}
+	caSecret, _ = client.Secrets("default").Get(context.TODO(), "acid-test-cluster.client-ca", metav1.GetOptions{})
+	if users := caSecret.Annotations[clientCertificateUsersAnnotationKey]; users != "" {
+		t.Errorf("expected no recorded client certificates, got %q", users)
+	}
+}
+
+func TestRenewClientCA(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{SecretsGetter: clientSet.CoreV1(), PodsGetter: clientSet.CoreV1()}
+	cluster := newClientCertificatesTestCluster(client, []string{"bar_user"})
+	now := time.Now()
+
+	// a CA expiring within the renewal interval
+	previousCA, _ := certs.GenerateCA("acid-test-cluster", 2*time.Hour, now)
+	_, err := client.Secrets("default").Create(context.TODO(), &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "acid-test-cluster.client-ca", Namespace: "default"},
+		Data:       map[string][]byte{"ca.crt": previousCA.Certificate, "ca.key": previousCA.PrivateKey},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("could not create client CA secret: %v", err)
+	}
+
+	if err := cluster.syncClientCertificates(); err != nil {
+		t.Fatalf("could not sync client certificates: %v", err)
+	}
+
+	caSecret, _ := client.Secrets("default").Get(context.TODO(), "acid-test-cluster.client-ca", metav1.GetOptions{})
+	bundle := certs.SplitBundle(caSecret.Data["ca.crt"])
+	if len(bundle) != 2 || !reflect.DeepEqual(bundle[1], previousCA.Certificate) {
+		t.Fatalf("expected the renewed CA followed by the previous one in the bundle, got %d certificates", len(bundle))
+	}
+	if certs.NeedsRenewal(bundle[0], 24*time.Hour, now) {
+		t.Errorf("expected the renewed CA to come first in the bundle")
+	}
+	// without pods there is nothing left to reload
+	if _, pending := caSecret.Annotations[clientCAReloadAnnotationKey]; pending {
+		t.Errorf("expected the reload of the client CA to be done")
+	}
+	certSecret, _ := client.Secrets("default").Get(context.TODO(), "bar-user.acid-test-cluster.client-certificate", metav1.GetOptions{})
+	if !certs.IsSignedBy(certSecret.Data["tls.crt"], bundle[0]) {
+		t.Errorf("expected the client certificate to be re-issued by the renewed CA")
+	}
+
+	// the previous CA is trusted until it expires
+	caSecret, err = cluster.pruneClientCA(caSecret, now.Add(time.Hour))
+	if err != nil || len(certs.SplitBundle(caSecret.Data["ca.crt"])) != 2 {
+		t.Errorf("expected the previous CA to be kept until it expires, got %v", err)
+	}
+	caSecret, err = cluster.pruneClientCA(caSecret, now.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("could not prune client CA: %v", err)
+	}
+	if bundle := certs.SplitBundle(caSecret.Data["ca.crt"]); len(bundle) != 1 || certs.IsExpired(bundle[0], now.Add(3*time.Hour)) {
+		t.Errorf("expected only the renewed CA to be left in the bundle, got %d certificates", len(bundle))
+	}
+	if _, pending := caSecret.Annotations[clientCAReloadAnnotationKey]; !pending {
+		t.Errorf("expected Postgres to reload the bundle after removing the previous CA")
+	}
+}
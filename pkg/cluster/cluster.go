@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
 	"regexp"
 	"strings"
@@ -25,8 +26,10 @@ import (
 	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
 	"github.com/zalando/postgres-operator/pkg/util/patroni"
 	"github.com/zalando/postgres-operator/pkg/util/teams"
+	"github.com/zalando/postgres-operator/pkg/util/tracing"
 	"github.com/zalando/postgres-operator/pkg/util/users"
 	"github.com/zalando/postgres-operator/pkg/util/volumes"
+	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
@@ -54,6 +57,11 @@ type Config struct {
 	InfrastructureRoles          map[string]spec.PgUser // inherited from the controller
 	PodServiceAccount            *v1.ServiceAccount
 	PodServiceAccountRoleBinding *rbacv1.RoleBinding
+	VolumeMigrations             *volumes.MigrationLimiter // shared by all clusters
+	Tracer                       *tracing.Tracer           // nil if tracing is disabled
+	TracingTransport             http.RoundTripper         // shared by the traced K8s clients of all clusters
+	// streams changes of the cluster to the watchers of the API
+	NotifyWatchers func(event spec.WatchEvent)
 }
 
 type kubeResources struct {
@@ -94,8 +102,14 @@ type Cluster struct {
 	streamApplications  []string
 	ConnectionPooler    map[PostgresRole]*ConnectionPoolerObjects
 	EBSVolumes          map[string]volumes.VolumeProperties
+	ebsVolumeClaims     map[string]string // provider volume id to the name of its volume claim template
 	VolumeResizer       volumes.VolumeResizer
+	volumeMigrations    map[string]*VolumeMigration // provider volume id to the state of its type migration
+	volumeMigrationsMu  sync.RWMutex
+	migrationsRestored  bool // migrations still running from before an operator restart are known
 	currentMajorVersion int
+	spans               []trace.Span // stack of the spans of the traced operation, the last one is active
+	spansMu             sync.Mutex
 }
 
 type compareStatefulsetResult struct {
@@ -142,15 +156,27 @@ func New(cfg Config, kubeClient k8sutil.KubernetesClient, pgSpec acidv1.Postgres
 		KubeClient:          kubeClient,
 		currentMajorVersion: 0,
 	}
-	cluster.logger = logger.WithField("pkg", "cluster").WithField("cluster-name", cluster.clusterName())
+	cluster.logger = newClusterLogger(logger).WithField("pkg", "cluster").WithField("cluster-name", cluster.clusterName())
+	cluster.setLogLevel()
 	cluster.teamsAPIClient = teams.NewTeamsAPI(cfg.OpConfig.TeamsAPIUrl, logger)
 	cluster.oauthTokenGetter = newSecretOauthTokenGetter(&kubeClient, cfg.OpConfig.OAuthTokenSecretName)
 	cluster.patroni = patroni.New(cluster.logger, nil)
 	cluster.eventRecorder = eventRecorder
+	if err := cluster.initTracedClients(); err != nil {
+		cluster.logger.Warningf("requests of the cluster will not be traced: %v", err)
+	}
 
 	cluster.EBSVolumes = make(map[string]volumes.VolumeProperties)
-	if cfg.OpConfig.StorageResizeMode != "pvc" || cfg.OpConfig.EnableEBSGp3Migration {
-		cluster.VolumeResizer = &volumes.EBSVolumeResizer{AWSRegion: cfg.OpConfig.AWSRegion}
+	cluster.volumeMigrations = make(map[string]*VolumeMigration)
+	if cfg.OpConfig.StorageResizeMode != "pvc" || cfg.OpConfig.EnableEBSGp3Migration || cfg.OpConfig.EnableVolumeMigration {
+		switch cfg.OpConfig.StorageResizeProvider {
+		case "gcp":
+			cluster.VolumeResizer = &volumes.GCEVolumeResizer{}
+		case "azure":
+			cluster.VolumeResizer = &volumes.AzureVolumeResizer{}
+		default:
+			cluster.VolumeResizer = &volumes.EBSVolumeResizer{AWSRegion: cfg.OpConfig.AWSRegion}
+		}
 	}
 
 	return cluster
@@ -170,12 +196,22 @@ func (c *Cluster) teamName() string {
 }
 
 func (c *Cluster) setProcessName(procName string, args ...interface{}) {
-	c.processMu.Lock()
-	defer c.processMu.Unlock()
-	c.currentProcess = Process{
+	process := Process{
 		Name:      fmt.Sprintf(procName, args...),
 		StartTime: time.Now(),
 	}
+	c.processMu.Lock()
+	c.currentProcess = process
+	c.processMu.Unlock()
+
+	if c.NotifyWatchers != nil {
+		c.NotifyWatchers(spec.WatchEvent{
+			Type:        spec.WatchEventProcess,
+			Time:        process.StartTime,
+			ClusterName: c.clusterName(),
+			Process:     process.Name,
+		})
+	}
 }
 
 // GetReference of Postgres CR object
@@ -316,6 +352,9 @@ func (c *Cluster) Create() error {
 	if c.Statefulset != nil {
 		return fmt.Errorf("statefulset already exists in the cluster")
 	}
+	if err = c.createCloneVolumeClaims(); err != nil {
+		return fmt.Errorf("could not create volume claims from volume snapshot: %v", err)
+	}
 	ss, err = c.createStatefulSet()
 	if err != nil {
 		return fmt.Errorf("could not create statefulset: %v", err)
@@ -344,6 +383,9 @@ func (c *Cluster) Create() error {
 		if err = c.syncDatabases(); err != nil {
 			return fmt.Errorf("could not sync databases: %v", err)
 		}
+		if err = c.syncTablespaces(); err != nil {
+			return fmt.Errorf("could not sync tablespaces: %v", err)
+		}
 		if err = c.syncPreparedDatabases(); err != nil {
 			return fmt.Errorf("could not sync prepared databases: %v", err)
 		}
@@ -398,6 +440,12 @@ func (c *Cluster) compareStatefulSetWith(statefulSet *appsv1.StatefulSet) *compa
 		needsReplace = true
 		reasons = append(reasons, "new statefulset's pod management policy do not match")
 	}
+	// without the StatefulSetAutoDeletePVC feature the API server drops the policy, so only compare it once stored
+	if c.Statefulset.Spec.PersistentVolumeClaimRetentionPolicy != nil &&
+		!reflect.DeepEqual(c.Statefulset.Spec.PersistentVolumeClaimRetentionPolicy, statefulSet.Spec.PersistentVolumeClaimRetentionPolicy) {
+		match = false
+		reasons = append(reasons, "new statefulset's persistent volume claim retention policy does not match the current one")
+	}
 
 	needsRollUpdate, reasons = c.compareContainers("initContainers", c.Statefulset.Spec.Template.Spec.InitContainers, statefulSet.Spec.Template.Spec.InitContainers, needsRollUpdate, reasons)
 	needsRollUpdate, reasons = c.compareContainers("containers", c.Statefulset.Spec.Template.Spec.Containers, statefulSet.Spec.Template.Spec.Containers, needsRollUpdate, reasons)
@@ -465,7 +513,7 @@ func (c *Cluster) compareStatefulSetWith(statefulSet *appsv1.StatefulSet) *compa
 		needsReplace = true
 		reasons = append(reasons, "new statefulset's volumeClaimTemplates contains different number of volumes to the old one")
 	}
-	for i := 0; i < len(c.Statefulset.Spec.VolumeClaimTemplates); i++ {
+	for i := 0; i < len(c.Statefulset.Spec.VolumeClaimTemplates) && i < len(statefulSet.Spec.VolumeClaimTemplates); i++ {
 		name := c.Statefulset.Spec.VolumeClaimTemplates[i].Name
 		// Some generated fields like creationTimestamp make it not possible to use DeepCompare on ObjectMeta
 		if name != statefulSet.Spec.VolumeClaimTemplates[i].Name {
@@ -761,6 +809,15 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 	c.KubeClient.SetPostgresCRDStatus(c.clusterName(), acidv1.ClusterStatusUpdating)
 	c.setSpec(newSpec)
 
+	span := c.startSpan("update")
+	defer func() {
+		var err error
+		if updateFailed {
+			err = fmt.Errorf("could not update cluster")
+		}
+		c.endSpan(span, err)
+	}()
+
 	defer func() {
 		if updateFailed {
 			c.KubeClient.SetPostgresCRDStatus(c.clusterName(), acidv1.ClusterStatusUpdateFailed)
@@ -784,7 +841,7 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 	// Service
 	if !reflect.DeepEqual(c.generateService(Master, &oldSpec.Spec), c.generateService(Master, &newSpec.Spec)) ||
 		!reflect.DeepEqual(c.generateService(Replica, &oldSpec.Spec), c.generateService(Replica, &newSpec.Spec)) {
-		if err := c.syncServices(); err != nil {
+		if err := c.traceStep("sync services", c.syncServices); err != nil {
 			c.logger.Errorf("could not sync services: %v", err)
 			updateFailed = true
 		}
@@ -797,6 +854,8 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 			reflect.DeepEqual(oldSpec.Spec.PreparedDatabases, newSpec.Spec.PreparedDatabases)
 		sameRotatedUsers := reflect.DeepEqual(oldSpec.Spec.UsersWithSecretRotation, newSpec.Spec.UsersWithSecretRotation) &&
 			reflect.DeepEqual(oldSpec.Spec.UsersWithInPlaceSecretRotation, newSpec.Spec.UsersWithInPlaceSecretRotation)
+		sameCertificateUsers := reflect.DeepEqual(oldSpec.Spec.UsersWithCertificateAuthentication, newSpec.Spec.UsersWithCertificateAuthentication)
+		samePasswordReferences := reflect.DeepEqual(oldSpec.Spec.UsersWithPasswordFromSecret, newSpec.Spec.UsersWithPasswordFromSecret)
 
 		// connection pooler needs one system user created who is initialized in initUsers
 		// only when disabled in oldSpec and enabled in newSpec
@@ -806,9 +865,9 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 		// only when streams were not specified in oldSpec but in newSpec
 		needStreamUser := len(oldSpec.Spec.Streams) == 0 && len(newSpec.Spec.Streams) > 0
 
-		if !sameUsers || !sameRotatedUsers || needPoolerUser || needStreamUser {
+		if !sameUsers || !sameRotatedUsers || !sameCertificateUsers || !samePasswordReferences || needPoolerUser || needStreamUser {
 			c.logger.Debugf("initialize users")
-			if err := c.initUsers(); err != nil {
+			if err := c.traceStep("init users", c.initUsers); err != nil {
 				c.logger.Errorf("could not init users - skipping sync of secrets and databases: %v", err)
 				userInitFailed = true
 				updateFailed = true
@@ -817,16 +876,24 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 
 			c.logger.Debugf("syncing secrets")
 			//TODO: mind the secrets of the deleted/new users
-			if err := c.syncSecrets(); err != nil {
+			if err := c.traceStep("sync secrets", c.syncSecrets); err != nil {
 				c.logger.Errorf("could not sync secrets: %v", err)
 				updateFailed = true
 			}
 		}
 	}()
 
+	// a lowered volume size must not be shadowed by a larger size recorded by autogrow
+	if oldSpec.Spec.Volume.Size != newSpec.Spec.Volume.Size {
+		if err := c.syncLoweredVolumeSize(oldSpec.Spec.Volume.Size, newSpec.Spec.Volume.Size); err != nil {
+			c.logger.Errorf("could not sync lowered volume size: %v", err)
+			updateFailed = true
+		}
+	}
+
 	// Volume
 	if c.OpConfig.StorageResizeMode != "off" {
-		c.syncVolumes()
+		c.traceStep("sync volumes", c.syncVolumes)
 	} else {
 		c.logger.Infof("Storage resize is disabled (storage_resize_mode is off). Skipping volume sync.")
 	}
@@ -850,17 +917,33 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 			c.logger.Debugf("syncing statefulsets")
 			syncStatefulSet = false
 			// TODO: avoid generating the StatefulSet object twice by passing it to syncStatefulSet
-			if err := c.syncStatefulSet(); err != nil {
+			if err := c.traceStep("sync statefulset", c.syncStatefulSet); err != nil {
 				c.logger.Errorf("could not sync statefulsets: %v", err)
 				updateFailed = true
 			}
 		}
 	}()
 
+	// volume rebuild
+	if c.OpConfig.EnableVolumeRebuild {
+		if err := c.traceStep("sync volume rebuild", c.syncVolumeRebuild); err != nil {
+			c.logger.Errorf("could not rebuild pods on new volumes: %v", err)
+			updateFailed = true
+		}
+	}
+
+	// volume claims of scaled down pods
+	if oldSpec.Spec.NumberOfInstances != newSpec.Spec.NumberOfInstances {
+		if err := c.traceStep("sync volume claims of scaled down pods", c.syncScaledDownVolumeClaims); err != nil {
+			c.logger.Errorf("could not sync volume claims of scaled down pods: %v", err)
+			updateFailed = true
+		}
+	}
+
 	// pod disruption budget
 	if oldSpec.Spec.NumberOfInstances != newSpec.Spec.NumberOfInstances {
 		c.logger.Debug("syncing pod disruption budgets")
-		if err := c.syncPodDisruptionBudget(true); err != nil {
+		if err := c.traceStep("sync pod disruption budget", func() error { return c.syncPodDisruptionBudget(true) }); err != nil {
 			c.logger.Errorf("could not sync pod disruption budget: %v", err)
 			updateFailed = true
 		}
@@ -906,21 +989,28 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 	// Roles and Databases
 	if !userInitFailed && !(c.databaseAccessDisabled() || c.getNumberOfInstances(&c.Spec) <= 0 || c.Spec.StandbyCluster != nil) {
 		c.logger.Debugf("syncing roles")
-		if err := c.syncRoles(); err != nil {
+		if err := c.traceStep("sync roles", c.syncRoles); err != nil {
 			c.logger.Errorf("could not sync roles: %v", err)
 			updateFailed = true
 		}
 		if !reflect.DeepEqual(oldSpec.Spec.Databases, newSpec.Spec.Databases) ||
 			!reflect.DeepEqual(oldSpec.Spec.PreparedDatabases, newSpec.Spec.PreparedDatabases) {
 			c.logger.Infof("syncing databases")
-			if err := c.syncDatabases(); err != nil {
+			if err := c.traceStep("sync databases", c.syncDatabases); err != nil {
 				c.logger.Errorf("could not sync databases: %v", err)
 				updateFailed = true
 			}
 		}
+		if !reflect.DeepEqual(oldSpec.Spec.Tablespaces, newSpec.Spec.Tablespaces) {
+			c.logger.Infof("syncing tablespaces")
+			if err := c.traceStep("sync tablespaces", c.syncTablespaces); err != nil {
+				c.logger.Errorf("could not sync tablespaces: %v", err)
+				updateFailed = true
+			}
+		}
 		if !reflect.DeepEqual(oldSpec.Spec.PreparedDatabases, newSpec.Spec.PreparedDatabases) {
 			c.logger.Infof("syncing prepared databases")
-			if err := c.syncPreparedDatabases(); err != nil {
+			if err := c.traceStep("sync prepared databases", c.syncPreparedDatabases); err != nil {
 				c.logger.Errorf("could not sync prepared databases: %v", err)
 				updateFailed = true
 			}
@@ -932,13 +1022,16 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 	// need to process. In the future we may want to do this more careful and
 	// check which databases we need to process, but even repeating the whole
 	// installation process should be good enough.
-	if _, err := c.syncConnectionPooler(oldSpec, newSpec, c.installLookupFunction); err != nil {
+	if err := c.traceStep("sync connection pooler", func() error {
+		_, err := c.syncConnectionPooler(oldSpec, newSpec, c.installLookupFunction)
+		return err
+	}); err != nil {
 		c.logger.Errorf("could not sync connection pooler: %v", err)
 		updateFailed = true
 	}
 
 	if len(newSpec.Spec.Streams) > 0 {
-		if err := c.syncStreams(); err != nil {
+		if err := c.traceStep("sync streams", c.syncStreams); err != nil {
 			c.logger.Errorf("could not sync streams: %v", err)
 			updateFailed = true
 		}
@@ -946,7 +1039,7 @@ func (c *Cluster) Update(oldSpec, newSpec *acidv1.Postgresql) error {
 
 	if !updateFailed {
 		// Major version upgrade must only fire after success of earlier operations and should stay last
-		if err := c.majorVersionUpgrade(); err != nil {
+		if err := c.traceStep("major version upgrade", c.majorVersionUpgrade); err != nil {
 			c.logger.Errorf("major version upgrade failed: %v", err)
 			updateFailed = true
 		}
@@ -992,6 +1085,7 @@ func (c *Cluster) Delete() {
 	if err := c.deleteStatefulSet(); err != nil {
 		c.logger.Warningf("could not delete statefulset: %v", err)
 	}
+	c.releaseVolumeMigrations()
 
 	if err := c.deleteSecrets(); err != nil {
 		c.logger.Warningf("could not delete secrets: %v", err)
@@ -1304,15 +1398,53 @@ func (c *Cluster) initRobotUsers() error {
 			AdminRole: adminRole,
 			IsDbOwner: isOwner,
 		}
+
+		// never fall back to a random password if the referenced one can not be read, but skip
+		// only this user, so its role keeps the current password and the rest of the cluster syncs
+		if passwordRef, exists := c.Spec.UsersWithPasswordFromSecret[username]; exists {
+			password, err := c.getPasswordFromSecret(namespace, passwordRef)
+			if err != nil {
+				c.logger.Warningf("skipping user %q: could not read password: %v", username, err)
+				c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeWarning, "Secrets", "Could not read password of user %q: %v", username, err)
+				continue
+			}
+			newRole.Password = password
+			newRole.ExternalPassword = true
+		}
+
 		if currentRole, present := c.pgUsers[username]; present {
 			c.pgUsers[username] = c.resolveNameConflict(&currentRole, &newRole)
 		} else {
 			c.pgUsers[username] = newRole
 		}
 	}
+
+	for username := range c.Spec.UsersWithPasswordFromSecret {
+		if _, exists := c.Spec.Users[username]; !exists {
+			c.logger.Warningf("password reference for user %q ignored: user is not defined in the manifest", username)
+		}
+	}
+
 	return nil
 }
 
+// getPasswordFromSecret reads a password from the referenced key of an existing secret
+func (c *Cluster) getPasswordFromSecret(namespace string, passwordRef acidv1.PasswordSecretReference) (string, error) {
+	secret, err := c.KubeClient.Secrets(namespace).Get(context.TODO(), passwordRef.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("could not get secret %s/%s: %v", namespace, passwordRef.SecretName, err)
+	}
+	password, exists := secret.Data[passwordRef.Key]
+	if !exists {
+		return "", fmt.Errorf("secret %s/%s has no key %q", namespace, passwordRef.SecretName, passwordRef.Key)
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("key %q of secret %s/%s is empty", passwordRef.Key, namespace, passwordRef.SecretName)
+	}
+
+	return string(password), nil
+}
+
 func (c *Cluster) initAdditionalOwnerRoles() {
 	if len(c.OpConfig.AdditionalOwnerRoles) == 0 {
 		return
@@ -1488,6 +1620,7 @@ func (c *Cluster) GetStatus() *ClusterStatus {
 		StatefulSet:         c.GetStatefulSet(),
 		PodDisruptionBudget: c.GetPodDisruptionBudget(),
 		CurrentProcess:      c.GetCurrentProcess(),
+		VolumeMigrations:    c.GetVolumeMigrations(),
 
 		Error: fmt.Errorf("error: %s", c.Error),
 	}
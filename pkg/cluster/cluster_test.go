@@ -1,11 +1,13 @@
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -182,6 +184,110 @@ func TestInitRobotUsers(t *testing.T) {
 	}
 }
 
+func TestInitRobotUsersWithPasswordFromSecret(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{SecretsGetter: clientSet.CoreV1()}
+	namespace := "default"
+
+	_, err := client.Secrets(namespace).Create(context.TODO(), &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy-credentials", Namespace: namespace},
+		Data:       map[string][]byte{"password": []byte("legacy"), "empty": []byte("")},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				Auth: config.Auth{
+					SuperUsername:       superUserName,
+					ReplicationUsername: replicationUserName,
+					SecretNameTemplate:  "{username}.{cluster}.credentials",
+				},
+			},
+		}, client, acidv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{Name: "acid-test", Namespace: namespace},
+			Spec: acidv1.PostgresSpec{
+				Users: map[string]acidv1.UserFlags{"foo": {}},
+			},
+		}, logger, recorder)
+	cluster.Name = "acid-test"
+	cluster.Namespace = namespace
+
+	tests := []struct {
+		subTest     string
+		passwordRef acidv1.PasswordSecretReference
+		event       string
+	}{
+		{
+			subTest:     "missing secret",
+			passwordRef: acidv1.PasswordSecretReference{SecretName: "missing", Key: "password"},
+			event:       `Warning Secrets Could not read password of user "foo": could not get secret default/missing: secrets "missing" not found`,
+		},
+		{
+			subTest:     "missing key",
+			passwordRef: acidv1.PasswordSecretReference{SecretName: "legacy-credentials", Key: "pass"},
+			event:       `Warning Secrets Could not read password of user "foo": secret default/legacy-credentials has no key "pass"`,
+		},
+		{
+			subTest:     "empty key",
+			passwordRef: acidv1.PasswordSecretReference{SecretName: "legacy-credentials", Key: "empty"},
+			event:       `Warning Secrets Could not read password of user "foo": key "empty" of secret default/legacy-credentials is empty`,
+		},
+		{
+			subTest:     "password from existing secret",
+			passwordRef: acidv1.PasswordSecretReference{SecretName: "legacy-credentials", Key: "password"},
+		},
+	}
+
+	for _, tt := range tests {
+		cluster.Spec.UsersWithPasswordFromSecret = map[string]acidv1.PasswordSecretReference{"foo": tt.passwordRef}
+		cluster.pgUsers = map[string]spec.PgUser{}
+		if err := cluster.initRobotUsers(); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.subTest, err)
+			continue
+		}
+		if tt.event != "" {
+			if _, exists := cluster.pgUsers["foo"]; exists {
+				t.Errorf("%s: user must not be initialized with a random password", tt.subTest)
+			}
+			if event := <-recorder.Events; event != tt.event {
+				t.Errorf("%s: expected warning event %q, got %q", tt.subTest, tt.event, event)
+			}
+			continue
+		}
+		if pgUser := cluster.pgUsers["foo"]; pgUser.Password != "legacy" || !pgUser.ExternalPassword {
+			t.Errorf("%s: expected password from secret, got %#v", tt.subTest, pgUser)
+		}
+	}
+
+	// the referenced password replaces the one in the user secret
+	generatedSecret := cluster.generateSingleUserSecret(namespace, cluster.pgUsers["foo"])
+	generatedSecret.Data["password"] = []byte("random")
+	_, err = client.Secrets(namespace).Create(context.TODO(), generatedSecret, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	retentionUsers := make([]string, 0)
+	err = cluster.updateSecret("foo", generatedSecret, &retentionUsers, time.Now())
+	assert.NoError(t, err)
+	updatedSecret, err := client.Secrets(namespace).Get(context.TODO(), generatedSecret.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy", string(updatedSecret.Data["password"]))
+
+	// one broken reference skips only its user
+	cluster.Spec.Users = map[string]acidv1.UserFlags{"foo": {}, "bar": {}, "baz": {}}
+	cluster.Spec.UsersWithPasswordFromSecret = map[string]acidv1.PasswordSecretReference{
+		"foo": {SecretName: "missing", Key: "password"},
+		"bar": {SecretName: "legacy-credentials", Key: "password"},
+	}
+	cluster.pgUsers = map[string]spec.PgUser{}
+	assert.NoError(t, cluster.initRobotUsers())
+	assert.NotContains(t, cluster.pgUsers, "foo")
+	assert.Equal(t, "legacy", cluster.pgUsers["bar"].Password)
+	assert.Contains(t, cluster.pgUsers, "baz")
+	assert.Equal(t, `Warning Secrets Could not read password of user "foo": could not get secret default/missing: secrets "missing" not found`, <-recorder.Events)
+}
+
 func TestInitAdditionalOwnerRoles(t *testing.T) {
 	manifestUsers := map[string]acidv1.UserFlags{"foo_owner": {}, "bar_owner": {}, "app_user": {}}
 	expectedUsers := map[string]spec.PgUser{
@@ -1,8 +1,12 @@
 package cluster
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,12 +15,17 @@ import (
 	acidzalando "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do"
 	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"github.com/zalando/postgres-operator/pkg/spec"
 	"github.com/zalando/postgres-operator/pkg/util"
 	"github.com/zalando/postgres-operator/pkg/util/config"
 	"github.com/zalando/postgres-operator/pkg/util/constants"
@@ -26,12 +35,15 @@ import (
 
 // ConnectionPoolerObjects K8s objects that are belong to connection pooler
 type ConnectionPoolerObjects struct {
-	Deployment  *appsv1.Deployment
-	Service     *v1.Service
-	Name        string
-	ClusterName string
-	Namespace   string
-	Role        PostgresRole
+	Deployment          *appsv1.Deployment
+	Service             *v1.Service
+	ConfigSecret        *v1.Secret
+	Autoscaler          *autoscalingv2.HorizontalPodAutoscaler
+	PodDisruptionBudget *policyv1.PodDisruptionBudget
+	Name                string
+	ClusterName         string
+	Namespace           string
+	Role                PostgresRole
 	// It could happen that a connection pooler was enabled, but the operator
 	// was not able to properly process a corresponding event or was restarted.
 	// In this case we will miss missing/require situation and a lookup function
@@ -39,6 +51,12 @@ type ConnectionPoolerObjects struct {
 	// this, we can remember the result in memory at least until the next
 	// restart.
 	LookupFunction bool
+	// Without autoscaling the HPA is looked up only once per operator run, to
+	// remove one left over from before. Remember that it was done.
+	AutoscalerChecked bool
+	// AdminPassword protects the admin console of pgcat. It is generated once
+	// and kept in the config secret, so the pods are not rolled on every sync.
+	AdminPassword string
 	// Careful with referencing cluster.spec this object pointer changes
 	// during runtime and lifetime of cluster
 }
@@ -168,7 +186,7 @@ func (c *Cluster) createConnectionPooler(LookupFunction InstallFunction) (SyncRe
 // MAX_DB_CONN would specify the global maximum for connections to a target
 // 	database.
 //
-// MAX_CLIENT_CONN is not configurable at the moment, just set it high enough.
+// MAX_CLIENT_CONN is set high enough unless configured in the manifest.
 //
 // DEFAULT_SIZE is a pool size per db/user (having in mind the use case when
 // 	most of the queries coming through a connection pooler are from the same
@@ -180,68 +198,1046 @@ func (c *Cluster) createConnectionPooler(LookupFunction InstallFunction) (SyncRe
 // 	have to wait for spinning up a new connections.
 //
 // RESERVE_SIZE is how many additional connections to allow for a pooler.
-func (c *Cluster) getConnectionPoolerEnvVars() []v1.EnvVar {
+//
+// DEFAULT_SIZE and RESERVE_SIZE are derived from MAX_DB_CONN unless configured
+// 	in the manifest. The image has no variables for server timeouts, ignored
+// 	startup parameters, per database pool modes and the auth file, they are
+// 	rendered into its configuration template instead (see pgbouncerPooler).
+//
+// CLIENT_TLS_CRT, CLIENT_TLS_KEY and CLIENT_CA_FILE point pgbouncer to the
+// 	mounted certificates. The image has no variables for the TLS modes and
+// 	the CA to verify Postgres with, they are rendered into its template.
+//
+func (c *Cluster) getConnectionPoolerEnvVars(role PostgresRole) []v1.EnvVar {
+	settings := c.getConnectionPoolerSettings(role)
+
+	envVars := []v1.EnvVar{
+		{
+			Name:  "CONNECTION_POOLER_PORT",
+			Value: fmt.Sprint(pgPort),
+		},
+		{
+			Name:  "CONNECTION_POOLER_MODE",
+			Value: settings.mode,
+		},
+		{
+			Name:  "CONNECTION_POOLER_DEFAULT_SIZE",
+			Value: fmt.Sprint(settings.defaultSize),
+		},
+		{
+			Name:  "CONNECTION_POOLER_MIN_SIZE",
+			Value: fmt.Sprint(settings.minSize),
+		},
+		{
+			Name:  "CONNECTION_POOLER_RESERVE_SIZE",
+			Value: fmt.Sprint(settings.reserveSize),
+		},
+		{
+			Name:  "CONNECTION_POOLER_MAX_CLIENT_CONN",
+			Value: fmt.Sprint(settings.maxClientConn),
+		},
+		{
+			Name:  "CONNECTION_POOLER_MAX_DB_CONN",
+			Value: fmt.Sprint(settings.maxDBConn),
+		},
+	}
+
+	tls := c.getConnectionPoolerTLS()
+	if tls.enabled() {
+		envVars = append(envVars,
+			v1.EnvVar{Name: "CONNECTION_POOLER_CLIENT_TLS_CRT", Value: tls.certificateFile},
+			v1.EnvVar{Name: "CONNECTION_POOLER_CLIENT_TLS_KEY", Value: tls.privateKeyFile},
+		)
+		if tls.caFile != "" {
+			envVars = append(envVars, v1.EnvVar{Name: "CONNECTION_POOLER_CLIENT_CA_FILE", Value: tls.caFile})
+		}
+	}
+
+	return envVars
+}
+
+// connectionPoolerInstances returns the number of pooler pods the connections
+// to the database are split across. With autoscaling it is the maximum of the
+// HPA, so the pods are not rolled on every scaling and never open more than
+// maxDBConnections together.
+func (c *Cluster) connectionPoolerInstances(connectionPoolerSpec *acidv1.ConnectionPooler) int32 {
+	numberOfInstances := connectionPoolerSpec.NumberOfInstances
+	if numberOfInstances == nil {
+		numberOfInstances = util.CoalesceInt32(
+			c.OpConfig.ConnectionPooler.NumberOfInstances,
+			k8sutil.Int32ToPointer(1))
+	}
+
+	if connectionPoolerSpec.Autoscaling == nil {
+		return *numberOfInstances
+	}
+
+	_, maxInstances := c.connectionPoolerAutoscalingBounds(connectionPoolerSpec.Autoscaling, numberOfInstances)
+	return maxInstances
+}
+
+// connectionPoolerAutoscalingBounds returns the minimum and maximum replicas
+// of an autoscaled pooler. The minimum defaults to the configured number of
+// instances and the maximum is not allowed to be lower than the minimum.
+func (c *Cluster) connectionPoolerAutoscalingBounds(autoscaling *acidv1.ConnectionPoolerAutoscaling, numberOfInstances *int32) (int32, int32) {
+	minInstances := int32(constants.ConnectionPoolerMinInstances)
+	if instances := util.CoalesceInt32(autoscaling.MinInstances, numberOfInstances); instances != nil && *instances > minInstances {
+		minInstances = *instances
+	}
+
+	maxInstances := autoscaling.MaxInstances
+	if maxInstances < minInstances {
+		maxInstances = minInstances
+	}
+
+	return minInstances, maxInstances
+}
+
+// connectionPoolerSettings holds the effective pool settings of a pooler pod,
+// shared by all pooler types
+type connectionPoolerSettings struct {
+	mode                    string
+	maxDBConn               int32
+	defaultSize             int32
+	minSize                 int32
+	reserveSize             int32
+	maxClientConn           int32
+	serverIdleTimeout       *int32
+	serverLifetime          *int32
+	ignoreStartupParameters []string
+	databasePoolModes       map[string]string
+	authFile                bool
+}
+
+// needPgbouncerTemplate tells if settings are used which the configuration
+// template of the pgbouncer image does not support
+func (s connectionPoolerSettings) needPgbouncerTemplate() bool {
+	return s.serverIdleTimeout != nil || s.serverLifetime != nil ||
+		len(s.ignoreStartupParameters) > 0 || len(s.databasePoolModes) > 0 || s.authFile
+}
+
+// databases returns the sorted names of databases with their own pool mode
+func (s connectionPoolerSettings) databases() []string {
+	databases := make([]string, 0, len(s.databasePoolModes))
+	for database := range s.databasePoolModes {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+
+	return databases
+}
+
+func (c *Cluster) getConnectionPoolerSettings(role PostgresRole) connectionPoolerSettings {
 	spec := &c.Spec
 	connectionPoolerSpec := spec.ConnectionPooler
 	if connectionPoolerSpec == nil {
 		connectionPoolerSpec = &acidv1.ConnectionPooler{}
 	}
-	effectiveMode := util.Coalesce(
-		connectionPoolerSpec.Mode,
-		c.OpConfig.ConnectionPooler.Mode)
+	effectiveMode := util.Coalesce(
+		connectionPoolerSpec.Mode,
+		c.OpConfig.ConnectionPooler.Mode)
+
+	maxDBConn := c.connectionPoolerMaxDBConnectionsPerPod(connectionPoolerSpec)
+
+	defaultSize := maxDBConn / 2
+	if connectionPoolerSpec.DefaultPoolSize != nil {
+		defaultSize = *connectionPoolerSpec.DefaultPoolSize
+	}
+	minSize := defaultSize / 2
+	reserveSize := minSize
+	if connectionPoolerSpec.ReservePoolSize != nil {
+		reserveSize = *connectionPoolerSpec.ReservePoolSize
+	}
+
+	maxClientConn := int32(constants.ConnectionPoolerMaxClientConnections)
+	if connectionPoolerSpec.MaxClientConnections != nil {
+		maxClientConn = *connectionPoolerSpec.MaxClientConnections
+	}
+
+	return connectionPoolerSettings{
+		mode:                    effectiveMode,
+		maxDBConn:               maxDBConn,
+		defaultSize:             defaultSize,
+		minSize:                 minSize,
+		reserveSize:             reserveSize,
+		maxClientConn:           maxClientConn,
+		serverIdleTimeout:       connectionPoolerSpec.ServerIdleTimeout,
+		serverLifetime:          connectionPoolerSpec.ServerLifetime,
+		ignoreStartupParameters: connectionPoolerSpec.IgnoreStartupParameters,
+		databasePoolModes:       connectionPoolerSpec.DatabasePoolModes,
+		authFile:                c.poolerAuthMode(spec) == poolerAuthModeFile,
+	}
+}
+
+// connectionPoolerMaxDBConnections returns the connections to the database all
+// pooler pods of a role may open together
+func (c *Cluster) connectionPoolerMaxDBConnections(connectionPoolerSpec *acidv1.ConnectionPooler) int32 {
+	effectiveMaxDBConn := util.CoalesceInt32(
+		connectionPoolerSpec.MaxDBConnections,
+		c.OpConfig.ConnectionPooler.MaxDBConnections)
+
+	if effectiveMaxDBConn == nil {
+		return constants.ConnectionPoolerMaxDBConnections
+	}
+
+	return *effectiveMaxDBConn
+}
+
+// connectionPoolerMaxDBConnectionsPerPod returns the connections to the
+// database a single pooler pod may open. It is at least 1, since pgbouncer
+// takes 0 as no limit at all.
+func (c *Cluster) connectionPoolerMaxDBConnectionsPerPod(connectionPoolerSpec *acidv1.ConnectionPooler) int32 {
+	maxDBConn := c.connectionPoolerMaxDBConnections(connectionPoolerSpec) / c.connectionPoolerInstances(connectionPoolerSpec)
+	if maxDBConn < 1 {
+		return 1
+	}
+
+	return maxDBConn
+}
+
+// validateConnectionPooler rejects pooler settings the pooler cannot work with
+func (c *Cluster) validateConnectionPooler(spec *acidv1.PostgresSpec) error {
+	connectionPoolerSpec := spec.ConnectionPooler
+	if connectionPoolerSpec == nil {
+		return nil
+	}
+
+	poolerType, err := c.getConnectionPoolerType(spec)
+	if err != nil {
+		return err
+	}
+	// odyssey is the only type without a default image
+	if _, ok := poolerType.(odysseyPooler); ok &&
+		util.Coalesce(connectionPoolerSpec.DockerImage, poolerType.defaultImage(&c.OpConfig.ConnectionPooler)) == "" {
+		return fmt.Errorf("no image configured for connection pooler type odyssey")
+	}
+
+	// pgcat offers TLS to clients, but cannot enforce it
+	if _, ok := poolerType.(pgcatPooler); ok {
+		switch connectionPoolerSpec.ClientTLSMode {
+		case "require", "verify-ca", "verify-full":
+			return fmt.Errorf("clientTLSMode %s is not supported by pgcat", connectionPoolerSpec.ClientTLSMode)
+		}
+	}
+
+	// every pod renders its share of maxDBConnections as max_db_connections
+	if connectionPoolerSpec.DefaultPoolSize != nil {
+		maxDBConn := c.connectionPoolerMaxDBConnectionsPerPod(connectionPoolerSpec)
+		if *connectionPoolerSpec.DefaultPoolSize > maxDBConn {
+			return fmt.Errorf("defaultPoolSize %d must not exceed the %d connections to the database per pooler pod",
+				*connectionPoolerSpec.DefaultPoolSize, maxDBConn)
+		}
+	}
+
+	return nil
+}
+
+const (
+	poolerConfigVolumeName         = "pooler-config"
+	poolerConfigMountPath          = "/etc/pooler"
+	poolerConfigChecksumAnnotation = "zalando-postgres-operator-pooler-config-checksum"
+	poolerAuthFileName             = "userlist.txt"
+	poolerAuthFileReloadAnnotation = "zalando-postgres-operator-pooler-auth-file-reload"
+	poolerAuthModeFile             = "file"
+	pgcatAdminUser                 = "admin"
+	pgcatAdminPasswordKey          = "admin-password"
+	poolerTLSVolumeName            = "pooler-tls"
+	poolerTLSCAVolumeName          = "pooler-tls-ca"
+	poolerTLSMountPath             = "/tls"
+	pgbouncerTemplateFileName      = "pgbouncer.ini.tmpl"
+	// startup parameters ignored by the template of the pgbouncer image
+	pgbouncerIgnoreStartupParameters = "extra_float_digits,options"
+	// TLS mode of the template of the pgbouncer image
+	pgbouncerDefaultTLSMode = "require"
+)
+
+// connectionPoolerTLS holds the effective TLS settings of the pooler pods.
+// Client connections are encrypted with the certificate from the pooler's
+// own tls section or, if not set, the one of the cluster. Connections to
+// Postgres are encrypted whenever clients use TLS, so it is end to end.
+type connectionPoolerTLS struct {
+	description     *acidv1.TLSDescription
+	certificateFile string
+	privateKeyFile  string
+	caFile          string
+	clientMode      string
+	serverMode      string
+}
+
+func (c *Cluster) getConnectionPoolerTLS() connectionPoolerTLS {
+	var result connectionPoolerTLS
+	connectionPoolerSpec := c.Spec.ConnectionPooler
+	if connectionPoolerSpec == nil {
+		connectionPoolerSpec = &acidv1.ConnectionPooler{}
+	}
+
+	description := connectionPoolerSpec.TLS
+	if description == nil || description.SecretName == "" {
+		description = c.Spec.TLS
+	}
+
+	if description != nil && description.SecretName != "" {
+		result.description = description
+		// use the same filenames as Secret resources by default
+		result.certificateFile = ensurePath(description.CertificateFile, poolerTLSMountPath, "tls.crt")
+		result.privateKeyFile = ensurePath(description.PrivateKeyFile, poolerTLSMountPath, "tls.key")
+		if description.CAFile != "" {
+			mountPathCA := poolerTLSMountPath
+			if description.CASecretName != "" {
+				mountPathCA = poolerTLSMountPath + "ca"
+			}
+			result.caFile = ensurePath(description.CAFile, mountPathCA, "")
+		}
+		// clients are only forced to use TLS when the pooler got a certificate
+		// of its own, the one of the cluster is just offered to them
+		defaultClientMode := "prefer"
+		if connectionPoolerSpec.TLS != nil && connectionPoolerSpec.TLS.SecretName != "" {
+			defaultClientMode = "require"
+		}
+		result.clientMode = util.Coalesce(connectionPoolerSpec.ClientTLSMode, defaultClientMode)
+		result.serverMode = "require"
+	}
+	result.serverMode = util.Coalesce(connectionPoolerSpec.ServerTLSMode, result.serverMode)
+
+	return result
+}
+
+func (t connectionPoolerTLS) enabled() bool {
+	return t.description != nil
+}
+
+// pgbouncerDefaults tells if the TLS settings match the template of the
+// pgbouncer image, which only takes the certificate files from the environment
+// and always requires TLS for client and server connections
+func (t connectionPoolerTLS) pgbouncerDefaults() bool {
+	return (!t.enabled() || t.clientMode == pgbouncerDefaultTLSMode) &&
+		util.Coalesce(t.serverMode, pgbouncerDefaultTLSMode) == pgbouncerDefaultTLSMode &&
+		t.caFile == ""
+}
+
+// serverTLSEnabled tells if connections to Postgres must be encrypted
+func (t connectionPoolerTLS) serverTLSEnabled() bool {
+	return t.serverMode != "" && t.serverMode != "disable" && t.serverMode != "allow"
+}
+
+// volumes returns the secret volumes with the certificates and their mounts
+func (t connectionPoolerTLS) volumes() ([]v1.Volume, []v1.VolumeMount) {
+	if !t.enabled() {
+		return nil, nil
+	}
+
+	// like the config volume, the volumes are kept by the merge patch of the
+	// deployment once TLS is disabled and must not block pods from starting
+	defaultMode := int32(0640)
+	volumes := []v1.Volume{
+		{
+			Name: poolerTLSVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName:  t.description.SecretName,
+					DefaultMode: &defaultMode,
+					Optional:    util.True(),
+				},
+			},
+		},
+	}
+	mounts := []v1.VolumeMount{
+		{
+			Name:      poolerTLSVolumeName,
+			MountPath: poolerTLSMountPath,
+			ReadOnly:  true,
+		},
+	}
+
+	// the ca file from CASecretName secret takes priority
+	if t.description.CAFile != "" && t.description.CASecretName != "" {
+		volumes = append(volumes, v1.Volume{
+			Name: poolerTLSCAVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName:  t.description.CASecretName,
+					DefaultMode: &defaultMode,
+					Optional:    util.True(),
+				},
+			},
+		})
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      poolerTLSCAVolumeName,
+			MountPath: poolerTLSMountPath + "ca",
+			ReadOnly:  true,
+		})
+	}
+
+	return volumes, mounts
+}
+
+// connectionPoolerType abstracts the differences between the supported pooler
+// implementations. The pgbouncer image configures itself from environment
+// variables, while pgcat and odyssey read a configuration file generated by
+// the operator and mounted from a secret, since it contains the credentials
+// of the pooler user.
+type connectionPoolerType interface {
+	// defaultImage returns the image configured in the operator for the type
+	defaultImage(config *config.ConnectionPooler) string
+	// envVars returns the pooler specific environment of the container
+	envVars(c *Cluster, role PostgresRole) []v1.EnvVar
+	// configFileName returns the name of the configuration file or an empty
+	// string if the pooler does not need one
+	configFileName() string
+	// configFile returns the content of the configuration file for the role
+	configFile(c *Cluster, role PostgresRole) string
+	// command returns the command to start the pooler with the given file
+	command(configFile string) []string
+	// authFileName returns the name of the file with the user credentials
+	// in auth file mode or an empty string if they are part of the config
+	authFileName() string
+}
+
+// pgbouncerPooler renders its configuration at startup from a template in the
+// image, which takes most settings from environment variables. The operator
+// replaces the template only if settings are used the image does not support.
+type pgbouncerPooler struct{}
+
+func (p pgbouncerPooler) defaultImage(config *config.ConnectionPooler) string {
+	return config.Image
+}
+
+func (p pgbouncerPooler) envVars(c *Cluster, role PostgresRole) []v1.EnvVar {
+	return c.getConnectionPoolerEnvVars(role)
+}
+
+func (p pgbouncerPooler) configFileName() string {
+	return pgbouncerTemplateFileName
+}
+
+// configFile returns the configuration template of the pgbouncer image with
+// the additional settings. Variables are still substituted from the
+// environment when the pooler starts. Files and settings of the image which
+// are not managed by the operator are taken from the operator configuration,
+// so they can follow the image.
+func (p pgbouncerPooler) configFile(c *Cluster, role PostgresRole) string {
+	settings := c.getConnectionPoolerSettings(role)
+	tls := c.getConnectionPoolerTLS()
+	if !settings.needPgbouncerTemplate() && tls.pgbouncerDefaults() {
+		return ""
+	}
+	poolerConfig := c.OpConfig.ConnectionPooler
+
+	// with the auth file clients are not looked up through the pooler user
+	server := "host=$PGHOST port=$PGPORT auth_user=$PGUSER"
+	if settings.authFile {
+		server = "host=$PGHOST port=$PGPORT"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[databases]\n")
+	for _, database := range settings.databases() {
+		fmt.Fprintf(&b, "%s = %s pool_mode=%s\n",
+			pgbouncerQuote(database), server, settings.databasePoolModes[database])
+	}
+	fmt.Fprintf(&b, "* = %s\n", server)
+
+	fmt.Fprintf(&b, "\n[pgbouncer]\n")
+	fmt.Fprintf(&b, "pool_mode = $CONNECTION_POOLER_MODE\n")
+	fmt.Fprintf(&b, "listen_port = $CONNECTION_POOLER_PORT\n")
+	fmt.Fprintf(&b, "listen_addr = *\n")
+	fmt.Fprintf(&b, "auth_type = md5\n")
+	if settings.authFile {
+		fmt.Fprintf(&b, "auth_file = %s/%s\n", poolerConfigMountPath, poolerAuthFileName)
+	} else {
+		fmt.Fprintf(&b, "auth_file = %s\n", util.Coalesce(poolerConfig.PgbouncerAuthFile, constants.ConnectionPoolerPgbouncerAuthFile))
+		fmt.Fprintf(&b, "auth_query = SELECT * FROM $PGSCHEMA.user_lookup($1)\n")
+	}
+	fmt.Fprintf(&b, "stats_users_prefix = %s\n", util.Coalesce(poolerConfig.PgbouncerStatsUsersPrefix, constants.ConnectionPoolerPgbouncerStatsUsersPrefix))
+	fmt.Fprintf(&b, "default_pool_size = $CONNECTION_POOLER_DEFAULT_SIZE\n")
+	fmt.Fprintf(&b, "reserve_pool_size = $CONNECTION_POOLER_RESERVE_SIZE\n")
+	fmt.Fprintf(&b, "max_client_conn = $CONNECTION_POOLER_MAX_CLIENT_CONN\n")
+	fmt.Fprintf(&b, "max_db_connections = $CONNECTION_POOLER_MAX_DB_CONN\n")
+	fmt.Fprintf(&b, "idle_transaction_timeout = 600\n")
+	fmt.Fprintf(&b, "server_login_retry = 5\n")
+
+	ignoreStartupParameters := pgbouncerIgnoreStartupParameters
+	if len(settings.ignoreStartupParameters) > 0 {
+		ignoreStartupParameters = strings.Join(settings.ignoreStartupParameters, ",")
+	}
+	fmt.Fprintf(&b, "ignore_startup_parameters = %s\n", ignoreStartupParameters)
+	if settings.serverIdleTimeout != nil {
+		fmt.Fprintf(&b, "server_idle_timeout = %d\n", *settings.serverIdleTimeout)
+	}
+	if settings.serverLifetime != nil {
+		fmt.Fprintf(&b, "server_lifetime = %d\n", *settings.serverLifetime)
+	}
+
+	// without a certificate the TLS settings of the image's template are kept,
+	// which encrypts client connections with a self-signed certificate
+	clientMode := pgbouncerDefaultTLSMode
+	certificateFile := util.Coalesce(poolerConfig.PgbouncerTLSCertificateFile, constants.ConnectionPoolerPgbouncerTLSCertificateFile)
+	privateKeyFile := util.Coalesce(poolerConfig.PgbouncerTLSPrivateKeyFile, constants.ConnectionPoolerPgbouncerTLSPrivateKeyFile)
+	if tls.enabled() {
+		clientMode = tls.clientMode
+		certificateFile = tls.certificateFile
+		privateKeyFile = tls.privateKeyFile
+	}
+	fmt.Fprintf(&b, "client_tls_sslmode = %s\n", clientMode)
+	fmt.Fprintf(&b, "client_tls_cert_file = %s\n", certificateFile)
+	fmt.Fprintf(&b, "client_tls_key_file = %s\n", privateKeyFile)
+	if tls.caFile != "" {
+		fmt.Fprintf(&b, "client_tls_ca_file = %s\n", tls.caFile)
+	}
+	fmt.Fprintf(&b, "server_tls_sslmode = %s\n", util.Coalesce(tls.serverMode, pgbouncerDefaultTLSMode))
+	if tls.caFile != "" {
+		fmt.Fprintf(&b, "server_tls_ca_file = %s\n", tls.caFile)
+	}
+
+	return b.String()
+}
+
+// pgbouncerTemplatePath returns where the pgbouncer image expects the
+// template it renders its configuration from
+func (c *Cluster) pgbouncerTemplatePath() string {
+	return util.Coalesce(c.OpConfig.ConnectionPooler.PgbouncerTemplatePath, constants.ConnectionPoolerPgbouncerTemplatePath)
+}
+
+func (p pgbouncerPooler) command(configFile string) []string {
+	return nil
+}
+
+func (p pgbouncerPooler) authFileName() string {
+	return poolerAuthFileName
+}
+
+// pgbouncerQuote quotes a database name like an SQL identifier, which is how
+// pgbouncer expects names with special characters
+func pgbouncerQuote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// pgcatPooler balances read queries of the master pooler between the master
+// and the replicas through the query parser, so clients can use one endpoint.
+type pgcatPooler struct{}
+
+func (p pgcatPooler) defaultImage(config *config.ConnectionPooler) string {
+	return config.PgcatImage
+}
+
+func (p pgcatPooler) envVars(c *Cluster, role PostgresRole) []v1.EnvVar {
+	return nil
+}
+
+func (p pgcatPooler) configFileName() string {
+	return "pgcat.toml"
+}
+
+func (p pgcatPooler) configFile(c *Cluster, role PostgresRole) string {
+	settings := c.getConnectionPoolerSettings(role)
+	poolerUser := c.poolerCredentials()
+	schema := c.poolerSchema(&c.Spec)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[general]\n")
+	fmt.Fprintf(&b, "host = \"0.0.0.0\"\n")
+	fmt.Fprintf(&b, "port = %d\n", pgPort)
+	fmt.Fprintf(&b, "admin_username = %q\n", pgcatAdminUser)
+	fmt.Fprintf(&b, "admin_password = %q\n", c.connectionPoolerAdminPassword(role))
+	// pgcat expects timeouts in milliseconds
+	if settings.serverIdleTimeout != nil {
+		fmt.Fprintf(&b, "idle_timeout = %d\n", *settings.serverIdleTimeout*1000)
+	}
+	if settings.serverLifetime != nil {
+		fmt.Fprintf(&b, "server_lifetime = %d\n", *settings.serverLifetime*1000)
+	}
+	// pgcat accepts TLS from clients once a certificate is configured, but
+	// cannot enforce it
+	tls := c.getConnectionPoolerTLS()
+	if tls.enabled() {
+		fmt.Fprintf(&b, "tls_certificate = %q\n", tls.certificateFile)
+		fmt.Fprintf(&b, "tls_private_key = %q\n", tls.privateKeyFile)
+	}
+	if tls.serverTLSEnabled() {
+		fmt.Fprintf(&b, "server_tls = true\n")
+		if strings.HasPrefix(tls.serverMode, "verify-") {
+			fmt.Fprintf(&b, "verify_server_certificate = true\n")
+		}
+	}
+
+	authFile := c.poolerAuthMode(&c.Spec) == poolerAuthModeFile
+	servers := fmt.Sprintf("[%q, %d, \"replica\"]", c.serviceAddress(Replica), pgPort)
+	defaultRole := "replica"
+	if role == Master {
+		servers = fmt.Sprintf("[%q, %d, \"primary\"], %s", c.serviceAddress(Master), pgPort, servers)
+		defaultRole = "any"
+	}
+
+	for _, database := range c.poolerDatabases() {
+		mode := settings.mode
+		if databaseMode, exists := settings.databasePoolModes[database]; exists {
+			mode = databaseMode
+		}
+		fmt.Fprintf(&b, "\n[pools.%q]\n", database)
+		fmt.Fprintf(&b, "pool_mode = %q\n", mode)
+		fmt.Fprintf(&b, "default_role = %q\n", defaultRole)
+		fmt.Fprintf(&b, "query_parser_enabled = true\n")
+		fmt.Fprintf(&b, "query_parser_read_write_splitting = true\n")
+		fmt.Fprintf(&b, "primary_reads_enabled = false\n")
+		if !authFile {
+			fmt.Fprintf(&b, "auth_query = %q\n", fmt.Sprintf("SELECT uname, phash FROM %s.user_lookup('$1')", schema))
+			fmt.Fprintf(&b, "auth_query_user = %q\n", poolerUser.Name)
+			fmt.Fprintf(&b, "auth_query_password = %q\n", poolerUser.Password)
+		}
+		for i, user := range c.poolerClientUsers() {
+			fmt.Fprintf(&b, "\n[pools.%q.users.%d]\n", database, i)
+			fmt.Fprintf(&b, "username = %q\n", user.Name)
+			if authFile {
+				fmt.Fprintf(&b, "password = %q\n", user.Password)
+			}
+			fmt.Fprintf(&b, "pool_size = %d\n", settings.defaultSize)
+			fmt.Fprintf(&b, "min_pool_size = %d\n", settings.minSize)
+		}
+		fmt.Fprintf(&b, "\n[pools.%q.shards.0]\n", database)
+		fmt.Fprintf(&b, "database = %q\n", database)
+		fmt.Fprintf(&b, "servers = [%s]\n", servers)
+	}
+
+	return b.String()
+}
+
+func (p pgcatPooler) command(configFile string) []string {
+	return []string{"pgcat", configFile}
+}
+
+func (p pgcatPooler) authFileName() string {
+	return ""
+}
+
+type odysseyPooler struct{}
+
+func (p odysseyPooler) defaultImage(config *config.ConnectionPooler) string {
+	return config.OdysseyImage
+}
+
+func (p odysseyPooler) envVars(c *Cluster, role PostgresRole) []v1.EnvVar {
+	return nil
+}
+
+func (p odysseyPooler) configFileName() string {
+	return "odyssey.conf"
+}
+
+func (p odysseyPooler) configFile(c *Cluster, role PostgresRole) string {
+	settings := c.getConnectionPoolerSettings(role)
+	poolerUser := c.poolerCredentials()
+	schema := c.poolerSchema(&c.Spec)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "daemonize no\n")
+	fmt.Fprintf(&b, "log_to_stdout yes\n")
+	fmt.Fprintf(&b, "client_max %d\n", settings.maxClientConn)
+
+	tls := c.getConnectionPoolerTLS()
+	fmt.Fprintf(&b, "\nlisten {\n\thost \"*\"\n\tport %d\n", pgPort)
+	if tls.enabled() {
+		fmt.Fprintf(&b, "\ttls %q\n", odysseyTLSMode(tls.clientMode))
+		fmt.Fprintf(&b, "\ttls_cert_file %q\n", tls.certificateFile)
+		fmt.Fprintf(&b, "\ttls_key_file %q\n", tls.privateKeyFile)
+		if tls.caFile != "" {
+			fmt.Fprintf(&b, "\ttls_ca_file %q\n", tls.caFile)
+		}
+	}
+	fmt.Fprintf(&b, "}\n")
+
+	fmt.Fprintf(&b, "\nstorage \"postgres_server\" {\n\ttype \"remote\"\n\thost %q\n\tport %d\n",
+		c.serviceAddress(role), c.servicePort(role))
+	if tls.serverMode != "" {
+		fmt.Fprintf(&b, "\ttls %q\n", odysseyTLSMode(tls.serverMode))
+		if tls.caFile != "" {
+			fmt.Fprintf(&b, "\ttls_ca_file %q\n", tls.caFile)
+		}
+	}
+	fmt.Fprintf(&b, "}\n")
+
+	poolSettings := func(mode string) {
+		fmt.Fprintf(&b, "\t\tstorage \"postgres_server\"\n")
+		fmt.Fprintf(&b, "\t\tpool %q\n", mode)
+		fmt.Fprintf(&b, "\t\tpool_size %d\n", settings.defaultSize)
+		if settings.serverIdleTimeout != nil {
+			fmt.Fprintf(&b, "\t\tpool_ttl %d\n", *settings.serverIdleTimeout)
+		}
+		if settings.serverLifetime != nil {
+			fmt.Fprintf(&b, "\t\tserver_lifetime %d\n", *settings.serverLifetime)
+		}
+	}
+
+	route := func(database, mode string) {
+		fmt.Fprintf(&b, "\ndatabase %s {\n", database)
+		if c.poolerAuthMode(&c.Spec) == poolerAuthModeFile {
+			// every known user gets a route with its own credentials
+			for _, user := range c.poolerClientUsers() {
+				fmt.Fprintf(&b, "\tuser %q {\n", user.Name)
+				fmt.Fprintf(&b, "\t\tauthentication \"md5\"\n")
+				fmt.Fprintf(&b, "\t\tpassword %q\n", user.Password)
+				fmt.Fprintf(&b, "\t\tstorage_password %q\n", user.Password)
+				poolSettings(mode)
+				fmt.Fprintf(&b, "\t}\n")
+			}
+		} else {
+			fmt.Fprintf(&b, "\tuser default {\n")
+			fmt.Fprintf(&b, "\t\tauthentication \"md5\"\n")
+			fmt.Fprintf(&b, "\t\tauth_query %q\n", fmt.Sprintf("SELECT uname, phash FROM %s.user_lookup($1)", schema))
+			fmt.Fprintf(&b, "\t\tauth_query_db \"postgres\"\n")
+			fmt.Fprintf(&b, "\t\tauth_query_user %q\n", poolerUser.Name)
+			poolSettings(mode)
+			fmt.Fprintf(&b, "\t}\n")
+		}
+		fmt.Fprintf(&b, "}\n")
+	}
+
+	// the pooler user looks up the password hashes of connecting clients
+	fmt.Fprintf(&b, "\ndatabase \"postgres\" {\n\tuser %q {\n", poolerUser.Name)
+	fmt.Fprintf(&b, "\t\tauthentication \"md5\"\n")
+	fmt.Fprintf(&b, "\t\tpassword %q\n", poolerUser.Password)
+	fmt.Fprintf(&b, "\t\tstorage \"postgres_server\"\n")
+	fmt.Fprintf(&b, "\t\tstorage_user %q\n", poolerUser.Name)
+	fmt.Fprintf(&b, "\t\tstorage_password %q\n", poolerUser.Password)
+	fmt.Fprintf(&b, "\t\tpool \"session\"\n")
+	fmt.Fprintf(&b, "\t}\n}\n")
+
+	for _, database := range settings.databases() {
+		route(fmt.Sprintf("%q", database), settings.databasePoolModes[database])
+	}
+	route("default", settings.mode)
+
+	return b.String()
+}
+
+func (p odysseyPooler) command(configFile string) []string {
+	return []string{"odyssey", configFile}
+}
+
+func (p odysseyPooler) authFileName() string {
+	return ""
+}
+
+// odysseyTLSMode translates a libpq sslmode to the odyssey tls mode, which
+// has no prefer mode
+func odysseyTLSMode(mode string) string {
+	if mode == "prefer" {
+		return "allow"
+	}
+	return strings.Replace(mode, "-", "_", -1)
+}
+
+// poolerType returns the effective type of the connection pooler
+func (c *Cluster) poolerType(spec *acidv1.PostgresSpec) string {
+	poolerType := c.OpConfig.ConnectionPooler.Type
+	if spec.ConnectionPooler != nil && spec.ConnectionPooler.Type != "" {
+		poolerType = spec.ConnectionPooler.Type
+	}
+
+	return util.Coalesce(poolerType, constants.ConnectionPoolerDefaultType)
+}
+
+func (c *Cluster) getConnectionPoolerType(spec *acidv1.PostgresSpec) (connectionPoolerType, error) {
+	switch poolerType := c.poolerType(spec); poolerType {
+	case "pgbouncer":
+		return pgbouncerPooler{}, nil
+	case "pgcat":
+		return pgcatPooler{}, nil
+	case "odyssey":
+		return odysseyPooler{}, nil
+	default:
+		return nil, fmt.Errorf("unknown connection pooler type %q", poolerType)
+	}
+}
+
+func (c *Cluster) poolerSchema(spec *acidv1.PostgresSpec) string {
+	specSchema := ""
+	if spec.ConnectionPooler != nil {
+		specSchema = spec.ConnectionPooler.Schema
+	}
+
+	return util.Coalesce(specSchema, c.OpConfig.ConnectionPooler.Schema)
+}
+
+// poolerAuthMode returns how the pooler authenticates clients, either through
+// the lookup function installed in every database or with the credentials of
+// the operator-managed users rendered into its configuration
+func (c *Cluster) poolerAuthMode(spec *acidv1.PostgresSpec) string {
+	authMode := c.OpConfig.ConnectionPooler.AuthMode
+	if spec.ConnectionPooler != nil && spec.ConnectionPooler.AuthMode != "" {
+		authMode = spec.ConnectionPooler.AuthMode
+	}
+
+	return util.Coalesce(authMode, constants.ConnectionPoolerDefaultAuthMode)
+}
+
+// poolerCredentials returns the pooler user with the password synced from its secret
+func (c *Cluster) poolerCredentials() spec.PgUser {
+	if poolerUser, exists := c.systemUsers[constants.ConnectionPoolerUserKeyName]; exists {
+		return poolerUser
+	}
+
+	return spec.PgUser{Name: c.poolerUser(&c.Spec)}
+}
+
+// poolerDatabases returns the sorted databases a pooler with a configuration
+// file has to define pools for
+func (c *Cluster) poolerDatabases() []string {
+	databases := []string{"postgres"}
+	for database := range c.Spec.Databases {
+		if !util.SliceContains(databases, database) {
+			databases = append(databases, database)
+		}
+	}
+	for database := range c.Spec.PreparedDatabases {
+		if !util.SliceContains(databases, database) {
+			databases = append(databases, database)
+		}
+	}
+	sort.Strings(databases)
+
+	return databases
+}
+
+// poolerClientUsers returns the login roles sorted by name which can connect
+// through a pooler that requires users to be listed in its configuration
+func (c *Cluster) poolerClientUsers() []spec.PgUser {
+	users := make([]spec.PgUser, 0)
+	for _, pgUser := range c.pgUsers {
+		if util.SliceContains(pgUser.Flags, constants.RoleFlagNoLogin) {
+			continue
+		}
+		users = append(users, pgUser)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+
+	return users
+}
+
+// poolerAuthFile renders the credentials of the pooler and client users in
+// the auth_file format of pgbouncer. Names and passwords are taken from the
+// users synced with their secrets, so rotated passwords are included.
+func (c *Cluster) poolerAuthFile() string {
+	quote := func(value string) string {
+		return `"` + strings.Replace(value, `"`, `""`, -1) + `"`
+	}
+
+	var b strings.Builder
+	for _, user := range append([]spec.PgUser{c.poolerCredentials()}, c.poolerClientUsers()...) {
+		if user.Password == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s\n", quote(user.Name), quote(user.Password))
+	}
+
+	return b.String()
+}
+
+// connectionPoolerConfigFiles returns the files mounted into the pooler pods
+// from the config secret by their name
+func (c *Cluster) connectionPoolerConfigFiles(poolerType connectionPoolerType, role PostgresRole) map[string]string {
+	files := make(map[string]string)
+	if fileName := poolerType.configFileName(); fileName != "" {
+		if content := poolerType.configFile(c, role); content != "" {
+			files[fileName] = content
+		}
+	}
+	if fileName := poolerType.authFileName(); fileName != "" && c.poolerAuthMode(&c.Spec) == poolerAuthModeFile {
+		files[fileName] = c.poolerAuthFile()
+	}
+
+	return files
+}
+
+func (c *Cluster) connectionPoolerConfigSecretName(role PostgresRole) string {
+	return c.connectionPoolerName(role) + "-config"
+}
+
+// generateConnectionPoolerConfigSecret returns the secret holding the pooler
+// configuration and auth files or nil if the pooler does not need any
+func (c *Cluster) generateConnectionPoolerConfigSecret(poolerType connectionPoolerType, role PostgresRole) *v1.Secret {
+	files := c.connectionPoolerConfigFiles(poolerType, role)
+	if len(files) == 0 {
+		return nil
+	}
 
-	numberOfInstances := connectionPoolerSpec.NumberOfInstances
-	if numberOfInstances == nil {
-		numberOfInstances = util.CoalesceInt32(
-			c.OpConfig.ConnectionPooler.NumberOfInstances,
-			k8sutil.Int32ToPointer(1))
+	data := make(map[string][]byte, len(files))
+	for fileName, content := range files {
+		data[fileName] = []byte(content)
+	}
+	if _, ok := poolerType.(pgcatPooler); ok {
+		data[pgcatAdminPasswordKey] = []byte(c.connectionPoolerAdminPassword(role))
 	}
 
-	effectiveMaxDBConn := util.CoalesceInt32(
-		connectionPoolerSpec.MaxDBConnections,
-		c.OpConfig.ConnectionPooler.MaxDBConnections)
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            c.connectionPoolerConfigSecretName(role),
+			Namespace:       c.Namespace,
+			Labels:          c.connectionPoolerLabels(role, true).MatchLabels,
+			Annotations:     c.annotationsSet(nil),
+			OwnerReferences: c.ownerReferences(),
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: data,
+	}
+}
 
-	if effectiveMaxDBConn == nil {
-		effectiveMaxDBConn = k8sutil.Int32ToPointer(
-			constants.ConnectionPoolerMaxDBConnections)
+// syncConnectionPoolerConfigSecret keeps the configuration and auth files of
+// poolers which need them up to date and removes the secret once unused
+func (c *Cluster) syncConnectionPoolerConfigSecret(role PostgresRole) error {
+	poolerType, err := c.getConnectionPoolerType(&c.Spec)
+	if err != nil {
+		return err
 	}
 
-	maxDBConn := *effectiveMaxDBConn / *numberOfInstances
+	// look up the secret by name, it might be left over from before a restart
+	secret, err := c.KubeClient.Secrets(c.Namespace).Get(context.TODO(), c.connectionPoolerConfigSecretName(role), metav1.GetOptions{})
+	if err != nil && !k8sutil.ResourceNotFound(err) {
+		return fmt.Errorf("could not get connection pooler config secret: %v", err)
+	}
+	secretExists := err == nil
+	if secretExists && c.ConnectionPooler[role].AdminPassword == "" {
+		c.ConnectionPooler[role].AdminPassword = string(secret.Data[pgcatAdminPasswordKey])
+	}
 
-	defaultSize := maxDBConn / 2
-	minSize := defaultSize / 2
-	reserveSize := minSize
+	desiredSecret := c.generateConnectionPoolerConfigSecret(poolerType, role)
+	if desiredSecret == nil {
+		if secretExists {
+			return c.deleteConnectionPoolerConfigSecret(role)
+		}
+		return nil
+	}
 
-	return []v1.EnvVar{
-		{
-			Name:  "CONNECTION_POOLER_PORT",
-			Value: fmt.Sprint(pgPort),
-		},
-		{
-			Name:  "CONNECTION_POOLER_MODE",
-			Value: effectiveMode,
-		},
-		{
-			Name:  "CONNECTION_POOLER_DEFAULT_SIZE",
-			Value: fmt.Sprint(defaultSize),
-		},
-		{
-			Name:  "CONNECTION_POOLER_MIN_SIZE",
-			Value: fmt.Sprint(minSize),
-		},
-		{
-			Name:  "CONNECTION_POOLER_RESERVE_SIZE",
-			Value: fmt.Sprint(reserveSize),
-		},
-		{
-			Name:  "CONNECTION_POOLER_MAX_CLIENT_CONN",
-			Value: fmt.Sprint(constants.ConnectionPoolerMaxClientConnections),
-		},
-		{
-			Name:  "CONNECTION_POOLER_MAX_DB_CONN",
-			Value: fmt.Sprint(maxDBConn),
-		},
+	if !secretExists {
+		secret, err = c.KubeClient.Secrets(c.Namespace).Create(context.TODO(), desiredSecret, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create connection pooler config secret: %v", err)
+		}
+		c.logger.Infof("connection pooler config secret %s has been created", util.NameFromMeta(secret.ObjectMeta))
+	} else if !reflect.DeepEqual(secret.Data, desiredSecret.Data) {
+		// rotated passwords are picked up by a reload instead of new pods
+		if authFileName := poolerType.authFileName(); authFileName != "" &&
+			!bytes.Equal(secret.Data[authFileName], desiredSecret.Data[authFileName]) {
+			if secret.Annotations == nil {
+				secret.Annotations = make(map[string]string)
+			}
+			secret.Annotations[poolerAuthFileReloadAnnotation] = "true"
+		}
+		secret.Data = desiredSecret.Data
+		secret, err = c.KubeClient.Secrets(c.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not update connection pooler config secret: %v", err)
+		}
+		c.logger.Infof("connection pooler config secret %s has been updated", util.NameFromMeta(secret.ObjectMeta))
+	}
+	c.ConnectionPooler[role].ConfigSecret = secret
+
+	return nil
+}
+
+// reloadConnectionPoolerAuthFile makes pgbouncer read the auth file again
+// after it changed. The kubelet updates the mounted secret with a delay, so
+// every pod is checked once per sync and only signaled once it sees the new
+// file. The reload stays pending for the next sync until all pods have it.
+func (c *Cluster) reloadConnectionPoolerAuthFile(role PostgresRole, pods []v1.Pod) error {
+	secret := c.ConnectionPooler[role].ConfigSecret
+	if secret == nil {
+		return nil
+	}
+	if _, pending := secret.Annotations[poolerAuthFileReloadAnnotation]; !pending {
+		return nil
 	}
+
+	authFile := strings.TrimSpace(string(secret.Data[poolerAuthFileName]))
+	reloaded := true
+	for i := range pods {
+		podName := util.NameFromMeta(pods[i].ObjectMeta)
+		mounted, err := c.execCommandInContainer(&podName, connectionPoolerContainer,
+			"cat", poolerConfigMountPath+"/"+poolerAuthFileName)
+		if err != nil {
+			c.logger.Warningf("could not read auth file of connection pooler pod %s: %v", podName, err)
+			reloaded = false
+			continue
+		}
+		if strings.TrimSpace(mounted) != authFile {
+			c.logger.Debugf("connection pooler pod %s does not see the new auth file yet", podName)
+			reloaded = false
+			continue
+		}
+		// pgbouncer is the main process of the image and reloads on SIGHUP
+		if _, err = c.execCommandInContainer(&podName, connectionPoolerContainer, "kill", "-HUP", "1"); err != nil {
+			c.logger.Warningf("could not reload auth file of connection pooler pod %s: %v", podName, err)
+			reloaded = false
+		}
+	}
+	if !reloaded {
+		return nil
+	}
+
+	delete(secret.Annotations, poolerAuthFileReloadAnnotation)
+	secret, err := c.KubeClient.Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("could not update connection pooler config secret: %v", err)
+	}
+	c.ConnectionPooler[role].ConfigSecret = secret
+	c.logger.Infof("connection pooler pods of role %s reloaded the auth file", role)
+
+	return nil
+}
+
+func (c *Cluster) deleteConnectionPoolerConfigSecret(role PostgresRole) error {
+	secretName := c.connectionPoolerConfigSecretName(role)
+	err := c.KubeClient.Secrets(c.Namespace).Delete(context.TODO(), secretName, c.deleteOptions)
+	if k8sutil.ResourceNotFound(err) {
+		c.logger.Debugf("connection pooler config secret was already deleted")
+	} else if err != nil {
+		return fmt.Errorf("could not delete connection pooler config secret: %v", err)
+	} else {
+		c.logger.Infof("connection pooler config secret %s has been deleted for role %s", secretName, role)
+	}
+	c.ConnectionPooler[role].ConfigSecret = nil
+
+	return nil
+}
+
+// connectionPoolerAdminPassword returns the password of the pgcat admin
+// console, which is separate from the pooler user used for the auth query
+func (c *Cluster) connectionPoolerAdminPassword(role PostgresRole) string {
+	pooler := c.ConnectionPooler[role]
+	if pooler == nil {
+		return util.RandomPassword(constants.PasswordLength)
+	}
+	if pooler.AdminPassword == "" {
+		pooler.AdminPassword = util.RandomPassword(constants.PasswordLength)
+	}
+
+	return pooler.AdminPassword
+}
+
+// poolerConfigChecksum identifies the configuration file a pooler pod was
+// started with, so changes to it roll the deployment
+func poolerConfigChecksum(config string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+}
+
+// poolerConfigFilesChecksum combines the checksum of the mounted files a
+// pooler reads only at startup. The auth file is left out, it holds the
+// passwords in plain text and pgbouncer reloads it without a restart.
+func poolerConfigFilesChecksum(files map[string]string) string {
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		if fileName == poolerAuthFileName {
+			continue
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	var config strings.Builder
+	for _, fileName := range fileNames {
+		config.WriteString(files[fileName])
+	}
+
+	return poolerConfigChecksum(config.String())
 }
 
 func (c *Cluster) generateConnectionPoolerPodTemplate(role PostgresRole) (
@@ -257,18 +1253,26 @@ func (c *Cluster) generateConnectionPoolerPodTemplate(role PostgresRole) (
 		makeDefaultConnectionPoolerResources(&c.OpConfig),
 		connectionPoolerContainer)
 
+	if err != nil {
+		return nil, fmt.Errorf("could not generate resource requirements: %v", err)
+	}
+
+	poolerType, err := c.getConnectionPoolerType(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.validateConnectionPooler(spec); err != nil {
+		return nil, fmt.Errorf("invalid connection pooler settings: %v", err)
+	}
+
 	effectiveDockerImage := util.Coalesce(
 		connectionPoolerSpec.DockerImage,
-		c.OpConfig.ConnectionPooler.Image)
+		poolerType.defaultImage(&c.OpConfig.ConnectionPooler))
 
 	effectiveSchema := util.Coalesce(
 		connectionPoolerSpec.Schema,
 		c.OpConfig.ConnectionPooler.Schema)
 
-	if err != nil {
-		return nil, fmt.Errorf("could not generate resource requirements: %v", err)
-	}
-
 	secretSelector := func(key string) *v1.SecretKeySelector {
 		effectiveUser := util.Coalesce(
 			connectionPoolerSpec.User,
@@ -310,7 +1314,7 @@ func (c *Cluster) generateConnectionPoolerPodTemplate(role PostgresRole) (
 			},
 		},
 	}
-	envVars = append(envVars, c.getConnectionPoolerEnvVars()...)
+	envVars = append(envVars, poolerType.envVars(c, role)...)
 
 	poolerContainer := v1.Container{
 		Name:            connectionPoolerContainer,
@@ -325,7 +1329,7 @@ func (c *Cluster) generateConnectionPoolerPodTemplate(role PostgresRole) (
 		},
 		Env: envVars,
 		ReadinessProbe: &v1.Probe{
-			Handler: v1.Handler{
+			ProbeHandler: v1.ProbeHandler{
 				TCPSocket: &v1.TCPSocketAction{
 					Port: intstr.IntOrString{IntVal: pgPort},
 				},
@@ -337,20 +1341,79 @@ func (c *Cluster) generateConnectionPoolerPodTemplate(role PostgresRole) (
 	}
 
 	tolerationsSpec := tolerations(&spec.Tolerations, c.OpConfig.PodToleration)
+	podAnnotations := c.generatePodAnnotations(spec)
+
+	// mount the certificates for client connections
+	tls := c.getConnectionPoolerTLS()
+	volumes, volumeMounts := tls.volumes()
+	poolerContainer.VolumeMounts = append(poolerContainer.VolumeMounts, volumeMounts...)
+
+	// configuration and auth files are mounted from a secret
+	if files := c.connectionPoolerConfigFiles(poolerType, role); len(files) > 0 {
+		if podAnnotations == nil {
+			podAnnotations = make(map[string]string)
+		}
+		podAnnotations[poolerConfigChecksumAnnotation] = poolerConfigFilesChecksum(files)
+
+		// the deployment is updated via merge patch which keeps volumes
+		// after switching back to pgbouncer, so the volume must not block
+		// pods from starting once the secret is gone
+		defaultMode := int32(0640)
+		volumes = append(volumes, v1.Volume{
+			Name: poolerConfigVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName:  c.connectionPoolerConfigSecretName(role),
+					DefaultMode: &defaultMode,
+					Optional:    util.True(),
+				},
+			},
+		})
+		poolerContainer.VolumeMounts = append(poolerContainer.VolumeMounts, v1.VolumeMount{
+			Name:      poolerConfigVolumeName,
+			MountPath: poolerConfigMountPath,
+			ReadOnly:  true,
+		})
+		if configFileName := poolerType.configFileName(); configFileName != "" {
+			poolerContainer.Command = poolerType.command(poolerConfigMountPath + "/" + configFileName)
+		}
+		// replace the template the pgbouncer image renders its configuration from
+		if _, exists := files[pgbouncerTemplateFileName]; exists {
+			poolerContainer.VolumeMounts = append(poolerContainer.VolumeMounts, v1.VolumeMount{
+				Name:      poolerConfigVolumeName,
+				MountPath: c.pgbouncerTemplatePath(),
+				SubPath:   pgbouncerTemplateFileName,
+				ReadOnly:  true,
+			})
+		}
+	}
 
 	podTemplate := &v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      c.connectionPoolerLabels(role, true).MatchLabels,
 			Namespace:   c.Namespace,
-			Annotations: c.annotationsSet(c.generatePodAnnotations(spec)),
+			Annotations: c.annotationsSet(podAnnotations),
 		},
 		Spec: v1.PodSpec{
 			TerminationGracePeriodSeconds: &gracePeriod,
 			Containers:                    []v1.Container{poolerContainer},
 			Tolerations:                   tolerationsSpec,
+			Volumes:                       volumes,
+			TopologySpreadConstraints:     c.generateConnectionPoolerTopologySpreadConstraints(role),
 		},
 	}
 
+	// give the pooler read access to the certificates like for Spilo pods
+	if tls.enabled() {
+		effectiveFSGroup := c.OpConfig.Resources.SpiloFSGroup
+		if spec.SpiloFSGroup != nil {
+			effectiveFSGroup = spec.SpiloFSGroup
+		}
+		if effectiveFSGroup != nil {
+			podTemplate.Spec.SecurityContext = &v1.PodSecurityContext{FSGroup: effectiveFSGroup}
+		}
+	}
+
 	nodeAffinity := c.nodeAffinity(c.OpConfig.NodeReadinessLabel, spec.NodeAffinity)
 	if c.OpConfig.EnablePodAntiAffinity {
 		labelsSet := labels.Set(c.connectionPoolerLabels(role, false).MatchLabels)
@@ -362,6 +1425,45 @@ func (c *Cluster) generateConnectionPoolerPodTemplate(role PostgresRole) (
 	return podTemplate, nil
 }
 
+// generateConnectionPoolerTopologySpreadConstraints spreads pooler pods of the
+// given role across nodes and/or zones. The per cluster settings take
+// precedence over the operator configuration.
+func (c *Cluster) generateConnectionPoolerTopologySpreadConstraints(role PostgresRole) []v1.TopologySpreadConstraint {
+	spreadAcrossZones := c.OpConfig.ConnectionPooler.SpreadAcrossZones
+	spreadAcrossNodes := c.OpConfig.ConnectionPooler.SpreadAcrossNodes
+	if spec := c.Spec.ConnectionPooler; spec != nil {
+		if spec.SpreadAcrossZones != nil {
+			spreadAcrossZones = *spec.SpreadAcrossZones
+		}
+		if spec.SpreadAcrossNodes != nil {
+			spreadAcrossNodes = *spec.SpreadAcrossNodes
+		}
+	}
+
+	topologyKeys := make([]string, 0)
+	if spreadAcrossZones {
+		topologyKeys = append(topologyKeys, v1.LabelTopologyZone)
+	}
+	if spreadAcrossNodes {
+		topologyKeys = append(topologyKeys, v1.LabelHostname)
+	}
+	if len(topologyKeys) == 0 {
+		return nil
+	}
+
+	constraints := make([]v1.TopologySpreadConstraint, 0, len(topologyKeys))
+	for _, topologyKey := range topologyKeys {
+		constraints = append(constraints, v1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: v1.ScheduleAnyway,
+			LabelSelector:     c.connectionPoolerLabels(role, false),
+		})
+	}
+
+	return constraints
+}
+
 func (c *Cluster) generateConnectionPoolerDeployment(connectionPooler *ConnectionPoolerObjects) (
 	*appsv1.Deployment, error) {
 	spec := &c.Spec
@@ -396,6 +1498,16 @@ func (c *Cluster) generateConnectionPoolerDeployment(connectionPooler *Connectio
 		return nil, err
 	}
 
+	// with autoscaling the HPA owns the replicas, so only a new deployment
+	// starts with the minimum and updates leave the replicas untouched
+	if connectionPoolerSpec.Autoscaling != nil {
+		minInstances, _ := c.connectionPoolerAutoscalingBounds(connectionPoolerSpec.Autoscaling, numberOfInstances)
+		numberOfInstances = nil
+		if connectionPooler.Deployment == nil {
+			numberOfInstances = &minInstances
+		}
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        connectionPooler.Name,
@@ -420,6 +1532,180 @@ func (c *Cluster) generateConnectionPoolerDeployment(connectionPooler *Connectio
 	return deployment, nil
 }
 
+// generateConnectionPoolerAutoscaler returns the HPA of the pooler deployment
+// or nil if autoscaling is not enabled
+func (c *Cluster) generateConnectionPoolerAutoscaler(connectionPooler *ConnectionPoolerObjects) *autoscalingv2.HorizontalPodAutoscaler {
+	if c.Spec.ConnectionPooler == nil || c.Spec.ConnectionPooler.Autoscaling == nil {
+		return nil
+	}
+	autoscaling := c.Spec.ConnectionPooler.Autoscaling
+
+	numberOfInstances := util.CoalesceInt32(
+		c.Spec.ConnectionPooler.NumberOfInstances,
+		c.OpConfig.ConnectionPooler.NumberOfInstances)
+	minInstances, maxInstances := c.connectionPoolerAutoscalingBounds(autoscaling, numberOfInstances)
+	if autoscaling.MaxInstances < minInstances {
+		c.logger.Warningf("maxInstances %d of connection pooler autoscaling is lower than minInstances %d, using %d",
+			autoscaling.MaxInstances, minInstances, minInstances)
+	}
+
+	metrics := make([]autoscalingv2.MetricSpec, 0)
+	if autoscaling.TargetClientConnections != nil {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{
+					Name: c.OpConfig.ConnectionPooler.AutoscalingMetric,
+				},
+				Target: autoscalingv2.MetricTarget{
+					Type:         autoscalingv2.AverageValueMetricType,
+					AverageValue: resource.NewQuantity(int64(*autoscaling.TargetClientConnections), resource.DecimalSI),
+				},
+			},
+		})
+	}
+	if autoscaling.TargetCPUUtilization != nil || len(metrics) == 0 {
+		targetCPUUtilization := util.CoalesceInt32(autoscaling.TargetCPUUtilization,
+			k8sutil.Int32ToPointer(constants.ConnectionPoolerTargetCPUUtilization))
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: v1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: targetCPUUtilization,
+				},
+			},
+		})
+	}
+
+	// owned by the deployment, so it is garbage collected together with it
+	var ownerReferences []metav1.OwnerReference
+	if connectionPooler.Deployment != nil {
+		controller := true
+		ownerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       connectionPooler.Deployment.Name,
+				UID:        connectionPooler.Deployment.UID,
+				Controller: &controller,
+			},
+		}
+	}
+
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            connectionPooler.Name,
+			Namespace:       connectionPooler.Namespace,
+			Labels:          c.connectionPoolerLabels(connectionPooler.Role, true).MatchLabels,
+			Annotations:     c.annotationsSet(nil),
+			OwnerReferences: ownerReferences,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       connectionPooler.Name,
+			},
+			MinReplicas: &minInstances,
+			MaxReplicas: maxInstances,
+			Metrics:     metrics,
+		},
+	}
+}
+
+// syncConnectionPoolerAutoscaler creates, updates or removes the HPA of the
+// pooler deployment according to the manifest
+func (c *Cluster) syncConnectionPoolerAutoscaler(role PostgresRole) error {
+	name := c.connectionPoolerName(role)
+	desiredHPA := c.generateConnectionPoolerAutoscaler(c.ConnectionPooler[role])
+	if desiredHPA == nil && c.ConnectionPooler[role].Autoscaler == nil && c.ConnectionPooler[role].AutoscalerChecked {
+		return nil
+	}
+
+	hpa, err := c.KubeClient.HorizontalPodAutoscalers(c.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !k8sutil.ResourceNotFound(err) {
+		// the operator does not need access to HPAs unless autoscaling is used
+		if desiredHPA == nil && apierrors.IsForbidden(err) {
+			c.logger.Debugf("not allowed to look up connection pooler autoscaler: %v", err)
+			c.ConnectionPooler[role].AutoscalerChecked = true
+			return nil
+		}
+		return fmt.Errorf("could not get connection pooler autoscaler: %v", err)
+	}
+	hpaExists := err == nil
+	c.ConnectionPooler[role].AutoscalerChecked = true
+
+	if desiredHPA == nil {
+		if hpaExists {
+			if err = c.KubeClient.HorizontalPodAutoscalers(c.Namespace).Delete(context.TODO(), name, c.deleteOptions); err != nil && !k8sutil.ResourceNotFound(err) {
+				return fmt.Errorf("could not delete connection pooler autoscaler: %v", err)
+			}
+			c.logger.Infof("connection pooler autoscaler %s has been deleted for role %s", name, role)
+		}
+		c.ConnectionPooler[role].Autoscaler = nil
+		return nil
+	}
+
+	if !hpaExists {
+		hpa, err = c.KubeClient.HorizontalPodAutoscalers(c.Namespace).Create(context.TODO(), desiredHPA, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create connection pooler autoscaler: %v", err)
+		}
+		c.logger.Infof("connection pooler autoscaler %s has been created for role %s", name, role)
+	} else if !reflect.DeepEqual(hpa.Spec.MinReplicas, desiredHPA.Spec.MinReplicas) ||
+		hpa.Spec.MaxReplicas != desiredHPA.Spec.MaxReplicas ||
+		!reflect.DeepEqual(hpa.Spec.Metrics, desiredHPA.Spec.Metrics) ||
+		!reflect.DeepEqual(hpa.Spec.ScaleTargetRef, desiredHPA.Spec.ScaleTargetRef) {
+		// keep the scaling behavior possibly defaulted by the API server
+		desiredHPA.Spec.Behavior = hpa.Spec.Behavior
+		hpa.Spec = desiredHPA.Spec
+		hpa, err = c.KubeClient.HorizontalPodAutoscalers(c.Namespace).Update(context.TODO(), hpa, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not update connection pooler autoscaler: %v", err)
+		}
+		c.logger.Infof("connection pooler autoscaler %s has been updated for role %s", name, role)
+	}
+	c.ConnectionPooler[role].Autoscaler = hpa
+
+	return nil
+}
+
+// syncConnectionPoolerPodDisruptionBudget creates the PDB of the pooler
+// deployment or replaces it if it differs from the expected one
+func (c *Cluster) syncConnectionPoolerPodDisruptionBudget(role PostgresRole) error {
+	name := c.connectionPoolerPodDisruptionBudgetName(role)
+	desiredPDB := c.generateConnectionPoolerPodDisruptionBudget(c.ConnectionPooler[role])
+
+	pdb, err := c.KubeClient.PodDisruptionBudgets(c.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !k8sutil.ResourceNotFound(err) {
+		return fmt.Errorf("could not get connection pooler pod disruption budget: %v", err)
+	}
+
+	if err == nil {
+		match, reason := k8sutil.SamePDB(pdb, desiredPDB)
+		if match {
+			c.ConnectionPooler[role].PodDisruptionBudget = pdb
+			return nil
+		}
+		// update happens via delete/create
+		c.logger.Infof("connection pooler pod disruption budget %s has to be replaced: %s", name, reason)
+		if err = c.KubeClient.PodDisruptionBudgets(c.Namespace).Delete(context.TODO(), name, c.deleteOptions); err != nil && !k8sutil.ResourceNotFound(err) {
+			return fmt.Errorf("could not delete connection pooler pod disruption budget: %v", err)
+		}
+	}
+
+	pdb, err = c.KubeClient.PodDisruptionBudgets(c.Namespace).Create(context.TODO(), desiredPDB, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("could not create connection pooler pod disruption budget: %v", err)
+	}
+	c.logger.Infof("connection pooler pod disruption budget %s has been created for role %s", name, role)
+	c.ConnectionPooler[role].PodDisruptionBudget = pdb
+
+	return nil
+}
+
 func (c *Cluster) generateConnectionPoolerService(connectionPooler *ConnectionPoolerObjects) *v1.Service {
 
 	spec := &c.Spec
@@ -546,6 +1832,29 @@ func (c *Cluster) deleteConnectionPooler(role PostgresRole) (err error) {
 		c.logger.Infof("connection pooler service %s has been deleted for role %s", service.Name, role)
 	}
 
+	// the config secret is deleted by name, since after a restart the operator
+	// does not know whether one exists
+	if err = c.deleteConnectionPoolerConfigSecret(role); err != nil {
+		return err
+	}
+
+	// the pod disruption budget would be garbage collected together with the
+	// deployment, but deleting it right away frees the name for a new one
+	if pdb := c.ConnectionPooler[role].PodDisruptionBudget; pdb != nil {
+		err = c.KubeClient.
+			PodDisruptionBudgets(c.Namespace).
+			Delete(context.TODO(), pdb.Name, c.deleteOptions)
+
+		if k8sutil.ResourceNotFound(err) {
+			c.logger.Debugf("connection pooler pod disruption budget was already deleted")
+		} else if err != nil {
+			return fmt.Errorf("could not delete connection pooler pod disruption budget: %v", err)
+		} else {
+			c.logger.Infof("connection pooler pod disruption budget %s has been deleted for role %s", pdb.Name, role)
+		}
+		c.ConnectionPooler[role].PodDisruptionBudget = nil
+	}
+
 	c.ConnectionPooler[role].Deployment = nil
 	c.ConnectionPooler[role].Service = nil
 	return nil
@@ -646,6 +1955,98 @@ func needSyncConnectionPoolerSpecs(oldSpec, newSpec *acidv1.ConnectionPooler, lo
 	return sync, reasons
 }
 
+// Check if the pooler container environment is outdated, e.g. because the
+// database connections have to be split across a new number of replicas
+func (c *Cluster) needSyncConnectionPoolerEnv(deployment *appsv1.Deployment, role PostgresRole) (sync bool, reasons []string) {
+	poolerType, err := c.getConnectionPoolerType(&c.Spec)
+	if err != nil || len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return false, nil
+	}
+
+	currentEnv := make(map[string]string)
+	for _, env := range deployment.Spec.Template.Spec.Containers[constants.ConnectionPoolerContainer].Env {
+		currentEnv[env.Name] = env.Value
+	}
+
+	for _, env := range poolerType.envVars(c, role) {
+		if value, exists := currentEnv[env.Name]; !exists || value != env.Value {
+			sync = true
+			reasons = append(reasons, fmt.Sprintf("env variable %s is different (having %q, required %q)",
+				env.Name, value, env.Value))
+		}
+	}
+
+	return sync, reasons
+}
+
+// Check if the pooler pods were started with an outdated configuration file,
+// e.g. after the password of the pooler user was rotated
+func (c *Cluster) needSyncConnectionPoolerConfig(deployment *appsv1.Deployment, role PostgresRole) (sync bool, reasons []string) {
+	poolerType, err := c.getConnectionPoolerType(&c.Spec)
+	if err != nil {
+		return false, nil
+	}
+
+	files := c.connectionPoolerConfigFiles(poolerType, role)
+	if len(files) == 0 {
+		return false, nil
+	}
+
+	checksum := poolerConfigFilesChecksum(files)
+	if deployment.Spec.Template.Annotations[poolerConfigChecksumAnnotation] != checksum {
+		return true, []string{"pooler configuration file has changed"}
+	}
+
+	return false, nil
+}
+
+// Check if the pooler pods mount the secrets with the current certificates,
+// e.g. after the tls section of the cluster was changed
+func (c *Cluster) needSyncConnectionPoolerTLS(deployment *appsv1.Deployment) (sync bool, reasons []string) {
+	current := make([]string, 0)
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil && (volume.Name == poolerTLSVolumeName || volume.Name == poolerTLSCAVolumeName) {
+			current = append(current, volume.Secret.SecretName)
+		}
+	}
+
+	desired := make([]string, 0)
+	volumes, _ := c.getConnectionPoolerTLS().volumes()
+	for _, volume := range volumes {
+		desired = append(desired, volume.Secret.SecretName)
+	}
+	// the merge patch cannot remove the volumes, while the mounts and the TLS
+	// settings go away with the container, which is checked with the env
+	if len(desired) == 0 {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(current, desired) {
+		return true, []string{fmt.Sprintf("TLS secrets are different (having %v, required %v)", current, desired)}
+	}
+
+	return false, nil
+}
+
+// Check if the pooler pods are spread as configured, the operator
+// configuration could have been changed
+func (c *Cluster) needSyncConnectionPoolerSpread(deployment *appsv1.Deployment, role PostgresRole) (sync bool, reasons []string) {
+	current := deployment.Spec.Template.Spec.TopologySpreadConstraints
+	desired := c.generateConnectionPoolerTopologySpreadConstraints(role)
+	// the merge patch cannot remove the constraints, but they only prefer a
+	// spread, so the ones left behind after disabling it do no harm
+	if len(desired) == 0 {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(current, desired) {
+		return true, []string{fmt.Sprintf("topology spread constraints are different (having %+v, required %+v)",
+			current, desired)}
+	}
+
+	return false, nil
+}
+
 // Check if we need to synchronize connection pooler deployment due to new
 // defaults, that are different from what we see in the DeploymentSpec
 func (c *Cluster) needSyncConnectionPoolerDefaults(Config *Config, spec *acidv1.ConnectionPooler, deployment *appsv1.Deployment) (sync bool, reasons []string) {
@@ -660,7 +2061,7 @@ func (c *Cluster) needSyncConnectionPoolerDefaults(Config *Config, spec *acidv1.
 	if spec == nil {
 		spec = &acidv1.ConnectionPooler{}
 	}
-	if spec.NumberOfInstances == nil &&
+	if spec.NumberOfInstances == nil && spec.Autoscaling == nil &&
 		*deployment.Spec.Replicas != *config.NumberOfInstances {
 
 		sync = true
@@ -669,12 +2070,12 @@ func (c *Cluster) needSyncConnectionPoolerDefaults(Config *Config, spec *acidv1.
 		reasons = append(reasons, msg)
 	}
 
-	if spec.DockerImage == "" &&
-		poolerContainer.Image != config.Image {
+	if poolerType, err := c.getConnectionPoolerType(&c.Spec); err == nil && spec.DockerImage == "" &&
+		poolerContainer.Image != poolerType.defaultImage(&config) {
 
 		sync = true
 		msg := fmt.Sprintf("dockerImage is different (having %s, required %s)",
-			poolerContainer.Image, config.Image)
+			poolerContainer.Image, poolerType.defaultImage(&config))
 		reasons = append(reasons, msg)
 	}
 
@@ -809,8 +2210,10 @@ func (c *Cluster) syncConnectionPooler(oldSpec, newSpec *acidv1.Postgresql, Look
 			// in between
 
 			// in this case also do not forget to install lookup function
-			// skip installation in standby clusters, since they are read-only
-			if !c.ConnectionPooler[role].LookupFunction && c.Spec.StandbyCluster == nil {
+			// skip installation in standby clusters, since they are read-only,
+			// and if clients are authenticated with the auth file
+			if !c.ConnectionPooler[role].LookupFunction && c.Spec.StandbyCluster == nil &&
+				c.poolerAuthMode(&newSpec.Spec) != poolerAuthModeFile {
 				connectionPooler := c.Spec.ConnectionPooler
 				specSchema := ""
 				specUser := ""
@@ -877,6 +2280,12 @@ func (c *Cluster) syncConnectionPoolerWorker(oldSpec, newSpec *acidv1.Postgresql
 	)
 
 	syncReason := make([]string, 0)
+
+	// the configuration file has to exist before pooler pods can start
+	if err = c.syncConnectionPoolerConfigSecret(role); err != nil {
+		return NoSync, fmt.Errorf("could not sync connection pooler config: %v", err)
+	}
+
 	deployment, err = c.KubeClient.
 		Deployments(c.Namespace).
 		Get(context.TODO(), c.connectionPoolerName(role), metav1.GetOptions{})
@@ -934,7 +2343,19 @@ func (c *Cluster) syncConnectionPoolerWorker(oldSpec, newSpec *acidv1.Postgresql
 		defaultsSync, defaultsReason := c.needSyncConnectionPoolerDefaults(&c.Config, newConnectionPooler, deployment)
 		syncReason = append(syncReason, defaultsReason...)
 
-		if specSync || defaultsSync {
+		envSync, envReason := c.needSyncConnectionPoolerEnv(deployment, role)
+		syncReason = append(syncReason, envReason...)
+
+		configSync, configReason := c.needSyncConnectionPoolerConfig(deployment, role)
+		syncReason = append(syncReason, configReason...)
+
+		spreadSync, spreadReason := c.needSyncConnectionPoolerSpread(deployment, role)
+		syncReason = append(syncReason, spreadReason...)
+
+		tlsSync, tlsReason := c.needSyncConnectionPoolerTLS(deployment)
+		syncReason = append(syncReason, tlsReason...)
+
+		if specSync || defaultsSync || envSync || configSync || spreadSync || tlsSync {
 			c.logger.Infof("update connection pooler deployment %s, reason: %+v",
 				c.connectionPoolerName(role), syncReason)
 			newDeployment, err = c.generateConnectionPoolerDeployment(c.ConnectionPooler[role])
@@ -951,6 +2372,14 @@ func (c *Cluster) syncConnectionPoolerWorker(oldSpec, newSpec *acidv1.Postgresql
 		}
 	}
 
+	if err = c.syncConnectionPoolerAutoscaler(role); err != nil {
+		return syncReason, fmt.Errorf("could not sync connection pooler autoscaler: %v", err)
+	}
+
+	if err = c.syncConnectionPoolerPodDisruptionBudget(role); err != nil {
+		return syncReason, fmt.Errorf("could not sync connection pooler pod disruption budget: %v", err)
+	}
+
 	newAnnotations := c.AnnotationsToPropagate(c.annotationsSet(c.ConnectionPooler[role].Deployment.Annotations))
 	if newAnnotations != nil {
 		deployment, err = updateConnectionPoolerAnnotations(c.KubeClient, c.ConnectionPooler[role].Deployment, newAnnotations)
@@ -988,6 +2417,10 @@ func (c *Cluster) syncConnectionPoolerWorker(oldSpec, newSpec *acidv1.Postgresql
 		}
 	}
 
+	if err = c.reloadConnectionPoolerAuthFile(role, pods); err != nil {
+		return syncReason, fmt.Errorf("could not reload connection pooler auth file: %v", err)
+	}
+
 	if service, err = c.KubeClient.Services(c.Namespace).Get(context.TODO(), c.connectionPoolerName(role), metav1.GetOptions{}); err == nil {
 		c.ConnectionPooler[role].Service = service
 		desiredSvc := c.generateConnectionPoolerService(c.ConnectionPooler[role])
@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -9,13 +10,16 @@ import (
 	"github.com/stretchr/testify/assert"
 	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	fakeacidv1 "github.com/zalando/postgres-operator/pkg/generated/clientset/versioned/fake"
+	"github.com/zalando/postgres-operator/pkg/spec"
 	"github.com/zalando/postgres-operator/pkg/util"
 	"github.com/zalando/postgres-operator/pkg/util/config"
+	"github.com/zalando/postgres-operator/pkg/util/constants"
 	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -267,6 +271,9 @@ func TestConnectionPoolerCreateDeletion(t *testing.T) {
 		DeploymentsGetter:  clientSet.AppsV1(),
 		PostgresqlsGetter:  acidClientSet.AcidV1(),
 		SecretsGetter:      clientSet.CoreV1(),
+
+		HorizontalPodAutoscalersGetter: clientSet.AutoscalingV2(),
+		PodDisruptionBudgetsGetter:     clientSet.PolicyV1(),
 	}
 
 	pg := acidv1.Postgresql{
@@ -377,6 +384,9 @@ func TestConnectionPoolerSync(t *testing.T) {
 		DeploymentsGetter:  clientSet.AppsV1(),
 		PostgresqlsGetter:  acidClientSet.AcidV1(),
 		SecretsGetter:      clientSet.CoreV1(),
+
+		HorizontalPodAutoscalersGetter: clientSet.AutoscalingV2(),
+		PodDisruptionBudgetsGetter:     clientSet.PolicyV1(),
 	}
 
 	pg := acidv1.Postgresql{
@@ -1004,3 +1014,793 @@ func TestConnectionPoolerServiceSpec(t *testing.T) {
 		}
 	}
 }
+
+func TestConnectionPoolerEnvVars(t *testing.T) {
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				ConnectionPooler: config.ConnectionPooler{
+					Mode:             "transaction",
+					MaxDBConnections: k8sutil.Int32ToPointer(60),
+				},
+			},
+		}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+
+	tests := []struct {
+		subTest  string
+		spec     *acidv1.ConnectionPooler
+		expected map[string]string
+	}{
+		{
+			subTest: "pool settings derived from maxDBConnections",
+			spec:    &acidv1.ConnectionPooler{NumberOfInstances: k8sutil.Int32ToPointer(2)},
+			expected: map[string]string{
+				"CONNECTION_POOLER_MODE":            "transaction",
+				"CONNECTION_POOLER_MAX_DB_CONN":     "30",
+				"CONNECTION_POOLER_DEFAULT_SIZE":    "15",
+				"CONNECTION_POOLER_MIN_SIZE":        "7",
+				"CONNECTION_POOLER_RESERVE_SIZE":    "7",
+				"CONNECTION_POOLER_MAX_CLIENT_CONN": "10000",
+			},
+		},
+		{
+			subTest: "pool settings from the manifest",
+			spec: &acidv1.ConnectionPooler{
+				NumberOfInstances:       k8sutil.Int32ToPointer(2),
+				DefaultPoolSize:         k8sutil.Int32ToPointer(20),
+				ReservePoolSize:         k8sutil.Int32ToPointer(3),
+				MaxClientConnections:    k8sutil.Int32ToPointer(500),
+				ServerIdleTimeout:       k8sutil.Int32ToPointer(300),
+				ServerLifetime:          k8sutil.Int32ToPointer(3600),
+				IgnoreStartupParameters: []string{"extra_float_digits", "search_path"},
+				DatabasePoolModes:       map[string]string{"reporting": "session", "app": "statement"},
+			},
+			expected: map[string]string{
+				"CONNECTION_POOLER_MAX_DB_CONN":     "30",
+				"CONNECTION_POOLER_DEFAULT_SIZE":    "20",
+				"CONNECTION_POOLER_MIN_SIZE":        "10",
+				"CONNECTION_POOLER_RESERVE_SIZE":    "3",
+				"CONNECTION_POOLER_MAX_CLIENT_CONN": "500",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		cluster.Spec.ConnectionPooler = tt.spec
+		envVars := make(map[string]string)
+		for _, env := range cluster.getConnectionPoolerEnvVars(Master) {
+			envVars[env.Name] = env.Value
+		}
+
+		for name, value := range tt.expected {
+			if envVars[name] != value {
+				t.Errorf("%s: expected %s=%q, got %q", tt.subTest, name, value, envVars[name])
+			}
+		}
+	}
+
+	// the image has no variables for the other settings, they go into its template
+	poolerConfig := pgbouncerPooler{}.configFile(cluster, Master)
+	for _, expected := range []string{
+		`"app" = host=$PGHOST port=$PGPORT auth_user=$PGUSER pool_mode=statement`,
+		`"reporting" = host=$PGHOST port=$PGPORT auth_user=$PGUSER pool_mode=session`,
+		"* = host=$PGHOST port=$PGPORT auth_user=$PGUSER",
+		"default_pool_size = $CONNECTION_POOLER_DEFAULT_SIZE",
+		"ignore_startup_parameters = extra_float_digits,search_path",
+		"server_idle_timeout = 300",
+		"server_lifetime = 3600",
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("pgbouncer template does not contain %q:\n%s", expected, poolerConfig)
+		}
+	}
+
+	// without spec.tls the template keeps enforcing TLS like the one of the image
+	for _, expected := range []string{
+		"client_tls_sslmode = require\nclient_tls_cert_file = /etc/ssl/certs/pgbouncer.crt\nclient_tls_key_file = /etc/ssl/certs/pgbouncer.key\n",
+		"server_tls_sslmode = require\n",
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("pgbouncer template does not contain the TLS settings of the image %q:\n%s", expected, poolerConfig)
+		}
+	}
+
+	cluster.Spec.ConnectionPooler = &acidv1.ConnectionPooler{NumberOfInstances: k8sutil.Int32ToPointer(2)}
+	if poolerConfig := (pgbouncerPooler{}).configFile(cluster, Master); poolerConfig != "" {
+		t.Errorf("expected the template of the image to be used without additional settings, got:\n%s", poolerConfig)
+	}
+
+	// each of the 2 pods gets 30 of the 60 connections
+	cluster.Spec.ConnectionPooler.DefaultPoolSize = k8sutil.Int32ToPointer(30)
+	if err := cluster.validateConnectionPooler(&cluster.Spec); err != nil {
+		t.Errorf("unexpected error for a default pool size within the connections per pod: %v", err)
+	}
+	cluster.Spec.ConnectionPooler.DefaultPoolSize = k8sutil.Int32ToPointer(31)
+	if err := cluster.validateConnectionPooler(&cluster.Spec); err == nil {
+		t.Errorf("expected error for a default pool size above the connections per pod")
+	}
+}
+
+func TestConnectionPoolerTypes(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{SecretsGetter: clientSet.CoreV1()}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				ConnectionPooler: config.ConnectionPooler{
+					Type:                                 "pgbouncer",
+					Image:                                "pgbouncer:1",
+					PgcatImage:                           "pgcat:1",
+					Schema:                               "pooler",
+					User:                                 "pooler",
+					Mode:                                 "transaction",
+					MaxDBConnections:                     k8sutil.Int32ToPointer(60),
+					ConnectionPoolerDefaultCPURequest:    "100m",
+					ConnectionPoolerDefaultCPULimit:      "100m",
+					ConnectionPoolerDefaultMemoryRequest: "100Mi",
+					ConnectionPoolerDefaultMemoryLimit:   "100Mi",
+				},
+			},
+		}, client, acidv1.Postgresql{}, logger, eventRecorder)
+	cluster.Name = "acid-test-cluster"
+	cluster.Namespace = "default"
+	cluster.Spec = acidv1.PostgresSpec{
+		ConnectionPooler: &acidv1.ConnectionPooler{
+			Type:              "pgcat",
+			NumberOfInstances: k8sutil.Int32ToPointer(2),
+			DatabasePoolModes: map[string]string{"foo": "session"},
+		},
+		Databases: map[string]string{"foo": "foo_owner"},
+	}
+	cluster.systemUsers[constants.ConnectionPoolerUserKeyName] = spec.PgUser{Name: "pooler", Password: "secret"}
+	cluster.pgUsers = map[string]spec.PgUser{
+		"foo_owner": {Name: "foo_owner"},
+		"foo_role":  {Name: "foo_role", Flags: []string{constants.RoleFlagNoLogin}},
+	}
+	cluster.ConnectionPooler = map[PostgresRole]*ConnectionPoolerObjects{
+		Master: {Name: cluster.connectionPoolerName(Master), Role: Master},
+	}
+
+	podTemplate, err := cluster.generateConnectionPoolerPodTemplate(Master)
+	if err != nil {
+		t.Fatalf("could not generate pod template: %v", err)
+	}
+	container := podTemplate.Spec.Containers[0]
+	if container.Image != "pgcat:1" {
+		t.Errorf("expected pgcat image, got %q", container.Image)
+	}
+	assert.Equal(t, []string{"pgcat", "/etc/pooler/pgcat.toml"}, container.Command)
+	if len(podTemplate.Spec.Volumes) != 1 || podTemplate.Spec.Volumes[0].Secret.SecretName != "acid-test-cluster-pooler-config" {
+		t.Errorf("expected config volume from secret, got %#v", podTemplate.Spec.Volumes)
+	}
+	for _, env := range container.Env {
+		if strings.HasPrefix(env.Name, "CONNECTION_POOLER_") {
+			t.Errorf("unexpected pgbouncer env variable %s for pgcat", env.Name)
+		}
+	}
+
+	poolerConfig := pgcatPooler{}.configFile(cluster, Master)
+	for _, expected := range []string{
+		`[pools."foo"]`,
+		`pool_mode = "session"`,
+		`[pools."postgres"]`,
+		`pool_mode = "transaction"`,
+		`auth_query = "SELECT uname, phash FROM pooler.user_lookup('$1')"`,
+		`auth_query_password = "secret"`,
+		`username = "foo_owner"`,
+		`pool_size = 15`,
+		`servers = [["acid-test-cluster", 5432, "primary"], ["acid-test-cluster-repl", 5432, "replica"]]`,
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("pgcat config does not contain %q:\n%s", expected, poolerConfig)
+		}
+	}
+	if strings.Contains(poolerConfig, "foo_role") {
+		t.Errorf("pgcat config must not contain roles without login:\n%s", poolerConfig)
+	}
+	if !strings.Contains(poolerConfig, `admin_username = "admin"`) || strings.Contains(poolerConfig, `admin_password = "secret"`) {
+		t.Errorf("pgcat admin console must not use the pooler user:\n%s", poolerConfig)
+	}
+	if podTemplate.Annotations[poolerConfigChecksumAnnotation] != poolerConfigChecksum(poolerConfig) {
+		t.Errorf("pod template annotation does not match the config checksum")
+	}
+
+	// the config secret is created and removed again after switching to pgbouncer
+	if err := cluster.syncConnectionPoolerConfigSecret(Master); err != nil {
+		t.Fatalf("could not sync pooler config secret: %v", err)
+	}
+	secret, err := client.Secrets("default").Get(context.TODO(), "acid-test-cluster-pooler-config", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("pooler config secret was not created: %v", err)
+	}
+	if string(secret.Data["pgcat.toml"]) != poolerConfig {
+		t.Errorf("pooler config secret does not contain the generated config")
+	}
+
+	// after a restart the admin password is read back from the secret
+	adminPassword := cluster.ConnectionPooler[Master].AdminPassword
+	cluster.ConnectionPooler[Master] = &ConnectionPoolerObjects{Name: cluster.connectionPoolerName(Master), Role: Master}
+	if err := cluster.syncConnectionPoolerConfigSecret(Master); err != nil {
+		t.Fatalf("could not sync pooler config secret: %v", err)
+	}
+	if cluster.ConnectionPooler[Master].AdminPassword != adminPassword {
+		t.Errorf("pgcat admin password changed after a restart")
+	}
+	cluster.ConnectionPooler[Master].ConfigSecret = nil
+
+	// pgbouncer needs no config without the per database pool modes
+	cluster.Spec.ConnectionPooler.Type = "pgbouncer"
+	cluster.Spec.ConnectionPooler.DatabasePoolModes = nil
+	if err := cluster.syncConnectionPoolerConfigSecret(Master); err != nil {
+		t.Fatalf("could not sync pooler config secret: %v", err)
+	}
+	if _, err := client.Secrets("default").Get(context.TODO(), "acid-test-cluster-pooler-config", metav1.GetOptions{}); err == nil {
+		t.Errorf("pooler config secret must be deleted for pgbouncer")
+	}
+
+	// odyssey has no default image
+	cluster.Spec.ConnectionPooler.Type = "odyssey"
+	if _, err = cluster.generateConnectionPoolerPodTemplate(Master); err == nil {
+		t.Errorf("expected error for odyssey without an image")
+	}
+	cluster.Spec.ConnectionPooler.Type = "pgbouncer"
+	podTemplate, err = cluster.generateConnectionPoolerPodTemplate(Master)
+	if err != nil {
+		t.Fatalf("could not generate pod template: %v", err)
+	}
+	if podTemplate.Spec.Containers[0].Image != "pgbouncer:1" || len(podTemplate.Spec.Volumes) != 0 {
+		t.Errorf("unexpected pgbouncer pod template %#v", podTemplate.Spec)
+	}
+
+	cluster.Spec.ConnectionPooler.Type = "unknown"
+	if _, err := cluster.generateConnectionPoolerPodTemplate(Master); err == nil {
+		t.Errorf("expected error for unknown pooler type")
+	}
+}
+
+func TestConnectionPoolerAutoscaling(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{
+		ServicesGetter:                 clientSet.CoreV1(),
+		PodsGetter:                     clientSet.CoreV1(),
+		DeploymentsGetter:              clientSet.AppsV1(),
+		SecretsGetter:                  clientSet.CoreV1(),
+		HorizontalPodAutoscalersGetter: clientSet.AutoscalingV2(),
+		PodDisruptionBudgetsGetter:     clientSet.PolicyV1(),
+	}
+
+	pg := acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acid-fake-cluster",
+			Namespace: "default",
+		},
+		Spec: acidv1.PostgresSpec{
+			ConnectionPooler: &acidv1.ConnectionPooler{
+				Autoscaling: &acidv1.ConnectionPoolerAutoscaling{
+					MinInstances:            k8sutil.Int32ToPointer(2),
+					MaxInstances:            6,
+					TargetClientConnections: k8sutil.Int32ToPointer(100),
+				},
+			},
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				ConnectionPooler: config.ConnectionPooler{
+					ConnectionPoolerDefaultCPURequest:    "100m",
+					ConnectionPoolerDefaultCPULimit:      "100m",
+					ConnectionPoolerDefaultMemoryRequest: "100Mi",
+					ConnectionPoolerDefaultMemoryLimit:   "100Mi",
+					NumberOfInstances:                    k8sutil.Int32ToPointer(1),
+					MaxDBConnections:                     k8sutil.Int32ToPointer(60),
+					AutoscalingMetric:                    "pooler_clients",
+				},
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+					PodRoleLabel:     "spilo-role",
+				},
+			},
+		}, client, pg, logger, eventRecorder)
+	cluster.Name = pg.Name
+	cluster.Namespace = pg.Namespace
+
+	maxDBConn := func() string {
+		for _, env := range cluster.ConnectionPooler[Master].Deployment.Spec.Template.Spec.Containers[0].Env {
+			if env.Name == "CONNECTION_POOLER_MAX_DB_CONN" {
+				return env.Value
+			}
+		}
+		return ""
+	}
+
+	if _, err := cluster.createConnectionPooler(mockInstallLookupFunction); err != nil {
+		t.Fatalf("could not create connection pooler: %v", err)
+	}
+
+	deployment := cluster.ConnectionPooler[Master].Deployment
+	assert.Equal(t, int32(2), *deployment.Spec.Replicas)
+	assert.Equal(t, "10", maxDBConn())
+
+	hpa, err := client.HorizontalPodAutoscalers("default").Get(context.TODO(), "acid-fake-cluster-pooler", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("connection pooler autoscaler was not created: %v", err)
+	}
+	assert.Equal(t, int32(2), *hpa.Spec.MinReplicas)
+	assert.Equal(t, int32(6), hpa.Spec.MaxReplicas)
+	assert.Equal(t, "acid-fake-cluster-pooler", hpa.Spec.ScaleTargetRef.Name)
+	if len(hpa.Spec.Metrics) != 1 || hpa.Spec.Metrics[0].Pods == nil ||
+		hpa.Spec.Metrics[0].Pods.Metric.Name != "pooler_clients" ||
+		hpa.Spec.Metrics[0].Pods.Target.AverageValue.Value() != 100 {
+		t.Errorf("unexpected autoscaler metrics %#v", hpa.Spec.Metrics)
+	}
+	if len(hpa.OwnerReferences) != 1 || hpa.OwnerReferences[0].Name != deployment.Name {
+		t.Errorf("autoscaler must be owned by the pooler deployment, got %#v", hpa.OwnerReferences)
+	}
+
+	// the HPA scales the deployment up, the operator keeps the replicas and
+	// the connections stay split across the maximum, so pods are not rolled
+	deployment.Spec.Replicas = k8sutil.Int32ToPointer(4)
+	if _, err = client.Deployments("default").Update(context.TODO(), deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("could not scale deployment: %v", err)
+	}
+	if _, err = cluster.syncConnectionPooler(&pg, &pg, mockInstallLookupFunction); err != nil {
+		t.Fatalf("could not sync connection pooler: %v", err)
+	}
+	deployment, _ = client.Deployments("default").Get(context.TODO(), "acid-fake-cluster-pooler", metav1.GetOptions{})
+	assert.Equal(t, int32(4), *deployment.Spec.Replicas)
+	assert.Equal(t, "10", maxDBConn())
+
+	// more pods than connections still limit each pod to one connection
+	pg.Spec.ConnectionPooler.MaxDBConnections = k8sutil.Int32ToPointer(5)
+	cluster.Spec = pg.Spec
+	assert.Equal(t, int32(1), cluster.getConnectionPoolerSettings(Master).maxDBConn)
+	pg.Spec.ConnectionPooler.MaxDBConnections = nil
+	cluster.Spec = pg.Spec
+
+	// the default pool size has to fit the share of a pod at the maximum
+	pg.Spec.ConnectionPooler.DefaultPoolSize = k8sutil.Int32ToPointer(11)
+	assert.Error(t, cluster.validateConnectionPooler(&pg.Spec))
+	pg.Spec.ConnectionPooler.DefaultPoolSize = k8sutil.Int32ToPointer(10)
+	assert.NoError(t, cluster.validateConnectionPooler(&pg.Spec))
+	pg.Spec.ConnectionPooler.DefaultPoolSize = nil
+
+	// disabling autoscaling removes the HPA
+	newSpec := pg.DeepCopy()
+	newSpec.Spec.ConnectionPooler.Autoscaling = nil
+	cluster.Spec = newSpec.Spec
+	if _, err = cluster.syncConnectionPooler(&pg, newSpec, mockInstallLookupFunction); err != nil {
+		t.Fatalf("could not sync connection pooler: %v", err)
+	}
+	if _, err = client.HorizontalPodAutoscalers("default").Get(context.TODO(), "acid-fake-cluster-pooler", metav1.GetOptions{}); err == nil {
+		t.Errorf("connection pooler autoscaler must be deleted when autoscaling is disabled")
+	}
+	assert.Nil(t, cluster.ConnectionPooler[Master].Autoscaler)
+
+	// without autoscaling further syncs do not look up the HPA
+	clientSet.ClearActions()
+	if _, err = cluster.syncConnectionPooler(newSpec, newSpec, mockInstallLookupFunction); err != nil {
+		t.Fatalf("could not sync connection pooler: %v", err)
+	}
+	for _, action := range clientSet.Actions() {
+		if action.GetResource().Resource == "horizontalpodautoscalers" {
+			t.Errorf("unexpected %s of connection pooler autoscaler without autoscaling", action.GetVerb())
+		}
+	}
+}
+
+func TestConnectionPoolerDisruptionBudgetAndSpread(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{
+		ServicesGetter:                 clientSet.CoreV1(),
+		PodsGetter:                     clientSet.CoreV1(),
+		DeploymentsGetter:              clientSet.AppsV1(),
+		SecretsGetter:                  clientSet.CoreV1(),
+		HorizontalPodAutoscalersGetter: clientSet.AutoscalingV2(),
+		PodDisruptionBudgetsGetter:     clientSet.PolicyV1(),
+	}
+
+	pg := acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acid-fake-cluster",
+			Namespace: "default",
+		},
+		Spec: acidv1.PostgresSpec{
+			ConnectionPooler: &acidv1.ConnectionPooler{
+				SpreadAcrossNodes: util.True(),
+			},
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				ConnectionPooler: config.ConnectionPooler{
+					ConnectionPoolerDefaultCPURequest:    "100m",
+					ConnectionPoolerDefaultCPULimit:      "100m",
+					ConnectionPoolerDefaultMemoryRequest: "100Mi",
+					ConnectionPoolerDefaultMemoryLimit:   "100Mi",
+					NumberOfInstances:                    k8sutil.Int32ToPointer(2),
+					SpreadAcrossZones:                    true,
+				},
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+					PodRoleLabel:     "spilo-role",
+				},
+				PDBNameFormat: "postgres-{cluster}-pdb",
+			},
+		}, client, pg, logger, eventRecorder)
+	cluster.Name = pg.Name
+	cluster.Namespace = pg.Namespace
+
+	topologyKeys := func() []string {
+		deployment, err := client.Deployments("default").Get(context.TODO(), "acid-fake-cluster-pooler", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("could not get connection pooler deployment: %v", err)
+		}
+		keys := make([]string, 0)
+		for _, constraint := range deployment.Spec.Template.Spec.TopologySpreadConstraints {
+			keys = append(keys, constraint.TopologyKey)
+		}
+		return keys
+	}
+
+	if _, err := cluster.createConnectionPooler(mockInstallLookupFunction); err != nil {
+		t.Fatalf("could not create connection pooler: %v", err)
+	}
+
+	pdb, err := client.PodDisruptionBudgets("default").Get(context.TODO(), "postgres-acid-fake-cluster-pooler-pdb", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("connection pooler pod disruption budget was not created: %v", err)
+	}
+	assert.Equal(t, intstr.FromInt(1), *pdb.Spec.MaxUnavailable)
+	assert.Equal(t, cluster.connectionPoolerLabels(Master, false), pdb.Spec.Selector)
+	assert.Equal(t, []string{"topology.kubernetes.io/zone", "kubernetes.io/hostname"}, topologyKeys())
+
+	// disabling the spread across zones in the manifest replaces the
+	// constraints, while disabling the budget in the operator configuration
+	// replaces the PDB
+	newSpec := pg.DeepCopy()
+	newSpec.Spec.ConnectionPooler.SpreadAcrossZones = util.False()
+	cluster.Spec = newSpec.Spec
+	cluster.OpConfig.EnablePodDisruptionBudget = util.False()
+	if _, err = cluster.syncConnectionPooler(&pg, newSpec, mockInstallLookupFunction); err != nil {
+		t.Fatalf("could not sync connection pooler: %v", err)
+	}
+	assert.Equal(t, []string{"kubernetes.io/hostname"}, topologyKeys())
+
+	// the merge patch keeps the last constraints once the spread is disabled
+	// completely, which must not trigger an update on every sync
+	newSpec.Spec.ConnectionPooler.SpreadAcrossNodes = util.False()
+	cluster.Spec = newSpec.Spec
+	if sync, reasons := cluster.needSyncConnectionPoolerSpread(cluster.ConnectionPooler[Master].Deployment, Master); sync {
+		t.Errorf("unexpected update of the spread constraints: %v", reasons)
+	}
+
+	pdb, err = client.PodDisruptionBudgets("default").Get(context.TODO(), "postgres-acid-fake-cluster-pooler-pdb", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("connection pooler pod disruption budget was not replaced: %v", err)
+	}
+	assert.Equal(t, intstr.FromString("100%"), *pdb.Spec.MaxUnavailable)
+
+	if err = cluster.deleteConnectionPooler(Master); err != nil {
+		t.Fatalf("could not delete connection pooler: %v", err)
+	}
+	if _, err = client.PodDisruptionBudgets("default").Get(context.TODO(), "postgres-acid-fake-cluster-pooler-pdb", metav1.GetOptions{}); err == nil {
+		t.Errorf("connection pooler pod disruption budget must be deleted together with the pooler")
+	}
+}
+
+func TestConnectionPoolerTLS(t *testing.T) {
+	spiloFSGroup := int64(103)
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{SecretsGetter: clientSet.CoreV1()}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				ConnectionPooler: config.ConnectionPooler{
+					Type:                                 "pgbouncer",
+					Schema:                               "pooler",
+					User:                                 "pooler",
+					Mode:                                 "transaction",
+					MaxDBConnections:                     k8sutil.Int32ToPointer(60),
+					ConnectionPoolerDefaultCPURequest:    "100m",
+					ConnectionPoolerDefaultCPULimit:      "100m",
+					ConnectionPoolerDefaultMemoryRequest: "100Mi",
+					ConnectionPoolerDefaultMemoryLimit:   "100Mi",
+				},
+				Resources: config.Resources{
+					SpiloFSGroup: &spiloFSGroup,
+				},
+			},
+		}, client, acidv1.Postgresql{}, logger, eventRecorder)
+	cluster.Name = "acid-test-cluster"
+	cluster.Namespace = "default"
+	cluster.Spec = acidv1.PostgresSpec{
+		ConnectionPooler: &acidv1.ConnectionPooler{
+			NumberOfInstances: k8sutil.Int32ToPointer(2),
+		},
+		TLS: &acidv1.TLSDescription{
+			SecretName:   "cluster-tls",
+			CAFile:       "ca.crt",
+			CASecretName: "cluster-ca",
+		},
+	}
+	cluster.systemUsers[constants.ConnectionPoolerUserKeyName] = spec.PgUser{Name: "pooler", Password: "secret"}
+	cluster.ConnectionPooler = map[PostgresRole]*ConnectionPoolerObjects{
+		Master: {Name: cluster.connectionPoolerName(Master), Role: Master},
+	}
+
+	// pgbouncer uses the certificate of the cluster, but only offers TLS
+	podTemplate, err := cluster.generateConnectionPoolerPodTemplate(Master)
+	if err != nil {
+		t.Fatalf("could not generate pod template: %v", err)
+	}
+	env := make(map[string]string)
+	for _, envVar := range podTemplate.Spec.Containers[0].Env {
+		env[envVar.Name] = envVar.Value
+	}
+	for name, expected := range map[string]string{
+		"CONNECTION_POOLER_CLIENT_TLS_CRT": "/tls/tls.crt",
+		"CONNECTION_POOLER_CLIENT_TLS_KEY": "/tls/tls.key",
+		"CONNECTION_POOLER_CLIENT_CA_FILE": "/tlsca/ca.crt",
+	} {
+		if env[name] != expected {
+			t.Errorf("expected %s to be %q, got %q", name, expected, env[name])
+		}
+	}
+
+	// the image has no variables for the TLS modes, they need the template
+	poolerConfig := pgbouncerPooler{}.configFile(cluster, Master)
+	for _, expected := range []string{
+		"client_tls_sslmode = prefer\n",
+		"client_tls_cert_file = /tls/tls.crt\n",
+		"client_tls_ca_file = /tlsca/ca.crt\n",
+		"server_tls_sslmode = require\n",
+		"server_tls_ca_file = /tlsca/ca.crt\n",
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("pgbouncer config does not contain %q:\n%s", expected, poolerConfig)
+		}
+	}
+	secretNames := make([]string, 0)
+	for _, volume := range podTemplate.Spec.Volumes {
+		secretNames = append(secretNames, volume.Secret.SecretName)
+	}
+	assert.Equal(t, []string{"cluster-tls", "cluster-ca", "acid-test-cluster-pooler-config"}, secretNames)
+	assert.Equal(t, 4, len(podTemplate.Spec.Containers[0].VolumeMounts))
+	if podTemplate.Spec.SecurityContext == nil || *podTemplate.Spec.SecurityContext.FSGroup != 103 {
+		t.Errorf("expected FSGroup 103 to read the certificates, got %#v", podTemplate.Spec.SecurityContext)
+	}
+
+	// a dedicated pooler certificate takes precedence over the cluster's one
+	cluster.Spec.ConnectionPooler.Type = "odyssey"
+	cluster.Spec.ConnectionPooler.TLS = &acidv1.TLSDescription{SecretName: "pooler-tls"}
+	cluster.Spec.ConnectionPooler.ClientTLSMode = "prefer"
+	cluster.Spec.ConnectionPooler.ServerTLSMode = "verify-full"
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: *podTemplate}}
+	if sync, _ := cluster.needSyncConnectionPoolerTLS(deployment); !sync {
+		t.Errorf("expected a sync of the pooler deployment for the new certificate")
+	}
+
+	poolerConfig = odysseyPooler{}.configFile(cluster, Master)
+	for _, expected := range []string{
+		"\ttls \"allow\"\n\ttls_cert_file \"/tls/tls.crt\"\n\ttls_key_file \"/tls/tls.key\"\n}",
+		"\tport 5432\n\ttls \"verify_full\"\n}",
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("odyssey config does not contain %q:\n%s", expected, poolerConfig)
+		}
+	}
+
+	// without any certificate only the server connections can be encrypted
+	cluster.Spec.TLS = nil
+	cluster.Spec.ConnectionPooler.Type = "pgcat"
+	cluster.Spec.ConnectionPooler.TLS = nil
+	poolerConfig = pgcatPooler{}.configFile(cluster, Master)
+	if strings.Contains(poolerConfig, "tls_certificate") {
+		t.Errorf("pgcat config must not contain a certificate:\n%s", poolerConfig)
+	}
+	if !strings.Contains(poolerConfig, "server_tls = true\nverify_server_certificate = true\n") {
+		t.Errorf("pgcat config does not verify the server certificate:\n%s", poolerConfig)
+	}
+
+	// pgcat cannot enforce TLS for clients
+	cluster.Spec.ConnectionPooler.ClientTLSMode = "require"
+	if err := cluster.validateConnectionPooler(&cluster.Spec); err == nil {
+		t.Errorf("expected error for clientTLSMode require with pgcat")
+	}
+
+	// a certificate of the pooler itself enforces TLS for clients by default
+	cluster.Spec.ConnectionPooler.Type = "pgbouncer"
+	cluster.Spec.ConnectionPooler.ClientTLSMode = ""
+	cluster.Spec.ConnectionPooler.TLS = &acidv1.TLSDescription{SecretName: "pooler-tls"}
+	assert.Equal(t, "require", cluster.getConnectionPoolerTLS().clientMode)
+
+	// the template of the image covers the default TLS modes without a CA
+	cluster.Spec.ConnectionPooler.ServerTLSMode = ""
+	if poolerConfig := (pgbouncerPooler{}).configFile(cluster, Master); poolerConfig != "" {
+		t.Errorf("expected the template of the image, got:\n%s", poolerConfig)
+	}
+	cluster.Spec.ConnectionPooler.ServerTLSMode = "verify-full"
+	if poolerConfig := (pgbouncerPooler{}).configFile(cluster, Master); !strings.Contains(poolerConfig, "server_tls_sslmode = verify-full\n") {
+		t.Errorf("pgbouncer config does not verify the server certificate:\n%s", poolerConfig)
+	}
+
+	// the volumes left behind by the merge patch after disabling TLS must not
+	// cause an update on every sync
+	podTemplate, err = cluster.generateConnectionPoolerPodTemplate(Master)
+	if err != nil {
+		t.Fatalf("could not generate pod template: %v", err)
+	}
+	cluster.Spec.ConnectionPooler.TLS = nil
+	deployment = &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: *podTemplate}}
+	if sync, reasons := cluster.needSyncConnectionPoolerTLS(deployment); sync {
+		t.Errorf("unexpected update of the TLS volumes: %v", reasons)
+	}
+}
+
+func TestConnectionPoolerAuthFile(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{
+		ServicesGetter:                 clientSet.CoreV1(),
+		PodsGetter:                     clientSet.CoreV1(),
+		DeploymentsGetter:              clientSet.AppsV1(),
+		SecretsGetter:                  clientSet.CoreV1(),
+		HorizontalPodAutoscalersGetter: clientSet.AutoscalingV2(),
+		PodDisruptionBudgetsGetter:     clientSet.PolicyV1(),
+	}
+
+	pg := acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acid-fake-cluster",
+			Namespace: "default",
+		},
+		Spec: acidv1.PostgresSpec{
+			EnableConnectionPooler: util.True(),
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				ConnectionPooler: config.ConnectionPooler{
+					ConnectionPoolerDefaultCPURequest:    "100m",
+					ConnectionPoolerDefaultCPULimit:      "100m",
+					ConnectionPoolerDefaultMemoryRequest: "100Mi",
+					ConnectionPoolerDefaultMemoryLimit:   "100Mi",
+					NumberOfInstances:                    k8sutil.Int32ToPointer(1),
+					User:                                 "pooler",
+					Type:                                 "pgbouncer",
+					AuthMode:                             "file",
+				},
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+					PodRoleLabel:     "spilo-role",
+				},
+			},
+		}, client, pg, logger, eventRecorder)
+	cluster.Name = pg.Name
+	cluster.Namespace = pg.Namespace
+	cluster.systemUsers[constants.ConnectionPoolerUserKeyName] = spec.PgUser{Name: "pooler", Password: "secret"}
+	cluster.pgUsers = map[string]spec.PgUser{
+		"foo_owner": {Name: "foo_owner", Password: `pw"1`},
+		"foo_role":  {Name: "foo_role", Flags: []string{constants.RoleFlagNoLogin}},
+	}
+
+	noLookupFunction := func(schema string, user string) error {
+		t.Errorf("lookup function must not be installed in auth file mode")
+		return nil
+	}
+
+	authFile := func() string {
+		secret, err := client.Secrets("default").Get(context.TODO(), "acid-fake-cluster-pooler-config", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("could not get pooler config secret: %v", err)
+		}
+		return string(secret.Data["userlist.txt"])
+	}
+
+	if _, err := cluster.createConnectionPooler(noLookupFunction); err != nil {
+		t.Fatalf("could not create connection pooler: %v", err)
+	}
+	assert.Equal(t, "\"pooler\" \"secret\"\n\"foo_owner\" \"pw\"\"1\"\n", authFile())
+
+	// pgbouncer reads the auth file through the configuration template
+	deployment := cluster.ConnectionPooler[Master].Deployment
+	templateMounted := false
+	for _, mount := range deployment.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if mount.MountPath == cluster.pgbouncerTemplatePath() {
+			templateMounted = true
+		}
+	}
+	if !templateMounted {
+		t.Errorf("pgbouncer configuration template is not mounted in auth file mode")
+	}
+	poolerConfig := pgbouncerPooler{}.configFile(cluster, Master)
+	if !strings.Contains(poolerConfig, "auth_file = /etc/pooler/userlist.txt\n") ||
+		strings.Contains(poolerConfig, "auth_query") || strings.Contains(poolerConfig, "auth_user") {
+		t.Errorf("pgbouncer template does not use the auth file:\n%s", poolerConfig)
+	}
+	// the cluster has no certificate, so the TLS settings of the image are kept
+	for _, expected := range []string{
+		"client_tls_sslmode = require\n",
+		"client_tls_cert_file = /etc/ssl/certs/pgbouncer.crt\n",
+		"client_tls_key_file = /etc/ssl/certs/pgbouncer.key\n",
+		"server_tls_sslmode = require\n",
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("pgbouncer template in auth file mode does not contain %q:\n%s", expected, poolerConfig)
+		}
+	}
+	checksum := deployment.Spec.Template.Annotations[poolerConfigChecksumAnnotation]
+
+	// the files and settings of the image follow the operator configuration
+	imageConfig := cluster.OpConfig.ConnectionPooler
+	cluster.OpConfig.ConnectionPooler.PgbouncerTemplatePath = "/etc/pgbouncer/custom.ini.tmpl"
+	cluster.OpConfig.ConnectionPooler.PgbouncerStatsUsersPrefix = "monitor_"
+	cluster.OpConfig.ConnectionPooler.PgbouncerTLSCertificateFile = "/etc/pgbouncer/tls.crt"
+	cluster.OpConfig.ConnectionPooler.PgbouncerTLSPrivateKeyFile = "/etc/pgbouncer/tls.key"
+	poolerConfig = pgbouncerPooler{}.configFile(cluster, Master)
+	for _, expected := range []string{
+		"stats_users_prefix = monitor_\n",
+		"client_tls_cert_file = /etc/pgbouncer/tls.crt\n",
+		"client_tls_key_file = /etc/pgbouncer/tls.key\n",
+	} {
+		if !strings.Contains(poolerConfig, expected) {
+			t.Errorf("pgbouncer template does not contain the configured %q:\n%s", expected, poolerConfig)
+		}
+	}
+	podTemplate, err := cluster.generateConnectionPoolerPodTemplate(Master)
+	if err != nil {
+		t.Fatalf("could not generate pod template: %v", err)
+	}
+	templateMounted = false
+	for _, mount := range podTemplate.Spec.Containers[0].VolumeMounts {
+		if mount.MountPath == "/etc/pgbouncer/custom.ini.tmpl" {
+			templateMounted = true
+		}
+	}
+	if !templateMounted {
+		t.Errorf("pgbouncer configuration template is not mounted at the configured path")
+	}
+	cluster.OpConfig.ConnectionPooler = imageConfig
+
+	// a password rotation creates a new login role, so the auth file is
+	// updated on the next sync and marked to be reloaded by the pooler pods
+	cluster.pgUsers["foo_owner"] = spec.PgUser{Name: "foo_owner231018", Password: "pw2", MemberOf: []string{"foo_owner"}}
+	if err := cluster.syncConnectionPoolerConfigSecret(Master); err != nil {
+		t.Fatalf("could not sync connection pooler config secret: %v", err)
+	}
+	assert.Equal(t, "\"pooler\" \"secret\"\n\"foo_owner231018\" \"pw2\"\n", authFile())
+	assert.Contains(t, cluster.ConnectionPooler[Master].ConfigSecret.Annotations, poolerAuthFileReloadAnnotation)
+
+	// the pods are not replaced and the reload is done once no pod is left
+	// with the old file
+	if _, err := cluster.syncConnectionPooler(&pg, &pg, noLookupFunction); err != nil {
+		t.Fatalf("could not sync connection pooler: %v", err)
+	}
+	secret, _ := client.Secrets("default").Get(context.TODO(), "acid-fake-cluster-pooler-config", metav1.GetOptions{})
+	assert.NotContains(t, secret.Annotations, poolerAuthFileReloadAnnotation)
+
+	deployment, _ = client.Deployments("default").Get(context.TODO(), "acid-fake-cluster-pooler", metav1.GetOptions{})
+	if deployment.Spec.Template.Annotations[poolerConfigChecksumAnnotation] != checksum {
+		t.Errorf("pooler pods must not be replaced after the auth file changed")
+	}
+
+	// poolers with a configuration file get the credentials rendered into it
+	poolerConfig = pgcatPooler{}.configFile(cluster, Master)
+	if strings.Contains(poolerConfig, "auth_query") || !strings.Contains(poolerConfig, "username = \"foo_owner231018\"\npassword = \"pw2\"\n") {
+		t.Errorf("pgcat config does not contain the user credentials:\n%s", poolerConfig)
+	}
+	poolerConfig = odysseyPooler{}.configFile(cluster, Master)
+	if strings.Contains(poolerConfig, "user default") || !strings.Contains(poolerConfig, "\tuser \"foo_owner231018\" {\n\t\tauthentication \"md5\"\n\t\tpassword \"pw2\"\n") {
+		t.Errorf("odyssey config does not contain the user credentials:\n%s", poolerConfig)
+	}
+}
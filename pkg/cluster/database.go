@@ -39,12 +39,15 @@ const (
 			WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema' ORDER BY 1`
 	getExtensionsSQL = `SELECT e.extname, n.nspname FROM pg_catalog.pg_extension e
 	        LEFT JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace ORDER BY 1;`
+	getTablespacesSQL = `SELECT spcname, pg_get_userbyid(spcowner) AS owner FROM pg_catalog.pg_tablespace;`
 
 	createDatabaseSQL       = `CREATE DATABASE "%s" OWNER "%s";`
 	createDatabaseSchemaSQL = `SET ROLE TO "%s"; CREATE SCHEMA IF NOT EXISTS "%s" AUTHORIZATION "%s"`
 	alterDatabaseOwnerSQL   = `ALTER DATABASE "%s" OWNER TO "%s";`
 	createExtensionSQL      = `CREATE EXTENSION IF NOT EXISTS "%s" SCHEMA "%s"`
 	alterExtensionSQL       = `ALTER EXTENSION "%s" SET SCHEMA "%s"`
+	createTablespaceSQL     = `CREATE TABLESPACE "%s" OWNER "%s" LOCATION '%s';`
+	alterTablespaceOwnerSQL = `ALTER TABLESPACE "%s" OWNER TO "%s";`
 
 	getPublicationsSQL = `SELECT p.pubname, string_agg(pt.schemaname || '.' || pt.tablename, ', ' ORDER BY pt.schemaname, pt.tablename)
 	        FROM pg_publication p
@@ -580,6 +583,83 @@ func (c *Cluster) execCreateOrAlterExtension(extName, schemaName, statement, doi
 	return nil
 }
 
+// getTablespaces returns the map of current tablespaces with owners
+// The caller is responsible for opening and closing the database connection
+func (c *Cluster) getTablespaces() (tablespaces map[string]string, err error) {
+	var (
+		rows *sql.Rows
+	)
+
+	if rows, err = c.pgDb.Query(getTablespacesSQL); err != nil {
+		return nil, fmt.Errorf("could not query tablespaces: %v", err)
+	}
+
+	defer func() {
+		if err2 := rows.Close(); err2 != nil {
+			if err != nil {
+				err = fmt.Errorf("error when closing query cursor: %v, previous error: %v", err2, err)
+			} else {
+				err = fmt.Errorf("error when closing query cursor: %v", err2)
+			}
+		}
+	}()
+
+	tablespaces = make(map[string]string)
+
+	for rows.Next() {
+		var spcname, owner string
+
+		if err = rows.Scan(&spcname, &owner); err != nil {
+			return nil, fmt.Errorf("error when processing row: %v", err)
+		}
+		tablespaces[spcname] = owner
+	}
+
+	return tablespaces, err
+}
+
+// executeCreateTablespace creates a new tablespace with the given owner in the directory
+// prepared on the tablespace volume. The caller is responsible for opening and closing
+// the database connection.
+func (c *Cluster) executeCreateTablespace(tablespaceName, owner string) error {
+	if !c.tablespaceNameOwnerValid(tablespaceName, owner) {
+		return nil
+	}
+	c.logger.Infof("creating tablespace %q owner %q", tablespaceName, owner)
+	if _, err := c.pgDb.Exec(fmt.Sprintf(createTablespaceSQL, tablespaceName, owner, tablespaceLocation(tablespaceName))); err != nil {
+		return fmt.Errorf("could not execute create tablespace: %v", err)
+	}
+	return nil
+}
+
+// executeAlterTablespaceOwner changes the owner of the given tablespace.
+// The caller is responsible for opening and closing the database connection.
+func (c *Cluster) executeAlterTablespaceOwner(tablespaceName, owner string) error {
+	if !c.tablespaceNameOwnerValid(tablespaceName, owner) {
+		return nil
+	}
+	c.logger.Infof("changing owner for tablespace %q to %q", tablespaceName, owner)
+	if _, err := c.pgDb.Exec(fmt.Sprintf(alterTablespaceOwnerSQL, tablespaceName, owner)); err != nil {
+		return fmt.Errorf("could not execute alter tablespace owner: %v", err)
+	}
+	return nil
+}
+
+func (c *Cluster) tablespaceNameOwnerValid(tablespaceName, owner string) bool {
+	if owner != c.OpConfig.SuperUsername {
+		if _, ok := c.pgUsers[owner]; !ok {
+			c.logger.Infof("skipping tablespace %q, user %q does not exist", tablespaceName, owner)
+			return false
+		}
+	}
+
+	if !databaseNameRegexp.MatchString(tablespaceName) || strings.HasPrefix(tablespaceName, "pg_") {
+		c.logger.Infof("tablespace %q has invalid name", tablespaceName)
+		return false
+	}
+	return true
+}
+
 // getPublications returns the list of current database publications with tables
 // The caller is responsible for opening and closing the database connection
 func (c *Cluster) getPublications() (publications map[string]string, err error) {
@@ -17,6 +17,11 @@ import (
 
 //ExecCommand executes arbitrary command inside the pod
 func (c *Cluster) ExecCommand(podName *spec.NamespacedName, command ...string) (string, error) {
+	return c.execCommandInContainer(podName, constants.PostgresContainerName, command...)
+}
+
+// execCommandInContainer executes a command inside the given container of the pod
+func (c *Cluster) execCommandInContainer(podName *spec.NamespacedName, containerName string, command ...string) (string, error) {
 	c.setProcessName("executing command %q", strings.Join(command, " "))
 
 	var (
@@ -29,17 +34,17 @@ func (c *Cluster) ExecCommand(podName *spec.NamespacedName, command ...string) (
 		return "", fmt.Errorf("could not get pod info: %v", err)
 	}
 
-	// iterate through all containers looking for the one running PostgreSQL.
+	// iterate through all containers looking for the requested one
 	targetContainer := -1
 	for i, cr := range pod.Spec.Containers {
-		if cr.Name == constants.PostgresContainerName {
+		if cr.Name == containerName {
 			targetContainer = i
 			break
 		}
 	}
 
 	if targetContainer < 0 {
-		return "", fmt.Errorf("could not find %s container to exec to", constants.PostgresContainerName)
+		return "", fmt.Errorf("could not find %s container to exec to", containerName)
 	}
 
 	req := c.KubeClient.RESTClient.Post().
@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/zalando/postgres-operator/pkg/spec"
@@ -9,8 +10,8 @@ import (
 	"github.com/zalando/postgres-operator/pkg/util/filesystems"
 )
 
-func (c *Cluster) getPostgresFilesystemInfo(podName *spec.NamespacedName) (device, fstype string, err error) {
-	out, err := c.ExecCommand(podName, "bash", "-c", fmt.Sprintf("df -T %s|tail -1", constants.PostgresDataMount))
+func (c *Cluster) getPostgresFilesystemInfo(podName *spec.NamespacedName, mountPath string) (device, fstype string, err error) {
+	out, err := c.ExecCommand(podName, "bash", "-c", fmt.Sprintf("df -T %s|tail -1", mountPath))
 	if err != nil {
 		return "", "", err
 	}
@@ -22,10 +23,30 @@ func (c *Cluster) getPostgresFilesystemInfo(podName *spec.NamespacedName) (devic
 	return fields[0], fields[1], nil
 }
 
-func (c *Cluster) resizePostgresFilesystem(podName *spec.NamespacedName, resizers []filesystems.FilesystemResizer) error {
+// getPostgresFilesystemUsage returns the size and the used bytes of the filesystem holding the data directory
+func (c *Cluster) getPostgresFilesystemUsage(podName *spec.NamespacedName) (size, used int64, err error) {
+	out, err := c.ExecCommand(podName, "bash", "-c", fmt.Sprintf("df -P -B1 %s|tail -1", constants.PostgresDataMount))
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) < 3 {
+		return 0, 0, fmt.Errorf("too few fields in the df output")
+	}
+	if size, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("could not parse filesystem size %q: %v", fields[1], err)
+	}
+	if used, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("could not parse used filesystem space %q: %v", fields[2], err)
+	}
+
+	return size, used, nil
+}
+
+func (c *Cluster) resizePostgresFilesystem(podName *spec.NamespacedName, mountPath string, resizers []filesystems.FilesystemResizer) error {
 	// resize2fs always writes to stderr, and ExecCommand considers a non-empty stderr an error
 	// first, determine the device and the filesystem
-	deviceName, fsType, err := c.getPostgresFilesystemInfo(podName)
+	deviceName, fsType, err := c.getPostgresFilesystemInfo(podName, mountPath)
 	if err != nil {
 		return fmt.Errorf("could not get device and type for the postgres filesystem: %v", err)
 	}
@@ -33,11 +54,14 @@ func (c *Cluster) resizePostgresFilesystem(podName *spec.NamespacedName, resizer
 		if !resizer.CanResizeFilesystem(fsType) {
 			continue
 		}
-		err := resizer.ResizeFilesystem(deviceName, func(cmd string) (out string, err error) {
+		commandExecutor := func(cmd string) (out string, err error) {
 			return c.ExecCommand(podName, "bash", "-c", cmd)
-		})
+		}
+		if mountedResizer, ok := resizer.(filesystems.MountedFilesystemResizer); ok {
+			return mountedResizer.ResizeMountedFilesystem(mountPath, commandExecutor)
+		}
 
-		return err
+		return resizer.ResizeFilesystem(deviceName, commandExecutor)
 	}
 	return fmt.Errorf("could not resize filesystem: no compatible resizers for the filesystem of type %q", fsType)
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -42,8 +45,42 @@ const (
 	connectionPoolerContainer      = "connection-pooler"
 	pgPort                         = 5432
 	operatorPort                   = 8080
+	walRelocationContainerName     = "relocate-pg-wal"
+	tablespacesContainerName       = "prepare-tablespaces"
 )
 
+// walRelocationScript prepares the WAL volume for initdb and moves the pg_wal directory of an
+// existing data directory to it, replacing it with a symlink. It is safe to rerun after an interruption.
+const walRelocationScript = `set -e
+PGDATA=` + constants.PostgresDataPath + `/data
+WALDIR=` + constants.PostgresWALPath + `
+if [ ! -d "$PGDATA" ]; then
+  mkdir -p "$WALDIR"
+  chown postgres: "$WALDIR" 2>/dev/null || true
+  chmod 700 "$WALDIR"
+elif [ -d "$PGDATA/pg_wal" ] && [ ! -L "$PGDATA/pg_wal" ]; then
+  rm -rf "$WALDIR" && mkdir -p "$WALDIR"
+  chown --reference="$PGDATA" "$WALDIR" 2>/dev/null || true
+  chmod 700 "$WALDIR"
+  cp -a "$PGDATA/pg_wal/." "$WALDIR/"
+  mv "$PGDATA/pg_wal" "$PGDATA/pg_wal.relocated"
+  ln -s "$WALDIR" "$PGDATA/pg_wal"
+elif [ -d "$PGDATA" ] && [ ! -e "$PGDATA/pg_wal" ] && [ -d "$WALDIR" ]; then
+  ln -s "$WALDIR" "$PGDATA/pg_wal"
+fi
+rm -rf "$PGDATA/pg_wal.relocated"
+`
+
+// tablespacesScript creates the tablespace locations given as arguments, Postgres expects them
+// to exist on every member before the tablespace is created or replayed
+const tablespacesScript = `set -e
+for location in "$@"; do
+  mkdir -p "$location"
+  chown postgres: "$location" 2>/dev/null || true
+  chmod 700 "$location"
+done
+`
+
 type pgUser struct {
 	Password string   `json:"password"`
 	Options  []string `json:"options"`
@@ -122,6 +159,10 @@ func (c *Cluster) podDisruptionBudgetName() string {
 	return c.OpConfig.PDBNameFormat.Format("cluster", c.Name)
 }
 
+func (c *Cluster) connectionPoolerPodDisruptionBudgetName(role PostgresRole) string {
+	return c.OpConfig.PDBNameFormat.Format("cluster", c.connectionPoolerName(role))
+}
+
 func makeDefaultResources(config *config.Config) acidv1.Resources {
 
 	defaultRequests := acidv1.ResourceDescription{
@@ -582,6 +623,70 @@ func generateVolumeMounts(volume acidv1.Volume) []v1.VolumeMount {
 	}
 }
 
+func generateWALVolumeMount(volume acidv1.Volume) v1.VolumeMount {
+	return v1.VolumeMount{
+		Name:      constants.WALVolumeName,
+		MountPath: constants.PostgresWALMount,
+		SubPath:   volume.SubPath,
+	}
+}
+
+// generateWALRelocationContainer returns an init container which moves pg_wal of existing
+// data directories to the WAL volume, new clusters are initialized with it via initdb
+func generateWALRelocationContainer(dockerImage string, resourceRequirements *v1.ResourceRequirements, volumeMounts []v1.VolumeMount) v1.Container {
+	return v1.Container{
+		Name:            walRelocationContainerName,
+		Image:           dockerImage,
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Command:         []string{"/bin/bash", "-c", walRelocationScript},
+		Resources:       *resourceRequirements,
+		VolumeMounts:    volumeMounts,
+	}
+}
+
+// tablespaceVolumeName returns the name of the volume claim template of a tablespace
+func tablespaceVolumeName(tablespaceName string) string {
+	return constants.TablespaceVolumeNamePrefix + strings.Replace(tablespaceName, "_", "-", -1)
+}
+
+func tablespaceMountPath(tablespaceName string) string {
+	return constants.PostgresTablespacesMount + "/" + tablespaceName
+}
+
+// tablespaceLocation returns the directory of the tablespace below the mount point,
+// which itself is not owned by the postgres user
+func tablespaceLocation(tablespaceName string) string {
+	return tablespaceMountPath(tablespaceName) + "/data"
+}
+
+func generateTablespaceVolumeMounts(tablespaces []acidv1.Tablespace) []v1.VolumeMount {
+	mounts := make([]v1.VolumeMount, 0, len(tablespaces))
+	for _, tablespace := range tablespaces {
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      tablespaceVolumeName(tablespace.Name),
+			MountPath: tablespaceMountPath(tablespace.Name),
+			SubPath:   tablespace.Volume.SubPath,
+		})
+	}
+	return mounts
+}
+
+// generateTablespacesContainer returns an init container which prepares the tablespace locations
+func generateTablespacesContainer(dockerImage string, resourceRequirements *v1.ResourceRequirements, tablespaces []acidv1.Tablespace, volumeMounts []v1.VolumeMount) v1.Container {
+	command := []string{"/bin/bash", "-c", tablespacesScript, tablespacesContainerName}
+	for _, tablespace := range tablespaces {
+		command = append(command, tablespaceLocation(tablespace.Name))
+	}
+	return v1.Container{
+		Name:            tablespacesContainerName,
+		Image:           dockerImage,
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Command:         command,
+		Resources:       *resourceRequirements,
+		VolumeMounts:    volumeMounts,
+	}
+}
+
 func generateContainer(
 	name string,
 	dockerImage *string,
@@ -911,7 +1016,8 @@ func (c *Cluster) generateSpiloPodEnvVars(
 		envVars = append(envVars, v1.EnvVar{Name: "KUBERNETES_USE_CONFIGMAPS", Value: "true"})
 	}
 
-	if spec.Clone != nil && spec.Clone.ClusterName != "" {
+	// volume snapshot clones start from the restored data directory
+	if spec.Clone != nil && spec.Clone.ClusterName != "" && spec.Clone.VolumeSnapshot == "" {
 		envVars = append(envVars, c.generateCloneEnvironment(spec.Clone)...)
 	}
 
@@ -1124,7 +1230,7 @@ func extractPgVersionFromBinPath(binPath string, template string) (string, error
 func generateSpiloReadinessProbe() *v1.Probe {
 	return &v1.Probe{
 		FailureThreshold: 3,
-		Handler: v1.Handler{
+		ProbeHandler: v1.ProbeHandler{
 			HTTPGet: &v1.HTTPGetAction{
 				Path:   "/readiness",
 				Port:   intstr.IntOrString{IntVal: patroni.ApiPort},
@@ -1185,7 +1291,21 @@ func (c *Cluster) generateStatefulSet(spec *acidv1.PostgresSpec) (*appsv1.Statef
 		}
 	}
 
-	spiloConfiguration, err := generateSpiloJSONConfiguration(&spec.PostgresqlParam, &spec.Patroni, &c.OpConfig, c.logger)
+	patroniConfiguration := spec.Patroni
+	if patroniConfiguration.PgHba, err = c.generatePgHba(spec); err != nil {
+		return nil, fmt.Errorf("could not generate pg_hba: %v", err)
+	}
+	// new clusters keep their WAL on the separate volume from the start
+	if spec.WALVolume != nil {
+		if _, ok := patroniConfiguration.InitDB["waldir"]; !ok {
+			initDB := map[string]string{"waldir": constants.PostgresWALPath}
+			for k, v := range patroniConfiguration.InitDB {
+				initDB[k] = v
+			}
+			patroniConfiguration.InitDB = initDB
+		}
+	}
+	spiloConfiguration, err := generateSpiloJSONConfiguration(&spec.PostgresqlParam, &patroniConfiguration, &c.OpConfig, c.logger)
 	if err != nil {
 		return nil, fmt.Errorf("could not generate Spilo JSON configuration: %v", err)
 	}
@@ -1216,6 +1336,15 @@ func (c *Cluster) generateStatefulSet(spec *acidv1.PostgresSpec) (*appsv1.Statef
 	}
 
 	volumeMounts := generateVolumeMounts(spec.Volume)
+	if spec.WALVolume != nil {
+		volumeMounts = append(volumeMounts, generateWALVolumeMount(*spec.WALVolume))
+		initContainers = append([]v1.Container{generateWALRelocationContainer(effectiveDockerImage, resourceRequirements, volumeMounts)}, initContainers...)
+	}
+	if len(spec.Tablespaces) > 0 {
+		tablespaceMounts := generateTablespaceVolumeMounts(spec.Tablespaces)
+		volumeMounts = append(volumeMounts, tablespaceMounts...)
+		initContainers = append([]v1.Container{generateTablespacesContainer(effectiveDockerImage, resourceRequirements, spec.Tablespaces, tablespaceMounts)}, initContainers...)
+	}
 
 	// configure TLS with a custom secret volume
 	if spec.TLS != nil && spec.TLS.SecretName != "" {
@@ -1272,6 +1401,27 @@ func (c *Cluster) generateStatefulSet(spec *acidv1.PostgresSpec) (*appsv1.Statef
 		}
 	}
 
+	// trust the operator-managed client CA for certificate authentication, never mount its private key.
+	// Manifests combining it with a custom CA file are rejected as invalid.
+	if len(c.certificateAuthenticationUsers(spec)) > 0 {
+		defaultMode := int32(0640)
+		additionalVolumes = append(additionalVolumes, acidv1.AdditionalVolume{
+			Name:      clientCAVolumeName,
+			MountPath: clientCAMountPath,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName:  c.clientCASecretName(),
+					Items:       []v1.KeyToPath{{Key: clientCACertKey, Path: clientCACertKey}},
+					DefaultMode: &defaultMode,
+				},
+			},
+		})
+		spiloEnvVars = appendEnvVars(
+			spiloEnvVars,
+			v1.EnvVar{Name: "SSL_CA_FILE", Value: clientCAMountPath + "/" + clientCACertKey},
+		)
+	}
+
 	// generate the spilo container
 	spiloContainer := generateContainer(constants.PostgresContainerName,
 		&effectiveDockerImage,
@@ -1378,10 +1528,25 @@ func (c *Cluster) generateStatefulSet(spec *acidv1.PostgresSpec) (*appsv1.Statef
 		return nil, fmt.Errorf("could not generate pod template: %v", err)
 	}
 
-	if volumeClaimTemplate, err = c.generatePersistentVolumeClaimTemplate(spec.Volume.Size,
-		spec.Volume.StorageClass, spec.Volume.Selector); err != nil {
+	if volumeClaimTemplate, err = c.generatePersistentVolumeClaimTemplate(constants.DataVolumeName, c.volumeSize(spec.Volume),
+		spec.Volume.StorageClass, spec.Volume.Selector, nil); err != nil {
 		return nil, fmt.Errorf("could not generate volume claim template: %v", err)
 	}
+	volumeClaimTemplates := []v1.PersistentVolumeClaim{*volumeClaimTemplate}
+	if spec.WALVolume != nil {
+		if volumeClaimTemplate, err = c.generatePersistentVolumeClaimTemplate(constants.WALVolumeName, spec.WALVolume.Size,
+			spec.WALVolume.StorageClass, spec.WALVolume.Selector, nil); err != nil {
+			return nil, fmt.Errorf("could not generate WAL volume claim template: %v", err)
+		}
+		volumeClaimTemplates = append(volumeClaimTemplates, *volumeClaimTemplate)
+	}
+	for _, tablespace := range spec.Tablespaces {
+		if volumeClaimTemplate, err = c.generatePersistentVolumeClaimTemplate(tablespaceVolumeName(tablespace.Name), tablespace.Volume.Size,
+			tablespace.Volume.StorageClass, tablespace.Volume.Selector, nil); err != nil {
+			return nil, fmt.Errorf("could not generate volume claim template for tablespace %q: %v", tablespace.Name, err)
+		}
+		volumeClaimTemplates = append(volumeClaimTemplates, *volumeClaimTemplate)
+	}
 
 	// global minInstances and maxInstances settings can overwrite manifest
 	numberOfInstances := c.getNumberOfInstances(spec)
@@ -1408,13 +1573,14 @@ func (c *Cluster) generateStatefulSet(spec *acidv1.PostgresSpec) (*appsv1.Statef
 			Annotations: c.AnnotationsToPropagate(c.annotationsSet(nil)),
 		},
 		Spec: appsv1.StatefulSetSpec{
-			Replicas:             &numberOfInstances,
-			Selector:             c.labelsSelector(),
-			ServiceName:          c.serviceName(Master),
-			Template:             *podTemplate,
-			VolumeClaimTemplates: []v1.PersistentVolumeClaim{*volumeClaimTemplate},
-			UpdateStrategy:       updateStrategy,
-			PodManagementPolicy:  podManagementPolicy,
+			Replicas:                             &numberOfInstances,
+			Selector:                             c.labelsSelector(),
+			ServiceName:                          c.serviceName(Master),
+			Template:                             *podTemplate,
+			VolumeClaimTemplates:                 volumeClaimTemplates,
+			UpdateStrategy:                       updateStrategy,
+			PodManagementPolicy:                  podManagementPolicy,
+			PersistentVolumeClaimRetentionPolicy: c.generateVolumeClaimRetentionPolicy(spec, numberOfInstances),
 		},
 	}
 
@@ -1636,8 +1802,8 @@ func (c *Cluster) addAdditionalVolumes(podSpec *v1.PodSpec,
 	podSpec.Volumes = volumes
 }
 
-func (c *Cluster) generatePersistentVolumeClaimTemplate(volumeSize, volumeStorageClass string,
-	volumeSelector *metav1.LabelSelector) (*v1.PersistentVolumeClaim, error) {
+func (c *Cluster) generatePersistentVolumeClaimTemplate(volumeName, volumeSize, volumeStorageClass string,
+	volumeSelector *metav1.LabelSelector, dataSource *v1.TypedLocalObjectReference) (*v1.PersistentVolumeClaim, error) {
 
 	var storageClassName *string
 	if volumeStorageClass != "" {
@@ -1652,7 +1818,7 @@ func (c *Cluster) generatePersistentVolumeClaimTemplate(volumeSize, volumeStorag
 	volumeMode := v1.PersistentVolumeFilesystem
 	volumeClaim := &v1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        constants.DataVolumeName,
+			Name:        volumeName,
 			Annotations: c.annotationsSet(nil),
 			Labels:      c.labelsSet(true),
 		},
@@ -1666,6 +1832,7 @@ func (c *Cluster) generatePersistentVolumeClaimTemplate(volumeSize, volumeStorag
 			StorageClassName: storageClassName,
 			VolumeMode:       &volumeMode,
 			Selector:         volumeSelector,
+			DataSource:       dataSource,
 		},
 	}
 
@@ -1732,9 +1899,94 @@ func (c *Cluster) generateSingleUserSecret(namespace string, pgUser spec.PgUser)
 		},
 	}
 
+	for key, value := range c.generateConnectionSecretData(pgUser.Name, pgUser.Name) {
+		secret.Data[key] = value
+	}
+
 	return &secret
 }
 
+// generateConnectionSecretData returns host, port, database and connection URIs
+// of the master and replica services and of the enabled connection poolers.
+// The login name can differ from the role name when passwords are rotated.
+func (c *Cluster) generateConnectionSecretData(loginName, roleName string) map[string][]byte {
+	data := make(map[string][]byte)
+	if !c.OpConfig.EnableSecretConnectionKeys {
+		return data
+	}
+
+	database := c.userDatabase(roleName)
+	port := strconv.Itoa(pgPort)
+	for service, serviceName := range c.connectionSecretServices() {
+		host := fmt.Sprintf("%s.%s.svc.%s", serviceName, c.Namespace, c.OpConfig.ClusterDomain)
+		uri := url.URL{
+			Scheme:   "postgresql",
+			User:     url.User(loginName),
+			Host:     net.JoinHostPort(host, port),
+			Path:     "/" + database,
+			RawQuery: "sslmode=require",
+		}
+		jdbcURI := fmt.Sprintf("jdbc:postgresql://%s/%s?user=%s&sslmode=require",
+			net.JoinHostPort(host, port), url.PathEscape(database), url.QueryEscape(loginName))
+
+		data[c.connectionSecretKey(service, "host")] = []byte(host)
+		data[c.connectionSecretKey(service, "port")] = []byte(port)
+		data[c.connectionSecretKey(service, "database")] = []byte(database)
+		data[c.connectionSecretKey(service, "uri")] = []byte(uri.String())
+		data[c.connectionSecretKey(service, "jdbc-uri")] = []byte(jdbcURI)
+	}
+
+	return data
+}
+
+// connectionSecretServices maps the service placeholder of the connection keys to the service names
+func (c *Cluster) connectionSecretServices() map[string]string {
+	services := map[string]string{
+		"master":  c.serviceName(Master),
+		"replica": c.serviceName(Replica),
+	}
+	if needMasterConnectionPoolerWorker(&c.Spec) {
+		services["pooler"] = c.connectionPoolerName(Master)
+	}
+	if needReplicaConnectionPoolerWorker(&c.Spec) {
+		services["pooler-replica"] = c.connectionPoolerName(Replica)
+	}
+
+	return services
+}
+
+// connectionSecretKeys returns all connection keys the operator may add to a secret
+func (c *Cluster) connectionSecretKeys() []string {
+	keys := make([]string, 0)
+	for _, service := range []string{"master", "replica", "pooler", "pooler-replica"} {
+		for _, key := range []string{"host", "port", "database", "uri", "jdbc-uri"} {
+			keys = append(keys, c.connectionSecretKey(service, key))
+		}
+	}
+
+	return keys
+}
+
+func (c *Cluster) connectionSecretKey(service, key string) string {
+	return c.OpConfig.SecretConnectionKeyTemplate.Format("service", service, "key", key)
+}
+
+// userDatabase returns the first database owned by the role or "postgres"
+func (c *Cluster) userDatabase(roleName string) string {
+	databases := make([]string, 0)
+	for database, owner := range c.Spec.Databases {
+		if owner == roleName {
+			databases = append(databases, database)
+		}
+	}
+	if len(databases) == 0 {
+		return "postgres"
+	}
+	sort.Strings(databases)
+
+	return databases[0]
+}
+
 func (c *Cluster) shouldCreateLoadBalancerForService(role PostgresRole, spec *acidv1.PostgresSpec) bool {
 
 	switch role {
@@ -2011,6 +2263,47 @@ func (c *Cluster) generatePodDisruptionBudget() *policyv1.PodDisruptionBudget {
 	}
 }
 
+// generateConnectionPoolerPodDisruptionBudget allows to evict only one pooler
+// pod at a time, so the remaining ones keep accepting connections
+func (c *Cluster) generateConnectionPoolerPodDisruptionBudget(connectionPooler *ConnectionPoolerObjects) *policyv1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(1)
+	pdbEnabled := c.OpConfig.EnablePodDisruptionBudget
+
+	// if PodDisruptionBudget is disabled, do not prevent any eviction
+	if pdbEnabled != nil && !(*pdbEnabled) {
+		maxUnavailable = intstr.FromString("100%")
+	}
+
+	// owned by the deployment, so it is garbage collected together with it
+	var ownerReferences []metav1.OwnerReference
+	if connectionPooler.Deployment != nil {
+		controller := true
+		ownerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       connectionPooler.Deployment.Name,
+				UID:        connectionPooler.Deployment.UID,
+				Controller: &controller,
+			},
+		}
+	}
+
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            c.connectionPoolerPodDisruptionBudgetName(connectionPooler.Role),
+			Namespace:       connectionPooler.Namespace,
+			Labels:          c.connectionPoolerLabels(connectionPooler.Role, true).MatchLabels,
+			Annotations:     c.annotationsSet(nil),
+			OwnerReferences: ownerReferences,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector:       c.connectionPoolerLabels(connectionPooler.Role, false),
+		},
+	}
+}
+
 // getClusterServiceConnectionParameters fetches cluster host name and port
 // TODO: perhaps we need to query the service (i.e. if non-standard port is used?)
 // TODO: handle clusters in different namespaces
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"time"
 
 	"testing"
@@ -1820,6 +1821,216 @@ func TestVolumeSelector(t *testing.T) {
 	}
 }
 
+func TestWALVolume(t *testing.T) {
+	testName := "TestWALVolume"
+	storageClass := "fast"
+	spec := acidv1.PostgresSpec{
+		TeamID:            "myapp",
+		NumberOfInstances: 1,
+		Resources: &acidv1.Resources{
+			ResourceRequests: acidv1.ResourceDescription{CPU: "1", Memory: "10"},
+			ResourceLimits:   acidv1.ResourceDescription{CPU: "1", Memory: "10"},
+		},
+		Volume:    acidv1.Volume{Size: "10G"},
+		WALVolume: &acidv1.Volume{Size: "2G", StorageClass: storageClass},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				PodManagementPolicy: "ordered_ready",
+				ProtectedRoles:      []string{"admin"},
+				Auth: config.Auth{
+					SuperUsername:       superUserName,
+					ReplicationUsername: replicationUserName,
+				},
+			},
+		}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+
+	sts, err := cluster.generateStatefulSet(&spec)
+	if err != nil {
+		t.Fatalf("%s: no statefulset created %v", testName, err)
+	}
+
+	// a second volume claim template is created for the WAL
+	claimTemplates := sts.Spec.VolumeClaimTemplates
+	if len(claimTemplates) != 2 || claimTemplates[1].Name != constants.WALVolumeName {
+		t.Fatalf("%s: expected volume claim templates %q and %q, got %#v", testName, constants.DataVolumeName, constants.WALVolumeName, claimTemplates)
+	}
+	walClaim := claimTemplates[1]
+	if walClaim.Spec.StorageClassName == nil || *walClaim.Spec.StorageClassName != storageClass {
+		t.Errorf("%s: expected storage class %q for WAL volume claim, got %v", testName, storageClass, walClaim.Spec.StorageClassName)
+	}
+	expectedSize := resource.MustParse("2G")
+	if size := walClaim.Spec.Resources.Requests[v1.ResourceStorage]; size.Cmp(expectedSize) != 0 {
+		t.Errorf("%s: expected WAL volume size %s, got %s", testName, expectedSize.String(), size.String())
+	}
+
+	// WAL volume is mounted into the postgres container
+	podSpec := sts.Spec.Template.Spec
+	walMount := v1.VolumeMount{Name: constants.WALVolumeName, MountPath: constants.PostgresWALMount}
+	volumeMountExists := func(mount v1.VolumeMount, mounts []v1.VolumeMount) bool {
+		for _, m := range mounts {
+			if reflect.DeepEqual(m, mount) {
+				return true
+			}
+		}
+		return false
+	}
+	if !volumeMountExists(walMount, podSpec.Containers[0].VolumeMounts) {
+		t.Errorf("%s: WAL volume not mounted into the postgres container", testName)
+	}
+
+	// existing WAL is moved by the first init container
+	if len(podSpec.InitContainers) == 0 || podSpec.InitContainers[0].Name != walRelocationContainerName {
+		t.Fatalf("%s: expected %q as first init container, got %#v", testName, walRelocationContainerName, podSpec.InitContainers)
+	}
+	if !volumeMountExists(walMount, podSpec.InitContainers[0].VolumeMounts) {
+		t.Errorf("%s: WAL volume not mounted into the %q init container", testName, walRelocationContainerName)
+	}
+
+	// new clusters are initialized with the WAL directory on the separate volume
+	var spiloConfiguration string
+	for _, env := range podSpec.Containers[0].Env {
+		if env.Name == "SPILO_CONFIGURATION" {
+			spiloConfiguration = env.Value
+		}
+	}
+	if !strings.Contains(spiloConfiguration, `{"waldir":"`+constants.PostgresWALPath+`"}`) {
+		t.Errorf("%s: expected initdb waldir %q in Spilo configuration, got %s", testName, constants.PostgresWALPath, spiloConfiguration)
+	}
+}
+
+func TestTablespaces(t *testing.T) {
+	testName := "TestTablespaces"
+	spec := acidv1.PostgresSpec{
+		TeamID:            "myapp",
+		NumberOfInstances: 1,
+		Resources: &acidv1.Resources{
+			ResourceRequests: acidv1.ResourceDescription{CPU: "1", Memory: "10"},
+			ResourceLimits:   acidv1.ResourceDescription{CPU: "1", Memory: "10"},
+		},
+		Volume: acidv1.Volume{Size: "10G"},
+		Tablespaces: []acidv1.Tablespace{
+			{Name: "hot_data", Volume: acidv1.Volume{Size: "5G", StorageClass: "fast"}},
+			{Name: "archive", Owner: "foo", Volume: acidv1.Volume{Size: "50G", StorageClass: "slow"}},
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				PodManagementPolicy: "ordered_ready",
+				ProtectedRoles:      []string{"admin"},
+				Auth: config.Auth{
+					SuperUsername:       superUserName,
+					ReplicationUsername: replicationUserName,
+				},
+			},
+		}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+
+	sts, err := cluster.generateStatefulSet(&spec)
+	if err != nil {
+		t.Fatalf("%s: no statefulset created %v", testName, err)
+	}
+
+	// one volume claim template per tablespace follows the data volume
+	expectedClaims := []struct {
+		name         string
+		storageClass string
+	}{
+		{constants.DataVolumeName, ""},
+		{"tablespace-hot-data", "fast"},
+		{"tablespace-archive", "slow"},
+	}
+	claimTemplates := sts.Spec.VolumeClaimTemplates
+	if len(claimTemplates) != len(expectedClaims) {
+		t.Fatalf("%s: expected %d volume claim templates, got %d", testName, len(expectedClaims), len(claimTemplates))
+	}
+	for i, expected := range expectedClaims {
+		if claimTemplates[i].Name != expected.name {
+			t.Errorf("%s: expected volume claim template %q, got %q", testName, expected.name, claimTemplates[i].Name)
+		}
+		if expected.storageClass != "" && *claimTemplates[i].Spec.StorageClassName != expected.storageClass {
+			t.Errorf("%s: expected storage class %q for %q, got %q", testName, expected.storageClass, expected.name, *claimTemplates[i].Spec.StorageClassName)
+		}
+	}
+
+	// tablespace volumes are mounted into the postgres container
+	podSpec := sts.Spec.Template.Spec
+	expectedMounts := map[string]string{
+		"tablespace-hot-data": constants.PostgresTablespacesMount + "/hot_data",
+		"tablespace-archive":  constants.PostgresTablespacesMount + "/archive",
+	}
+	for _, mount := range podSpec.Containers[0].VolumeMounts {
+		if mountPath, ok := expectedMounts[mount.Name]; ok && mountPath == mount.MountPath {
+			delete(expectedMounts, mount.Name)
+		}
+	}
+	if len(expectedMounts) > 0 {
+		t.Errorf("%s: tablespace volumes not mounted into the postgres container: %v", testName, expectedMounts)
+	}
+
+	// locations are prepared by an init container
+	if len(podSpec.InitContainers) == 0 || podSpec.InitContainers[0].Name != tablespacesContainerName {
+		t.Fatalf("%s: expected %q as first init container, got %#v", testName, tablespacesContainerName, podSpec.InitContainers)
+	}
+	expectedCommand := []string{"/bin/bash", "-c", tablespacesScript, tablespacesContainerName,
+		constants.PostgresTablespacesMount + "/hot_data/data", constants.PostgresTablespacesMount + "/archive/data"}
+	if !reflect.DeepEqual(podSpec.InitContainers[0].Command, expectedCommand) {
+		t.Errorf("%s: expected init container command %v, got %v", testName, expectedCommand, podSpec.InitContainers[0].Command)
+	}
+	if len(podSpec.InitContainers[0].VolumeMounts) != 2 {
+		t.Errorf("%s: expected tablespace volumes mounted into init container, got %v", testName, podSpec.InitContainers[0].VolumeMounts)
+	}
+}
+
+func TestCloneFromVolumeSnapshot(t *testing.T) {
+	testName := "TestCloneFromVolumeSnapshot"
+	spec := acidv1.PostgresSpec{
+		TeamID:            "myapp",
+		NumberOfInstances: 2,
+		Resources: &acidv1.Resources{
+			ResourceRequests: acidv1.ResourceDescription{CPU: "1", Memory: "10"},
+			ResourceLimits:   acidv1.ResourceDescription{CPU: "1", Memory: "10"},
+		},
+		Volume: acidv1.Volume{Size: "10G"},
+		Clone: &acidv1.CloneDescription{
+			ClusterName:    "acid-source-cluster",
+			VolumeSnapshot: "acid-source-cluster-20220101-000000",
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				PodManagementPolicy: "ordered_ready",
+				ProtectedRoles:      []string{"admin"},
+				Auth: config.Auth{
+					SuperUsername:       superUserName,
+					ReplicationUsername: replicationUserName,
+				},
+			},
+		}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+
+	sts, err := cluster.generateStatefulSet(&spec)
+	if err != nil {
+		t.Fatalf("%s: no statefulset created %v", testName, err)
+	}
+
+	// the snapshot is only referenced by the claims created for the bootstrap, later pods must not depend on it
+	if dataSource := sts.Spec.VolumeClaimTemplates[0].Spec.DataSource; dataSource != nil {
+		t.Errorf("%s: expected no data source in the volume claim template, got %#v", testName, dataSource)
+	}
+
+	// the data directory comes from the snapshot, so Spilo must not clone on its own
+	for _, env := range sts.Spec.Template.Spec.Containers[0].Env {
+		if strings.HasPrefix(env.Name, "CLONE_") {
+			t.Errorf("%s: unexpected clone environment variable %s", testName, env.Name)
+		}
+	}
+}
+
 // inject sidecars through all available mechanisms and check the resulting container specs
 func TestSidecars(t *testing.T) {
 	var err error
@@ -2219,6 +2430,63 @@ func TestGenerateService(t *testing.T) {
 
 }
 
+func TestGenerateSingleUserSecretConnectionKeys(t *testing.T) {
+	enablePooler := true
+	pg := acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acid-test-cluster",
+			Namespace: "default",
+		},
+		Spec: acidv1.PostgresSpec{
+			Databases:              map[string]string{"foo": "foo_owner", "bar": "foo_owner"},
+			EnableConnectionPooler: &enablePooler,
+		},
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				Resources: config.Resources{
+					ClusterDomain: "cluster.local",
+				},
+				Auth: config.Auth{
+					SecretNameTemplate:          "{username}.{cluster}.credentials",
+					EnableSecretConnectionKeys:  true,
+					SecretConnectionKeyTemplate: "{service}-{key}",
+				},
+			},
+		}, k8sutil.KubernetesClient{}, pg, logger, eventRecorder)
+	cluster.Name = pg.Name
+	cluster.Namespace = pg.Namespace
+
+	secret := cluster.generateSingleUserSecret("default", spec.PgUser{Name: "foo_owner", Namespace: "default", Password: "secret"})
+	expected := map[string]string{
+		"username":        "foo_owner",
+		"password":        "secret",
+		"master-host":     "acid-test-cluster.default.svc.cluster.local",
+		"master-port":     "5432",
+		"master-database": "bar",
+		"master-uri":      "postgresql://foo_owner@acid-test-cluster.default.svc.cluster.local:5432/bar?sslmode=require",
+		"master-jdbc-uri": "jdbc:postgresql://acid-test-cluster.default.svc.cluster.local:5432/bar?user=foo_owner&sslmode=require",
+		"replica-host":    "acid-test-cluster-repl.default.svc.cluster.local",
+		"pooler-host":     "acid-test-cluster-pooler.default.svc.cluster.local",
+		"pooler-uri":      "postgresql://foo_owner@acid-test-cluster-pooler.default.svc.cluster.local:5432/bar?sslmode=require",
+	}
+	for key, value := range expected {
+		assert.Equal(t, value, string(secret.Data[key]), "secret key %s", key)
+	}
+	_, exists := secret.Data["pooler-replica-host"]
+	assert.False(t, exists, "no keys expected for the disabled replica pooler")
+	assert.Len(t, secret.Data, 2+3*5)
+
+	secret = cluster.generateSingleUserSecret("default", spec.PgUser{Name: "app_user", Namespace: "default", Password: "secret"})
+	assert.Equal(t, "postgres", string(secret.Data["replica-database"]))
+
+	cluster.OpConfig.EnableSecretConnectionKeys = false
+	secret = cluster.generateSingleUserSecret("default", spec.PgUser{Name: "app_user", Namespace: "default", Password: "secret"})
+	assert.Len(t, secret.Data, 2)
+}
+
 func TestCreateLoadBalancerLogic(t *testing.T) {
 	var cluster = New(
 		Config{
@@ -2282,9 +2550,12 @@ func newLBFakeClient() (k8sutil.KubernetesClient, *fake.Clientset) {
 	clientSet := fake.NewSimpleClientset()
 
 	return k8sutil.KubernetesClient{
-		DeploymentsGetter: clientSet.AppsV1(),
-		PodsGetter:        clientSet.CoreV1(),
-		ServicesGetter:    clientSet.CoreV1(),
+		DeploymentsGetter:              clientSet.AppsV1(),
+		HorizontalPodAutoscalersGetter: clientSet.AutoscalingV2(),
+		PodDisruptionBudgetsGetter:     clientSet.PolicyV1(),
+		PodsGetter:                     clientSet.CoreV1(),
+		SecretsGetter:                  clientSet.CoreV1(),
+		ServicesGetter:                 clientSet.CoreV1(),
 	}, clientSet
 }
 
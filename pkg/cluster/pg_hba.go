@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+)
+
+// spiloPgHba returns the leading entries of Spilo's default pg_hba. They allow
+// loopback and local replication connections and reject all other connections
+// without SSL. The pam entries for human users are only added if requested.
+// The entries follow configure_spilo.py of the Spilo image the operator
+// defaults to (spilo-14:2.1-p7) and have to be updated together with
+// docker_image.
+func (c *Cluster) spiloPgHba(pam bool) []string {
+	pamRole := c.OpConfig.PamRoleName
+	replicationUser := c.OpConfig.ReplicationUsername
+
+	pgHba := []string{"local all all trust"}
+	for _, address := range []string{"127.0.0.1/32", "::1/128"} {
+		if pam {
+			pgHba = append(pgHba, fmt.Sprintf("hostssl all +%s %s pam", pamRole, address))
+		}
+		pgHba = append(pgHba, fmt.Sprintf("host all all %s md5", address))
+	}
+	pgHba = append(pgHba,
+		fmt.Sprintf("local replication %s trust", replicationUser),
+		fmt.Sprintf("hostssl replication %s all md5", replicationUser),
+		"hostnossl all all all reject",
+	)
+	if pam {
+		pgHba = append(pgHba, fmt.Sprintf("hostssl all +%s all pam", pamRole))
+	}
+
+	return pgHba
+}
+
+// defaultPgHba returns the pg_hba entries Spilo generates when none are
+// configured. Patroni replaces the whole list once the operator passes
+// entries, so the certificate lines cannot just be prepended to Spilo's.
+func (c *Cluster) defaultPgHba() []string {
+	return append(c.spiloPgHba(true), "hostssl all all all md5")
+}
+
+// operatorPgHba returns the entries required by Patroni, the operator, the
+// connection pooler, event streams and infrastructure roles. Human users from
+// the Teams API authenticate via PAM. Instead of every role, only these get
+// password access with SSL. They precede the structured rules from the
+// manifest.
+func (c *Cluster) operatorPgHba(spec *acidv1.PostgresSpec) []string {
+	pgHba := c.spiloPgHba(c.OpConfig.EnableTeamsAPI)
+	for _, role := range c.operatorManagedRoles(spec) {
+		if role == c.OpConfig.ReplicationUsername {
+			continue
+		}
+		pgHba = append(pgHba, fmt.Sprintf("hostssl all %s all md5", role))
+	}
+
+	return pgHba
+}
+
+// operatorManagedRoles returns the roles which need access independent of the manifest's pg_hba rules
+func (c *Cluster) operatorManagedRoles(pgSpec *acidv1.PostgresSpec) []string {
+	roles := []string{c.OpConfig.SuperUsername, c.OpConfig.ReplicationUsername}
+	if needConnectionPooler(pgSpec) {
+		roles = append(roles, c.poolerUser(pgSpec))
+	}
+	if len(pgSpec.Streams) > 0 {
+		roles = append(roles, fmt.Sprintf("%s%s", constants.EventStreamSourceSlotPrefix, constants.UserRoleNameSuffix))
+	}
+
+	infrastructureRoles := make([]string, 0)
+	for _, pgUser := range c.pgUsers {
+		if pgUser.Origin == spec.RoleOriginInfrastructure {
+			infrastructureRoles = append(infrastructureRoles, pgUser.Name)
+		}
+	}
+	sort.Strings(infrastructureRoles)
+
+	return append(roles, infrastructureRoles...)
+}
+
+// validatePgHbaRules rejects rules referencing roles the operator manages,
+// since those could lock out Patroni, the operator or replication. Rules of
+// type local or hostnossl are rejected, too, as the entries of the operator
+// in front of them already match every such connection. Rules which would
+// render into a line Postgres cannot parse are rejected as well, because one
+// invalid line makes Postgres discard the whole pg_hba file.
+func (c *Cluster) validatePgHbaRules(spec *acidv1.PostgresSpec) error {
+	if len(spec.Patroni.PgHba) > 0 {
+		return fmt.Errorf("pgHbaRules cannot be combined with patroni.pg_hba")
+	}
+
+	managedRoles := c.operatorManagedRoles(spec)
+	for i, rule := range spec.PgHbaRules {
+		if rule.Type != "host" && rule.Type != "hostssl" {
+			return fmt.Errorf("pg_hba rule %d has type %q, only host and hostssl rules can take effect", i, rule.Type)
+		}
+		for _, field := range []string{rule.Database, rule.User, rule.Address} {
+			if strings.ContainsAny(field, " \t\r\n#") {
+				return fmt.Errorf("pg_hba rule %d must not contain whitespace or comments: %q", i, field)
+			}
+		}
+		if rule.Address == "" && !rule.UseAllowedSourceRanges {
+			return fmt.Errorf("pg_hba rule %d requires either an address or useAllowedSourceRanges", i)
+		}
+		if rule.Address != "" && rule.UseAllowedSourceRanges {
+			return fmt.Errorf("pg_hba rule %d cannot have an address and useAllowedSourceRanges at the same time", i)
+		}
+		if rule.UseAllowedSourceRanges && len(spec.AllowedSourceRanges) == 0 {
+			return fmt.Errorf("pg_hba rule %d uses allowedSourceRanges, but none are defined", i)
+		}
+		if rule.Method == "cert" && rule.Type != "hostssl" {
+			return fmt.Errorf("pg_hba rule %d with method cert requires type hostssl", i)
+		}
+		for _, user := range strings.Split(rule.User, ",") {
+			for _, role := range managedRoles {
+				if user == role {
+					return fmt.Errorf("pg_hba rule %d must not reference role %q which is managed by the operator", i, role)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Cluster) formatPgHbaRule(rule acidv1.PgHbaRule, allowedSourceRanges []string) []string {
+	database := rule.Database
+	if database == "" {
+		database = "all"
+	}
+	user := rule.User
+	if user == "" {
+		user = "all"
+	}
+
+	addresses := []string{rule.Address}
+	if rule.UseAllowedSourceRanges {
+		addresses = allowedSourceRanges
+	}
+
+	entries := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		entries = append(entries, strings.Join([]string{rule.Type, database, user, address, rule.Method}, " "))
+	}
+
+	return entries
+}
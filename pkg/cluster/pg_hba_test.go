@@ -0,0 +1,184 @@
+package cluster
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/spec"
+)
+
+func TestGeneratePgHba(t *testing.T) {
+	client, _ := newFakeK8sSyncSecretsClient()
+
+	spiloDefaults := []string{
+		"local all all trust",
+		"hostssl all +zalandos 127.0.0.1/32 pam",
+		"host all all 127.0.0.1/32 md5",
+		"hostssl all +zalandos ::1/128 pam",
+		"host all all ::1/128 md5",
+		"local replication standby trust",
+		"hostssl replication standby all md5",
+		"hostnossl all all all reject",
+		"hostssl all +zalandos all pam",
+		"hostssl all all all md5",
+	}
+	operatorEntries := []string{
+		"local all all trust",
+		"host all all 127.0.0.1/32 md5",
+		"host all all ::1/128 md5",
+		"local replication standby trust",
+		"hostssl replication standby all md5",
+		"hostnossl all all all reject",
+		"hostssl all postgres all md5",
+		"hostssl all infra_role all md5",
+	}
+
+	tests := []struct {
+		subTest  string
+		users    []string
+		pgHba    []string
+		rules    []acidv1.PgHbaRule
+		ranges   []string
+		teamsAPI bool
+		expected []string
+		err      error
+	}{
+		{
+			subTest:  "no certificate users keep custom pg_hba",
+			pgHba:    []string{"hostssl all all all md5"},
+			expected: []string{"hostssl all all all md5"},
+		},
+		{
+			subTest:  "no certificate users and no custom pg_hba",
+			expected: nil,
+		},
+		{
+			subTest:  "certificate users precede custom pg_hba",
+			users:    []string{"foo", "bar_user", "foo"},
+			pgHba:    []string{"hostssl all all all md5"},
+			expected: []string{"hostssl all bar_user all cert", "hostssl all foo all cert", "hostssl all all all md5"},
+		},
+		{
+			subTest:  "certificate users precede Spilo defaults",
+			users:    []string{"foo"},
+			expected: append([]string{"hostssl all foo all cert"}, spiloDefaults...),
+		},
+		{
+			subTest: "structured rules follow the operator entries",
+			rules: []acidv1.PgHbaRule{
+				{Type: "hostssl", Database: "app", User: "foo", Address: "10.0.0.0/8", Method: "scram-sha-256"},
+				{Type: "hostssl", UseAllowedSourceRanges: true, Method: "md5"},
+			},
+			ranges: []string{"127.0.0.1/32", "10.0.0.0/8"},
+			expected: append(operatorEntries,
+				"hostssl app foo 10.0.0.0/8 scram-sha-256",
+				"hostssl all all 127.0.0.1/32 md5",
+				"hostssl all all 10.0.0.0/8 md5",
+			),
+		},
+		{
+			subTest:  "operator entries share Spilo's pam entries with the Teams API",
+			rules:    []acidv1.PgHbaRule{{Type: "host", Address: "all", Method: "reject"}},
+			teamsAPI: true,
+			expected: append(append(append([]string{}, spiloDefaults[:len(spiloDefaults)-1]...),
+				"hostssl all postgres all md5", "hostssl all infra_role all md5"), "host all all all reject"),
+		},
+		{
+			subTest: "certificate users precede structured rules",
+			users:   []string{"foo"},
+			rules:   []acidv1.PgHbaRule{{Type: "host", Address: "all", Method: "reject"}},
+			expected: append(append([]string{"hostssl all foo all cert"}, operatorEntries...),
+				"host all all all reject"),
+		},
+		{
+			subTest: "structured rule must not reference the replication user",
+			rules:   []acidv1.PgHbaRule{{Type: "host", Database: "replication", User: "standby", Address: "all", Method: "reject"}},
+			err:     errors.New(`pg_hba rule 0 must not reference role "standby" which is managed by the operator`),
+		},
+		{
+			subTest: "structured rule must not reference infrastructure roles",
+			rules:   []acidv1.PgHbaRule{{Type: "host", User: "foo,infra_role", Address: "all", Method: "reject"}},
+			err:     errors.New(`pg_hba rule 0 must not reference role "infra_role" which is managed by the operator`),
+		},
+		{
+			subTest: "local rules are shadowed by the operator entries",
+			rules:   []acidv1.PgHbaRule{{Type: "local", Method: "peer"}},
+			err:     errors.New(`pg_hba rule 0 has type "local", only host and hostssl rules can take effect`),
+		},
+		{
+			subTest: "hostnossl rules are shadowed by the operator entries",
+			rules: []acidv1.PgHbaRule{
+				{Type: "hostssl", Address: "all", Method: "md5"},
+				{Type: "hostnossl", Address: "all", Method: "md5"},
+			},
+			err: errors.New(`pg_hba rule 1 has type "hostnossl", only host and hostssl rules can take effect`),
+		},
+		{
+			subTest: "structured rule needs an address",
+			rules:   []acidv1.PgHbaRule{{Type: "host", Method: "md5"}},
+			err:     errors.New("pg_hba rule 0 requires either an address or useAllowedSourceRanges"),
+		},
+		{
+			subTest: "structured rule cannot have an address and use the source ranges",
+			rules:   []acidv1.PgHbaRule{{Type: "host", Address: "all", UseAllowedSourceRanges: true, Method: "md5"}},
+			ranges:  []string{"10.0.0.0/8"},
+			err:     errors.New("pg_hba rule 0 cannot have an address and useAllowedSourceRanges at the same time"),
+		},
+		{
+			subTest: "structured rule using source ranges needs allowedSourceRanges",
+			rules:   []acidv1.PgHbaRule{{Type: "hostssl", UseAllowedSourceRanges: true, Method: "md5"}},
+			err:     errors.New("pg_hba rule 0 uses allowedSourceRanges, but none are defined"),
+		},
+		{
+			subTest: "structured rule database must not contain whitespace",
+			rules:   []acidv1.PgHbaRule{{Type: "host", Database: "app all", Address: "all", Method: "md5"}},
+			err:     errors.New(`pg_hba rule 0 must not contain whitespace or comments: "app all"`),
+		},
+		{
+			subTest: "structured rule user must not contain whitespace",
+			rules:   []acidv1.PgHbaRule{{Type: "host", User: "foo\tbar", Address: "all", Method: "md5"}},
+			err:     errors.New(`pg_hba rule 0 must not contain whitespace or comments: "foo\tbar"`),
+		},
+		{
+			subTest: "structured rule address must not contain whitespace",
+			rules:   []acidv1.PgHbaRule{{Type: "host", Address: "10.0.0.0/8 md5", Method: "md5"}},
+			err:     errors.New(`pg_hba rule 0 must not contain whitespace or comments: "10.0.0.0/8 md5"`),
+		},
+		{
+			subTest: "structured rule with method cert requires hostssl",
+			rules:   []acidv1.PgHbaRule{{Type: "host", User: "foo", Address: "all", Method: "cert"}},
+			err:     errors.New("pg_hba rule 0 with method cert requires type hostssl"),
+		},
+		{
+			subTest: "structured rules cannot be combined with custom pg_hba",
+			pgHba:   []string{"hostssl all all all md5"},
+			rules:   []acidv1.PgHbaRule{{Type: "hostssl", Address: "all", Method: "md5"}},
+			err:     errors.New("pgHbaRules cannot be combined with patroni.pg_hba"),
+		},
+	}
+
+	for _, tt := range tests {
+		cluster := newClientCertificatesTestCluster(client, tt.users)
+		cluster.OpConfig.SuperUsername = "postgres"
+		cluster.pgUsers["infra_role"] = spec.PgUser{Name: "infra_role", Origin: spec.RoleOriginInfrastructure}
+		cluster.Spec.Patroni.PgHba = tt.pgHba
+		cluster.Spec.PgHbaRules = tt.rules
+		cluster.Spec.AllowedSourceRanges = tt.ranges
+		cluster.OpConfig.EnableTeamsAPI = tt.teamsAPI
+
+		result, err := cluster.generatePgHba(&cluster.Spec)
+		if err != nil {
+			if tt.err == nil || err.Error() != tt.err.Error() {
+				t.Errorf("%s: expected error %v, got %v", tt.subTest, tt.err, err)
+			}
+			continue
+		} else if tt.err != nil {
+			t.Errorf("%s: expected error %v", tt.subTest, tt.err)
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tt.subTest, tt.expected, result)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"time"
@@ -370,6 +371,103 @@ func (c *Cluster) getPatroniMemberData(pod *v1.Pod) (patroni.MemberData, error)
 	return memberData, nil
 }
 
+// GetMembers returns the Patroni members of the cluster together with the node and zone their pods run in
+func (c *Cluster) GetMembers() ([]MemberStatus, error) {
+	pods, err := c.listPods()
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pods found")
+	}
+
+	// ask the master first, but any other member can tell about the cluster as well
+	sort.SliceStable(pods, func(i, j int) bool {
+		return PostgresRole(pods[i].Labels[c.OpConfig.PodRoleLabel]) == Master &&
+			PostgresRole(pods[j].Labels[c.OpConfig.PodRoleLabel]) != Master
+	})
+	var members []patroni.ClusterMember
+	for i := range pods {
+		if members, err = c.patroni.GetClusterMembers(&pods[i]); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get Patroni cluster members: %v", err)
+	}
+
+	podsByName := make(map[string]*v1.Pod, len(pods))
+	for i := range pods {
+		podsByName[pods[i].Name] = &pods[i]
+	}
+	zones := make(map[string]string)
+
+	result := make([]MemberStatus, 0, len(pods))
+	for _, member := range members {
+		status := MemberStatus{
+			Name:     member.Name,
+			Role:     member.Role,
+			State:    member.State,
+			Timeline: member.Timeline,
+		}
+		role := PostgresRole(member.Role)
+		if role != Leader && role != StandbyLeader && member.Lag != math.MaxUint64 {
+			lag := uint64(member.Lag)
+			status.Lag = &lag
+		}
+
+		pod, ok := podsByName[member.Name]
+		if !ok {
+			status.Error = "no pod found for the member"
+			result = append(result, status)
+			continue
+		}
+		delete(podsByName, member.Name)
+		c.setMemberPodStatus(&status, pod, zones)
+		if memberData, err := c.patroni.GetMemberData(pod); err != nil {
+			status.Error = fmt.Sprintf("could not get member data: %v", err)
+		} else {
+			status.PendingRestart = memberData.PendingRestart
+			status.ServerVersion = memberData.ServerVersion
+			status.PatroniVersion = memberData.Patroni.Version
+		}
+		result = append(result, status)
+	}
+
+	// pods Patroni does not know about yet, e.g. while they are starting
+	for _, pod := range podsByName {
+		status := MemberStatus{
+			Name:  pod.Name,
+			Error: "pod is not a member of the Patroni cluster",
+		}
+		c.setMemberPodStatus(&status, pod, zones)
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+func (c *Cluster) setMemberPodStatus(status *MemberStatus, pod *v1.Pod, zones map[string]string) {
+	status.Node = pod.Spec.NodeName
+	if status.Node == "" {
+		return
+	}
+	zone, ok := zones[status.Node]
+	if !ok {
+		node, err := c.KubeClient.Nodes().Get(context.TODO(), status.Node, metav1.GetOptions{})
+		if err != nil {
+			c.logger.Debugf("could not get node %s of pod %s: %v", status.Node, pod.Name, err)
+		} else {
+			zone = util.Coalesce(node.Labels[v1.LabelTopologyZone], node.Labels[v1.LabelFailureDomainBetaZone])
+		}
+		zones[status.Node] = zone
+	}
+	status.Zone = zone
+}
+
 func (c *Cluster) recreatePod(podName spec.NamespacedName) (*v1.Pod, error) {
 	stopCh := make(chan struct{})
 	ch := c.registerPodSubscriber(podName)
@@ -2,9 +2,11 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +17,9 @@ import (
 	"github.com/zalando/postgres-operator/pkg/util/config"
 	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
 	"github.com/zalando/postgres-operator/pkg/util/patroni"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestGetSwitchoverCandidate(t *testing.T) {
@@ -92,3 +97,104 @@ func TestGetSwitchoverCandidate(t *testing.T) {
 		}
 	}
 }
+
+func TestGetMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clientSet := fake.NewSimpleClientset()
+	client := k8sutil.KubernetesClient{
+		PodsGetter:  clientSet.CoreV1(),
+		NodesGetter: clientSet.CoreV1(),
+	}
+
+	var cluster = New(
+		Config{
+			OpConfig: config.Config{
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+					PodRoleLabel:     "spilo-role",
+				},
+			},
+		}, client, acidv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "acid-test-cluster",
+				Namespace: "default",
+			},
+		}, logger, eventRecorder)
+
+	_, err := clientSet.CoreV1().Nodes().Create(context.TODO(), &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-a",
+			Labels: map[string]string{v1.LabelTopologyZone: "eu-central-1a"},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	for i, role := range []string{"replica", "master", ""} {
+		pod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("acid-test-cluster-%d", i),
+				Namespace: "default",
+				Labels: map[string]string{
+					"application":  "spilo",
+					"cluster-name": "acid-test-cluster",
+					"spilo-role":   role,
+				},
+			},
+			Spec:   v1.PodSpec{NodeName: "node-a"},
+			Status: v1.PodStatus{PodIP: fmt.Sprintf("192.168.100.%d", i+1)},
+		}
+		if _, err := clientSet.CoreV1().Pods("default").Create(context.TODO(), &pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("could not create pod: %v", err)
+		}
+	}
+
+	clusterJson := `{"members": [{"name": "acid-test-cluster-1", "role": "leader", "state": "running", "timeline": 2}, {"name": "acid-test-cluster-0", "role": "replica", "state": "running", "timeline": 2, "lag": 16}]}`
+	memberJson := `{"state": "running", "role": "replica", "server_version": 140005, "pending_restart": true, "patroni": {"version": "2.1.4", "scope": "acid-test-cluster"}}`
+
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	mockClient.EXPECT().Get(gomock.Any()).DoAndReturn(func(url string) (*http.Response, error) {
+		// the cluster members have to be requested from the master
+		if strings.HasSuffix(url, "/cluster") && !strings.HasPrefix(url, "http://192.168.100.2:") {
+			t.Errorf("cluster members requested from %s instead of the master", url)
+		}
+		body := memberJson
+		if strings.HasSuffix(url, "/cluster") {
+			body = clusterJson
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}, nil
+	}).AnyTimes()
+	cluster.patroni = patroni.New(patroniLogger, mockClient)
+
+	members, err := cluster.GetMembers()
+	if err != nil {
+		t.Fatalf("could not get members: %v", err)
+	}
+	if len(members) != 3 {
+		t.Fatalf("expected 3 members, got %d: %#v", len(members), members)
+	}
+
+	replica, leader, starting := members[0], members[1], members[2]
+	if replica.Lag == nil || *replica.Lag != 16 {
+		t.Errorf("expected lag of 16 bytes for the replica, got %v", replica.Lag)
+	}
+	if leader.Lag != nil {
+		t.Errorf("expected no lag for the leader, got %d", *leader.Lag)
+	}
+	if leader.Role != "leader" || leader.Timeline != 2 || leader.ServerVersion != 140005 ||
+		!leader.PendingRestart || leader.PatroniVersion != "2.1.4" {
+		t.Errorf("unexpected Patroni data for the leader: %#v", leader)
+	}
+	if leader.Node != "node-a" || leader.Zone != "eu-central-1a" {
+		t.Errorf("unexpected node or zone for the leader: %#v", leader)
+	}
+	if starting.Name != "acid-test-cluster-2" || starting.Error == "" || starting.Zone != "eu-central-1a" {
+		t.Errorf("expected pod without Patroni member to be reported with an error: %#v", starting)
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 const (
 	rollingUpdatePodAnnotationKey = "zalando-postgres-operator-rolling-update-required"
+	walReplayPausedAnnotationKey  = "zalando-postgres-operator-wal-replay-paused"
 )
 
 func (c *Cluster) listResources() error {
@@ -0,0 +1,376 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util"
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+	"github.com/zalando/postgres-operator/pkg/util/retryutil"
+)
+
+var volumeSnapshotResource = schema.GroupVersionResource{
+	Group:    constants.VolumeSnapshotAPIGroup,
+	Version:  "v1",
+	Resource: "volumesnapshots",
+}
+
+// volumeSnapshot holds the fields of a CSI VolumeSnapshot the operator relies on
+type volumeSnapshot struct {
+	name       string
+	source     string
+	created    time.Time
+	taken      bool
+	readyToUse bool
+	err        string
+}
+
+func volumeSnapshotFromUnstructured(obj *unstructured.Unstructured) volumeSnapshot {
+	snapshot := volumeSnapshot{
+		name:    obj.GetName(),
+		created: obj.GetCreationTimestamp().Time,
+	}
+	snapshot.source, _, _ = unstructured.NestedString(obj.Object, "spec", "source", "persistentVolumeClaimName")
+	creationTime, _, _ := unstructured.NestedString(obj.Object, "status", "creationTime")
+	snapshot.taken = creationTime != ""
+	snapshot.readyToUse, _, _ = unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	snapshot.err, _, _ = unstructured.NestedString(obj.Object, "status", "error", "message")
+	return snapshot
+}
+
+// volumeSnapshotDataSource returns the data source for a volume claim restored from the given snapshot
+func volumeSnapshotDataSource(snapshotName string) *v1.TypedLocalObjectReference {
+	apiGroup := constants.VolumeSnapshotAPIGroup
+	return &v1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     constants.VolumeSnapshotKind,
+		Name:     snapshotName,
+	}
+}
+
+// listVolumeSnapshots returns the volume snapshots of the cluster, newest first
+func (c *Cluster) listVolumeSnapshots() ([]volumeSnapshot, error) {
+	listOptions := metav1.ListOptions{
+		LabelSelector: c.labelsSet(false).String(),
+	}
+
+	list, err := c.KubeClient.DynamicClient.Resource(volumeSnapshotResource).Namespace(c.Namespace).List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("could not list volume snapshots: %v", err)
+	}
+
+	snapshots := make([]volumeSnapshot, 0, len(list.Items))
+	for i := range list.Items {
+		snapshots = append(snapshots, volumeSnapshotFromUnstructured(&list.Items[i]))
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].created.After(snapshots[j].created)
+	})
+
+	return snapshots, nil
+}
+
+// latestVolumeSnapshot returns the newest snapshot ready to restore volumes from, nil if there is none
+func (c *Cluster) latestVolumeSnapshot() (*volumeSnapshot, error) {
+	snapshots, err := c.listVolumeSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		if snapshots[i].readyToUse {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// syncVolumeSnapshots takes a new snapshot of a replica's data volume once the interval passed
+// since the last one and deletes snapshots exceeding the retention
+func (c *Cluster) syncVolumeSnapshots() error {
+	c.setProcessName("syncing volume snapshots")
+
+	interval := constants.VolumeSnapshotDefaultInterval
+	if c.Spec.VolumeSnapshots.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(c.Spec.VolumeSnapshots.Interval); err != nil {
+			return fmt.Errorf("could not parse volume snapshot interval: %v", err)
+		}
+	}
+	retention := constants.VolumeSnapshotDefaultRetention
+	if c.Spec.VolumeSnapshots.Retention != nil {
+		retention = int(*c.Spec.VolumeSnapshots.Retention)
+	}
+
+	if err := c.resumeWALReplayOfPods(); err != nil {
+		return err
+	}
+
+	snapshots, err := c.listVolumeSnapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 || time.Since(snapshots[0].created) >= interval {
+		if err := c.createVolumeSnapshot(); err != nil {
+			return fmt.Errorf("could not create volume snapshot: %v", err)
+		}
+		if snapshots, err = c.listVolumeSnapshots(); err != nil {
+			return err
+		}
+	}
+
+	// pending snapshots do not count towards the retention, so they never push out usable ones,
+	// failed snapshots are removed right away
+	kept := 0
+	for _, snapshot := range snapshots {
+		if snapshot.readyToUse && kept < retention {
+			kept++
+			continue
+		}
+		if !snapshot.readyToUse && snapshot.err == "" {
+			continue
+		}
+		c.logger.Infof("deleting volume snapshot %q", snapshot.name)
+		err := c.KubeClient.DynamicClient.Resource(volumeSnapshotResource).Namespace(c.Namespace).Delete(context.TODO(), snapshot.name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete volume snapshot %q: %v", snapshot.name, err)
+		}
+	}
+
+	return nil
+}
+
+// createVolumeSnapshot snapshots the data volume of a running replica. Patroni is put into maintenance
+// mode first, so the replica cannot be promoted while its WAL replay is suspended. This pauses the
+// whole cluster, so no failover happens until the maintenance mode ends. Replay is suspended
+// after a checkpoint, so the snapshot only needs to recover from the restart point. The wait for the
+// snapshot is kept short, since the cluster lock is held. If the storage backend has not cut the
+// snapshot by then, replay is resumed anyway and an error is returned, as the snapshot is just
+// crash-consistent.
+func (c *Cluster) createVolumeSnapshot() error {
+	if c.Spec.WALVolume != nil || len(c.Spec.Tablespaces) > 0 {
+		return fmt.Errorf("volume snapshots of clusters with WAL or tablespace volumes are not supported")
+	}
+
+	replicas, err := c.getRolePods(Replica)
+	if err != nil {
+		return fmt.Errorf("could not get replica pods: %v", err)
+	}
+	var replica *v1.Pod
+	for i, pod := range replicas {
+		if pod.Status.Phase == v1.PodRunning && !c.getRollingUpdateFlagFromPod(&pod) {
+			replica = &replicas[i]
+			break
+		}
+	}
+	if replica == nil {
+		return fmt.Errorf("no running replica to take the snapshot from")
+	}
+	podName := util.NameFromMeta(replica.ObjectMeta)
+
+	// a maintenance mode set by someone else must not be ended after the snapshot
+	memberData, err := c.patroni.GetMemberData(replica)
+	if err != nil {
+		return fmt.Errorf("could not get Patroni member data of pod %q: %v", podName, err)
+	}
+	if memberData.Pause {
+		return fmt.Errorf("cluster is in Patroni maintenance mode")
+	}
+
+	// the annotation is set first, so the next sync resumes replay and ends the maintenance mode if it is not done below
+	patchData, err := metaAnnotationsPatch(map[string]string{walReplayPausedAnnotationKey: "true"})
+	if err != nil {
+		return fmt.Errorf("could not form patch for pod annotation: %v", err)
+	}
+	if _, err := c.KubeClient.Pods(replica.Namespace).Patch(context.TODO(), replica.Name, types.MergePatchType, patchData, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("could not annotate pod %q: %v", podName, err)
+	}
+	defer func() {
+		if err := c.resumeWALReplay(replica); err != nil {
+			c.logger.Warningf("could not resume WAL replay after volume snapshot, retrying on next sync: %v", err)
+		}
+	}()
+
+	c.logger.Debugf("pausing Patroni to take a volume snapshot of pod %q", podName)
+	if err := c.patroni.SetConfig(replica, map[string]interface{}{"pause": true}); err != nil {
+		return fmt.Errorf("could not pause Patroni: %v", err)
+	}
+
+	c.logger.Debugf("suspending WAL replay to take a volume snapshot of pod %q", podName)
+	if _, err := c.execPostgresCommand(&podName, `psql -d postgres -c "CHECKPOINT" -c "SELECT pg_catalog.pg_wal_replay_pause()"`); err != nil {
+		return fmt.Errorf("could not checkpoint replica: %v", err)
+	}
+
+	snapshotName := fmt.Sprintf("%s-%s", c.Name, time.Now().UTC().Format("20060102-150405"))
+	claimName := constants.DataVolumeName + "-" + replica.Name
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": volumeSnapshotResource.GroupVersion().String(),
+			"kind":       constants.VolumeSnapshotKind,
+			"spec": map[string]interface{}{
+				"source": map[string]interface{}{
+					"persistentVolumeClaimName": claimName,
+				},
+			},
+		},
+	}
+	snapshot.SetName(snapshotName)
+	snapshot.SetNamespace(c.Namespace)
+	snapshot.SetLabels(c.labelsSet(true))
+	snapshot.SetAnnotations(c.annotationsSet(nil))
+	if c.Spec.VolumeSnapshots.VolumeSnapshotClass != "" {
+		if err := unstructured.SetNestedField(snapshot.Object, c.Spec.VolumeSnapshots.VolumeSnapshotClass, "spec", "volumeSnapshotClassName"); err != nil {
+			return fmt.Errorf("could not set volume snapshot class: %v", err)
+		}
+	}
+
+	c.logger.Infof("creating volume snapshot %q of volume claim %q", snapshotName, claimName)
+	snapshots := c.KubeClient.DynamicClient.Resource(volumeSnapshotResource).Namespace(c.Namespace)
+	if _, err := snapshots.Create(context.TODO(), snapshot, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("could not create volume snapshot: %v", err)
+	}
+
+	// replay can continue once the storage backend has cut the snapshot, uploading may take much longer
+	var snapshotErr error
+	taken := false
+	_ = retryutil.Retry(constants.VolumeSnapshotWaitInterval, constants.VolumeSnapshotWaitTimeout,
+		func() (bool, error) {
+			obj, err := snapshots.Get(context.TODO(), snapshotName, metav1.GetOptions{})
+			if err != nil {
+				snapshotErr = err
+				return false, err
+			}
+			current := volumeSnapshotFromUnstructured(obj)
+			if current.err != "" {
+				snapshotErr = fmt.Errorf("volume snapshot failed: %s", current.err)
+				return false, snapshotErr
+			}
+			taken = current.taken
+			return taken, nil
+		})
+	if snapshotErr != nil {
+		return fmt.Errorf("volume snapshot %q was not taken: %v", snapshotName, snapshotErr)
+	}
+	if !taken {
+		c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeWarning, "VolumeSnapshot",
+			"Volume snapshot %q of pod %q was not taken within %v, it is only crash-consistent", snapshotName, podName, constants.VolumeSnapshotWaitTimeout)
+		return fmt.Errorf("volume snapshot %q was not taken within %v, resuming WAL replay of pod %q anyway",
+			snapshotName, constants.VolumeSnapshotWaitTimeout, podName)
+	}
+	c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeNormal, "VolumeSnapshot", "Volume snapshot %q of pod %q taken", snapshotName, podName)
+
+	return nil
+}
+
+// resumeWALReplayOfPods resumes WAL replay on pods left suspended by an interrupted volume snapshot
+func (c *Cluster) resumeWALReplayOfPods() error {
+	pods, err := c.listPods()
+	if err != nil {
+		return fmt.Errorf("could not list pods: %v", err)
+	}
+	for i, pod := range pods {
+		if _, ok := pod.Annotations[walReplayPausedAnnotationKey]; !ok {
+			continue
+		}
+		c.logger.Infof("resuming WAL replay of pod %q suspended by a previous volume snapshot", pod.Name)
+		if err := c.resumeWALReplay(&pods[i]); err != nil {
+			return fmt.Errorf("could not resume WAL replay: %v", err)
+		}
+	}
+	return nil
+}
+
+// resumeWALReplay resumes WAL replay of the pod, ends the maintenance mode of Patroni and removes the
+// annotation marking the pod as suspended
+func (c *Cluster) resumeWALReplay(pod *v1.Pod) error {
+	podName := util.NameFromMeta(pod.ObjectMeta)
+	if _, err := c.execPostgresCommand(&podName, `psql -d postgres -c "SELECT pg_catalog.pg_wal_replay_resume()"`); err != nil {
+		return fmt.Errorf("could not resume WAL replay of pod %q: %v", podName, err)
+	}
+	if err := c.patroni.SetConfig(pod, map[string]interface{}{"pause": false}); err != nil {
+		return fmt.Errorf("could not resume Patroni via pod %q: %v", podName, err)
+	}
+	patchData := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, walReplayPausedAnnotationKey))
+	if _, err := c.KubeClient.Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.MergePatchType, patchData, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("could not remove annotation of pod %q: %v", podName, err)
+	}
+	return nil
+}
+
+// createReplicaVolumeClaims creates the data volume claims of pods about to be added by a scale up
+// from the latest volume snapshot. The statefulset adopts them instead of creating empty volumes.
+func (c *Cluster) createReplicaVolumeClaims(currentReplicas, desiredReplicas int32) error {
+	if currentReplicas >= desiredReplicas {
+		return nil
+	}
+
+	snapshot, err := c.latestVolumeSnapshot()
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		c.logger.Infof("no volume snapshot ready, new replicas are bootstrapped from the primary")
+		return nil
+	}
+
+	return c.createDataVolumeClaimsFromSnapshot(snapshot.name, currentReplicas, desiredReplicas)
+}
+
+// createCloneVolumeClaims creates the data volume claims of the initial pods of a clone from the
+// volume snapshot in the clone section. The snapshot is only referenced by these claims and not by
+// the volume claim template, so later pods do not depend on it once the source cluster deleted it.
+func (c *Cluster) createCloneVolumeClaims() error {
+	if c.Spec.Clone == nil || c.Spec.Clone.VolumeSnapshot == "" {
+		return nil
+	}
+
+	return c.createDataVolumeClaimsFromSnapshot(c.Spec.Clone.VolumeSnapshot, 0, c.getNumberOfInstances(&c.Spec))
+}
+
+// createDataVolumeClaimsFromSnapshot creates the data volume claims of the pods with the ordinals
+// from first up to last, exclusively, restored from the given volume snapshot. Existing claims are kept.
+func (c *Cluster) createDataVolumeClaimsFromSnapshot(snapshotName string, first, last int32) error {
+	for ordinal := first; ordinal < last; ordinal++ {
+		claim, err := c.generatePersistentVolumeClaimTemplate(constants.DataVolumeName, c.volumeSize(c.Spec.Volume),
+			c.Spec.Volume.StorageClass, c.Spec.Volume.Selector, volumeSnapshotDataSource(snapshotName))
+		if err != nil {
+			return fmt.Errorf("could not generate volume claim: %v", err)
+		}
+		claim.Name = fmt.Sprintf("%s-%s-%d", constants.DataVolumeName, c.statefulSetName(), ordinal)
+		claim.Namespace = c.Namespace
+
+		_, err = c.KubeClient.PersistentVolumeClaims(c.Namespace).Create(context.TODO(), claim, metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not create volume claim %q: %v", claim.Name, err)
+		}
+		c.logger.Infof("created volume claim %q from volume snapshot %q", claim.Name, snapshotName)
+	}
+
+	return nil
+}
+
+// execPostgresCommand runs a shell command in the Postgres container as the postgres user
+func (c *Cluster) execPostgresCommand(podName *spec.NamespacedName, command string) (string, error) {
+	userID, err := c.ExecCommand(podName, "/bin/bash", "-c", "/usr/bin/id -u")
+	if err != nil {
+		return "", fmt.Errorf("could not check user id: %v", err)
+	}
+	if strings.TrimSuffix(userID, "\n") != "0" {
+		return c.ExecCommand(podName, "/bin/bash", "-c", command)
+	}
+	return c.ExecCommand(podName, "/bin/su", "postgres", "-c", command)
+}
@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/util/config"
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+)
+
+func newFakeVolumeSnapshot(name, namespace string, labels map[string]string, age time.Duration, readyToUse bool, errorMessage string) *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": volumeSnapshotResource.GroupVersion().String(),
+			"kind":       constants.VolumeSnapshotKind,
+			"spec": map[string]interface{}{
+				"source": map[string]interface{}{
+					"persistentVolumeClaimName": constants.DataVolumeName + "-" + name,
+				},
+			},
+			"status": map[string]interface{}{
+				"readyToUse": readyToUse,
+			},
+		},
+	}
+	if errorMessage != "" {
+		unstructured.SetNestedField(snapshot.Object, errorMessage, "status", "error", "message")
+	}
+	snapshot.SetName(name)
+	snapshot.SetNamespace(namespace)
+	snapshot.SetLabels(labels)
+	snapshot.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-age)))
+	return snapshot
+}
+
+func newVolumeSnapshotTestCluster(clusterName, namespace string, snapshots ...runtime.Object) *Cluster {
+	clientSet := fake.NewSimpleClientset()
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{volumeSnapshotResource: "VolumeSnapshotList"}, snapshots...)
+
+	client := k8sutil.KubernetesClient{
+		PersistentVolumeClaimsGetter: clientSet.CoreV1(),
+		PodsGetter:                   clientSet.CoreV1(),
+		DynamicClient:                dynamicClient,
+	}
+
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+				},
+			},
+		}, client, acidv1.Postgresql{}, logger, eventRecorder)
+	cluster.Name = clusterName
+	cluster.Namespace = namespace
+
+	return cluster
+}
+
+func TestSyncVolumeSnapshotsRetention(t *testing.T) {
+	clusterName := "acid-test-cluster"
+	namespace := "default"
+	labels := map[string]string{"application": "spilo", "cluster-name": clusterName}
+	retention := int32(2)
+
+	cluster := newVolumeSnapshotTestCluster(clusterName, namespace,
+		newFakeVolumeSnapshot("pending", namespace, labels, time.Hour, false, ""),
+		newFakeVolumeSnapshot("ready-1", namespace, labels, 2*time.Hour, true, ""),
+		newFakeVolumeSnapshot("failed", namespace, labels, 3*time.Hour, false, "quota exceeded"),
+		newFakeVolumeSnapshot("ready-2", namespace, labels, 26*time.Hour, true, ""),
+		newFakeVolumeSnapshot("ready-3", namespace, labels, 50*time.Hour, true, ""),
+		newFakeVolumeSnapshot("other-cluster", namespace, map[string]string{"application": "spilo", "cluster-name": "other"}, 100*time.Hour, true, ""),
+	)
+	cluster.Spec.VolumeSnapshots = &acidv1.VolumeSnapshotDescription{
+		Interval:  "24h",
+		Retention: &retention,
+	}
+
+	// latest snapshot is recent, so no new one is taken and only the retention is applied
+	err := cluster.syncVolumeSnapshots()
+	assert.NoError(t, err)
+
+	list, err := cluster.KubeClient.DynamicClient.Resource(volumeSnapshotResource).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"other-cluster", "pending", "ready-1", "ready-2"}, names)
+
+	latest, err := cluster.latestVolumeSnapshot()
+	assert.NoError(t, err)
+	if assert.NotNil(t, latest) {
+		assert.Equal(t, "ready-1", latest.name)
+	}
+}
+
+func TestCreateReplicaVolumeClaims(t *testing.T) {
+	clusterName := "acid-test-cluster"
+	namespace := "default"
+	labels := map[string]string{"application": "spilo", "cluster-name": clusterName}
+
+	cluster := newVolumeSnapshotTestCluster(clusterName, namespace,
+		newFakeVolumeSnapshot("older", namespace, labels, 30*time.Hour, true, ""),
+		newFakeVolumeSnapshot("latest", namespace, labels, 6*time.Hour, true, ""),
+		newFakeVolumeSnapshot("pending", namespace, labels, time.Hour, false, ""),
+	)
+	cluster.Spec.Volume = acidv1.Volume{Size: "10Gi", StorageClass: "fast"}
+
+	// scaling down or keeping the size creates nothing
+	assert.NoError(t, cluster.createReplicaVolumeClaims(2, 2))
+	pvcs, err := cluster.KubeClient.PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, pvcs.Items)
+
+	assert.NoError(t, cluster.createReplicaVolumeClaims(1, 3))
+	for ordinal := 1; ordinal < 3; ordinal++ {
+		name := fmt.Sprintf("%s-%s-%d", constants.DataVolumeName, clusterName, ordinal)
+		pvc, err := cluster.KubeClient.PersistentVolumeClaims(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, volumeSnapshotDataSource("latest"), pvc.Spec.DataSource)
+		assert.Equal(t, "fast", *pvc.Spec.StorageClassName)
+		assert.Equal(t, "10Gi", pvc.Spec.Resources.Requests.Storage().String())
+		assert.Equal(t, []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}, pvc.Spec.AccessModes)
+	}
+	_, err = cluster.KubeClient.PersistentVolumeClaims(namespace).Get(context.TODO(), constants.DataVolumeName+"-"+clusterName+"-0", metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestCreateCloneVolumeClaims(t *testing.T) {
+	clusterName := "acid-test-cluster"
+	namespace := "default"
+
+	cluster := newVolumeSnapshotTestCluster(clusterName, namespace)
+	cluster.Spec.Volume = acidv1.Volume{Size: "10Gi"}
+	cluster.Spec.NumberOfInstances = 2
+	cluster.OpConfig.MinInstances = -1
+	cluster.OpConfig.MaxInstances = -1
+
+	// without a snapshot in the clone section nothing is created
+	assert.NoError(t, cluster.createCloneVolumeClaims())
+	pvcs, err := cluster.KubeClient.PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, pvcs.Items)
+
+	cluster.Spec.Clone = &acidv1.CloneDescription{ClusterName: "acid-source-cluster", VolumeSnapshot: "acid-source-cluster-20220101-000000"}
+	assert.NoError(t, cluster.createCloneVolumeClaims())
+	pvcs, err = cluster.KubeClient.PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, pvcs.Items, 2)
+	for ordinal := 0; ordinal < 2; ordinal++ {
+		name := fmt.Sprintf("%s-%s-%d", constants.DataVolumeName, clusterName, ordinal)
+		pvc, err := cluster.KubeClient.PersistentVolumeClaims(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, volumeSnapshotDataSource("acid-source-cluster-20220101-000000"), pvc.Spec.DataSource)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -31,14 +32,19 @@ var requirePrimaryRestartWhenDecreased = []string{
 
 // Sync syncs the cluster, making sure the actual Kubernetes objects correspond to what is defined in the manifest.
 // Unlike the update, sync does not error out if some objects do not exist and takes care of creating them.
-func (c *Cluster) Sync(newSpec *acidv1.Postgresql) error {
-	var err error
+func (c *Cluster) Sync(newSpec *acidv1.Postgresql) (err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	oldSpec := c.Postgresql
 	c.setSpec(newSpec)
 
+	// ends the span with the error returned by the sync
+	span := c.startSpan("sync")
+	defer func() {
+		c.endSpan(span, err)
+	}()
+
 	defer func() {
 		if err != nil {
 			c.logger.Warningf("error while syncing cluster state: %v", err)
@@ -48,53 +54,78 @@ func (c *Cluster) Sync(newSpec *acidv1.Postgresql) error {
 		}
 	}()
 
-	if err = c.initUsers(); err != nil {
+	if err = c.traceStep("init users", c.initUsers); err != nil {
 		err = fmt.Errorf("could not init users: %v", err)
 		return err
 	}
 
 	//TODO: mind the secrets of the deleted/new users
-	if err = c.syncSecrets(); err != nil {
+	if err = c.traceStep("sync secrets", c.syncSecrets); err != nil {
 		err = fmt.Errorf("could not sync secrets: %v", err)
 		return err
 	}
 
-	if err = c.syncServices(); err != nil {
+	if err = c.traceStep("sync services", c.syncServices); err != nil {
 		err = fmt.Errorf("could not sync services: %v", err)
 		return err
 	}
 
 	// sync volume may already transition volumes to gp3, if iops/throughput or type is specified
-	if err = c.syncVolumes(); err != nil {
+	if err = c.traceStep("sync volumes", c.syncVolumes); err != nil {
 		return err
 	}
 
-	if c.OpConfig.EnableEBSGp3Migration && len(c.EBSVolumes) > 0 {
-		err = c.executeEBSMigration()
-		if nil != err {
+	if policy := c.getVolumeMigrationPolicy(); policy != nil && c.VolumeResizer != nil {
+		if err = c.populateVolumeMetaData(); err != nil {
+			err = fmt.Errorf("could not read volume meta data for the volume migration: %v", err)
+			return err
+		}
+		if err = c.traceStep("migrate volumes", func() error { return c.executeVolumeMigration(policy) }); err != nil {
+			err = fmt.Errorf("could not migrate volumes: %v", err)
 			return err
 		}
 	}
 
 	c.logger.Debug("syncing statefulsets")
-	if err = c.syncStatefulSet(); err != nil {
+	if err = c.traceStep("sync statefulset", c.syncStatefulSet); err != nil {
 		if !k8sutil.ResourceAlreadyExists(err) {
 			err = fmt.Errorf("could not sync statefulsets: %v", err)
 			return err
 		}
 	}
 
+	// claims cannot shrink or change their storage class, pods are moved to new volumes instead
+	if c.OpConfig.EnableVolumeRebuild {
+		c.logger.Debug("syncing volume rebuild")
+		if err = c.traceStep("sync volume rebuild", c.syncVolumeRebuild); err != nil {
+			c.logger.Warningf("could not rebuild pods on new volumes: %v", err)
+		}
+	}
+
+	c.logger.Debug("syncing volume claims of scaled down pods")
+	if err = c.traceStep("sync volume claims of scaled down pods", c.syncScaledDownVolumeClaims); err != nil {
+		c.logger.Warningf("could not sync volume claims of scaled down pods: %v", err)
+	}
+
 	c.logger.Debug("syncing pod disruption budgets")
-	if err = c.syncPodDisruptionBudget(false); err != nil {
+	if err = c.traceStep("sync pod disruption budget", func() error { return c.syncPodDisruptionBudget(false) }); err != nil {
 		err = fmt.Errorf("could not sync pod disruption budget: %v", err)
 		return err
 	}
 
+	// snapshots are taken from a replica, a failed attempt is retried with the next sync
+	if c.Spec.VolumeSnapshots != nil && c.getNumberOfInstances(&c.Spec) > 1 {
+		c.logger.Debug("syncing volume snapshots")
+		if err = c.traceStep("sync volume snapshots", c.syncVolumeSnapshots); err != nil {
+			c.logger.Warningf("could not sync volume snapshots: %v", err)
+		}
+	}
+
 	// create a logical backup job unless we are running without pods or disable that feature explicitly
 	if c.Spec.EnableLogicalBackup && c.getNumberOfInstances(&c.Spec) > 0 {
 
 		c.logger.Debug("syncing logical backup job")
-		if err = c.syncLogicalBackupJob(); err != nil {
+		if err = c.traceStep("sync logical backup job", c.syncLogicalBackupJob); err != nil {
 			err = fmt.Errorf("could not sync the logical backup job: %v", err)
 			return err
 		}
@@ -103,34 +134,41 @@ func (c *Cluster) Sync(newSpec *acidv1.Postgresql) error {
 	// create database objects unless we are running without pods or disabled that feature explicitly
 	if !(c.databaseAccessDisabled() || c.getNumberOfInstances(&newSpec.Spec) <= 0 || c.Spec.StandbyCluster != nil) {
 		c.logger.Debug("syncing roles")
-		if err = c.syncRoles(); err != nil {
+		if err = c.traceStep("sync roles", c.syncRoles); err != nil {
 			c.logger.Errorf("could not sync roles: %v", err)
 		}
 		c.logger.Debug("syncing databases")
-		if err = c.syncDatabases(); err != nil {
+		if err = c.traceStep("sync databases", c.syncDatabases); err != nil {
 			c.logger.Errorf("could not sync databases: %v", err)
 		}
+		c.logger.Debug("syncing tablespaces")
+		if err = c.traceStep("sync tablespaces", c.syncTablespaces); err != nil {
+			c.logger.Errorf("could not sync tablespaces: %v", err)
+		}
 		c.logger.Debug("syncing prepared databases with schemas")
-		if err = c.syncPreparedDatabases(); err != nil {
+		if err = c.traceStep("sync prepared databases", c.syncPreparedDatabases); err != nil {
 			c.logger.Errorf("could not sync prepared database: %v", err)
 		}
 	}
 
 	// sync connection pooler
-	if _, err = c.syncConnectionPooler(&oldSpec, newSpec, c.installLookupFunction); err != nil {
+	if err = c.traceStep("sync connection pooler", func() error {
+		_, err := c.syncConnectionPooler(&oldSpec, newSpec, c.installLookupFunction)
+		return err
+	}); err != nil {
 		return fmt.Errorf("could not sync connection pooler: %v", err)
 	}
 
 	if len(c.Spec.Streams) > 0 {
 		c.logger.Debug("syncing streams")
-		if err = c.syncStreams(); err != nil {
+		if err = c.traceStep("sync streams", c.syncStreams); err != nil {
 			err = fmt.Errorf("could not sync streams: %v", err)
 			return err
 		}
 	}
 
 	// Major version upgrade must only run after success of all earlier operations, must remain last item in sync
-	if err := c.majorVersionUpgrade(); err != nil {
+	if err := c.traceStep("major version upgrade", c.majorVersionUpgrade); err != nil {
 		c.logger.Errorf("major version upgrade failed: %v", err)
 	}
 
@@ -337,6 +375,7 @@ func (c *Cluster) syncStatefulSet() error {
 
 		// statefulset is already there, make sure we use its definition in order to compare with the spec.
 		c.Statefulset = sset
+		c.keepWALVolume(sset)
 
 		desiredSts, err := c.generateStatefulSet(&c.Spec)
 		if err != nil {
@@ -358,6 +397,13 @@ func (c *Cluster) syncStatefulSet() error {
 
 			c.logStatefulSetChanges(c.Statefulset, desiredSts, false, cmp.reasons)
 
+			if c.Spec.VolumeSnapshots != nil && c.Spec.VolumeSnapshots.BootstrapReplicas &&
+				c.Statefulset.Spec.Replicas != nil && desiredSts.Spec.Replicas != nil {
+				if err := c.createReplicaVolumeClaims(*c.Statefulset.Spec.Replicas, *desiredSts.Spec.Replicas); err != nil {
+					c.logger.Warningf("could not create volume claims of new replicas from volume snapshot: %v", err)
+				}
+			}
+
 			if !cmp.replace {
 				if err := c.updateStatefulSet(desiredSts); err != nil {
 					return fmt.Errorf("could not update statefulset: %v", err)
@@ -418,7 +464,12 @@ func (c *Cluster) syncStatefulSet() error {
 		// do not attempt a restart
 		if !reflect.DeepEqual(patroniConfig, acidv1.Patroni{}) || len(pgParameters) > 0 {
 			// compare config returned from Patroni with what is specified in the manifest
-			configPatched, restartPrimaryFirst, err = c.checkAndSetGlobalPostgreSQLConfiguration(&pod, patroniConfig, c.Spec.Patroni, pgParameters, c.Spec.Parameters)
+			desiredPatroniConfig := c.Spec.Patroni
+			if desiredPatroniConfig.PgHba, err = c.generatePgHba(&c.Spec); err != nil {
+				c.logger.Warningf("could not generate pg_hba: %v", err)
+				continue
+			}
+			configPatched, restartPrimaryFirst, err = c.checkAndSetGlobalPostgreSQLConfiguration(&pod, patroniConfig, desiredPatroniConfig, pgParameters, c.Spec.Parameters)
 			if err != nil {
 				c.logger.Warningf("could not set PostgreSQL configuration options for pod %s: %v", pods[i].Name, err)
 				continue
@@ -682,6 +733,10 @@ func (c *Cluster) syncSecrets() error {
 		}
 	}
 
+	if err := c.syncClientCertificates(); err != nil {
+		return fmt.Errorf("could not sync client certificates: %v", err)
+	}
+
 	return nil
 }
 
@@ -750,7 +805,25 @@ func (c *Cluster) updateSecret(
 	allowedRoleTypes := []spec.RoleOrigin{spec.RoleOriginManifest, spec.RoleOriginBootstrap}
 	rotationAllowed := !pwdUser.IsDbOwner && util.SliceContains(allowedRoleTypes, pwdUser.Origin)
 
-	if (c.OpConfig.EnablePasswordRotation && rotationAllowed) || rotationEnabledInManifest {
+	if pwdUser.ExternalPassword {
+		// passwords from referenced secrets are not rotated but copied into the user secret
+		if secretUsername != string(secret.Data["username"]) {
+			*retentionUsers = append(*retentionUsers, secretUsername)
+			secret.Data["username"] = []byte(secretUsername)
+			delete(secret.Data, "nextRotation")
+			updateSecret = true
+		}
+		if pwdUser.Password != string(secret.Data["password"]) {
+			secret.Data["password"] = []byte(pwdUser.Password)
+			updateSecret = true
+		}
+		if updateSecret {
+			updateSecretMsg = fmt.Sprintf("updating the secret %s from the referenced password of user %s", secretName, secretUsername)
+		}
+		if rotationEnabledInManifest {
+			c.logger.Warningf("password rotation is not supported for user %s with a password from a referenced secret", secretUsername)
+		}
+	} else if (c.OpConfig.EnablePasswordRotation && rotationAllowed) || rotationEnabledInManifest {
 		updateSecretMsg, err = c.rotatePasswordInSecret(secret, secretUsername, pwdUser.Origin, currentTime, retentionUsers)
 		if err != nil {
 			c.logger.Warnf("password rotation failed for user %s: %v", secretUsername, err)
@@ -786,6 +859,24 @@ func (c *Cluster) updateSecret(
 		userMap[userKey] = pwdUser
 	}
 
+	// keep connection keys in line with the current login name and enabled services
+	connectionData := c.generateConnectionSecretData(string(secret.Data["username"]), secretUsername)
+	for _, key := range c.connectionSecretKeys() {
+		value, desired := connectionData[key]
+		current, exists := secret.Data[key]
+		if desired && (!exists || !bytes.Equal(current, value)) {
+			secret.Data[key] = value
+		} else if !desired && exists {
+			delete(secret.Data, key)
+		} else {
+			continue
+		}
+		if !updateSecret {
+			updateSecret = true
+			updateSecretMsg = fmt.Sprintf("updating connection keys in secret %s", secretName)
+		}
+	}
+
 	if updateSecret {
 		c.logger.Debugln(updateSecretMsg)
 		if _, err = c.KubeClient.Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
@@ -1047,6 +1138,48 @@ func (c *Cluster) syncDatabases() error {
 	return nil
 }
 
+// syncTablespaces creates the tablespaces of the manifest on their volumes and keeps their owners
+// in sync. Tablespaces removed from the manifest are left untouched.
+func (c *Cluster) syncTablespaces() error {
+	if len(c.Spec.Tablespaces) == 0 {
+		return nil
+	}
+	c.setProcessName("syncing tablespaces")
+
+	if err := c.initDbConn(); err != nil {
+		return fmt.Errorf("could not init database connection")
+	}
+	defer func() {
+		if err := c.closeDbConn(); err != nil {
+			c.logger.Errorf("could not close database connection: %v", err)
+		}
+	}()
+
+	currentTablespaces, err := c.getTablespaces()
+	if err != nil {
+		return fmt.Errorf("could not get current tablespaces: %v", err)
+	}
+
+	for _, tablespace := range c.Spec.Tablespaces {
+		newOwner := tablespace.Owner
+		if newOwner == "" {
+			newOwner = c.OpConfig.SuperUsername
+		}
+		currentOwner, exists := currentTablespaces[tablespace.Name]
+		if !exists {
+			if err = c.executeCreateTablespace(tablespace.Name, newOwner); err != nil {
+				return err
+			}
+		} else if currentOwner != newOwner {
+			if err = c.executeAlterTablespaceOwner(tablespace.Name, newOwner); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (c *Cluster) syncPreparedDatabases() error {
 	c.setProcessName("syncing prepared databases")
 	for preparedDbName, preparedDB := range c.Spec.PreparedDatabases {
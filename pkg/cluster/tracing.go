@@ -0,0 +1,113 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	"github.com/zalando/postgres-operator/pkg/util/patroni"
+	"github.com/zalando/postgres-operator/pkg/util/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+)
+
+// initTracedClients replaces the K8s and Patroni clients with ones recording a span for every
+// request made while a sync or update of the cluster is traced. The K8s clients only wrap the
+// transport shared by all clusters, so no cluster opens its own connections to the API server.
+func (c *Cluster) initTracedClients() error {
+	if c.Tracer == nil {
+		return nil
+	}
+
+	if c.RestConfig != nil && c.TracingTransport != nil {
+		// the shared transport already carries the TLS settings and credentials
+		tracedConfig := rest.AnonymousClientConfig(c.RestConfig)
+		tracedConfig.TLSClientConfig = rest.TLSClientConfig{}
+		tracedConfig.Transport = tracing.NewTransport(c.TracingTransport, "kubernetes", c.activeContext)
+		kubeClient, err := k8sutil.NewFromConfig(tracedConfig)
+		if err != nil {
+			return fmt.Errorf("could not create traced K8s client: %v", err)
+		}
+		c.KubeClient = kubeClient
+	}
+
+	c.patroni = patroni.New(c.logger, &http.Client{
+		Timeout:   patroni.Timeout,
+		Transport: tracing.NewTransport(nil, "patroni", c.activeContext),
+	})
+
+	return nil
+}
+
+// startSpan starts a span as child of the currently active one, or a new trace if there is none,
+// and makes it the active span of the cluster until it is ended with endSpan
+func (c *Cluster) startSpan(name string) trace.Span {
+	if c.Tracer == nil {
+		return nil
+	}
+
+	c.spansMu.Lock()
+	defer c.spansMu.Unlock()
+
+	ctx := context.Background()
+	if len(c.spans) > 0 {
+		ctx = trace.ContextWithSpan(ctx, c.spans[len(c.spans)-1])
+	}
+	_, span := c.Tracer.Start(ctx, name)
+	if len(c.spans) == 0 {
+		span.SetAttributes(
+			attribute.String("cluster", c.Name),
+			attribute.String("namespace", c.Namespace))
+	}
+	c.spans = append(c.spans, span)
+
+	return span
+}
+
+// endSpan finishes the span and makes its parent the active span again
+func (c *Cluster) endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+
+	c.spansMu.Lock()
+	for i := len(c.spans) - 1; i >= 0; i-- {
+		if c.spans[i] == span {
+			c.spans = c.spans[:i]
+			break
+		}
+	}
+	c.spansMu.Unlock()
+
+	tracing.End(span, err)
+}
+
+func (c *Cluster) activeSpan() trace.Span {
+	c.spansMu.Lock()
+	defer c.spansMu.Unlock()
+
+	if len(c.spans) == 0 {
+		return nil
+	}
+	return c.spans[len(c.spans)-1]
+}
+
+// activeContext returns a context carrying the active span, or nil if no operation is traced
+func (c *Cluster) activeContext() context.Context {
+	span := c.activeSpan()
+	if span == nil {
+		return nil
+	}
+	return trace.ContextWithSpan(context.Background(), span)
+}
+
+// traceStep runs one step of a sync or update in its own span
+func (c *Cluster) traceStep(name string, step func() error) error {
+	span := c.startSpan(name)
+	err := step()
+	c.endSpan(span, err)
+
+	return err
+}
@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	"github.com/zalando/postgres-operator/pkg/util/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// recordingExporter keeps the exported spans after the tracer is shut down
+type recordingExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (e recordingExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestTraceStep(t *testing.T) {
+	exporter := recordingExporter{tracetest.NewInMemoryExporter()}
+	tracer := tracing.NewWithExporter(exporter, resource.Empty(), logger)
+
+	cluster := New(
+		Config{Tracer: tracer},
+		k8sutil.NewMockKubernetesClient(),
+		acidv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "acid-test",
+				Namespace: "test",
+			},
+		},
+		logger,
+		record.NewFakeRecorder(1),
+	)
+
+	if cluster.activeSpan() != nil {
+		t.Fatalf("expected no active span before the sync")
+	}
+
+	var err error
+	span := cluster.startSpan("sync")
+	cluster.traceStep("sync secrets", func() error {
+		if cluster.activeSpan() == span {
+			t.Errorf("expected the step to be the active span while it runs")
+		}
+		return nil
+	})
+	err = cluster.traceStep("sync statefulset", func() error {
+		return fmt.Errorf("could not sync statefulset")
+	})
+	if cluster.activeSpan() != span {
+		t.Errorf("expected the sync to be the active span again after the steps")
+	}
+	cluster.endSpan(span, err)
+
+	if cluster.activeSpan() != nil {
+		t.Errorf("expected no active span after the sync")
+	}
+
+	stopCh := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go tracer.Run(stopCh, wg)
+	close(stopCh)
+	wg.Wait()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	secrets, statefulSet, root := spans[0], spans[1], spans[2]
+	if root.Name != "sync" || root.Parent.IsValid() {
+		t.Errorf("expected sync to be the root span, got %q with parent %s", root.Name, root.Parent.SpanID())
+	}
+	attributes := map[attribute.Key]string{}
+	for _, attr := range root.Attributes {
+		attributes[attr.Key] = attr.Value.AsString()
+	}
+	if attributes["cluster"] != "acid-test" || attributes["namespace"] != "test" {
+		t.Errorf("expected cluster attributes on the root span, got %v", root.Attributes)
+	}
+	for _, step := range []tracetest.SpanStub{secrets, statefulSet} {
+		if step.Parent.SpanID() != root.SpanContext.SpanID() || step.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("expected step %q to be a child of the sync", step.Name)
+		}
+	}
+	if secrets.Status.Code != codes.Ok || statefulSet.Status.Description != "could not sync statefulset" ||
+		root.Status.Code != codes.Error {
+		t.Errorf("unexpected status of the spans: %#v, %#v, %#v", secrets.Status, statefulSet.Status, root.Status)
+	}
+}
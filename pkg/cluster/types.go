@@ -49,6 +49,27 @@ type Process struct {
 	StartTime time.Time
 }
 
+// VolumeMigration describes the migration of a volume to another type
+type VolumeMigration struct {
+	VolumeID   string
+	SourceType string
+	TargetType string
+	State      VolumeMigrationState
+	Message    string
+	LastUpdate time.Time
+}
+
+// VolumeMigrationState is the state of a volume type migration
+type VolumeMigrationState string
+
+const (
+	VolumeMigrationSkipped    VolumeMigrationState = "skipped"
+	VolumeMigrationWaiting    VolumeMigrationState = "waiting"
+	VolumeMigrationInProgress VolumeMigrationState = "in progress"
+	VolumeMigrationCompleted  VolumeMigrationState = "completed"
+	VolumeMigrationFailed     VolumeMigrationState = "failed"
+)
+
 // WorkerStatus describes status of the worker
 type WorkerStatus struct {
 	CurrentCluster types.NamespacedName
@@ -67,11 +88,27 @@ type ClusterStatus struct {
 	StatefulSet         *appsv1.StatefulSet
 	PodDisruptionBudget *policyv1.PodDisruptionBudget
 
-	CurrentProcess Process
-	Worker         uint32
-	Status         acidv1.PostgresStatus
-	Spec           acidv1.PostgresSpec
-	Error          error
+	CurrentProcess   Process
+	Worker           uint32
+	VolumeMigrations []VolumeMigration
+	Status           acidv1.PostgresStatus
+	Spec             acidv1.PostgresSpec
+	Error            error
+}
+
+// MemberStatus describes a Patroni member of the cluster and where its pod runs
+type MemberStatus struct {
+	Name           string
+	Role           string
+	State          string
+	Timeline       int
+	Lag            *uint64 // in bytes, unset for leaders and unknown lag
+	PendingRestart bool
+	ServerVersion  int
+	PatroniVersion string
+	Node           string
+	Zone           string
+	Error          string
 }
 
 type TemplateParams map[string]interface{}
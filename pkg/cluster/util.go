@@ -585,6 +585,33 @@ func (c *Cluster) setSpec(newSpec *acidv1.Postgresql) {
 	c.specMu.Lock()
 	c.Postgresql = *newSpec
 	c.specMu.Unlock()
+	c.setLogLevel()
+}
+
+// newClusterLogger gives a cluster a logger of its own that shares output, format and hooks
+// with the operator logger, so that the log level can be changed for a single cluster
+func newClusterLogger(entry *logrus.Entry) *logrus.Entry {
+	logger := logrus.New()
+	logger.Out = entry.Logger.Out
+	logger.Hooks = entry.Logger.Hooks
+	logger.Formatter = entry.Logger.Formatter
+	logger.SetLevel(entry.Logger.GetLevel())
+
+	return logger.WithFields(entry.Data)
+}
+
+// setLogLevel turns on debug logging for the cluster if requested by an annotation of the manifest
+func (c *Cluster) setLogLevel() {
+	level := logrus.InfoLevel
+	if c.OpConfig.DebugLogging || c.ObjectMeta.Annotations[constants.PostgresqlDebugLoggingAnnotation] == "true" {
+		level = logrus.DebugLevel
+	}
+	if c.logger.Logger.GetLevel() == level {
+		return
+	}
+
+	c.logger.Logger.SetLevel(level)
+	c.logger.Infof("log level of the cluster set to %s", level)
 }
 
 // GetSpec returns a copy of the operator-side spec of a Postgres cluster in a thread-safe manner
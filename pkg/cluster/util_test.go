@@ -4,11 +4,13 @@ import (
 	"context"
 	"testing"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	fakeacidv1 "github.com/zalando/postgres-operator/pkg/generated/clientset/versioned/fake"
 	"github.com/zalando/postgres-operator/pkg/util"
 	"github.com/zalando/postgres-operator/pkg/util/config"
+	"github.com/zalando/postgres-operator/pkg/util/constants"
 	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sFake "k8s.io/client-go/kubernetes/fake"
@@ -179,3 +181,34 @@ func Test_trimCronjobName(t *testing.T) {
 		})
 	}
 }
+
+func TestDebugLoggingAnnotation(t *testing.T) {
+	operatorLogger := logrus.New()
+	operatorLogger.SetLevel(logrus.InfoLevel)
+
+	cluster := New(Config{}, k8sutil.KubernetesClient{}, acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "acid-test-cluster",
+			Namespace:   "default",
+			Annotations: map[string]string{constants.PostgresqlDebugLoggingAnnotation: "true"},
+		},
+	}, operatorLogger.WithField("test", "logging"), eventRecorder)
+
+	if !cluster.logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		t.Errorf("expected debug logging to be enabled for the annotated cluster")
+	}
+	if operatorLogger.IsLevelEnabled(logrus.DebugLevel) {
+		t.Errorf("debug logging of the cluster must not change the operator log level")
+	}
+
+	// removing the annotation turns debug logging off again
+	cluster.setSpec(&acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "acid-test-cluster",
+			Namespace: "default",
+		},
+	})
+	if cluster.logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		t.Errorf("expected debug logging to be disabled after removing the annotation")
+	}
+}
@@ -2,13 +2,18 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/aws/aws-sdk-go/aws"
 	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
@@ -16,6 +21,8 @@ import (
 	"github.com/zalando/postgres-operator/pkg/util"
 	"github.com/zalando/postgres-operator/pkg/util/constants"
 	"github.com/zalando/postgres-operator/pkg/util/filesystems"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	"github.com/zalando/postgres-operator/pkg/util/retryutil"
 	"github.com/zalando/postgres-operator/pkg/util/volumes"
 )
 
@@ -28,6 +35,17 @@ func (c *Cluster) syncVolumes() error {
 	if err != nil {
 		return fmt.Errorf("could not parse volume size from the manifest: %v", err)
 	}
+	if c.Spec.WALVolume != nil {
+		if _, err = resource.ParseQuantity(c.Spec.WALVolume.Size); err != nil {
+			return fmt.Errorf("could not parse WAL volume size from the manifest: %v", err)
+		}
+	}
+
+	if c.Spec.Volume.Autogrow != nil && c.OpConfig.StorageResizeMode != "off" {
+		if err = c.autogrowVolume(); err != nil {
+			c.logger.Warningf("could not autogrow volume: %v", err)
+		}
+	}
 
 	if c.OpConfig.StorageResizeMode == "mixed" {
 		// mixed op uses AWS API to adjust size, throughput, iops, and calls pvc change for file system resize
@@ -74,16 +92,18 @@ func (c *Cluster) syncVolumes() error {
 func (c *Cluster) syncUnderlyingEBSVolume() error {
 	c.logger.Infof("starting to sync EBS volumes: type, iops, throughput, and size")
 
-	var (
-		err     error
-		newSize resource.Quantity
-	)
+	var err error
 
-	targetValue := c.Spec.Volume
-	if newSize, err = resource.ParseQuantity(targetValue.Size); err != nil {
-		return fmt.Errorf("could not parse volume size: %v", err)
+	targetValues := make(map[string]acidv1.Volume)
+	targetSizes := make(map[string]int64)
+	for _, clusterVolume := range c.clusterVolumes() {
+		newSize, err := resource.ParseQuantity(clusterVolume.volume.Size)
+		if err != nil {
+			return fmt.Errorf("could not parse size of volume %q: %v", clusterVolume.name, err)
+		}
+		targetValues[clusterVolume.name] = clusterVolume.volume
+		targetSizes[clusterVolume.name] = quantityToGigabyte(newSize)
 	}
-	targetSize := quantityToGigabyte(newSize)
 
 	awsGp3 := aws.String("gp3")
 	awsIo2 := aws.String("io2")
@@ -91,6 +111,13 @@ func (c *Cluster) syncUnderlyingEBSVolume() error {
 	errors := make([]string, 0)
 
 	for _, volume := range c.EBSVolumes {
+		volumeName, ok := c.ebsVolumeClaims[volume.VolumeID]
+		if !ok {
+			volumeName = constants.DataVolumeName
+		}
+		targetValue := targetValues[volumeName]
+		targetSize := targetSizes[volumeName]
+
 		var modifyIops *int64
 		var modifyThroughput *int64
 		var modifySize *int64
@@ -112,6 +139,26 @@ func (c *Cluster) syncUnderlyingEBSVolume() error {
 			modifySize = &targetSize
 		}
 
+		if c.OpConfig.StorageResizeProvider == "gcp" || c.OpConfig.StorageResizeProvider == "azure" {
+			// gp3 defaults and thresholds only apply to EBS, other providers validate the values themselves
+			if targetValue.Iops != nil && volume.Iops != *targetValue.Iops {
+				modifyIops = targetValue.Iops
+			}
+			if targetValue.Throughput != nil && volume.Throughput != *targetValue.Throughput {
+				modifyThroughput = targetValue.Throughput
+			}
+			if targetValue.VolumeType != "" && volume.VolumeType != targetValue.VolumeType {
+				modifyType = &targetValue.VolumeType
+			}
+			if modifyType != nil || modifyIops != nil || modifyThroughput != nil || modifySize != nil {
+				err = c.VolumeResizer.ModifyVolume(volume.VolumeID, modifyType, modifySize, modifyIops, modifyThroughput)
+				if err != nil {
+					errors = append(errors, fmt.Sprintf("modify failed, showing current volume values: volume-id=%s size=%d iops=%d throughput=%d: %v", volume.VolumeID, volume.Size, volume.Iops, volume.Throughput, err))
+				}
+			}
+			continue
+		}
+
 		if modifyIops != nil || modifyThroughput != nil || modifySize != nil {
 			if modifyIops != nil || modifyThroughput != nil {
 				// we default to gp3 if iops and throughput are configured
@@ -144,26 +191,38 @@ func (c *Cluster) syncUnderlyingEBSVolume() error {
 func (c *Cluster) populateVolumeMetaData() error {
 	c.logger.Infof("starting reading ebs meta data")
 
-	pvs, err := c.listPersistentVolumes()
-	if err != nil {
-		return fmt.Errorf("could not list persistent volumes: %v", err)
-	}
-	if len(pvs) == 0 {
-		c.EBSVolumes = make(map[string]volumes.VolumeProperties)
-		return fmt.Errorf("no persistent volumes found")
-	}
-	c.logger.Debugf("found %d persistent volumes, size of known volumes %d", len(pvs), len(c.EBSVolumes))
-
 	volumeIds := []string{}
-	var volumeID string
-	for _, pv := range pvs {
-		volumeID, err = c.VolumeResizer.ExtractVolumeID(pv.Spec.AWSElasticBlockStore.VolumeID)
+	volumeClaims := make(map[string]string)
+	pvCount := 0
+	for _, clusterVolume := range c.clusterVolumes() {
+		pvs, err := c.listPersistentVolumes(clusterVolume.name)
 		if err != nil {
-			continue
+			return fmt.Errorf("could not list persistent volumes: %v", err)
 		}
+		pvCount += len(pvs)
+
+		var volumeID string
+		for _, pv := range pvs {
+			if pv.Spec.AWSElasticBlockStore != nil {
+				volumeID, err = c.VolumeResizer.ExtractVolumeID(pv.Spec.AWSElasticBlockStore.VolumeID)
+			} else if c.VolumeResizer.VolumeBelongsToProvider(pv) {
+				volumeID, err = c.VolumeResizer.GetProviderVolumeID(pv)
+			} else {
+				continue
+			}
+			if err != nil {
+				continue
+			}
 
-		volumeIds = append(volumeIds, volumeID)
+			volumeIds = append(volumeIds, volumeID)
+			volumeClaims[volumeID] = clusterVolume.name
+		}
+	}
+	if pvCount == 0 {
+		c.EBSVolumes = make(map[string]volumes.VolumeProperties)
+		return fmt.Errorf("no persistent volumes found")
 	}
+	c.logger.Debugf("found %d persistent volumes, size of known volumes %d", pvCount, len(c.EBSVolumes))
 
 	currentVolumes, err := c.VolumeResizer.DescribeVolumes(volumeIds)
 	if nil != err {
@@ -179,6 +238,7 @@ func (c *Cluster) populateVolumeMetaData() error {
 	for _, volume := range currentVolumes {
 		c.EBSVolumes[volume.VolumeID] = volume
 	}
+	c.ebsVolumeClaims = volumeClaims
 
 	return nil
 }
@@ -187,21 +247,23 @@ func (c *Cluster) populateVolumeMetaData() error {
 func (c *Cluster) syncVolumeClaims() error {
 	c.setProcessName("syncing volume claims")
 
-	needsResizing, err := c.volumeClaimsNeedResizing(c.Spec.Volume)
-	if err != nil {
-		return fmt.Errorf("could not compare size of the volume claims: %v", err)
-	}
+	for _, clusterVolume := range c.clusterVolumes() {
+		needsResizing, err := c.volumeClaimsNeedResizing(clusterVolume.name, clusterVolume.volume)
+		if err != nil {
+			return fmt.Errorf("could not compare size of the %q volume claims: %v", clusterVolume.name, err)
+		}
 
-	if !needsResizing {
-		c.logger.Infof("volume claims do not require changes")
-		return nil
-	}
+		if !needsResizing {
+			c.logger.Infof("%q volume claims do not require changes", clusterVolume.name)
+			continue
+		}
 
-	if err := c.resizeVolumeClaims(c.Spec.Volume); err != nil {
-		return fmt.Errorf("could not sync volume claims: %v", err)
-	}
+		if err := c.resizeVolumeClaims(clusterVolume.name, clusterVolume.volume); err != nil {
+			return fmt.Errorf("could not sync %q volume claims: %v", clusterVolume.name, err)
+		}
 
-	c.logger.Infof("volume claims have been synced successfully")
+		c.logger.Infof("%q volume claims have been synced successfully", clusterVolume.name)
+	}
 
 	return nil
 }
@@ -210,19 +272,21 @@ func (c *Cluster) syncVolumeClaims() error {
 func (c *Cluster) syncEbsVolumes() error {
 	c.setProcessName("syncing EBS volumes")
 
-	act, err := c.volumesNeedResizing()
-	if err != nil {
-		return fmt.Errorf("could not compare size of the volumes: %v", err)
-	}
-	if !act {
-		return nil
-	}
+	for _, clusterVolume := range c.clusterVolumes() {
+		act, err := c.volumesNeedResizing(clusterVolume)
+		if err != nil {
+			return fmt.Errorf("could not compare size of the %q volumes: %v", clusterVolume.name, err)
+		}
+		if !act {
+			continue
+		}
 
-	if err := c.resizeVolumes(); err != nil {
-		return fmt.Errorf("could not sync volumes: %v", err)
-	}
+		if err := c.resizeVolumes(clusterVolume); err != nil {
+			return fmt.Errorf("could not sync %q volumes: %v", clusterVolume.name, err)
+		}
 
-	c.logger.Infof("volumes have been synced successfully")
+		c.logger.Infof("%q volumes have been synced successfully", clusterVolume.name)
+	}
 
 	return nil
 }
@@ -240,12 +304,39 @@ func (c *Cluster) listPersistentVolumeClaims() ([]v1.PersistentVolumeClaim, erro
 	return pvcs.Items, nil
 }
 
+// filterVolumeClaims returns the claims created from the volume claim template with the given name,
+// which the statefulset controller names <template>-<statefulset>-<ordinal>
+func (c *Cluster) filterVolumeClaims(pvcs []v1.PersistentVolumeClaim, volumeName string) []v1.PersistentVolumeClaim {
+	prefix := volumeName + "-" + c.statefulSetName() + "-"
+	result := make([]v1.PersistentVolumeClaim, 0, len(pvcs))
+	for _, pvc := range pvcs {
+		if !strings.HasPrefix(pvc.Name, prefix) {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(pvc.Name, prefix)); err == nil {
+			result = append(result, pvc)
+		}
+	}
+	return result
+}
+
 func (c *Cluster) deletePersistentVolumeClaims() error {
-	c.logger.Debugln("deleting PVCs")
 	pvcs, err := c.listPersistentVolumeClaims()
 	if err != nil {
 		return err
 	}
+
+	if whenDeleted, _ := c.volumeClaimRetentionPolicy(&c.Spec); whenDeleted == appsv1.RetainPersistentVolumeClaimRetentionPolicyType {
+		c.logger.Infof("retaining %d PVCs of the deleted cluster", len(pvcs))
+		for i := range pvcs {
+			if err := c.labelRetainedVolumeClaim(&pvcs[i], true); err != nil {
+				c.logger.Warningf("could not label retained PersistentVolumeClaim: %v", err)
+			}
+		}
+		return nil
+	}
+
+	c.logger.Debugln("deleting PVCs")
 	for _, pvc := range pvcs {
 		c.logger.Debugf("deleting PVC %q", util.NameFromMeta(pvc.ObjectMeta))
 		if err := c.KubeClient.PersistentVolumeClaims(pvc.Namespace).Delete(context.TODO(), pvc.Name, c.deleteOptions); err != nil {
@@ -261,12 +352,126 @@ func (c *Cluster) deletePersistentVolumeClaims() error {
 	return nil
 }
 
-func (c *Cluster) resizeVolumeClaims(newVolume acidv1.Volume) error {
-	c.logger.Debugln("resizing PVCs")
+// volumeClaimRetentionPolicy returns whether volume claims are retained or deleted when the cluster is
+// deleted and when it is scaled down. The cluster manifest takes precedence over the operator configuration.
+func (c *Cluster) volumeClaimRetentionPolicy(spec *acidv1.PostgresSpec) (whenDeleted, whenScaled appsv1.PersistentVolumeClaimRetentionPolicyType) {
+	deleted := c.OpConfig.PersistentVolumeClaimRetentionPolicy["when_deleted"]
+	scaled := c.OpConfig.PersistentVolumeClaimRetentionPolicy["when_scaled"]
+	if policy := spec.PersistentVolumeClaimRetentionPolicy; policy != nil {
+		deleted = util.Coalesce(policy.WhenDeleted, deleted)
+		scaled = util.Coalesce(policy.WhenScaled, scaled)
+	}
+	return c.volumeClaimRetentionPolicyType(deleted), c.volumeClaimRetentionPolicyType(scaled)
+}
+
+// volumeClaimRetentionPolicyType accepts the policy in any case, everything but delete retains the claims
+func (c *Cluster) volumeClaimRetentionPolicyType(policy string) appsv1.PersistentVolumeClaimRetentionPolicyType {
+	if strings.EqualFold(policy, string(appsv1.DeletePersistentVolumeClaimRetentionPolicyType)) {
+		return appsv1.DeletePersistentVolumeClaimRetentionPolicyType
+	}
+	if policy != "" && !strings.EqualFold(policy, string(appsv1.RetainPersistentVolumeClaimRetentionPolicyType)) {
+		c.logger.Warningf("unknown persistent volume claim retention policy %q, retaining volume claims", policy)
+	}
+	return appsv1.RetainPersistentVolumeClaimRetentionPolicyType
+}
+
+// generateVolumeClaimRetentionPolicy maps the scale down policy to the statefulset, so Kubernetes applies
+// it as well where the StatefulSetAutoDeletePVC feature is enabled. Claims are kept on a scale to zero.
+// Deleting the statefulset always retains them, as it is also deleted by hand, e.g. to change immutable
+// fields. The policy for deleted clusters is applied by deletePersistentVolumeClaims.
+func (c *Cluster) generateVolumeClaimRetentionPolicy(spec *acidv1.PostgresSpec, numberOfInstances int32) *appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy {
+	_, whenScaled := c.volumeClaimRetentionPolicy(spec)
+	if numberOfInstances == 0 {
+		whenScaled = appsv1.RetainPersistentVolumeClaimRetentionPolicyType
+	}
+	return &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+		WhenDeleted: appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+		WhenScaled:  whenScaled,
+	}
+}
+
+// labelRetainedVolumeClaim marks a volume claim kept after its pod is gone with the name of the cluster it
+// came from, or removes the label once the claim is used again
+func (c *Cluster) labelRetainedVolumeClaim(pvc *v1.PersistentVolumeClaim, retained bool) error {
+	_, labeled := pvc.Labels[constants.RetainedVolumeClaimLabel]
+	if labeled == retained {
+		return nil
+	}
+	if retained {
+		if pvc.Labels == nil {
+			pvc.Labels = make(map[string]string)
+		}
+		pvc.Labels[constants.RetainedVolumeClaimLabel] = c.Name
+	} else {
+		delete(pvc.Labels, constants.RetainedVolumeClaimLabel)
+	}
+
+	c.logger.Debugf("updating retained label of persistent volume claim %q", pvc.Name)
+	if _, err := c.KubeClient.PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), pvc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update persistent volume claim %q: %v", pvc.Name, err)
+	}
+	return nil
+}
+
+// syncScaledDownVolumeClaims applies the retention policy to the volume claims of pods removed by a scale
+// down. Claims are never deleted while their pod is still terminating or when the cluster is scaled to zero.
+func (c *Cluster) syncScaledDownVolumeClaims() error {
+	_, whenScaled := c.volumeClaimRetentionPolicy(&c.Spec)
+	numberOfInstances := c.getNumberOfInstances(&c.Spec)
+
+	pvcs, err := c.listPersistentVolumeClaims()
+	if err != nil {
+		return fmt.Errorf("could not list persistent volume claims: %v", err)
+	}
+	pods, err := c.listPods()
+	if err != nil {
+		return fmt.Errorf("could not list pods: %v", err)
+	}
+	podNames := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		podNames[pod.Name] = true
+	}
+
+	for _, clusterVolume := range c.clusterVolumes() {
+		claims := c.filterVolumeClaims(pvcs, clusterVolume.name)
+		for i, pvc := range claims {
+			podName := strings.TrimPrefix(pvc.Name, clusterVolume.name+"-")
+			ordinal, err := strconv.Atoi(strings.TrimPrefix(podName, c.statefulSetName()+"-"))
+			if err != nil {
+				continue
+			}
+			if int32(ordinal) < numberOfInstances {
+				if err := c.labelRetainedVolumeClaim(&claims[i], false); err != nil {
+					return err
+				}
+				continue
+			}
+			if podNames[podName] {
+				continue
+			}
+			if whenScaled != appsv1.DeletePersistentVolumeClaimRetentionPolicyType || numberOfInstances == 0 {
+				if err := c.labelRetainedVolumeClaim(&claims[i], true); err != nil {
+					return err
+				}
+				continue
+			}
+			c.logger.Infof("deleting persistent volume claim %q of scaled down pod %q", pvc.Name, podName)
+			if err := c.KubeClient.PersistentVolumeClaims(pvc.Namespace).Delete(context.TODO(), pvc.Name, c.deleteOptions); err != nil && !k8sutil.ResourceNotFound(err) {
+				return fmt.Errorf("could not delete persistent volume claim %q: %v", pvc.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Cluster) resizeVolumeClaims(volumeName string, newVolume acidv1.Volume) error {
+	c.logger.Debugf("resizing %q PVCs", volumeName)
 	pvcs, err := c.listPersistentVolumeClaims()
 	if err != nil {
 		return err
 	}
+	pvcs = c.filterVolumeClaims(pvcs, volumeName)
 	newQuantity, err := resource.ParseQuantity(newVolume.Size)
 	if err != nil {
 		return fmt.Errorf("could not parse volume size: %v", err)
@@ -290,13 +495,17 @@ func (c *Cluster) resizeVolumeClaims(newVolume acidv1.Volume) error {
 	return nil
 }
 
-func (c *Cluster) listPersistentVolumes() ([]*v1.PersistentVolume, error) {
+// listPersistentVolumes returns the volumes bound to the claims of the given claim template, all when the name is empty
+func (c *Cluster) listPersistentVolumes(volumeName string) ([]*v1.PersistentVolume, error) {
 	result := make([]*v1.PersistentVolume, 0)
 
 	pvcs, err := c.listPersistentVolumeClaims()
 	if err != nil {
 		return nil, fmt.Errorf("could not list cluster's PersistentVolumeClaims: %v", err)
 	}
+	if volumeName != "" {
+		pvcs = c.filterVolumeClaims(pvcs, volumeName)
+	}
 
 	pods, err := c.listPods()
 	if err != nil {
@@ -328,14 +537,14 @@ func (c *Cluster) listPersistentVolumes() ([]*v1.PersistentVolume, error) {
 }
 
 // resizeVolumes resize persistent volumes compatible with the given resizer interface
-func (c *Cluster) resizeVolumes() error {
+func (c *Cluster) resizeVolumes(clusterVolume clusterVolume) error {
 	if c.VolumeResizer == nil {
 		return fmt.Errorf("no volume resizer set for EBS volume handling")
 	}
 
 	c.setProcessName("resizing EBS volumes")
 
-	newQuantity, err := resource.ParseQuantity(c.Spec.Volume.Size)
+	newQuantity, err := resource.ParseQuantity(clusterVolume.volume.Size)
 	if err != nil {
 		return fmt.Errorf("could not parse volume size: %v", err)
 	}
@@ -344,7 +553,7 @@ func (c *Cluster) resizeVolumes() error {
 	resizer := c.VolumeResizer
 	var totalIncompatible int
 
-	pvs, err := c.listPersistentVolumes()
+	pvs, err := c.listPersistentVolumes(clusterVolume.name)
 	if err != nil {
 		return fmt.Errorf("could not list persistent volumes: %v", err)
 	}
@@ -383,8 +592,8 @@ func (c *Cluster) resizeVolumes() error {
 			return fmt.Errorf("could not resize EBS volume %q: %v", awsVolumeID, err)
 		}
 		c.logger.Debugf("resizing the filesystem on the volume %q", pv.Name)
-		podName := getPodNameFromPersistentVolume(pv)
-		if err := c.resizePostgresFilesystem(podName, []filesystems.FilesystemResizer{&filesystems.Ext234Resize{}}); err != nil {
+		podName := getPodNameFromPersistentVolume(pv, clusterVolume.name)
+		if err := c.resizePostgresFilesystem(podName, clusterVolume.mountPath, []filesystems.FilesystemResizer{&filesystems.Ext234Resize{}, &filesystems.XFSResize{}}); err != nil {
 			return fmt.Errorf("could not resize the filesystem on pod %q: %v", podName, err)
 		}
 		c.logger.Debugf("filesystem resize successful on volume %q", pv.Name)
@@ -406,7 +615,7 @@ func (c *Cluster) resizeVolumes() error {
 	return nil
 }
 
-func (c *Cluster) volumeClaimsNeedResizing(newVolume acidv1.Volume) (bool, error) {
+func (c *Cluster) volumeClaimsNeedResizing(volumeName string, newVolume acidv1.Volume) (bool, error) {
 	newSize, err := resource.ParseQuantity(newVolume.Size)
 	manifestSize := quantityToGigabyte(newSize)
 	if err != nil {
@@ -416,7 +625,7 @@ func (c *Cluster) volumeClaimsNeedResizing(newVolume acidv1.Volume) (bool, error
 	if err != nil {
 		return false, fmt.Errorf("could not receive persistent volume claims: %v", err)
 	}
-	for _, pvc := range pvcs {
+	for _, pvc := range c.filterVolumeClaims(pvcs, volumeName) {
 		currentSize := quantityToGigabyte(pvc.Spec.Resources.Requests[v1.ResourceStorage])
 		if currentSize != manifestSize {
 			return true, nil
@@ -425,11 +634,11 @@ func (c *Cluster) volumeClaimsNeedResizing(newVolume acidv1.Volume) (bool, error
 	return false, nil
 }
 
-func (c *Cluster) volumesNeedResizing() (bool, error) {
-	newQuantity, _ := resource.ParseQuantity(c.Spec.Volume.Size)
+func (c *Cluster) volumesNeedResizing(clusterVolume clusterVolume) (bool, error) {
+	newQuantity, _ := resource.ParseQuantity(clusterVolume.volume.Size)
 	newSize := quantityToGigabyte(newQuantity)
 
-	vols, err := c.listPersistentVolumes()
+	vols, err := c.listPersistentVolumes(clusterVolume.name)
 	if err != nil {
 		return false, err
 	}
@@ -442,10 +651,275 @@ func (c *Cluster) volumesNeedResizing() (bool, error) {
 	return false, nil
 }
 
+// volumeSize returns the size of the data volume. A size recorded in the status by
+// autogrow takes precedence over the manifest as long as it is larger.
+func (c *Cluster) volumeSize(volume acidv1.Volume) string {
+	if c.Status.VolumeSize == "" {
+		return volume.Size
+	}
+	statusSize, err := resource.ParseQuantity(c.Status.VolumeSize)
+	if err != nil {
+		c.logger.Warningf("could not parse volume size %q from the status: %v", c.Status.VolumeSize, err)
+		return volume.Size
+	}
+	manifestSize, err := resource.ParseQuantity(volume.Size)
+	if err != nil || statusSize.Cmp(manifestSize) > 0 {
+		return c.Status.VolumeSize
+	}
+	return volume.Size
+}
+
+// targetVolume returns the volume definition of the manifest with the effective size
+func (c *Cluster) targetVolume() acidv1.Volume {
+	volume := c.Spec.Volume
+	volume.Size = c.volumeSize(volume)
+	return volume
+}
+
+// clusterVolume is a volume claim template of the statefulset together with its target definition
+type clusterVolume struct {
+	name      string
+	mountPath string
+	volume    acidv1.Volume
+}
+
+// clusterVolumes returns the persistent volumes of the cluster synced with the manifest
+func (c *Cluster) clusterVolumes() []clusterVolume {
+	result := []clusterVolume{{name: constants.DataVolumeName, mountPath: constants.PostgresDataMount, volume: c.targetVolume()}}
+	if c.Spec.WALVolume != nil {
+		result = append(result, clusterVolume{name: constants.WALVolumeName, mountPath: constants.PostgresWALMount, volume: *c.Spec.WALVolume})
+	}
+	for _, tablespace := range c.Spec.Tablespaces {
+		result = append(result, clusterVolume{name: tablespaceVolumeName(tablespace.Name), mountPath: tablespaceMountPath(tablespace.Name), volume: tablespace.Volume})
+	}
+	return result
+}
+
+// keepWALVolume puts the WAL volume of the statefulset back into the spec when
+// it was removed from the manifest. Postgres would otherwise start with pg_wal
+// pointing to the unmounted volume, while the WAL on it is not moved back.
+func (c *Cluster) keepWALVolume(statefulSet *appsv1.StatefulSet) {
+	if c.Spec.WALVolume != nil || statefulSet == nil {
+		return
+	}
+
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		if template.Name != constants.WALVolumeName {
+			continue
+		}
+		storage := template.Spec.Resources.Requests[v1.ResourceStorage]
+		walVolume := acidv1.Volume{
+			Selector: template.Spec.Selector,
+			Size:     storage.String(),
+		}
+		if template.Spec.StorageClassName != nil {
+			walVolume.StorageClass = *template.Spec.StorageClassName
+		}
+		for _, container := range statefulSet.Spec.Template.Spec.Containers {
+			for _, mount := range container.VolumeMounts {
+				if mount.Name == constants.WALVolumeName {
+					walVolume.SubPath = mount.SubPath
+				}
+			}
+		}
+
+		c.logger.Warningf("walVolume cannot be removed from the manifest, keeping it with size %s", walVolume.Size)
+		c.eventRecorder.Event(c.GetReference(), v1.EventTypeWarning, "WALVolume",
+			"walVolume cannot be removed from the manifest, the volume is kept")
+		c.Spec.WALVolume = &walVolume
+		return
+	}
+}
+
+// autogrowVolume enlarges the data volume by the step of the autogrow policy once the
+// filesystem usage on any pod reaches its threshold, but never beyond its maximum size.
+// The new size is either written to the manifest or to the status of the cluster.
+func (c *Cluster) autogrowVolume() error {
+	policy := c.Spec.Volume.Autogrow
+	c.setProcessName("checking volume usage for autogrow")
+
+	threshold := int64(constants.VolumeAutogrowDefaultThreshold)
+	if policy.ThresholdPercent != nil {
+		threshold = int64(*policy.ThresholdPercent)
+	}
+	maxSize, err := resource.ParseQuantity(policy.MaxSize)
+	if err != nil {
+		return fmt.Errorf("could not parse maximum volume size: %v", err)
+	}
+	currentSize, err := resource.ParseQuantity(c.volumeSize(c.Spec.Volume))
+	if err != nil {
+		return fmt.Errorf("could not parse volume size: %v", err)
+	}
+
+	pods, err := c.listPods()
+	if err != nil {
+		return fmt.Errorf("could not list pods: %v", err)
+	}
+	usage := int64(0)
+	resizePending := false
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		podName := util.NameFromMeta(pod.ObjectMeta)
+		size, used, err := c.getPostgresFilesystemUsage(&podName)
+		if err != nil {
+			c.logger.Warningf("could not get filesystem usage of pod %q: %v", podName, err)
+			continue
+		}
+		if size > 0 && used*100/size > usage {
+			usage = used * 100 / size
+		}
+		if volumeResizePending(size, currentSize) {
+			resizePending = true
+		}
+	}
+	// volumes are expanded asynchronously, so the usage is not reliable
+	// until the filesystem of every pod has reached the recorded size
+	if resizePending {
+		c.logger.Infof("filesystem has not been resized to %s yet, skipping autogrow", currentSize.String())
+		return nil
+	}
+	if usage < threshold {
+		c.logger.Debugf("volume usage of %d%% is below the autogrow threshold of %d%%", usage, threshold)
+		return nil
+	}
+
+	newSize, err := autogrowVolumeSize(currentSize, policy.Step, maxSize)
+	if err != nil {
+		return err
+	}
+	if newSize.Cmp(currentSize) <= 0 {
+		c.logger.Warningf("volume usage of %d%% reached the autogrow threshold of %d%%, but the volume cannot grow beyond the maximum size %s",
+			usage, threshold, policy.MaxSize)
+		return nil
+	}
+
+	c.logger.Infof("volume usage of %d%% reached the autogrow threshold of %d%%, growing volume from %s to %s",
+		usage, threshold, currentSize.String(), newSize.String())
+	if err = c.recordVolumeSize(newSize.String()); err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeNormal, "Autogrow", "Volume size increased from %s to %s at %d%% usage",
+		currentSize.String(), newSize.String(), usage)
+
+	return nil
+}
+
+// volumeResizePending tells if the filesystem size reported by df is still below
+// the size of the volume, allowing for the space the filesystem itself takes
+func volumeResizePending(filesystemSize int64, volumeSize resource.Quantity) bool {
+	return filesystemSize*100 < volumeSize.Value()*(100-constants.VolumeAutogrowFilesystemOverheadPercent)
+}
+
+// autogrowVolumeSize adds the step, given as quantity or percentage of the current size, rounds up
+// to full gigabytes as volumes are resized in GB and caps the result at the maximum size
+func autogrowVolumeSize(currentSize resource.Quantity, step string, maxSize resource.Quantity) (resource.Quantity, error) {
+	if step == "" {
+		step = constants.VolumeAutogrowDefaultStep
+	}
+
+	var increment int64
+	if strings.HasSuffix(step, "%") {
+		percent, err := strconv.ParseInt(strings.TrimSuffix(step, "%"), 10, 64)
+		if err != nil {
+			return currentSize, fmt.Errorf("could not parse autogrow step %q: %v", step, err)
+		}
+		increment = currentSize.Value() * percent / 100
+	} else {
+		stepSize, err := resource.ParseQuantity(step)
+		if err != nil {
+			return currentSize, fmt.Errorf("could not parse autogrow step %q: %v", step, err)
+		}
+		increment = stepSize.Value()
+	}
+
+	newGigabytes := (currentSize.Value() + increment + constants.Gigabyte - 1) / constants.Gigabyte
+	newSize := resource.MustParse(fmt.Sprintf("%dGi", newGigabytes))
+	if newSize.Cmp(maxSize) > 0 {
+		return maxSize, nil
+	}
+	return newSize, nil
+}
+
+// recordVolumeSize writes the new volume size to the manifest or the status, depending on storage_autogrow_target
+func (c *Cluster) recordVolumeSize(size string) error {
+	var (
+		patch       []byte
+		err         error
+		subresource []string
+	)
+
+	if c.OpConfig.StorageAutogrowTarget == "manifest" {
+		patch, err = json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"volume": map[string]string{"size": size}}})
+	} else {
+		patch, err = json.Marshal(map[string]interface{}{"status": map[string]string{"volumeSize": size}})
+		subresource = []string{"status"}
+	}
+	if err != nil {
+		return fmt.Errorf("could not marshal volume size patch: %v", err)
+	}
+
+	_, err = c.KubeClient.Postgresqls(c.Namespace).Patch(context.TODO(), c.Name, types.MergePatchType, patch, metav1.PatchOptions{}, subresource...)
+	if err != nil {
+		return fmt.Errorf("could not record volume size %s: %v", size, err)
+	}
+
+	c.specMu.Lock()
+	if c.OpConfig.StorageAutogrowTarget == "manifest" {
+		c.Spec.Volume.Size = size
+	} else {
+		c.Status.VolumeSize = size
+	}
+	c.specMu.Unlock()
+
+	return nil
+}
+
+// syncLoweredVolumeSize handles a data volume size lowered in the manifest below the size recorded by
+// autogrow in the status, which would otherwise keep the volumes at the larger size. With volume
+// rebuild enabled the recorded size is removed, so the pods are rebuilt on smaller volumes.
+func (c *Cluster) syncLoweredVolumeSize(oldSize, newSize string) error {
+	if c.Status.VolumeSize == "" {
+		return nil
+	}
+	oldQuantity, err := resource.ParseQuantity(oldSize)
+	if err != nil {
+		return nil
+	}
+	newQuantity, err := resource.ParseQuantity(newSize)
+	if err != nil || newQuantity.Cmp(oldQuantity) >= 0 {
+		return nil
+	}
+	if c.volumeSize(c.Spec.Volume) == newSize {
+		return nil
+	}
+
+	if !c.OpConfig.EnableVolumeRebuild {
+		c.logger.Warningf("volume size lowered to %s, but the size %s recorded by autogrow takes precedence and volume rebuild is disabled",
+			newSize, c.Status.VolumeSize)
+		c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeWarning, "VolumeRebuild",
+			"Volume size %s is not applied: the size %s recorded by autogrow takes precedence unless enable_volume_rebuild is set", newSize, c.Status.VolumeSize)
+		return nil
+	}
+
+	patch := []byte(`{"status":{"volumeSize":null}}`)
+	if _, err = c.KubeClient.Postgresqls(c.Namespace).Patch(context.TODO(), c.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+		return fmt.Errorf("could not remove volume size %s recorded by autogrow: %v", c.Status.VolumeSize, err)
+	}
+	c.logger.Infof("removed volume size %s recorded by autogrow, volumes are rebuilt with %s", c.Status.VolumeSize, newSize)
+
+	c.specMu.Lock()
+	c.Status.VolumeSize = ""
+	c.specMu.Unlock()
+
+	return nil
+}
+
 // getPodNameFromPersistentVolume returns a pod name that it extracts from the volume claim ref.
-func getPodNameFromPersistentVolume(pv *v1.PersistentVolume) *spec.NamespacedName {
+func getPodNameFromPersistentVolume(pv *v1.PersistentVolume, volumeName string) *spec.NamespacedName {
 	namespace := pv.Spec.ClaimRef.Namespace
-	name := pv.Spec.ClaimRef.Name[len(constants.DataVolumeName)+1:]
+	name := pv.Spec.ClaimRef.Name[len(volumeName)+1:]
 	return &spec.NamespacedName{Namespace: namespace, Name: name}
 }
 
@@ -453,45 +927,434 @@ func quantityToGigabyte(q resource.Quantity) int64 {
 	return q.ScaledValue(0) / (1 * constants.Gigabyte)
 }
 
-func (c *Cluster) executeEBSMigration() error {
-	pvs, err := c.listPersistentVolumes()
-	if err != nil {
-		return fmt.Errorf("could not list persistent volumes: %v", err)
+// volumeMigrationPolicy describes which volumes are migrated to which type
+type volumeMigrationPolicy struct {
+	sourceType string
+	targetType string
+	iops       int64
+	throughput int64
+	maxSize    int64
+	limiter    *volumes.MigrationLimiter
+}
+
+// getVolumeMigrationPolicy returns the configured volume type migration, nil if none is enabled.
+// The gp3 migration option is a fixed policy for EBS volumes, which migrates all volumes at once
+// as before instead of sharing the limit of concurrent migrations.
+func (c *Cluster) getVolumeMigrationPolicy() *volumeMigrationPolicy {
+	if c.OpConfig.EnableVolumeMigration {
+		return &volumeMigrationPolicy{
+			sourceType: c.OpConfig.VolumeMigrationSourceType,
+			targetType: c.OpConfig.VolumeMigrationTargetType,
+			iops:       c.OpConfig.VolumeMigrationIops,
+			throughput: c.OpConfig.VolumeMigrationThroughput,
+			maxSize:    c.OpConfig.VolumeMigrationMaxSize,
+			limiter:    c.VolumeMigrations,
+		}
 	}
-	if len(pvs) == 0 {
-		c.logger.Warningf("no persistent volumes found - skipping EBS migration")
+	if c.OpConfig.EnableEBSGp3Migration {
+		return &volumeMigrationPolicy{
+			sourceType: "gp2",
+			targetType: "gp3",
+			iops:       3000,
+			throughput: 125,
+			// the gp3 migration never included volumes of exactly the maximum size
+			maxSize: c.OpConfig.EnableEBSGp3MigrationMaxSize - 1,
+		}
+	}
+	return nil
+}
+
+// migrationValue returns the value to provision after a migration, the current one is kept if higher
+func migrationValue(minimum, current int64) *int64 {
+	if minimum <= 0 {
 		return nil
 	}
-	c.logger.Debugf("found %d volumes, size of known volumes %d", len(pvs), len(c.EBSVolumes))
+	if current > minimum {
+		return &current
+	}
+	return &minimum
+}
 
-	if len(pvs) == len(c.EBSVolumes) {
-		hasGp2 := false
-		for _, v := range c.EBSVolumes {
-			if v.VolumeType == "gp2" {
-				hasGp2 = true
+// executeVolumeMigration modifies the volumes of the source type to the target type, as far as the limit of
+// concurrent migrations across all clusters allows. Volumes count as migrating until the provider reports
+// their modification as completed. Expects the volume meta data to be populated.
+func (c *Cluster) executeVolumeMigration(policy *volumeMigrationPolicy) error {
+	if policy.sourceType == "" || policy.targetType == "" {
+		return fmt.Errorf("source and target type of the volume migration are required")
+	}
+
+	// forget volumes which are gone, e.g. after a scale down
+	for _, migration := range c.GetVolumeMigrations() {
+		if _, ok := c.EBSVolumes[migration.VolumeID]; !ok {
+			c.VolumeMigrations.Release(migration.VolumeID)
+			c.volumeMigrationsMu.Lock()
+			delete(c.volumeMigrations, migration.VolumeID)
+			c.volumeMigrationsMu.Unlock()
+		}
+	}
+
+	// release the slots of completed migrations first, so the next volumes can take them right away
+	inProgress := make([]string, 0)
+	for _, migration := range c.GetVolumeMigrations() {
+		if migration.State == VolumeMigrationInProgress {
+			inProgress = append(inProgress, migration.VolumeID)
+		}
+	}
+	if len(inProgress) > 0 {
+		modifications, err := c.VolumeResizer.DescribeVolumeModifications(inProgress)
+		if err != nil {
+			return fmt.Errorf("could not describe volume modifications: %v", err)
+		}
+		for _, volumeID := range inProgress {
+			volume := c.EBSVolumes[volumeID]
+			switch state := modifications[volumeID]; {
+			case state == constants.EBSVolumeStateFailed:
+				policy.limiter.Release(volumeID)
+				c.logger.Warningf("migration of volume %s to type %q failed", volumeID, policy.targetType)
+				c.setVolumeMigrationState(volume, policy, VolumeMigrationFailed, "modification failed")
+			case volume.VolumeType == policy.targetType && (state == "" || state == constants.EBSVolumeStateCompleted):
+				policy.limiter.Release(volumeID)
+				c.setVolumeMigrationState(volume, policy, VolumeMigrationCompleted, "")
+				c.logger.Infof("volume %s has been migrated to type %q", volumeID, policy.targetType)
+				c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeNormal, "VolumeMigration",
+					"Volume %s has been migrated to type %q", volumeID, policy.targetType)
 			}
 		}
+	}
 
-		if !hasGp2 {
-			c.logger.Infof("no EBS gp2 volumes left to migrate")
-			return nil
+	// the limiter does not know the migrations started before the operator was restarted
+	if policy.limiter != nil && !c.migrationsRestored {
+		if err := c.restoreVolumeMigrations(policy); err != nil {
+			return err
 		}
 	}
 
-	var i3000 int64 = 3000
-	var i125 int64 = 125
+	// hand out free slots in a stable order
+	volumeIDs := make([]string, 0, len(c.EBSVolumes))
+	for volumeID := range c.EBSVolumes {
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	sort.Strings(volumeIDs)
+
+	for _, volumeID := range volumeIDs {
+		volume := c.EBSVolumes[volumeID]
+		if volume.VolumeType != policy.sourceType {
+			continue
+		}
+		c.volumeMigrationsMu.RLock()
+		migration, known := c.volumeMigrations[volume.VolumeID]
+		c.volumeMigrationsMu.RUnlock()
 
-	for _, volume := range c.EBSVolumes {
-		if volume.VolumeType == "gp2" && volume.Size < c.OpConfig.EnableEBSGp3MigrationMaxSize {
-			c.logger.Infof("modifying EBS volume %s to type gp3 migration (%d)", volume.VolumeID, volume.Size)
-			err = c.VolumeResizer.ModifyVolume(volume.VolumeID, aws.String("gp3"), &volume.Size, &i3000, &i125)
-			if nil != err {
-				c.logger.Warningf("modifying volume %s failed: %v", volume.VolumeID, err)
+		if known && migration.State == VolumeMigrationInProgress {
+			continue
+		}
+		if policy.maxSize > 0 && volume.Size > policy.maxSize {
+			c.logger.Debugf("skipping migration of volume %s to type %q (%d GB)", volume.VolumeID, policy.targetType, volume.Size)
+			c.setVolumeMigrationState(volume, policy, VolumeMigrationSkipped,
+				fmt.Sprintf("size of %d GB exceeds the maximum of %d GB", volume.Size, policy.maxSize))
+			continue
+		}
+		if !policy.limiter.Acquire(volume.VolumeID) {
+			c.setVolumeMigrationState(volume, policy, VolumeMigrationWaiting,
+				fmt.Sprintf("%d volumes are already migrating", policy.limiter.InProgress()))
+			continue
+		}
+
+		c.logger.Infof("migrating volume %s from type %q to %q (%d GB)", volume.VolumeID, volume.VolumeType, policy.targetType, volume.Size)
+		size := volume.Size
+		targetType := policy.targetType
+		err := c.VolumeResizer.ModifyVolume(volume.VolumeID, &targetType, &size,
+			migrationValue(policy.iops, volume.Iops), migrationValue(policy.throughput, volume.Throughput))
+		if err != nil {
+			policy.limiter.Release(volume.VolumeID)
+			c.logger.Warningf("modifying volume %s failed: %v", volume.VolumeID, err)
+			c.setVolumeMigrationState(volume, policy, VolumeMigrationFailed, err.Error())
+			continue
+		}
+		c.setVolumeMigrationState(volume, policy, VolumeMigrationInProgress, "")
+		c.eventRecorder.Eventf(c.GetReference(), v1.EventTypeNormal, "VolumeMigration",
+			"Migrating volume %s from type %q to %q", volume.VolumeID, volume.VolumeType, policy.targetType)
+	}
+
+	return nil
+}
+
+// restoreVolumeMigrations registers the volumes of the target type which are still modifying with the
+// limiter. They count against the limit even if other clusters took the free slots in the meantime, which
+// holds back new migrations until enough of them completed.
+func (c *Cluster) restoreVolumeMigrations(policy *volumeMigrationPolicy) error {
+	volumeIDs := make([]string, 0)
+	c.volumeMigrationsMu.RLock()
+	for volumeID, volume := range c.EBSVolumes {
+		if _, known := c.volumeMigrations[volumeID]; !known && volume.VolumeType == policy.targetType {
+			volumeIDs = append(volumeIDs, volumeID)
+		}
+	}
+	c.volumeMigrationsMu.RUnlock()
+
+	if len(volumeIDs) > 0 {
+		sort.Strings(volumeIDs)
+		modifications, err := c.VolumeResizer.DescribeVolumeModifications(volumeIDs)
+		if err != nil {
+			return fmt.Errorf("could not describe volume modifications: %v", err)
+		}
+		for _, volumeID := range volumeIDs {
+			if state := modifications[volumeID]; state != constants.EBSVolumeStateModifying && state != constants.EBSVolumeStateOptimizing {
+				continue
 			}
-		} else {
-			c.logger.Debugf("skipping EBS volume %s to type gp3 migration (%d)", volume.VolumeID, volume.Size)
+			c.logger.Infof("volume %s is still migrating to type %q", volumeID, policy.targetType)
+			policy.limiter.Register(volumeID)
+			c.setVolumeMigrationState(c.EBSVolumes[volumeID], policy, VolumeMigrationInProgress, "")
+		}
+	}
+	c.migrationsRestored = true
+
+	return nil
+}
+
+func (c *Cluster) setVolumeMigrationState(volume volumes.VolumeProperties, policy *volumeMigrationPolicy, state VolumeMigrationState, message string) {
+	c.volumeMigrationsMu.Lock()
+	defer c.volumeMigrationsMu.Unlock()
+
+	c.volumeMigrations[volume.VolumeID] = &VolumeMigration{
+		VolumeID:   volume.VolumeID,
+		SourceType: policy.sourceType,
+		TargetType: policy.targetType,
+		State:      state,
+		Message:    message,
+		LastUpdate: time.Now(),
+	}
+}
+
+// GetVolumeMigrations returns the state of the volume type migrations of the cluster
+func (c *Cluster) GetVolumeMigrations() []VolumeMigration {
+	c.volumeMigrationsMu.RLock()
+	defer c.volumeMigrationsMu.RUnlock()
+
+	result := make([]VolumeMigration, 0, len(c.volumeMigrations))
+	for _, migration := range c.volumeMigrations {
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].VolumeID < result[j].VolumeID
+	})
+	return result
+}
+
+// releaseVolumeMigrations frees the migration slots of a deleted cluster
+func (c *Cluster) releaseVolumeMigrations() {
+	for _, migration := range c.GetVolumeMigrations() {
+		if migration.State == VolumeMigrationInProgress {
+			c.VolumeMigrations.Release(migration.VolumeID)
+		}
+	}
+}
+
+// podsNeedVolumeRebuild returns the pods whose volume claims are larger than the claim templates of the
+// statefulset or use a different storage class. Neither can be changed on an existing claim.
+func (c *Cluster) podsNeedVolumeRebuild(pvcs []v1.PersistentVolumeClaim) map[string]string {
+	pods := make(map[string]string)
+	if c.Statefulset == nil {
+		return pods
+	}
+
+	for _, template := range c.Statefulset.Spec.VolumeClaimTemplates {
+		templateSize := template.Spec.Resources.Requests[v1.ResourceStorage]
+		for _, pvc := range c.filterVolumeClaims(pvcs, template.Name) {
+			podName := strings.TrimPrefix(pvc.Name, template.Name+"-")
+			if _, ok := pods[podName]; ok {
+				continue
+			}
+			currentSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+			if currentSize.Cmp(templateSize) > 0 {
+				pods[podName] = fmt.Sprintf("volume claim %q is larger than %s", pvc.Name, templateSize.String())
+				continue
+			}
+			// without a storage class in the template the default class applies, which is unknown here
+			if template.Spec.StorageClassName != nil && pvc.Spec.StorageClassName != nil &&
+				*template.Spec.StorageClassName != *pvc.Spec.StorageClassName {
+				pods[podName] = fmt.Sprintf("volume claim %q uses storage class %q instead of %q",
+					pvc.Name, *pvc.Spec.StorageClassName, *template.Spec.StorageClassName)
+			}
+		}
+	}
+
+	return pods
+}
+
+// syncVolumeRebuild moves pods whose volumes cannot be changed in place to new volumes. One pod is
+// rebuilt per sync, replicas first, then the primary switches over and is rebuilt as a replica as well.
+// The next pod is only rebuilt once Patroni reports all members as running with acceptable lag, so the
+// cluster lock is not held while a new replica bootstraps.
+func (c *Cluster) syncVolumeRebuild() error {
+	pvcs, err := c.listPersistentVolumeClaims()
+	if err != nil {
+		return fmt.Errorf("could not list persistent volume claims: %v", err)
+	}
+	podsToRebuild := c.podsNeedVolumeRebuild(pvcs)
+	if len(podsToRebuild) == 0 {
+		return nil
+	}
+	if c.getNumberOfInstances(&c.Spec) < 2 {
+		c.logger.Warningf("%d pods need to be rebuilt on new volumes, which requires at least two instances", len(podsToRebuild))
+		return nil
+	}
+
+	c.setProcessName("rebuilding pods on new volumes")
+	pods, err := c.listPods()
+	if err != nil {
+		return fmt.Errorf("could not list pods: %v", err)
+	}
+
+	var masterPod *v1.Pod
+	for i, pod := range pods {
+		if PostgresRole(pod.Labels[c.OpConfig.PodRoleLabel]) == Master {
+			masterPod = &pods[i]
+		}
+	}
+	if masterPod == nil {
+		return fmt.Errorf("no master pod found")
+	}
+	// a rebuilt pod may not even exist again, so expect a member for every replica of the statefulset
+	numberOfPods := len(pods)
+	if c.Statefulset.Spec.Replicas != nil && int(*c.Statefulset.Spec.Replicas) > numberOfPods {
+		numberOfPods = int(*c.Statefulset.Spec.Replicas)
+	}
+	if err := c.membersCaughtUp(masterPod, numberOfPods); err != nil {
+		c.logger.Infof("postponing rebuild of pods on new volumes: %v", err)
+		return nil
+	}
+
+	for _, pod := range pods {
+		reason, ok := podsToRebuild[pod.Name]
+		if !ok || pod.Name == masterPod.Name {
+			continue
+		}
+		c.logger.Infof("rebuilding replica pod %q: %s", pod.Name, reason)
+		if err := c.rebuildPod(util.NameFromMeta(pod.ObjectMeta)); err != nil {
+			return fmt.Errorf("could not rebuild replica pod %q: %v", pod.Name, err)
+		}
+		return nil
+	}
+
+	if _, ok := podsToRebuild[masterPod.Name]; !ok {
+		return nil
+	}
+
+	// the primary is only rebuilt once it runs as a replica, a failed switchover is retried with the next sync
+	masterCandidate, err := c.getSwitchoverCandidate(masterPod)
+	if err != nil {
+		return fmt.Errorf("skipping switchover: %v", err)
+	}
+	if err := c.Switchover(masterPod, masterCandidate); err != nil {
+		return fmt.Errorf("could not perform switch over: %v", err)
+	}
+	c.logger.Infof("rebuilding former master pod %q: %s", masterPod.Name, podsToRebuild[masterPod.Name])
+	if err := c.rebuildPod(util.NameFromMeta(masterPod.ObjectMeta)); err != nil {
+		return fmt.Errorf("could not rebuild former master pod %q: %v", masterPod.Name, err)
+	}
+
+	return nil
+}
+
+// rebuildPod deletes a pod together with its volume claims, so the statefulset recreates both from
+// its current templates. The new pod bootstraps as a replica from the primary or the WAL archive.
+func (c *Cluster) rebuildPod(podName spec.NamespacedName) error {
+	// claims in use are only marked for deletion and removed once the pod is gone
+	oldClaims := make(map[string]types.UID)
+	for _, template := range c.Statefulset.Spec.VolumeClaimTemplates {
+		claimName := template.Name + "-" + podName.Name
+		pvc, err := c.KubeClient.PersistentVolumeClaims(podName.Namespace).Get(context.TODO(), claimName, metav1.GetOptions{})
+		if k8sutil.ResourceNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not get persistent volume claim %q: %v", claimName, err)
+		}
+		oldClaims[claimName] = pvc.UID
+		c.logger.Debugf("deleting persistent volume claim %q", claimName)
+		if err := c.KubeClient.PersistentVolumeClaims(podName.Namespace).Delete(context.TODO(), claimName, c.deleteOptions); err != nil && !k8sutil.ResourceNotFound(err) {
+			return fmt.Errorf("could not delete persistent volume claim %q: %v", claimName, err)
+		}
+	}
+
+	if err := c.deletePod(podName); err != nil {
+		return fmt.Errorf("could not delete pod: %v", err)
+	}
+
+	return c.waitForVolumeClaimsReplaced(podName, oldClaims)
+}
+
+// waitForVolumeClaimsReplaced waits until the old volume claims of a deleted pod are gone and makes sure
+// the statefulset recreates the pod together with new ones
+func (c *Cluster) waitForVolumeClaimsReplaced(podName spec.NamespacedName, oldClaims map[string]types.UID) error {
+	err := retryutil.Retry(c.OpConfig.ResourceCheckInterval, c.OpConfig.ResourceCheckTimeout,
+		func() (bool, error) {
+			for claimName, uid := range oldClaims {
+				pvc, err := c.KubeClient.PersistentVolumeClaims(podName.Namespace).Get(context.TODO(), claimName, metav1.GetOptions{})
+				if k8sutil.ResourceNotFound(err) {
+					continue
+				}
+				if err != nil {
+					return false, err
+				}
+				if pvc.UID == uid {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+	if err != nil {
+		return fmt.Errorf("old persistent volume claims of pod %q were not removed: %v", podName, err)
+	}
+
+	// the statefulset controller may have recreated the pod while the old claims were still terminating,
+	// but it creates missing claims only together with a new pod
+	for claimName := range oldClaims {
+		_, err := c.KubeClient.PersistentVolumeClaims(podName.Namespace).Get(context.TODO(), claimName, metav1.GetOptions{})
+		if err == nil {
+			continue
+		}
+		if !k8sutil.ResourceNotFound(err) {
+			return fmt.Errorf("could not get persistent volume claim %q: %v", claimName, err)
+		}
+		c.logger.Debugf("persistent volume claim %q is missing, deleting pod %q once more", claimName, podName)
+		if err := c.deletePod(podName); err != nil && !k8sutil.ResourceNotFound(err) {
+			return fmt.Errorf("could not delete pod: %v", err)
+		}
+		break
+	}
+	c.logger.Infof("pod %q is recreated on new volumes, the next pod follows once it has caught up", podName)
+
+	return nil
+}
+
+// membersCaughtUp checks that Patroni knows a member for every pod and that all replicas are
+// running or streaming with no more lag than allowed on failover
+func (c *Cluster) membersCaughtUp(masterPod *v1.Pod, numberOfPods int) error {
+	members, err := c.patroni.GetClusterMembers(masterPod)
+	if err != nil {
+		return fmt.Errorf("could not get Patroni cluster members: %v", err)
+	}
+	if len(members) < numberOfPods {
+		return fmt.Errorf("only %d of %d pods are Patroni members", len(members), numberOfPods)
+	}
+
+	maxLag := uint64(constants.PatroniDefaultMaximumLagOnFailover)
+	if c.Spec.Patroni.MaximumLagOnFailover > 0 {
+		maxLag = uint64(c.Spec.Patroni.MaximumLagOnFailover)
+	}
+	for _, member := range members {
+		if member.State != "running" && member.State != "streaming" {
+			return fmt.Errorf("member %q is in state %q", member.Name, member.State)
+		}
+		role := PostgresRole(member.Role)
+		if role == Leader || role == Master || role == StandbyLeader {
+			continue
+		}
+		if uint64(member.Lag) > maxLag {
+			return fmt.Errorf("member %q lags behind by more than %d bytes", member.Name, maxLag)
 		}
-		c.EBSVolumes[volume.VolumeID] = volume
 	}
 
 	return nil
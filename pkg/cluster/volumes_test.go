@@ -1,15 +1,20 @@
 package cluster
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"testing"
 
 	"context"
 
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/golang/mock/gomock"
@@ -17,9 +22,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/zalando/postgres-operator/mocks"
 	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	fakeacidv1 "github.com/zalando/postgres-operator/pkg/generated/clientset/versioned/fake"
 	"github.com/zalando/postgres-operator/pkg/util/config"
 	"github.com/zalando/postgres-operator/pkg/util/constants"
 	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	"github.com/zalando/postgres-operator/pkg/util/patroni"
 	"github.com/zalando/postgres-operator/pkg/util/volumes"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -79,16 +86,21 @@ func TestResizeVolumeClaim(t *testing.T) {
 	pvcList := CreatePVCs(namespace, clusterName, filterLabels, 2, "1Gi")
 	// add another PVC with different cluster name
 	pvcList.Items = append(pvcList.Items, CreatePVCs(namespace, clusterName+"-2", labels.Set{}, 1, "1Gi").Items[0])
+	// add a WAL volume claim of the cluster
+	walPVC := CreatePVCs(namespace, clusterName, filterLabels, 1, "1Gi").Items[0]
+	walPVC.Name = constants.WALVolumeName + "-" + clusterName + "-0"
+	cluster.KubeClient.PersistentVolumeClaims(namespace).Create(context.TODO(), &walPVC, metav1.CreateOptions{})
 
 	for _, pvc := range pvcList.Items {
 		cluster.KubeClient.PersistentVolumeClaims(namespace).Create(context.TODO(), &pvc, metav1.CreateOptions{})
 	}
 
 	// test resizing
-	cluster.resizeVolumeClaims(acidv1.Volume{Size: newVolumeSize})
+	cluster.resizeVolumeClaims(constants.DataVolumeName, acidv1.Volume{Size: newVolumeSize})
 
 	pvcs, err := cluster.listPersistentVolumeClaims()
 	assert.NoError(t, err)
+	pvcs = cluster.filterVolumeClaims(pvcs, constants.DataVolumeName)
 
 	// check if listPersistentVolumeClaims returns only the PVCs matching the filter
 	if len(pvcs) != len(pvcList.Items)-1 {
@@ -114,6 +126,324 @@ func TestResizeVolumeClaim(t *testing.T) {
 	if unchangedSize != expectedSize {
 		t.Errorf("%s: volume size changed, got %v, expected %v", testName, unchangedSize, expectedSize)
 	}
+
+	// check if WAL PVC was not resized
+	walPVC2, err := cluster.KubeClient.PersistentVolumeClaims(namespace).Get(context.TODO(), walPVC.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	unchangedSize = quantityToGigabyte(walPVC2.Spec.Resources.Requests[v1.ResourceStorage])
+	if unchangedSize != expectedSize {
+		t.Errorf("%s: WAL volume size changed, got %v, expected %v", testName, unchangedSize, expectedSize)
+	}
+}
+
+func TestFilterVolumeClaims(t *testing.T) {
+	cluster := New(Config{}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+	cluster.Name = "acid-test"
+
+	pvcs := []v1.PersistentVolumeClaim{}
+	for _, name := range []string{
+		"pgdata-acid-test-0",
+		"pgdata-acid-test-1",
+		"pgwal-acid-test-0",
+		"tablespace-hot-acid-test-0",
+		"tablespace-hot-archive-acid-test-0",
+		"pgdata-acid-test-2-0",
+	} {
+		pvcs = append(pvcs, v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	tests := []struct {
+		volumeName string
+		expected   []string
+	}{
+		{constants.DataVolumeName, []string{"pgdata-acid-test-0", "pgdata-acid-test-1"}},
+		{constants.WALVolumeName, []string{"pgwal-acid-test-0"}},
+		{"tablespace-hot", []string{"tablespace-hot-acid-test-0"}},
+		{"tablespace-hot-archive", []string{"tablespace-hot-archive-acid-test-0"}},
+	}
+
+	for _, tt := range tests {
+		names := []string{}
+		for _, pvc := range cluster.filterVolumeClaims(pvcs, tt.volumeName) {
+			names = append(names, pvc.Name)
+		}
+		assert.Equal(t, tt.expected, names, "claims of volume %q", tt.volumeName)
+	}
+}
+
+func TestVolumeClaimRetention(t *testing.T) {
+	client, _ := newFakeK8sPVCclient()
+	clusterName := "acid-test-cluster"
+	namespace := "default"
+
+	var cluster = New(
+		Config{
+			OpConfig: config.Config{
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+					MinInstances:     -1,
+					MaxInstances:     -1,
+				},
+				PersistentVolumeClaimRetentionPolicy: map[string]string{"when_deleted": "delete", "when_scaled": "delete"},
+			},
+		}, client, acidv1.Postgresql{}, logger, eventRecorder)
+	cluster.Name = clusterName
+	cluster.Namespace = namespace
+	cluster.Spec.NumberOfInstances = 2
+	filterLabels := cluster.labelsSet(false)
+
+	// claims of four pods, the first one was retained by an earlier scale down
+	pvcList := CreatePVCs(namespace, clusterName, filterLabels, 4, "1Gi")
+	pvcList.Items[0].Labels = map[string]string{"application": "spilo", "cluster-name": clusterName, constants.RetainedVolumeClaimLabel: clusterName}
+	for _, pvc := range pvcList.Items {
+		cluster.KubeClient.PersistentVolumeClaims(namespace).Create(context.TODO(), &pvc, metav1.CreateOptions{})
+	}
+	// the third pod is still terminating
+	terminatingPod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: clusterName + "-2", Namespace: namespace, Labels: filterLabels}}
+	cluster.KubeClient.Pods(namespace).Create(context.TODO(), &terminatingPod, metav1.CreateOptions{})
+
+	getClaim := func(ordinal int) (*v1.PersistentVolumeClaim, error) {
+		name := fmt.Sprintf("%s-%s-%d", constants.DataVolumeName, clusterName, ordinal)
+		return cluster.KubeClient.PersistentVolumeClaims(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+
+	assert.NoError(t, cluster.syncScaledDownVolumeClaims())
+	for ordinal := 0; ordinal < 3; ordinal++ {
+		pvc, err := getClaim(ordinal)
+		if assert.NoError(t, err) {
+			assert.NotContains(t, pvc.Labels, constants.RetainedVolumeClaimLabel, "claim %d", ordinal)
+		}
+	}
+	_, err := getClaim(3)
+	assert.Error(t, err, "claim of scaled down pod not deleted")
+
+	// the manifest overrides the operator configuration
+	cluster.KubeClient.Pods(namespace).Delete(context.TODO(), terminatingPod.Name, metav1.DeleteOptions{})
+	cluster.Spec.PersistentVolumeClaimRetentionPolicy = &acidv1.PersistentVolumeClaimRetentionPolicy{WhenDeleted: "retain", WhenScaled: "retain"}
+	assert.NoError(t, cluster.syncScaledDownVolumeClaims())
+	pvc, err := getClaim(2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, clusterName, pvc.Labels[constants.RetainedVolumeClaimLabel])
+	}
+
+	assert.NoError(t, cluster.deletePersistentVolumeClaims())
+	for ordinal := 0; ordinal < 3; ordinal++ {
+		pvc, err := getClaim(ordinal)
+		if assert.NoError(t, err, "claim %d of deleted cluster not retained", ordinal) {
+			assert.Equal(t, clusterName, pvc.Labels[constants.RetainedVolumeClaimLabel])
+		}
+	}
+}
+
+func TestGenerateVolumeClaimRetentionPolicy(t *testing.T) {
+	cluster := New(
+		Config{
+			OpConfig: config.Config{
+				PersistentVolumeClaimRetentionPolicy: map[string]string{"when_deleted": "DELETE", "when_scaled": "delete"},
+			},
+		}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+
+	tests := []struct {
+		subtest           string
+		policy            *acidv1.PersistentVolumeClaimRetentionPolicy
+		numberOfInstances int32
+		expected          appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy
+	}{
+		{
+			subtest:           "operator configuration in any case",
+			numberOfInstances: 2,
+			expected:          appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{WhenDeleted: "Retain", WhenScaled: "Delete"},
+		},
+		{
+			subtest:           "manifest takes precedence",
+			policy:            &acidv1.PersistentVolumeClaimRetentionPolicy{WhenScaled: "Retain"},
+			numberOfInstances: 2,
+			expected:          appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{WhenDeleted: "Retain", WhenScaled: "Retain"},
+		},
+		{
+			subtest:           "unknown values retain",
+			policy:            &acidv1.PersistentVolumeClaimRetentionPolicy{WhenDeleted: "remove", WhenScaled: "keep"},
+			numberOfInstances: 2,
+			expected:          appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{WhenDeleted: "Retain", WhenScaled: "Retain"},
+		},
+		{
+			subtest:           "claims are kept when scaling to zero",
+			numberOfInstances: 0,
+			expected:          appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{WhenDeleted: "Retain", WhenScaled: "Retain"},
+		},
+		{
+			subtest:           "deleting the statefulset by hand keeps the claims",
+			policy:            &acidv1.PersistentVolumeClaimRetentionPolicy{WhenDeleted: "Delete"},
+			numberOfInstances: 2,
+			expected:          appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{WhenDeleted: "Retain", WhenScaled: "Delete"},
+		},
+	}
+
+	for _, tt := range tests {
+		cluster.Spec.PersistentVolumeClaimRetentionPolicy = tt.policy
+		policy := cluster.generateVolumeClaimRetentionPolicy(&cluster.Spec, tt.numberOfInstances)
+		assert.Equal(t, tt.expected, *policy, tt.subtest)
+	}
+}
+
+func TestPodsNeedVolumeRebuild(t *testing.T) {
+	cluster := New(Config{}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+	cluster.Name = "acid-test"
+
+	newClaim := func(name, size, storageClass string) v1.PersistentVolumeClaim {
+		claim := v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeClaimSpec{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		}
+		if storageClass != "" {
+			claim.Spec.StorageClassName = &storageClass
+		}
+		return claim
+	}
+
+	dataTemplate := newClaim(constants.DataVolumeName, "10Gi", "fast")
+	walTemplate := newClaim(constants.WALVolumeName, "5Gi", "")
+	cluster.Statefulset = &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{
+			VolumeClaimTemplates: []v1.PersistentVolumeClaim{dataTemplate, walTemplate},
+		},
+	}
+
+	pvcs := []v1.PersistentVolumeClaim{
+		newClaim("pgdata-acid-test-0", "20Gi", "fast"),
+		newClaim("pgdata-acid-test-1", "10Gi", "slow"),
+		newClaim("pgdata-acid-test-2", "10Gi", "fast"),
+		newClaim("pgdata-acid-test-3", "5Gi", "fast"),
+		newClaim("pgwal-acid-test-2", "5Gi", "standard"),
+		newClaim("pgwal-acid-test-3", "8Gi", "standard"),
+	}
+
+	pods := cluster.podsNeedVolumeRebuild(pvcs)
+	rebuilt := []string{}
+	for podName := range pods {
+		rebuilt = append(rebuilt, podName)
+	}
+	// growing claims is done in place and without a storage class in the template any class is accepted
+	assert.ElementsMatch(t, []string{"acid-test-0", "acid-test-1", "acid-test-3"}, rebuilt)
+	assert.Contains(t, pods["acid-test-1"], "storage class")
+	assert.Contains(t, pods["acid-test-3"], "pgwal-acid-test-3")
+
+	cluster.Statefulset = nil
+	assert.Empty(t, cluster.podsNeedVolumeRebuild(pvcs))
+}
+
+func TestMembersCaughtUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cluster := New(Config{}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, eventRecorder)
+	leader := `{"name": "acid-test-cluster-0", "role": "leader", "state": "running", "timeline": 1}`
+
+	tests := []struct {
+		subtest       string
+		replicas      string
+		numberOfPods  int
+		maxLag        float32
+		expectedError string
+	}{
+		{
+			subtest:      "streaming replica",
+			replicas:     `{"name": "acid-test-cluster-1", "role": "replica", "state": "streaming", "timeline": 1, "lag": 0}`,
+			numberOfPods: 2,
+		},
+		{
+			subtest:       "rebuilt pod not yet a member",
+			replicas:      `{"name": "acid-test-cluster-1", "role": "replica", "state": "streaming", "timeline": 1, "lag": 0}`,
+			numberOfPods:  3,
+			expectedError: "only 2 of 3 pods are Patroni members",
+		},
+		{
+			subtest:       "replica still bootstrapping",
+			replicas:      `{"name": "acid-test-cluster-1", "role": "replica", "state": "creating replica", "timeline": 1, "lag": "unknown"}`,
+			numberOfPods:  2,
+			expectedError: `member "acid-test-cluster-1" is in state "creating replica"`,
+		},
+		{
+			subtest:       "replica lagging behind",
+			replicas:      `{"name": "acid-test-cluster-1", "role": "replica", "state": "running", "timeline": 1, "lag": 2097152}`,
+			numberOfPods:  2,
+			expectedError: `member "acid-test-cluster-1" lags behind by more than 1048576 bytes`,
+		},
+		{
+			subtest:      "lag allowed by the manifest",
+			replicas:     `{"name": "acid-test-cluster-1", "role": "replica", "state": "running", "timeline": 1, "lag": 2097152}`,
+			numberOfPods: 2,
+			maxLag:       33554432,
+		},
+	}
+
+	for _, tt := range tests {
+		response := http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"members": [` + leader + `, ` + tt.replicas + `]}`))),
+		}
+		mockClient := mocks.NewMockHTTPClient(ctrl)
+		mockClient.EXPECT().Get(gomock.Any()).Return(&response, nil)
+		cluster.patroni = patroni.New(patroniLogger, mockClient)
+		cluster.Spec.Patroni.MaximumLagOnFailover = tt.maxLag
+
+		err := cluster.membersCaughtUp(newMockPod("192.168.100.1"), tt.numberOfPods)
+		if tt.expectedError == "" {
+			assert.NoError(t, err, tt.subtest)
+		} else {
+			assert.EqualError(t, err, tt.expectedError, tt.subtest)
+		}
+	}
+}
+
+func TestKeepWALVolume(t *testing.T) {
+	cluster := New(Config{}, k8sutil.KubernetesClient{}, acidv1.Postgresql{}, logger, record.NewFakeRecorder(10))
+	storageClass := "fast"
+	statefulSet := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "postgres",
+							VolumeMounts: []v1.VolumeMount{
+								{Name: constants.DataVolumeName},
+								{Name: constants.WALVolumeName, SubPath: "wal"},
+							},
+						},
+					},
+				},
+			},
+			VolumeClaimTemplates: []v1.PersistentVolumeClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: constants.WALVolumeName},
+					Spec: v1.PersistentVolumeClaimSpec{
+						StorageClassName: &storageClass,
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("5Gi")},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cluster.keepWALVolume(statefulSet)
+	assert.Equal(t, &acidv1.Volume{Size: "5Gi", StorageClass: "fast", SubPath: "wal"}, cluster.Spec.WALVolume)
+
+	// a WAL volume in the manifest is left alone
+	cluster.Spec.WALVolume = &acidv1.Volume{Size: "10Gi"}
+	cluster.keepWALVolume(statefulSet)
+	assert.Equal(t, "10Gi", cluster.Spec.WALVolume.Size)
+
+	cluster.Spec.WALVolume = nil
+	cluster.keepWALVolume(&appsv1.StatefulSet{})
+	assert.Nil(t, cluster.Spec.WALVolume)
 }
 
 func TestQuantityToGigabyte(t *testing.T) {
@@ -149,6 +479,169 @@ func TestQuantityToGigabyte(t *testing.T) {
 	}
 }
 
+func TestAutogrowVolumeSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		step     string
+		maxSize  string
+		expected string
+	}{
+		{
+			"default step of 10 percent rounded up to full gigabytes",
+			"5Gi",
+			"",
+			"100Gi",
+			"6Gi",
+		},
+		{
+			"percentage step",
+			"100Gi",
+			"25%",
+			"1Ti",
+			"125Gi",
+		},
+		{
+			"quantity step",
+			"10Gi",
+			"5Gi",
+			"100Gi",
+			"15Gi",
+		},
+		{
+			"capped at maximum size",
+			"90Gi",
+			"20Gi",
+			"100Gi",
+			"100Gi",
+		},
+		{
+			"maximum size already reached",
+			"100Gi",
+			"20Gi",
+			"100Gi",
+			"100Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		current := resource.MustParse(tt.current)
+		maxSize := resource.MustParse(tt.maxSize)
+		newSize, err := autogrowVolumeSize(current, tt.step, maxSize)
+		assert.NoError(t, err)
+		if newSize.Cmp(resource.MustParse(tt.expected)) != 0 {
+			t.Errorf("%s: got %s, expected %s", tt.name, newSize.String(), tt.expected)
+		}
+	}
+}
+
+func TestVolumeResizePending(t *testing.T) {
+	volumeSize := resource.MustParse("10Gi")
+
+	// ext4 on a 10Gi volume reports a bit less in df
+	assert.False(t, volumeResizePending(10*constants.Gigabyte*98/100, volumeSize))
+	// the filesystem still has the size before growing to 10Gi
+	assert.True(t, volumeResizePending(9*constants.Gigabyte, volumeSize))
+}
+
+func TestRecordVolumeSize(t *testing.T) {
+	for _, target := range []string{"manifest", "status"} {
+		acidClientSet := fakeacidv1.NewSimpleClientset()
+		client := k8sutil.KubernetesClient{
+			PostgresqlsGetter: acidClientSet.AcidV1(),
+		}
+		pg := acidv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "acid-test-cluster",
+				Namespace: "default",
+			},
+			Spec: acidv1.PostgresSpec{
+				Volume: acidv1.Volume{
+					Size: "10Gi",
+					Autogrow: &acidv1.VolumeAutogrow{
+						MaxSize: "20Gi",
+					},
+				},
+			},
+		}
+		_, err := acidClientSet.AcidV1().Postgresqls("default").Create(context.TODO(), &pg, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		cluster := New(
+			Config{
+				OpConfig: config.Config{
+					StorageAutogrowTarget: target,
+				},
+			}, client, pg, logger, eventRecorder)
+
+		assert.NoError(t, cluster.recordVolumeSize("11Gi"))
+		assert.Equal(t, "11Gi", cluster.volumeSize(cluster.Spec.Volume), target)
+
+		stored, err := acidClientSet.AcidV1().Postgresqls("default").Get(context.TODO(), "acid-test-cluster", metav1.GetOptions{})
+		assert.NoError(t, err)
+		if target == "manifest" {
+			assert.Equal(t, "11Gi", stored.Spec.Volume.Size)
+			assert.Equal(t, "", cluster.Status.VolumeSize)
+		} else {
+			assert.Equal(t, "10Gi", stored.Spec.Volume.Size)
+			assert.Equal(t, "11Gi", stored.Status.VolumeSize)
+		}
+
+		// a larger size in the manifest wins over the recorded size
+		cluster.Spec.Volume.Size = "15Gi"
+		assert.Equal(t, "15Gi", cluster.volumeSize(cluster.Spec.Volume), target)
+	}
+}
+
+func TestSyncLoweredVolumeSize(t *testing.T) {
+	for _, enableVolumeRebuild := range []bool{false, true} {
+		acidClientSet := fakeacidv1.NewSimpleClientset()
+		client := k8sutil.KubernetesClient{
+			PostgresqlsGetter: acidClientSet.AcidV1(),
+		}
+		pg := acidv1.Postgresql{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "acid-test-cluster",
+				Namespace: "default",
+			},
+			Spec: acidv1.PostgresSpec{
+				Volume: acidv1.Volume{Size: "10Gi"},
+			},
+			Status: acidv1.PostgresStatus{VolumeSize: "20Gi"},
+		}
+		_, err := acidClientSet.AcidV1().Postgresqls("default").Create(context.TODO(), &pg, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		recorder := record.NewFakeRecorder(1)
+		cluster := New(
+			Config{
+				OpConfig: config.Config{
+					StorageAutogrowTarget: "status",
+					EnableVolumeRebuild:   enableVolumeRebuild,
+				},
+			}, client, pg, logger, recorder)
+
+		// raising the size in the manifest does not touch the recorded size
+		cluster.Spec.Volume.Size = "15Gi"
+		assert.NoError(t, cluster.syncLoweredVolumeSize("10Gi", "15Gi"))
+		assert.Equal(t, "20Gi", cluster.Status.VolumeSize)
+
+		cluster.Spec.Volume.Size = "5Gi"
+		assert.NoError(t, cluster.syncLoweredVolumeSize("15Gi", "5Gi"))
+		stored, err := acidClientSet.AcidV1().Postgresqls("default").Get(context.TODO(), "acid-test-cluster", metav1.GetOptions{})
+		assert.NoError(t, err)
+		if enableVolumeRebuild {
+			assert.Equal(t, "5Gi", cluster.volumeSize(cluster.Spec.Volume))
+			assert.Equal(t, "", stored.Status.VolumeSize)
+			assert.Empty(t, recorder.Events)
+		} else {
+			assert.Equal(t, "20Gi", cluster.volumeSize(cluster.Spec.Volume))
+			assert.Equal(t, "20Gi", stored.Status.VolumeSize)
+			assert.Contains(t, <-recorder.Events, "Warning VolumeRebuild Volume size 5Gi is not applied")
+		}
+	}
+}
+
 func CreatePVCs(namespace string, clusterName string, labels labels.Set, n int, size string) v1.PersistentVolumeClaimList {
 	// define and create PVCs for 1Gi volumes
 	storage1Gi, _ := resource.ParseQuantity(size)
@@ -226,10 +719,164 @@ func TestMigrateEBS(t *testing.T) {
 	cluster.VolumeResizer = resizer
 	err := cluster.populateVolumeMetaData()
 	assert.NoError(t, err)
-	err = cluster.executeEBSMigration()
+	err = cluster.executeVolumeMigration(cluster.getVolumeMigrationPolicy())
 	assert.NoError(t, err)
 }
 
+func TestVolumeMigrationPolicy(t *testing.T) {
+	client, _ := newFakeK8sPVCclient()
+	clusterName := "acid-test-cluster"
+	namespace := "default"
+
+	var cluster = New(
+		Config{
+			OpConfig: config.Config{
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+				},
+				StorageResizeMode:         "pvc",
+				EnableVolumeMigration:     true,
+				VolumeMigrationSourceType: "gp2",
+				VolumeMigrationTargetType: "gp3",
+				VolumeMigrationIops:       3000,
+				VolumeMigrationThroughput: 125,
+				VolumeMigrationMaxSize:    1000,
+			},
+			// another cluster is migrating a volume already
+			VolumeMigrations: volumes.NewMigrationLimiter(2),
+		}, client, acidv1.Postgresql{}, logger, record.NewFakeRecorder(10))
+	cluster.Spec.Volume.Size = "1Gi"
+	cluster.Name = clusterName
+	cluster.Namespace = namespace
+	cluster.VolumeMigrations.Acquire("vol-other-cluster")
+
+	initTestVolumesAndPods(cluster.KubeClient, namespace, clusterName, cluster.labelsSet(false), []testVolume{testVol, testVol, testVol})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resizer := mocks.NewMockVolumeResizer(ctrl)
+	for i := 1; i <= 3; i++ {
+		resizer.EXPECT().ExtractVolumeID(gomock.Eq(fmt.Sprintf("aws://eu-central-1b/ebs-volume-%d", i))).Return(fmt.Sprintf("ebs-volume-%d", i), nil).Times(3)
+	}
+	migratedVolumes := []volumes.VolumeProperties{
+		{VolumeID: "ebs-volume-1", VolumeType: "gp3", Size: 100, Iops: 3000, Throughput: 125},
+		{VolumeID: "ebs-volume-2", VolumeType: "gp2", Size: 2000, Iops: 6000},
+		{VolumeID: "ebs-volume-3", VolumeType: "gp2", Size: 1000, Iops: 3000}}
+	gomock.InOrder(
+		resizer.EXPECT().DescribeVolumes(gomock.Any()).Return(
+			[]volumes.VolumeProperties{
+				{VolumeID: "ebs-volume-1", VolumeType: "gp2", Size: 100, Iops: 300},
+				{VolumeID: "ebs-volume-2", VolumeType: "gp2", Size: 2000, Iops: 6000},
+				{VolumeID: "ebs-volume-3", VolumeType: "gp2", Size: 1000, Iops: 3000}}, nil),
+		resizer.EXPECT().DescribeVolumes(gomock.Any()).Return(migratedVolumes, nil),
+		resizer.EXPECT().DescribeVolumeModifications(gomock.Eq([]string{"ebs-volume-1"})).Return(
+			map[string]string{"ebs-volume-1": "optimizing"}, nil),
+		resizer.EXPECT().DescribeVolumes(gomock.Any()).Return(migratedVolumes, nil),
+		resizer.EXPECT().DescribeVolumeModifications(gomock.Eq([]string{"ebs-volume-1"})).Return(
+			map[string]string{"ebs-volume-1": "completed"}, nil),
+	)
+	// the first sync migrates one volume up to the limit, the next one follows once the first modification
+	// completed, the type is reported as gp3 while it is still optimizing
+	resizer.EXPECT().ModifyVolume(gomock.Eq("ebs-volume-1"), gomock.Eq(aws.String("gp3")), gomock.Eq(aws.Int64(100)),
+		gomock.Eq(aws.Int64(3000)), gomock.Eq(aws.Int64(125))).Return(nil)
+	resizer.EXPECT().ModifyVolume(gomock.Eq("ebs-volume-3"), gomock.Eq(aws.String("gp3")), gomock.Eq(aws.Int64(1000)),
+		gomock.Eq(aws.Int64(3000)), gomock.Eq(aws.Int64(125))).Return(nil)
+	cluster.VolumeResizer = resizer
+
+	states := func() map[string]VolumeMigrationState {
+		result := make(map[string]VolumeMigrationState)
+		for _, migration := range cluster.GetVolumeMigrations() {
+			result[migration.VolumeID] = migration.State
+		}
+		return result
+	}
+
+	assert.NoError(t, cluster.populateVolumeMetaData())
+	assert.NoError(t, cluster.executeVolumeMigration(cluster.getVolumeMigrationPolicy()))
+	assert.Equal(t, map[string]VolumeMigrationState{
+		"ebs-volume-1": VolumeMigrationInProgress,
+		"ebs-volume-2": VolumeMigrationSkipped,
+		"ebs-volume-3": VolumeMigrationWaiting,
+	}, states())
+	assert.Equal(t, 2, cluster.VolumeMigrations.InProgress())
+
+	assert.NoError(t, cluster.populateVolumeMetaData())
+	assert.NoError(t, cluster.executeVolumeMigration(cluster.getVolumeMigrationPolicy()))
+	assert.Equal(t, VolumeMigrationInProgress, states()["ebs-volume-1"])
+	assert.Equal(t, VolumeMigrationWaiting, states()["ebs-volume-3"])
+
+	assert.NoError(t, cluster.populateVolumeMetaData())
+	assert.NoError(t, cluster.executeVolumeMigration(cluster.getVolumeMigrationPolicy()))
+	assert.Equal(t, map[string]VolumeMigrationState{
+		"ebs-volume-1": VolumeMigrationCompleted,
+		"ebs-volume-2": VolumeMigrationSkipped,
+		"ebs-volume-3": VolumeMigrationInProgress,
+	}, states())
+	assert.Equal(t, 2, cluster.VolumeMigrations.InProgress())
+}
+
+func TestRestoreVolumeMigrations(t *testing.T) {
+	client, _ := newFakeK8sPVCclient()
+	clusterName := "acid-test-cluster"
+	namespace := "default"
+
+	var cluster = New(
+		Config{
+			OpConfig: config.Config{
+				Resources: config.Resources{
+					ClusterLabels:    map[string]string{"application": "spilo"},
+					ClusterNameLabel: "cluster-name",
+				},
+				StorageResizeMode:         "pvc",
+				EnableVolumeMigration:     true,
+				VolumeMigrationSourceType: "gp2",
+				VolumeMigrationTargetType: "gp3",
+			},
+			VolumeMigrations: volumes.NewMigrationLimiter(1),
+		}, client, acidv1.Postgresql{}, logger, record.NewFakeRecorder(10))
+	cluster.Spec.Volume.Size = "1Gi"
+	cluster.Name = clusterName
+	cluster.Namespace = namespace
+
+	initTestVolumesAndPods(cluster.KubeClient, namespace, clusterName, cluster.labelsSet(false), []testVolume{testVol, testVol, testVol})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resizer := mocks.NewMockVolumeResizer(ctrl)
+	for i := 1; i <= 3; i++ {
+		resizer.EXPECT().ExtractVolumeID(gomock.Eq(fmt.Sprintf("aws://eu-central-1b/ebs-volume-%d", i))).Return(fmt.Sprintf("ebs-volume-%d", i), nil).Times(2)
+	}
+	resizer.EXPECT().DescribeVolumes(gomock.Any()).Return(
+		[]volumes.VolumeProperties{
+			{VolumeID: "ebs-volume-1", VolumeType: "gp3", Size: 100},
+			{VolumeID: "ebs-volume-2", VolumeType: "gp2", Size: 100},
+			{VolumeID: "ebs-volume-3", VolumeType: "gp3", Size: 100}}, nil).Times(2)
+	// the migrations of the previous operator run are only looked up once
+	resizer.EXPECT().DescribeVolumeModifications(gomock.Eq([]string{"ebs-volume-1", "ebs-volume-3"})).Return(
+		map[string]string{"ebs-volume-1": "modifying", "ebs-volume-3": "completed"}, nil)
+	resizer.EXPECT().DescribeVolumeModifications(gomock.Eq([]string{"ebs-volume-1"})).Return(
+		map[string]string{"ebs-volume-1": "optimizing"}, nil)
+	cluster.VolumeResizer = resizer
+
+	// the running migration takes the only slot, so the gp2 volume has to wait
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, cluster.populateVolumeMetaData())
+		assert.NoError(t, cluster.executeVolumeMigration(cluster.getVolumeMigrationPolicy()))
+		states := make(map[string]VolumeMigrationState)
+		for _, migration := range cluster.GetVolumeMigrations() {
+			states[migration.VolumeID] = migration.State
+		}
+		assert.Equal(t, map[string]VolumeMigrationState{
+			"ebs-volume-1": VolumeMigrationInProgress,
+			"ebs-volume-2": VolumeMigrationWaiting,
+		}, states)
+		assert.Equal(t, 1, cluster.VolumeMigrations.InProgress())
+	}
+}
+
 func initTestVolumesAndPods(client k8sutil.KubernetesClient, namespace, clustername string, labels labels.Set, volumes []testVolume) {
 	i := 0
 	for _, v := range volumes {
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// apiClusterActions maps the actions of the REST API write endpoints to cluster events
+var apiClusterActions = map[string]EventType{
+	"sync":             EventSync,
+	"switchover":       EventSwitchover,
+	"restart":          EventRestart,
+	"reload":           EventReload,
+	"rotate-passwords": EventRotatePasswords,
+}
+
+// AuthenticateAPIToken checks a bearer token of a REST API write request and returns the name of the caller
+func (c *Controller) AuthenticateAPIToken(token string) (string, error) {
+	if c.opConfig.APITokenSecretName == emptyName && len(c.opConfig.APITokenReviewUsers) == 0 {
+		return "", fmt.Errorf("write endpoints are disabled")
+	}
+	if token == "" {
+		return "", fmt.Errorf("no bearer token given")
+	}
+
+	if c.opConfig.APITokenSecretName != emptyName {
+		secret, err := c.KubeClient.Secrets(c.opConfig.APITokenSecretName.Namespace).Get(
+			context.TODO(), c.opConfig.APITokenSecretName.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("could not get API token secret %q: %v", c.opConfig.APITokenSecretName, err)
+		}
+		for name, value := range secret.Data {
+			if len(value) > 0 && subtle.ConstantTimeCompare(value, []byte(token)) == 1 {
+				return name, nil
+			}
+		}
+	}
+
+	if len(c.opConfig.APITokenReviewUsers) > 0 {
+		review, err := c.KubeClient.TokenReviews().Create(context.TODO(), &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{Token: token},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("could not review token: %v", err)
+		}
+		if review.Status.Authenticated {
+			if util.SliceContains(c.opConfig.APITokenReviewUsers, review.Status.User.Username) {
+				return review.Status.User.Username, nil
+			}
+			return "", fmt.Errorf("user %q is not allowed to use the write endpoints", review.Status.User.Username)
+		}
+	}
+
+	return "", fmt.Errorf("invalid bearer token")
+}
+
+// QueueClusterAction queues an action requested through the REST API for the cluster and returns the worker ID
+func (c *Controller) QueueClusterAction(namespace, name, action, candidate, caller string) (uint32, error) {
+	clusterName := spec.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	eventType, ok := apiClusterActions[action]
+	if !ok {
+		return 0, fmt.Errorf("unknown action %q", action)
+	}
+	if candidate != "" && eventType != EventSwitchover {
+		return 0, fmt.Errorf("a candidate can only be given for a switchover")
+	}
+
+	c.clustersMu.RLock()
+	_, ok = c.clusters[clusterName]
+	c.clustersMu.RUnlock()
+	if !ok {
+		return 0, spec.ErrClusterNotFound
+	}
+
+	// always act on the latest manifest, the informer cache may lag behind
+	pg, err := c.KubeClient.Postgresqls(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return 0, spec.ErrClusterNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not get postgresql manifest: %v", err)
+	}
+	if pg.Error != "" {
+		return 0, fmt.Errorf("postgresql manifest is invalid: %s", pg.Error)
+	}
+
+	workerID := c.clusterWorkerID(clusterName)
+	clusterEvent := ClusterEvent{
+		EventTime: time.Now(),
+		EventType: eventType,
+		UID:       pg.GetUID(),
+		NewSpec:   pg,
+		WorkerID:  workerID,
+		Candidate: candidate,
+	}
+
+	lg := c.logger.WithField("worker", workerID).WithField("cluster-name", clusterName)
+	if err := c.clusterEventQueues[workerID].Add(clusterEvent); err != nil {
+		return 0, fmt.Errorf("could not queue %s event: %v", eventType, err)
+	}
+	lg.Infof("%s event has been queued on request of %s", eventType, caller)
+
+	return workerID, nil
+}
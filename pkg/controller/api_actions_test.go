@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-tokens",
+			Namespace: v1.NamespaceDefault,
+		},
+		Data: map[string][]byte{
+			"portal": []byte("portal-token"),
+			"empty":  []byte(""),
+		},
+	})
+	// the fake client does not authenticate, so only accept tokens named after a user
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "sa-token" || review.Spec.Token == "other-token" {
+			review.Status.Authenticated = true
+			review.Status.User.Username = "system:serviceaccount:default:" + review.Spec.Token
+		}
+		return true, review, nil
+	})
+
+	controller := NewController(&spec.ControllerConfig{}, "api-test")
+	controller.KubeClient = k8sutil.KubernetesClient{
+		SecretsGetter:      client.CoreV1(),
+		TokenReviewsGetter: client.AuthenticationV1(),
+	}
+
+	tests := []struct {
+		subTest     string
+		secretName  spec.NamespacedName
+		reviewUsers []string
+		token       string
+		caller      string
+		expectError bool
+	}{
+		{
+			subTest:     "write endpoints disabled",
+			token:       "portal-token",
+			expectError: true,
+		},
+		{
+			subTest:    "token from secret",
+			secretName: spec.NamespacedName{Namespace: v1.NamespaceDefault, Name: "api-tokens"},
+			token:      "portal-token",
+			caller:     "portal",
+		},
+		{
+			subTest:     "empty token does not match empty secret value",
+			secretName:  spec.NamespacedName{Namespace: v1.NamespaceDefault, Name: "api-tokens"},
+			token:       "",
+			expectError: true,
+		},
+		{
+			subTest:     "unknown token",
+			secretName:  spec.NamespacedName{Namespace: v1.NamespaceDefault, Name: "api-tokens"},
+			token:       "sa-token",
+			expectError: true,
+		},
+		{
+			subTest:     "token of allowed user",
+			secretName:  spec.NamespacedName{Namespace: v1.NamespaceDefault, Name: "api-tokens"},
+			reviewUsers: []string{"system:serviceaccount:default:sa-token"},
+			token:       "sa-token",
+			caller:      "system:serviceaccount:default:sa-token",
+		},
+		{
+			subTest:     "token of user not allowed",
+			reviewUsers: []string{"system:serviceaccount:default:sa-token"},
+			token:       "other-token",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		controller.opConfig.APITokenSecretName = tt.secretName
+		controller.opConfig.APITokenReviewUsers = tt.reviewUsers
+
+		caller, err := controller.AuthenticateAPIToken(tt.token)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("%s: expected error, got caller %q", tt.subTest, caller)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.subTest, err)
+		}
+		if caller != tt.caller {
+			t.Errorf("%s: expected caller %q, got %q", tt.subTest, tt.caller, caller)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
@@ -22,15 +23,19 @@ import (
 	"github.com/zalando/postgres-operator/pkg/util/constants"
 	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
 	"github.com/zalando/postgres-operator/pkg/util/ringlog"
+	"github.com/zalando/postgres-operator/pkg/util/tracing"
+	"github.com/zalando/postgres-operator/pkg/util/volumes"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/reference"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 // Controller represents operator controller
@@ -51,12 +56,16 @@ type Controller struct {
 	controllerID     string
 	curWorkerID      uint32 //initialized with 0
 	curWorkerCluster sync.Map
-	clusterWorkers   map[spec.NamespacedName]uint32
+	clusterWorkers   map[spec.NamespacedName]uint32 // protected by clustersMu, read by the log formatter
 	clustersMu       sync.RWMutex
 	clusters         map[spec.NamespacedName]*cluster.Cluster
 	clusterLogs      map[spec.NamespacedName]ringlog.RingLogger
 	clusterHistory   map[spec.NamespacedName]ringlog.RingLogger // history of the cluster changes
+	historyStore     historyStore                               // nil if the history is kept in memory only
+	changedHistory   sync.Map                                   // clusters with history or logs not persisted yet
+	historyMu        sync.Mutex
 	teamClusters     map[string][]spec.NamespacedName
+	watchers         *clusterWatchers
 
 	postgresqlInformer   cache.SharedIndexInformer
 	postgresTeamInformer cache.SharedIndexInformer
@@ -70,6 +79,10 @@ type Controller struct {
 
 	workerLogs map[uint32]ringlog.RingLogger
 
+	volumeMigrations *volumes.MigrationLimiter
+	tracer           *tracing.Tracer
+	tracingTransport http.RoundTripper
+
 	PodServiceAccount            *v1.ServiceAccount
 	PodServiceAccountRoleBinding *rbacv1.RoleBinding
 }
@@ -77,8 +90,9 @@ type Controller struct {
 // NewController creates a new controller
 func NewController(controllerConfig *spec.ControllerConfig, controllerId string) *Controller {
 	logger := logrus.New()
+	jsonFormatter := &jsonLogFormatter{}
 	if controllerConfig.EnableJsonLogging {
-		logger.SetFormatter(&logrus.JSONFormatter{})
+		logger.SetFormatter(jsonFormatter)
 	} else {
 		if os.Getenv("LOG_NOQUOTE") != "" {
 			logger.SetFormatter(&logrus.TextFormatter{PadLevelText: true, DisableQuote: true})
@@ -112,10 +126,13 @@ func NewController(controllerConfig *spec.ControllerConfig, controllerId string)
 		clusterLogs:      make(map[spec.NamespacedName]ringlog.RingLogger),
 		clusterHistory:   make(map[spec.NamespacedName]ringlog.RingLogger),
 		teamClusters:     make(map[string][]spec.NamespacedName),
+		watchers:         newClusterWatchers(),
 		stopCh:           make(chan struct{}),
 		podCh:            make(chan cluster.PodEvent),
 	}
 	logger.Hooks.Add(c)
+	jsonFormatter.currentProcess = c.clusterProcess
+	jsonFormatter.clusterWorker = c.clusterWorker
 
 	return c
 }
@@ -304,6 +321,32 @@ func logMultiLineConfig(log *logrus.Entry, config string) {
 	}
 }
 
+func (c *Controller) initTracer() {
+	tracer, err := tracing.New(c.opConfig.TracingExporter, c.opConfig.TracingOTLPEndpoint, "postgres-operator", c.logger.WithField("pkg", "tracing"))
+	if err != nil {
+		c.logger.Warningf("could not enable tracing: %v", err)
+		return
+	}
+	if tracer == nil {
+		return
+	}
+	c.tracer = tracer
+
+	// every cluster wraps the same transport to record the spans of its requests,
+	// and shares one rate limiter with the others to keep the request budget of the operator
+	if c.config.RestConfig != nil {
+		c.config.RestConfig = rest.CopyConfig(c.config.RestConfig)
+		c.config.RestConfig.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(rest.DefaultQPS, rest.DefaultBurst)
+		tracingTransport, err := rest.TransportFor(c.config.RestConfig)
+		if err != nil {
+			c.logger.Warningf("could not create transport for traced requests: %v", err)
+		} else {
+			c.tracingTransport = tracingTransport
+		}
+	}
+	c.logger.Infof("tracing cluster syncs and updates with the %s exporter", c.opConfig.TracingExporter)
+}
+
 func (c *Controller) initController() {
 	c.initClients()
 	c.controllerID = os.Getenv("CONTROLLER_ID")
@@ -366,6 +409,10 @@ func (c *Controller) initController() {
 		})
 	}
 
+	c.volumeMigrations = volumes.NewMigrationLimiter(c.opConfig.VolumeMigrationMaxConcurrent)
+	c.initTracer()
+	c.initHistoryStore()
+
 	c.apiserver = apiserver.New(c, c.opConfig.APIPort, c.logger.Logger)
 }
 
@@ -458,6 +505,16 @@ func (c *Controller) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
 	go c.apiserver.Run(stopCh, wg)
 	go c.kubeNodesInformer(stopCh, wg)
 
+	if c.tracer != nil {
+		wg.Add(1)
+		go c.tracer.Run(stopCh, wg)
+	}
+
+	if c.historyStore != nil {
+		wg.Add(1)
+		go c.persistClusterHistory(stopCh, wg)
+	}
+
 	if c.opConfig.EnablePostgresTeamCRD {
 		go c.runPostgresTeamInformer(stopCh, wg)
 	}
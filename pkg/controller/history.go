@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	"github.com/zalando/postgres-operator/pkg/util/ringlog"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Backends to persist the history and logs of clusters across restarts of the operator
+const (
+	historyStorageConfigMap = "configmap"
+	historyStorageFile      = "file"
+)
+
+const (
+	historyFlushInterval   = 30 * time.Second
+	historyConfigMapSuffix = "-operator-history"
+	historyConfigMapKey    = "history.json"
+	// stay well below the size limit of 1MiB of ConfigMaps
+	historyMaxSize = 900 * 1024
+)
+
+// clusterRecords is the persisted form of the logs and the history of a cluster
+type clusterRecords struct {
+	Logs    []*spec.LogEntry
+	History []*spec.Diff
+}
+
+type historyStore interface {
+	// load returns nil if nothing was persisted for the cluster yet
+	load(clusterName spec.NamespacedName) ([]byte, error)
+	// the owner references are only used by storages within K8s
+	save(clusterName spec.NamespacedName, data []byte, ownerReferences []metav1.OwnerReference) error
+	delete(clusterName spec.NamespacedName) error
+}
+
+// configMapHistoryStore keeps the records of a cluster in a ConfigMap next to the cluster
+type configMapHistoryStore struct {
+	client           k8sutil.KubernetesClient
+	clusterNameLabel string
+}
+
+func (s *configMapHistoryStore) configMapName(clusterName spec.NamespacedName) string {
+	return clusterName.Name + historyConfigMapSuffix
+}
+
+func (s *configMapHistoryStore) load(clusterName spec.NamespacedName) ([]byte, error) {
+	cm, err := s.client.ConfigMaps(clusterName.Namespace).Get(context.TODO(), s.configMapName(clusterName), metav1.GetOptions{})
+	if err != nil {
+		if k8sutil.ResourceNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not get config map: %v", err)
+	}
+
+	return []byte(cm.Data[historyConfigMapKey]), nil
+}
+
+// ownedByCluster reports if the config map was created for the cluster and not just shares its name
+func (s *configMapHistoryStore) ownedByCluster(cm *v1.ConfigMap, clusterName spec.NamespacedName) bool {
+	return cm.Labels[s.clusterNameLabel] == clusterName.Name
+}
+
+func (s *configMapHistoryStore) save(clusterName spec.NamespacedName, data []byte, ownerReferences []metav1.OwnerReference) error {
+	name := s.configMapName(clusterName)
+	cm, err := s.client.ConfigMaps(clusterName.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !k8sutil.ResourceNotFound(err) {
+			return fmt.Errorf("could not get config map: %v", err)
+		}
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: clusterName.Namespace,
+				Labels: map[string]string{
+					s.clusterNameLabel: clusterName.Name,
+				},
+				OwnerReferences: ownerReferences,
+			},
+			Data: map[string]string{historyConfigMapKey: string(data)},
+		}
+		if _, err = s.client.ConfigMaps(clusterName.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("could not create config map: %v", err)
+		}
+		return nil
+	}
+
+	if !s.ownedByCluster(cm, clusterName) {
+		return fmt.Errorf("config map %q exists, but is not labeled with %s=%s", name, s.clusterNameLabel, clusterName.Name)
+	}
+	cm.Data = map[string]string{historyConfigMapKey: string(data)}
+	// config maps persisted by older operator versions get their owner on the next save
+	if len(ownerReferences) > 0 {
+		cm.OwnerReferences = ownerReferences
+	}
+	if _, err = s.client.ConfigMaps(clusterName.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update config map: %v", err)
+	}
+
+	return nil
+}
+
+func (s *configMapHistoryStore) delete(clusterName spec.NamespacedName) error {
+	name := s.configMapName(clusterName)
+	cm, err := s.client.ConfigMaps(clusterName.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if k8sutil.ResourceNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not get config map: %v", err)
+	}
+	if !s.ownedByCluster(cm, clusterName) {
+		return fmt.Errorf("config map %q is not labeled with %s=%s, not deleting it", name, s.clusterNameLabel, clusterName.Name)
+	}
+
+	err = s.client.ConfigMaps(clusterName.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !k8sutil.ResourceNotFound(err) {
+		return fmt.Errorf("could not delete config map: %v", err)
+	}
+
+	return nil
+}
+
+// fileHistoryStore keeps the records of every cluster in a file of its own,
+// meant for a directory on a persistent volume mounted into the operator pod
+type fileHistoryStore struct {
+	path string
+}
+
+func (s *fileHistoryStore) fileName(clusterName spec.NamespacedName) string {
+	// namespaces cannot contain dots, so the file names are unique
+	return filepath.Join(s.path, fmt.Sprintf("%s.%s.json", clusterName.Namespace, clusterName.Name))
+}
+
+func (s *fileHistoryStore) load(clusterName spec.NamespacedName) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.fileName(clusterName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+
+	return data, nil
+}
+
+func (s *fileHistoryStore) save(clusterName spec.NamespacedName, data []byte, ownerReferences []metav1.OwnerReference) error {
+	if err := os.MkdirAll(s.path, 0755); err != nil {
+		return fmt.Errorf("could not create directory: %v", err)
+	}
+
+	// write to a temporary file first to never leave a truncated file behind
+	fileName := s.fileName(clusterName)
+	if err := ioutil.WriteFile(fileName+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("could not write file: %v", err)
+	}
+	if err := os.Rename(fileName+".tmp", fileName); err != nil {
+		return fmt.Errorf("could not replace file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *fileHistoryStore) delete(clusterName spec.NamespacedName) error {
+	if err := os.Remove(s.fileName(clusterName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not delete file: %v", err)
+	}
+
+	return nil
+}
+
+func (c *Controller) initHistoryStore() {
+	switch c.opConfig.ClusterHistoryStorage {
+	case "":
+		return
+	case historyStorageConfigMap:
+		c.historyStore = &configMapHistoryStore{
+			client:           c.KubeClient,
+			clusterNameLabel: c.opConfig.ClusterNameLabel,
+		}
+	case historyStorageFile:
+		c.historyStore = &fileHistoryStore{path: c.opConfig.ClusterHistoryStoragePath}
+	default:
+		c.logger.Warningf("unknown cluster history storage %q, history and logs of clusters are kept in memory only",
+			c.opConfig.ClusterHistoryStorage)
+		return
+	}
+	c.logger.Infof("persisting history and logs of clusters using the %s storage", c.opConfig.ClusterHistoryStorage)
+}
+
+// markHistoryChanged schedules the records of the cluster to be persisted with the next flush
+func (c *Controller) markHistoryChanged(clusterName spec.NamespacedName) {
+	if c.historyStore != nil {
+		c.changedHistory.Store(clusterName, true)
+	}
+}
+
+// loadClusterHistory fills the ring logs of a cluster with the records persisted before the restart
+func (c *Controller) loadClusterHistory(clusterName spec.NamespacedName, logs, history ringlog.RingLogger) {
+	if c.historyStore == nil {
+		return
+	}
+
+	data, err := c.historyStore.load(clusterName)
+	if err != nil {
+		c.logger.Warningf("could not load persisted history of cluster %q: %v", clusterName, err)
+		return
+	}
+	if data == nil {
+		return
+	}
+
+	var records clusterRecords
+	if err = json.Unmarshal(data, &records); err != nil {
+		c.logger.Warningf("could not decode persisted history of cluster %q: %v", clusterName, err)
+		return
+	}
+
+	records.expire(c.historyRetentionStart())
+	for _, e := range records.Logs {
+		logs.Insert(e)
+	}
+	for _, e := range records.History {
+		history.Insert(e)
+	}
+}
+
+// historyRetentionStart returns the time before which records are dropped, a retention of 0 keeps all of them
+func (c *Controller) historyRetentionStart() time.Time {
+	if c.opConfig.ClusterHistoryRetention <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-c.opConfig.ClusterHistoryRetention)
+}
+
+// expire drops all records older than the given time
+func (r *clusterRecords) expire(before time.Time) {
+	logs := make([]*spec.LogEntry, 0, len(r.Logs))
+	for _, e := range r.Logs {
+		if e.Time.After(before) {
+			logs = append(logs, e)
+		}
+	}
+	r.Logs = logs
+
+	history := make([]*spec.Diff, 0, len(r.History))
+	for _, e := range r.History {
+		if e.ProcessTime.After(before) {
+			history = append(history, e)
+		}
+	}
+	r.History = history
+}
+
+// encode returns the records as JSON, dropping the oldest ones until they fit into the size limit
+func (r *clusterRecords) encode(maxSize int) ([]byte, error) {
+	for {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) <= maxSize || (len(r.Logs) == 0 && len(r.History) == 0) {
+			return data, nil
+		}
+		if len(r.Logs) > 0 {
+			r.Logs = r.Logs[len(r.Logs)/10+1:]
+		}
+		if len(r.History) > 0 {
+			r.History = r.History[len(r.History)/10+1:]
+		}
+	}
+}
+
+func (c *Controller) saveClusterHistory(clusterName spec.NamespacedName) error {
+	// do not bring back the records of a cluster deleted in the meantime
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	c.clustersMu.RLock()
+	logs, logsFound := c.clusterLogs[clusterName]
+	history, historyFound := c.clusterHistory[clusterName]
+	cl, clusterFound := c.clusters[clusterName]
+	c.clustersMu.RUnlock()
+	if !logsFound || !historyFound {
+		return nil
+	}
+
+	records := clusterRecords{}
+	for _, e := range logs.Walk() {
+		logEntry := *e.(*spec.LogEntry)
+		// implied by the storage, the API does not show it either
+		logEntry.ClusterName = nil
+		records.Logs = append(records.Logs, &logEntry)
+	}
+	for _, e := range history.Walk() {
+		records.History = append(records.History, e.(*spec.Diff))
+	}
+	records.expire(c.historyRetentionStart())
+
+	data, err := records.encode(historyMaxSize)
+	if err != nil {
+		return fmt.Errorf("could not encode history: %v", err)
+	}
+
+	// owned by the postgresql object, so the records are garbage collected together with the cluster
+	var ownerReferences []metav1.OwnerReference
+	if clusterFound {
+		if ref := cl.GetReference(); ref != nil && ref.UID != "" {
+			ownerReferences = []metav1.OwnerReference{
+				{
+					APIVersion: ref.APIVersion,
+					Kind:       ref.Kind,
+					Name:       ref.Name,
+					UID:        ref.UID,
+				},
+			}
+		}
+	}
+
+	return c.historyStore.save(clusterName, data, ownerReferences)
+}
+
+func (c *Controller) flushClusterHistory() {
+	c.changedHistory.Range(func(key, _ interface{}) bool {
+		clusterName := key.(spec.NamespacedName)
+		c.changedHistory.Delete(clusterName)
+		if err := c.saveClusterHistory(clusterName); err != nil {
+			// log without the cluster name, it would mark the history as changed again
+			c.logger.Warningf("could not persist history of cluster %q: %v", clusterName, err)
+		}
+		return true
+	})
+}
+
+func (c *Controller) deleteClusterHistory(clusterName spec.NamespacedName) {
+	if c.historyStore == nil {
+		return
+	}
+
+	c.historyMu.Lock()
+	defer c.historyMu.Unlock()
+
+	c.changedHistory.Delete(clusterName)
+	if err := c.historyStore.delete(clusterName); err != nil {
+		c.logger.Warningf("could not delete persisted history of cluster %q: %v", clusterName, err)
+	}
+}
+
+// persistClusterHistory periodically writes the changed history and logs of clusters to the storage
+func (c *Controller) persistClusterHistory(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(historyFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flushClusterHistory()
+		case <-stopCh:
+			c.flushClusterHistory()
+			return
+		}
+	}
+}
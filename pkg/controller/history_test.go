@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	acidv1 "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	"github.com/zalando/postgres-operator/pkg/cluster"
+	"github.com/zalando/postgres-operator/pkg/spec"
+	"github.com/zalando/postgres-operator/pkg/util/k8sutil"
+	"github.com/zalando/postgres-operator/pkg/util/ringlog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterHistoryPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	clusterName := spec.NamespacedName{Namespace: "default", Name: "acid-test"}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, storage := range []string{historyStorageConfigMap, historyStorageFile} {
+		client := fake.NewSimpleClientset()
+		controller := NewController(&spec.ControllerConfig{}, "history-test")
+		controller.KubeClient = k8sutil.KubernetesClient{ConfigMapsGetter: client.CoreV1()}
+		controller.opConfig.ClusterHistoryStorage = storage
+		controller.opConfig.ClusterHistoryStoragePath = dir
+		controller.opConfig.ClusterHistoryRetention = 24 * time.Hour
+		controller.opConfig.ClusterNameLabel = "cluster-name"
+		controller.initHistoryStore()
+
+		logs := ringlog.New(10)
+		history := ringlog.New(10)
+		controller.clusterLogs[clusterName] = logs
+		controller.clusterHistory[clusterName] = history
+
+		// the expired entries must not survive the restart
+		logs.Insert(&spec.LogEntry{Time: now.Add(-48 * time.Hour), Level: logrus.InfoLevel, Message: "expired"})
+		logs.Insert(&spec.LogEntry{Time: now, Level: logrus.WarnLevel, ClusterName: &clusterName, Message: "could not sync"})
+		history.Insert(&spec.Diff{ProcessTime: now.Add(-48 * time.Hour), Diff: []string{"expired"}})
+		history.Insert(&spec.Diff{EventTime: now, ProcessTime: now, Diff: []string{"numberOfInstances: 2 -> 3"}})
+
+		controller.markHistoryChanged(clusterName)
+		controller.flushClusterHistory()
+
+		// a restarted operator starts with empty ring logs
+		restartedLogs := ringlog.New(10)
+		restartedHistory := ringlog.New(10)
+		controller.loadClusterHistory(clusterName, restartedLogs, restartedHistory)
+
+		expectedLogs := []interface{}{&spec.LogEntry{Time: now, Level: logrus.WarnLevel, Message: "could not sync"}}
+		if loaded := restartedLogs.Walk(); !reflect.DeepEqual(loaded, expectedLogs) {
+			t.Errorf("%s: expected logs %#v, got %#v", storage, expectedLogs[0], loaded)
+		}
+		expectedHistory := []interface{}{&spec.Diff{EventTime: now, ProcessTime: now, Diff: []string{"numberOfInstances: 2 -> 3"}}}
+		if loaded := restartedHistory.Walk(); !reflect.DeepEqual(loaded, expectedHistory) {
+			t.Errorf("%s: expected history %#v, got %#v", storage, expectedHistory[0], loaded)
+		}
+
+		// records are removed together with the cluster
+		delete(controller.clusterLogs, clusterName)
+		delete(controller.clusterHistory, clusterName)
+		controller.deleteClusterHistory(clusterName)
+		if data, err := controller.historyStore.load(clusterName); err != nil || data != nil {
+			t.Errorf("%s: expected no records after deleting the cluster, got %q, %v", storage, data, err)
+		}
+
+		// a cluster deleted before the flush is not persisted again
+		controller.markHistoryChanged(clusterName)
+		controller.flushClusterHistory()
+		if data, _ := controller.historyStore.load(clusterName); data != nil {
+			t.Errorf("%s: expected records of a deleted cluster not to be persisted", storage)
+		}
+	}
+}
+
+func TestClusterRecordsEncode(t *testing.T) {
+	records := clusterRecords{}
+	for i := 0; i < 100; i++ {
+		records.Logs = append(records.Logs, &spec.LogEntry{Message: "a log line that is long enough to matter"})
+		records.History = append(records.History, &spec.Diff{Diff: []string{"a diff line that is long enough to matter"}})
+	}
+	records.Logs[99].Message = "latest"
+
+	data, err := records.encode(4096)
+	if err != nil {
+		t.Fatalf("could not encode records: %v", err)
+	}
+	if len(data) > 4096 {
+		t.Errorf("expected at most 4096 bytes, got %d", len(data))
+	}
+	if len(records.Logs) == 0 || records.Logs[len(records.Logs)-1].Message != "latest" {
+		t.Errorf("expected the latest entries to be kept")
+	}
+
+	empty := clusterRecords{}
+	if _, err := empty.encode(1); err != nil {
+		t.Errorf("could not encode empty records: %v", err)
+	}
+}
+
+func TestConfigMapHistoryStoreOwnership(t *testing.T) {
+	clusterName := spec.NamespacedName{Namespace: "default", Name: "acid-test"}
+	client := fake.NewSimpleClientset()
+	controller := NewController(&spec.ControllerConfig{}, "history-test")
+	controller.KubeClient = k8sutil.KubernetesClient{ConfigMapsGetter: client.CoreV1()}
+	controller.opConfig.ClusterHistoryStorage = historyStorageConfigMap
+	controller.opConfig.ClusterNameLabel = "cluster-name"
+	controller.initHistoryStore()
+
+	pg := acidv1.Postgresql{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName.Name, Namespace: clusterName.Namespace, UID: "some-uid"},
+	}
+	controller.clusters[clusterName] = cluster.New(controller.makeClusterConfig(), controller.KubeClient, pg,
+		controller.logger, controller.eventRecorder)
+	controller.clusterLogs[clusterName] = ringlog.New(10)
+	controller.clusterHistory[clusterName] = ringlog.New(10)
+
+	if err := controller.saveClusterHistory(clusterName); err != nil {
+		t.Fatalf("could not persist history: %v", err)
+	}
+	cm, err := client.CoreV1().ConfigMaps(clusterName.Namespace).Get(context.TODO(), "acid-test-operator-history", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get config map: %v", err)
+	}
+	expectedOwners := []metav1.OwnerReference{
+		{APIVersion: "acid.zalan.do/v1", Kind: "postgresql", Name: clusterName.Name, UID: "some-uid"},
+	}
+	if !reflect.DeepEqual(cm.OwnerReferences, expectedOwners) {
+		t.Errorf("expected owner references %#v, got %#v", expectedOwners, cm.OwnerReferences)
+	}
+
+	// a config map of the same name not created for the cluster is neither overwritten nor deleted
+	cm.Labels = map[string]string{"app": "something-else"}
+	cm.Data = map[string]string{"key": "value"}
+	if _, err := client.CoreV1().ConfigMaps(clusterName.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("could not update config map: %v", err)
+	}
+	if err := controller.saveClusterHistory(clusterName); err == nil {
+		t.Errorf("expected error when saving into a config map of another owner")
+	}
+	if err := controller.historyStore.delete(clusterName); err == nil {
+		t.Errorf("expected error when deleting a config map of another owner")
+	}
+	cm, err = client.CoreV1().ConfigMaps(clusterName.Namespace).Get(context.TODO(), "acid-test-operator-history", metav1.GetOptions{})
+	if err != nil || !reflect.DeepEqual(cm.Data, map[string]string{"key": "value"}) {
+		t.Errorf("expected the config map to be left untouched, got %#v, %v", cm, err)
+	}
+}
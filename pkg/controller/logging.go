@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+)
+
+// jsonLogFormatter adds the cluster, namespace, worker and current process of the cluster to JSON
+// formatted log entries, so that log aggregators can filter on them
+type jsonLogFormatter struct {
+	logrus.JSONFormatter
+	currentProcess func(clusterName spec.NamespacedName) string
+	clusterWorker  func(clusterName spec.NamespacedName) (uint32, bool)
+}
+
+// Format implements the logrus Formatter interface
+func (f *jsonLogFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	clusterName, ok := e.Data["cluster-name"].(spec.NamespacedName)
+	if !ok {
+		return f.JSONFormatter.Format(e)
+	}
+
+	data := make(logrus.Fields, len(e.Data)+4)
+	for k, v := range e.Data {
+		data[k] = v
+	}
+	data["cluster"] = clusterName.Name
+	data["namespace"] = clusterName.Namespace
+	if f.currentProcess != nil {
+		if process := f.currentProcess(clusterName); process != "" {
+			data["process"] = process
+		}
+	}
+	if _, ok := data["worker"]; !ok && f.clusterWorker != nil {
+		if workerID, ok := f.clusterWorker(clusterName); ok {
+			data["worker"] = workerID
+		}
+	}
+
+	entry := *e
+	entry.Data = data
+	return f.JSONFormatter.Format(&entry)
+}
+
+// clusterProcess returns the name of the process the cluster is currently or was last busy with
+func (c *Controller) clusterProcess(clusterName spec.NamespacedName) string {
+	c.clustersMu.RLock()
+	cl, ok := c.clusters[clusterName]
+	c.clustersMu.RUnlock()
+	if !ok {
+		return ""
+	}
+
+	return cl.GetCurrentProcess().Name
+}
+
+// clusterWorker returns the worker the events of the cluster are queued for, if one was assigned yet
+func (c *Controller) clusterWorker(clusterName spec.NamespacedName) (uint32, bool) {
+	c.clustersMu.RLock()
+	workerID, ok := c.clusterWorkers[clusterName]
+	c.clustersMu.RUnlock()
+
+	return workerID, ok
+}
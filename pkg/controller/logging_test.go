@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+)
+
+func TestJSONLogFormatter(t *testing.T) {
+	clusterName := spec.NamespacedName{Namespace: "default", Name: "acid-test-cluster"}
+	formatter := &jsonLogFormatter{
+		currentProcess: func(name spec.NamespacedName) string {
+			if name == clusterName {
+				return "syncing statefulset"
+			}
+			return ""
+		},
+		clusterWorker: func(name spec.NamespacedName) (uint32, bool) {
+			return 5, name == clusterName
+		},
+	}
+
+	entry := logrus.NewEntry(logrus.New()).WithField("cluster-name", clusterName).WithField("worker", uint32(2))
+	entry.Message = "cluster has been synced"
+	entry.Level = logrus.InfoLevel
+
+	out, err := formatter.Format(entry)
+	if err != nil {
+		t.Fatalf("could not format entry: %v", err)
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatalf("could not parse formatted entry %s: %v", out, err)
+	}
+
+	expected := map[string]interface{}{
+		"cluster":      "acid-test-cluster",
+		"namespace":    "default",
+		"cluster-name": "default/acid-test-cluster",
+		"process":      "syncing statefulset",
+		"worker":       float64(2),
+		"level":        "info",
+		"msg":          "cluster has been synced",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected %q to be %v, got %v", key, value, fields[key])
+		}
+	}
+	if _, ok := entry.Data["cluster"]; ok {
+		t.Errorf("formatter must not modify the fields of the log entry")
+	}
+
+	// entries of the cluster logger get the worker of the cluster
+	out, err = formatter.Format(logrus.NewEntry(logrus.New()).WithField("cluster-name", clusterName))
+	if err != nil {
+		t.Fatalf("could not format entry: %v", err)
+	}
+	fields = make(map[string]interface{})
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatalf("could not parse formatted entry %s: %v", out, err)
+	}
+	if fields["worker"] != float64(5) {
+		t.Errorf("expected worker 5 of the cluster, got %v", fields["worker"])
+	}
+
+	// entries without a cluster are formatted as they are
+	out, err = formatter.Format(logrus.NewEntry(logrus.New()).WithField("pkg", "controller"))
+	if err != nil {
+		t.Fatalf("could not format entry: %v", err)
+	}
+	fields = make(map[string]interface{})
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatalf("could not parse formatted entry %s: %v", out, err)
+	}
+	if _, ok := fields["cluster"]; ok {
+		t.Errorf("unexpected cluster field in entry without cluster: %s", out)
+	}
+}
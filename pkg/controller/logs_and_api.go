@@ -35,6 +35,24 @@ func (c *Controller) ClusterStatus(namespace, cluster string) (*cluster.ClusterS
 	return status, nil
 }
 
+// ClusterMembers provides the Patroni members of the cluster
+func (c *Controller) ClusterMembers(namespace, cluster string) ([]cluster.MemberStatus, error) {
+
+	clusterName := spec.NamespacedName{
+		Namespace: namespace,
+		Name:      cluster,
+	}
+
+	c.clustersMu.RLock()
+	cl, ok := c.clusters[clusterName]
+	c.clustersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("could not find cluster")
+	}
+
+	return cl.GetMembers()
+}
+
 // ClusterDatabasesMap returns for each cluster the list of databases running there
 func (c *Controller) ClusterDatabasesMap() map[string][]string {
 
@@ -169,6 +187,7 @@ func (c *Controller) Fire(e *logrus.Entry) error {
 		logEntry.Worker = &id
 	}
 	clusterRingLog.Insert(logEntry)
+	c.markHistoryChanged(clusterName)
 
 	if logEntry.Worker == nil {
 		return nil
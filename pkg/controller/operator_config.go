@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"time"
 
@@ -58,6 +59,8 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 	result.EnablePasswordRotation = fromCRD.PostgresUsersConfiguration.EnablePasswordRotation
 	result.PasswordRotationInterval = util.CoalesceUInt32(fromCRD.PostgresUsersConfiguration.PasswordRotationInterval, 90)
 	result.PasswordRotationUserRetention = util.CoalesceUInt32(fromCRD.PostgresUsersConfiguration.DeepCopy().PasswordRotationUserRetention, 180)
+	result.ClientCertificateValidity = util.CoalesceDuration(time.Duration(fromCRD.PostgresUsersConfiguration.ClientCertificateValidity), "8760h")
+	result.ClientCertificateRenewBefore = util.CoalesceDuration(time.Duration(fromCRD.PostgresUsersConfiguration.ClientCertificateRenewBefore), "720h")
 
 	// major version upgrade config
 	result.MajorVersionUpgradeMode = util.Coalesce(fromCRD.MajorVersionUpgrade.MajorVersionUpgradeMode, "off")
@@ -84,9 +87,31 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 	result.PDBNameFormat = fromCRD.Kubernetes.PDBNameFormat
 	result.EnablePodDisruptionBudget = util.CoalesceBool(fromCRD.Kubernetes.EnablePodDisruptionBudget, util.True())
 	result.StorageResizeMode = util.Coalesce(fromCRD.Kubernetes.StorageResizeMode, "pvc")
+	result.StorageAutogrowTarget = util.Coalesce(fromCRD.Kubernetes.StorageAutogrowTarget, "status")
+	result.EnableVolumeRebuild = fromCRD.Kubernetes.EnableVolumeRebuild
+	result.PersistentVolumeClaimRetentionPolicy = util.CoalesceStrMap(fromCRD.Kubernetes.PersistentVolumeClaimRetentionPolicy,
+		map[string]string{"when_deleted": "Delete", "when_scaled": "Retain"})
+	for key, policy := range result.PersistentVolumeClaimRetentionPolicy {
+		if key != "when_deleted" && key != "when_scaled" {
+			panic(fmt.Errorf("unknown key %q in persistent_volume_claim_retention_policy", key))
+		}
+		if !strings.EqualFold(policy, "retain") && !strings.EqualFold(policy, "delete") {
+			panic(fmt.Errorf("persistent_volume_claim_retention_policy %s must be Retain or Delete, got %q", key, policy))
+		}
+	}
 	result.EnableInitContainers = util.CoalesceBool(fromCRD.Kubernetes.EnableInitContainers, util.True())
 	result.EnableSidecars = util.CoalesceBool(fromCRD.Kubernetes.EnableSidecars, util.True())
 	result.SecretNameTemplate = fromCRD.Kubernetes.SecretNameTemplate
+	result.ClientCertificateSecretNameTemplate = config.StringTemplate(util.Coalesce(
+		string(fromCRD.Kubernetes.ClientCertificateSecretNameTemplate),
+		"{username}.{cluster}.client-certificate.{tprkind}.{tprgroup}"))
+	result.ClientCASecretNameTemplate = config.StringTemplate(util.Coalesce(
+		string(fromCRD.Kubernetes.ClientCASecretNameTemplate),
+		"{cluster}.client-ca.{tprkind}.{tprgroup}"))
+	result.EnableSecretConnectionKeys = fromCRD.Kubernetes.EnableSecretConnectionKeys
+	result.SecretConnectionKeyTemplate = config.StringTemplate(util.Coalesce(
+		string(fromCRD.Kubernetes.SecretConnectionKeyTemplate),
+		"{service}-{key}"))
 	result.OAuthTokenSecretName = fromCRD.Kubernetes.OAuthTokenSecretName
 	result.EnableCrossNamespaceSecret = fromCRD.Kubernetes.EnableCrossNamespaceSecret
 
@@ -167,6 +192,14 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 	result.AdditionalSecretMountPath = util.Coalesce(fromCRD.AWSGCP.AdditionalSecretMountPath, "/meta/credentials")
 	result.EnableEBSGp3Migration = fromCRD.AWSGCP.EnableEBSGp3Migration
 	result.EnableEBSGp3MigrationMaxSize = util.CoalesceInt64(fromCRD.AWSGCP.EnableEBSGp3MigrationMaxSize, 1000)
+	result.EnableVolumeMigration = fromCRD.AWSGCP.EnableVolumeMigration
+	result.VolumeMigrationSourceType = fromCRD.AWSGCP.VolumeMigrationSourceType
+	result.VolumeMigrationTargetType = fromCRD.AWSGCP.VolumeMigrationTargetType
+	result.VolumeMigrationIops = fromCRD.AWSGCP.VolumeMigrationIops
+	result.VolumeMigrationThroughput = fromCRD.AWSGCP.VolumeMigrationThroughput
+	result.VolumeMigrationMaxSize = fromCRD.AWSGCP.VolumeMigrationMaxSize
+	result.VolumeMigrationMaxConcurrent = util.CoalesceInt(fromCRD.AWSGCP.VolumeMigrationMaxConcurrent, 1)
+	result.StorageResizeProvider = util.Coalesce(fromCRD.AWSGCP.StorageResizeProvider, "aws")
 
 	// logical backup config
 	result.LogicalBackupSchedule = util.Coalesce(fromCRD.LogicalBackup.Schedule, "30 00 * * *")
@@ -185,6 +218,8 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 	// debug config
 	result.DebugLogging = fromCRD.OperatorDebug.DebugLogging
 	result.EnableDBAccess = fromCRD.OperatorDebug.EnableDBAccess
+	result.TracingExporter = fromCRD.OperatorDebug.TracingExporter
+	result.TracingOTLPEndpoint = fromCRD.OperatorDebug.TracingOTLPEndpoint
 
 	// Teams API config
 	result.EnableTeamsAPI = fromCRD.TeamsAPI.EnableTeamsAPI
@@ -206,6 +241,11 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 	result.APIPort = util.CoalesceInt(fromCRD.LoggingRESTAPI.APIPort, 8080)
 	result.RingLogLines = util.CoalesceInt(fromCRD.LoggingRESTAPI.RingLogLines, 100)
 	result.ClusterHistoryEntries = util.CoalesceInt(fromCRD.LoggingRESTAPI.ClusterHistoryEntries, 1000)
+	result.ClusterHistoryStorage = fromCRD.LoggingRESTAPI.ClusterHistoryStorage
+	result.ClusterHistoryStoragePath = util.Coalesce(fromCRD.LoggingRESTAPI.ClusterHistoryStoragePath, "/var/lib/postgres-operator/history")
+	result.ClusterHistoryRetention = util.CoalesceDuration(time.Duration(fromCRD.LoggingRESTAPI.ClusterHistoryRetention), "168h")
+	result.APITokenSecretName = fromCRD.LoggingRESTAPI.APITokenSecretName
+	result.APITokenReviewUsers = fromCRD.LoggingRESTAPI.APITokenReviewUsers
 
 	// Scalyr config
 	result.ScalyrAPIKey = fromCRD.Scalyr.ScalyrAPIKey
@@ -242,10 +282,48 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 		panic(fmt.Errorf(msg, result.ConnectionPooler.User))
 	}
 
+	result.ConnectionPooler.Type = util.Coalesce(
+		fromCRD.ConnectionPooler.Type,
+		constants.ConnectionPoolerDefaultType)
+
+	result.ConnectionPooler.AuthMode = util.Coalesce(
+		fromCRD.ConnectionPooler.AuthMode,
+		constants.ConnectionPoolerDefaultAuthMode)
+
 	result.ConnectionPooler.Image = util.Coalesce(
 		fromCRD.ConnectionPooler.Image,
 		"registry.opensource.zalan.do/acid/pgbouncer")
 
+	result.ConnectionPooler.PgcatImage = util.Coalesce(
+		fromCRD.ConnectionPooler.PgcatImage,
+		constants.ConnectionPoolerPgcatImage)
+
+	result.ConnectionPooler.OdysseyImage = fromCRD.ConnectionPooler.OdysseyImage
+
+	result.ConnectionPooler.PgbouncerTemplatePath = util.Coalesce(
+		fromCRD.ConnectionPooler.PgbouncerTemplatePath,
+		constants.ConnectionPoolerPgbouncerTemplatePath)
+
+	result.ConnectionPooler.PgbouncerAuthFile = util.Coalesce(
+		fromCRD.ConnectionPooler.PgbouncerAuthFile,
+		constants.ConnectionPoolerPgbouncerAuthFile)
+
+	result.ConnectionPooler.PgbouncerStatsUsersPrefix = util.Coalesce(
+		fromCRD.ConnectionPooler.PgbouncerStatsUsersPrefix,
+		constants.ConnectionPoolerPgbouncerStatsUsersPrefix)
+
+	result.ConnectionPooler.PgbouncerTLSCertificateFile = util.Coalesce(
+		fromCRD.ConnectionPooler.PgbouncerTLSCertificateFile,
+		constants.ConnectionPoolerPgbouncerTLSCertificateFile)
+
+	result.ConnectionPooler.PgbouncerTLSPrivateKeyFile = util.Coalesce(
+		fromCRD.ConnectionPooler.PgbouncerTLSPrivateKeyFile,
+		constants.ConnectionPoolerPgbouncerTLSPrivateKeyFile)
+
+	if result.ConnectionPooler.Type == "odyssey" && result.ConnectionPooler.OdysseyImage == "" {
+		panic(fmt.Errorf("connection pooler type odyssey requires connection_pooler_odyssey_image to be set"))
+	}
+
 	result.ConnectionPooler.Mode = util.Coalesce(
 		fromCRD.ConnectionPooler.Mode,
 		constants.ConnectionPoolerDefaultMode)
@@ -270,5 +348,12 @@ func (c *Controller) importConfigurationFromCRD(fromCRD *acidv1.OperatorConfigur
 		fromCRD.ConnectionPooler.MaxDBConnections,
 		k8sutil.Int32ToPointer(constants.ConnectionPoolerMaxDBConnections))
 
+	result.ConnectionPooler.AutoscalingMetric = util.Coalesce(
+		fromCRD.ConnectionPooler.AutoscalingMetric,
+		constants.ConnectionPoolerAutoscalingMetric)
+
+	result.ConnectionPooler.SpreadAcrossZones = fromCRD.ConnectionPooler.SpreadAcrossZones
+	result.ConnectionPooler.SpreadAcrossNodes = fromCRD.ConnectionPooler.SpreadAcrossNodes
+
 	return result
 }
@@ -170,13 +170,17 @@ func (c *Controller) addCluster(lg *logrus.Entry, clusterName spec.NamespacedNam
 	cl.Run(c.stopCh)
 	teamName := strings.ToLower(cl.Spec.TeamID)
 
+	clusterLogs := ringlog.New(c.opConfig.RingLogLines)
+	clusterHistory := ringlog.New(c.opConfig.ClusterHistoryEntries)
+	c.loadClusterHistory(clusterName, clusterLogs, clusterHistory)
+
 	defer c.clustersMu.Unlock()
 	c.clustersMu.Lock()
 
 	c.teamClusters[teamName] = append(c.teamClusters[teamName], clusterName)
 	c.clusters[clusterName] = cl
-	c.clusterLogs[clusterName] = ringlog.New(c.opConfig.RingLogLines)
-	c.clusterHistory[clusterName] = ringlog.New(c.opConfig.ClusterHistoryEntries)
+	c.clusterLogs[clusterName] = clusterLogs
+	c.clusterHistory[clusterName] = clusterHistory
 
 	return cl, nil
 }
@@ -188,10 +192,10 @@ func (c *Controller) processEvent(event ClusterEvent) {
 
 	lg := c.logger.WithField("worker", event.WorkerID)
 
-	if event.EventType == EventAdd || event.EventType == EventSync || event.EventType == EventRepair {
-		clusterName = util.NameFromMeta(event.NewSpec.ObjectMeta)
-	} else {
+	if event.EventType == EventUpdate || event.EventType == EventDelete {
 		clusterName = util.NameFromMeta(event.OldSpec.ObjectMeta)
+	} else {
+		clusterName = util.NameFromMeta(event.NewSpec.ObjectMeta)
 	}
 	lg = lg.WithField("cluster-name", clusterName)
 
@@ -275,10 +279,18 @@ func (c *Controller) processEvent(event ClusterEvent) {
 		cl.Error = ""
 		lg.Infoln("cluster has been updated")
 
-		clHistory.Insert(&spec.Diff{
+		diff := &spec.Diff{
 			EventTime:   event.EventTime,
 			ProcessTime: time.Now(),
 			Diff:        util.Diff(event.OldSpec, event.NewSpec),
+		}
+		clHistory.Insert(diff)
+		c.markHistoryChanged(clusterName)
+		c.notifyWatchers(spec.WatchEvent{
+			Type:        spec.WatchEventHistory,
+			Time:        diff.ProcessTime,
+			ClusterName: clusterName,
+			Diff:        diff,
 		})
 	case EventDelete:
 		if !clusterFound {
@@ -310,6 +322,7 @@ func (c *Controller) processEvent(event ClusterEvent) {
 				}
 			}
 		}()
+		c.deleteClusterHistory(clusterName)
 
 		lg.Infof("cluster has been deleted")
 	case EventSync:
@@ -335,6 +348,31 @@ func (c *Controller) processEvent(event ClusterEvent) {
 		cl.Error = ""
 
 		lg.Infof("cluster has been synced")
+	case EventSwitchover, EventRestart, EventReload, EventRotatePasswords:
+		if !clusterFound {
+			lg.Warningln("cluster does not exist")
+			return
+		}
+		lg.Infof("%s of the cluster started", event.EventType)
+
+		c.curWorkerCluster.Store(event.WorkerID, cl)
+		switch event.EventType {
+		case EventSwitchover:
+			err = cl.ManualSwitchover(event.Candidate)
+		case EventRestart:
+			err = cl.RestartPostgres()
+		case EventReload:
+			err = cl.ReloadPostgres()
+		case EventRotatePasswords:
+			err = cl.RotatePasswords(event.NewSpec)
+		}
+		if err != nil {
+			lg.Errorf("%s of the cluster failed: %v", event.EventType, err)
+			c.eventRecorder.Eventf(cl.GetReference(), v1.EventTypeWarning, "API", "%s failed: %v", event.EventType, err)
+			return
+		}
+
+		lg.Infof("%s of the cluster done", event.EventType)
 	}
 }
 
@@ -530,6 +568,14 @@ func (c *Controller) postgresqlUpdate(prev, cur interface{}) {
 	pgOld := c.postgresqlCheck(prev)
 	pgNew := c.postgresqlCheck(cur)
 	if pgOld != nil && pgNew != nil {
+		if pgOld.Status != pgNew.Status {
+			c.notifyWatchers(spec.WatchEvent{
+				Type:        spec.WatchEventStatus,
+				Time:        time.Now(),
+				ClusterName: util.NameFromMeta(pgNew.ObjectMeta),
+				Status:      pgNew.Status.PostgresClusterStatus,
+			})
+		}
 		// Avoid the inifinite recursion for status updates
 		if reflect.DeepEqual(pgOld.Spec, pgNew.Spec) {
 			if reflect.DeepEqual(pgNew.Annotations, pgOld.Annotations) {
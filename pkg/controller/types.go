@@ -18,6 +18,12 @@ const (
 	EventDelete EventType = "DELETE"
 	EventSync   EventType = "SYNC"
 	EventRepair EventType = "REPAIR"
+
+	// events only queued through the REST API write endpoints
+	EventSwitchover      EventType = "SWITCHOVER"
+	EventRestart         EventType = "RESTART"
+	EventReload          EventType = "RELOAD"
+	EventRotatePasswords EventType = "ROTATE_PASSWORDS"
 )
 
 // ClusterEvent carries the payload of the Cluster TPR events.
@@ -28,4 +34,5 @@ type ClusterEvent struct {
 	OldSpec   *acidv1.Postgresql
 	NewSpec   *acidv1.Postgresql
 	WorkerID  uint32
+	Candidate string // optional switchover candidate
 }
@@ -33,10 +33,17 @@ func (c *Controller) makeClusterConfig() cluster.Config {
 		PgTeamMap:           &c.pgTeamMap,
 		InfrastructureRoles: infrastructureRoles,
 		PodServiceAccount:   c.PodServiceAccount,
+		VolumeMigrations:    c.volumeMigrations,
+		Tracer:              c.tracer,
+		TracingTransport:    c.tracingTransport,
+		NotifyWatchers:      c.notifyWatchers,
 	}
 }
 
 func (c *Controller) clusterWorkerID(clusterName spec.NamespacedName) uint32 {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+
 	workerID, ok := c.clusterWorkers[clusterName]
 	if ok {
 		return workerID
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/zalando/postgres-operator/pkg/spec"
+)
+
+// events buffered per watcher, a watcher falling further behind is disconnected
+const watchEventsBuffer = 100
+
+type clusterWatcher struct {
+	clusterName *spec.NamespacedName // nil to watch all clusters
+	events      chan spec.WatchEvent
+}
+
+// clusterWatchers distributes changes of clusters to the clients watching them via the API
+type clusterWatchers struct {
+	mu       sync.Mutex
+	nextID   uint64
+	watchers map[uint64]*clusterWatcher
+}
+
+func newClusterWatchers() *clusterWatchers {
+	return &clusterWatchers{
+		watchers: make(map[uint64]*clusterWatcher),
+	}
+}
+
+func (w *clusterWatchers) add(clusterName *spec.NamespacedName) (<-chan spec.WatchEvent, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	watcher := &clusterWatcher{
+		clusterName: clusterName,
+		events:      make(chan spec.WatchEvent, watchEventsBuffer),
+	}
+	w.watchers[id] = watcher
+
+	return watcher.events, func() {
+		w.remove(id)
+	}
+}
+
+func (w *clusterWatchers) remove(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if watcher, ok := w.watchers[id]; ok {
+		close(watcher.events)
+		delete(w.watchers, id)
+	}
+}
+
+// notify never blocks the operator, watchers not reading their events in time are dropped
+func (w *clusterWatchers) notify(event spec.WatchEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, watcher := range w.watchers {
+		if watcher.clusterName != nil && *watcher.clusterName != event.ClusterName {
+			continue
+		}
+		select {
+		case watcher.events <- event:
+		default:
+			close(watcher.events)
+			delete(w.watchers, id)
+		}
+	}
+}
+
+// WatchClusters streams changes of the status, the current process and the history of a single
+// cluster or of all clusters if no cluster is given. The returned function stops the watch.
+func (c *Controller) WatchClusters(namespace, cluster string) (<-chan spec.WatchEvent, func(), error) {
+	if cluster == "" {
+		events, stop := c.watchers.add(nil)
+		return events, stop, nil
+	}
+
+	clusterName := spec.NamespacedName{
+		Namespace: namespace,
+		Name:      cluster,
+	}
+
+	c.clustersMu.RLock()
+	_, ok := c.clusters[clusterName]
+	c.clustersMu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("could not find cluster")
+	}
+
+	events, stop := c.watchers.add(&clusterName)
+	return events, stop, nil
+}
+
+func (c *Controller) notifyWatchers(event spec.WatchEvent) {
+	c.watchers.notify(event)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/zalando/postgres-operator/pkg/cluster"
+	"github.com/zalando/postgres-operator/pkg/spec"
+)
+
+func TestWatchClusters(t *testing.T) {
+	controller := NewController(&spec.ControllerConfig{}, "watch-test")
+	first := spec.NamespacedName{Namespace: "default", Name: "acid-first"}
+	second := spec.NamespacedName{Namespace: "default", Name: "acid-second"}
+	controller.clusters[first] = &cluster.Cluster{}
+
+	if _, _, err := controller.WatchClusters(second.Namespace, second.Name); err == nil {
+		t.Errorf("expected error when watching an unknown cluster")
+	}
+
+	allEvents, stopAll, err := controller.WatchClusters("", "")
+	if err != nil {
+		t.Fatalf("could not watch all clusters: %v", err)
+	}
+	firstEvents, stopFirst, err := controller.WatchClusters(first.Namespace, first.Name)
+	if err != nil {
+		t.Fatalf("could not watch cluster: %v", err)
+	}
+
+	controller.notifyWatchers(spec.WatchEvent{Type: spec.WatchEventProcess, ClusterName: first, Process: "syncing cluster"})
+	controller.notifyWatchers(spec.WatchEvent{Type: spec.WatchEventStatus, ClusterName: second, Status: "Running"})
+
+	if len(allEvents) != 2 {
+		t.Errorf("expected 2 events for the watcher of all clusters, got %d", len(allEvents))
+	}
+	if len(firstEvents) != 1 {
+		t.Fatalf("expected 1 event for the watcher of a single cluster, got %d", len(firstEvents))
+	}
+	if event := <-firstEvents; event.ClusterName != first || event.Process != "syncing cluster" {
+		t.Errorf("unexpected event %#v", event)
+	}
+
+	// a watcher not reading its events is dropped instead of blocking the operator
+	for i := 0; i < watchEventsBuffer; i++ {
+		controller.notifyWatchers(spec.WatchEvent{Type: spec.WatchEventProcess, ClusterName: first})
+	}
+	for range allEvents {
+	}
+	if len(controller.watchers.watchers) != 1 {
+		t.Errorf("expected the slow watcher to be dropped, %d watchers left", len(controller.watchers.watchers))
+	}
+
+	stopFirst()
+	for range firstEvents {
+	}
+	if len(controller.watchers.watchers) != 0 {
+		t.Errorf("expected no watchers after stopping, got %d", len(controller.watchers.watchers))
+	}
+	// stopping a dropped watcher is fine
+	stopAll()
+}
@@ -3,6 +3,7 @@ package spec
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -20,6 +21,9 @@ type NamespacedName types.NamespacedName
 
 const fileWithNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 
+// ErrClusterNotFound is returned for requests about clusters the operator does not know
+var ErrClusterNotFound = errors.New("could not find cluster")
+
 // RoleOrigin contains the code of the origin of a role
 type RoleOrigin int
 
@@ -58,6 +62,8 @@ type PgUser struct {
 	AdminRole  string            `yaml:"admin_role"`
 	IsDbOwner  bool              `yaml:"is_db_owner"`
 	Deleted    bool              `yaml:"deleted"`
+	// password is taken from a secret referenced in the manifest
+	ExternalPassword bool `yaml:"-"`
 }
 
 func (user *PgUser) Valid() bool {
@@ -95,6 +101,23 @@ type Diff struct {
 	Diff        []string
 }
 
+// Types of the events sent to watchers of clusters
+const (
+	WatchEventStatus  = "status"
+	WatchEventProcess = "process"
+	WatchEventHistory = "history"
+)
+
+// WatchEvent describes a change of a cluster streamed to the watchers of the API
+type WatchEvent struct {
+	Type        string
+	Time        time.Time
+	ClusterName NamespacedName
+	Status      string `json:",omitempty"`
+	Process     string `json:",omitempty"`
+	Diff        *Diff  `json:",omitempty"`
+}
+
 // ControllerStatus describes status of the controller
 type ControllerStatus struct {
 	LastSyncTime    int64
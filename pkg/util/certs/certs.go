@@ -0,0 +1,184 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// clock skew tolerated between the operator and the database pods
+const notBeforeSkew = 5 * time.Minute
+
+// KeyPair holds a PEM encoded certificate together with its private key
+type KeyPair struct {
+	Certificate []byte
+	PrivateKey  []byte
+}
+
+// GenerateCA creates a self-signed certificate authority valid for the given duration
+func GenerateCA(commonName string, validity time.Duration, now time.Time) (*KeyPair, error) {
+	template, err := newTemplate(commonName, validity, now)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate private key: %v", err)
+	}
+
+	return encode(template, template, &key.PublicKey, key, key)
+}
+
+// IssueClientCertificate creates a certificate for TLS client authentication
+// signed by the given CA. PostgreSQL maps the common name to the role name.
+func IssueClientCertificate(ca *KeyPair, commonName string, validity time.Duration, now time.Time) (*KeyPair, error) {
+	caCert, err := ParseCertificate(ca.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CA certificate: %v", err)
+	}
+	caKey, err := parsePrivateKey(ca.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CA private key: %v", err)
+	}
+
+	template, err := newTemplate(commonName, validity, now)
+	if err != nil {
+		return nil, err
+	}
+	// never outlive the signing CA
+	if template.NotAfter.After(caCert.NotAfter) {
+		template.NotAfter = caCert.NotAfter
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate private key: %v", err)
+	}
+
+	return encode(template, caCert, &key.PublicKey, caKey, key)
+}
+
+// ParseCertificate decodes the first certificate of a PEM bundle
+func ParseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// SplitBundle returns the PEM encoded certificates of a bundle in their order, skipping anything else
+func SplitBundle(bundlePEM []byte) [][]byte {
+	certificates := make([][]byte, 0)
+	for {
+		var block *pem.Block
+		block, bundlePEM = pem.Decode(bundlePEM)
+		if block == nil {
+			return certificates
+		}
+		if block.Type == "CERTIFICATE" {
+			certificates = append(certificates, pem.EncodeToMemory(block))
+		}
+	}
+}
+
+// Bundle concatenates PEM encoded certificates
+func Bundle(certificates [][]byte) []byte {
+	return bytes.Join(certificates, nil)
+}
+
+// IsExpired returns true if the certificate can not be parsed or is not valid anymore
+func IsExpired(certPEM []byte, now time.Time) bool {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return true
+	}
+
+	return now.After(cert.NotAfter)
+}
+
+// NeedsRenewal returns true if the certificate can not be parsed, is not yet
+// valid or expires before now plus the renewal interval
+func NeedsRenewal(certPEM []byte, renewBefore time.Duration, now time.Time) bool {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return true
+	}
+
+	return now.Before(cert.NotBefore) || now.Add(renewBefore).After(cert.NotAfter)
+}
+
+// IsSignedBy checks if the certificate was issued by the given CA certificate
+func IsSignedBy(certPEM, caPEM []byte) bool {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return false
+	}
+	caCert, err := ParseCertificate(caPEM)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(cert.RawIssuer, caCert.RawSubject) && cert.CheckSignatureFrom(caCert) == nil
+}
+
+func newTemplate(commonName string, validity time.Duration, now time.Time) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate serial number: %v", err)
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-notBeforeSkew),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func encode(template, parent *x509.Certificate, pub *ecdsa.PublicKey, signer, key *ecdsa.PrivateKey) (*KeyPair, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, fmt.Errorf("could not create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal private key: %v", err)
+	}
+
+	return &KeyPair{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}),
+	}, nil
+}
+
+func parsePrivateKey(keyPEM []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return ecKey, nil
+}
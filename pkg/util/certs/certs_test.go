@@ -0,0 +1,119 @@
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestIssueClientCertificate(t *testing.T) {
+	now := time.Now()
+
+	ca, err := GenerateCA("acid-test-cluster", 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("could not generate CA: %v", err)
+	}
+
+	client, err := IssueClientCertificate(ca, "foo_user", 48*time.Hour, now)
+	if err != nil {
+		t.Fatalf("could not issue client certificate: %v", err)
+	}
+
+	cert, err := ParseCertificate(client.Certificate)
+	if err != nil {
+		t.Fatalf("could not parse client certificate: %v", err)
+	}
+	if cert.Subject.CommonName != "foo_user" {
+		t.Errorf("expected common name %q, got %q", "foo_user", cert.Subject.CommonName)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("expected client auth key usage, got %v", cert.ExtKeyUsage)
+	}
+
+	caCert, _ := ParseCertificate(ca.Certificate)
+	if cert.NotAfter.After(caCert.NotAfter) {
+		t.Errorf("client certificate expires after its CA: %v > %v", cert.NotAfter, caCert.NotAfter)
+	}
+
+	if !IsSignedBy(client.Certificate, ca.Certificate) {
+		t.Errorf("client certificate is not signed by the CA")
+	}
+
+	otherCA, _ := GenerateCA("acid-other-cluster", 24*time.Hour, now)
+	if IsSignedBy(client.Certificate, otherCA.Certificate) {
+		t.Errorf("client certificate must not be accepted by another CA")
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	ca, _ := GenerateCA("acid-test-cluster", 24*time.Hour, now)
+
+	tests := []struct {
+		subTest     string
+		certificate []byte
+		renewBefore time.Duration
+		now         time.Time
+		expected    bool
+	}{
+		{
+			subTest:     "valid certificate",
+			certificate: ca.Certificate,
+			renewBefore: time.Hour,
+			now:         now,
+			expected:    false,
+		},
+		{
+			subTest:     "certificate within renewal interval",
+			certificate: ca.Certificate,
+			renewBefore: 25 * time.Hour,
+			now:         now,
+			expected:    true,
+		},
+		{
+			subTest:     "expired certificate",
+			certificate: ca.Certificate,
+			renewBefore: time.Hour,
+			now:         now.Add(48 * time.Hour),
+			expected:    true,
+		},
+		{
+			subTest:     "invalid certificate",
+			certificate: []byte("foo"),
+			renewBefore: time.Hour,
+			now:         now,
+			expected:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		if result := NeedsRenewal(tt.certificate, tt.renewBefore, tt.now); result != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.subTest, tt.expected, result)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	now := time.Now()
+	current, _ := GenerateCA("acid-test-cluster", 48*time.Hour, now)
+	previous, _ := GenerateCA("acid-test-cluster", time.Hour, now)
+
+	bundle := Bundle([][]byte{current.Certificate, previous.Certificate})
+	certificates := SplitBundle(append(bundle, previous.PrivateKey...))
+	if len(certificates) != 2 {
+		t.Fatalf("expected 2 certificates in the bundle, got %d", len(certificates))
+	}
+	if string(certificates[0]) != string(current.Certificate) || string(certificates[1]) != string(previous.Certificate) {
+		t.Errorf("expected the certificates of the bundle in their order")
+	}
+	if len(SplitBundle([]byte("no certificate"))) != 0 {
+		t.Errorf("expected no certificates from invalid input")
+	}
+
+	if IsExpired(previous.Certificate, now) || !IsExpired(previous.Certificate, now.Add(2*time.Hour)) {
+		t.Errorf("expected the previous CA to expire after one hour")
+	}
+	if !IsExpired([]byte("no certificate"), now) {
+		t.Errorf("expected invalid certificates to count as expired")
+	}
+}
@@ -96,20 +96,28 @@ type InfrastructureRole struct {
 
 // Auth describes authentication specific configuration parameters
 type Auth struct {
-	SecretNameTemplate            StringTemplate        `name:"secret_name_template" default:"{username}.{cluster}.credentials.{tprkind}.{tprgroup}"`
-	PamRoleName                   string                `name:"pam_role_name" default:"zalandos"`
-	PamConfiguration              string                `name:"pam_configuration" default:"https://info.example.com/oauth2/tokeninfo?access_token= uid realm=/employees"`
-	TeamsAPIUrl                   string                `name:"teams_api_url" default:"https://teams.example.com/api/"`
-	OAuthTokenSecretName          spec.NamespacedName   `name:"oauth_token_secret_name" default:"postgresql-operator"`
-	InfrastructureRolesSecretName spec.NamespacedName   `name:"infrastructure_roles_secret_name"`
-	InfrastructureRoles           []*InfrastructureRole `name:"-"`
-	InfrastructureRolesDefs       string                `name:"infrastructure_roles_secrets"`
-	SuperUsername                 string                `name:"super_username" default:"postgres"`
-	ReplicationUsername           string                `name:"replication_username" default:"standby"`
-	AdditionalOwnerRoles          []string              `name:"additional_owner_roles" default:""`
-	EnablePasswordRotation        bool                  `name:"enable_password_rotation" default:"false"`
-	PasswordRotationInterval      uint32                `name:"password_rotation_interval" default:"90"`
-	PasswordRotationUserRetention uint32                `name:"password_rotation_user_retention" default:"180"`
+	SecretNameTemplate                  StringTemplate        `name:"secret_name_template" default:"{username}.{cluster}.credentials.{tprkind}.{tprgroup}"`
+	PamRoleName                         string                `name:"pam_role_name" default:"zalandos"`
+	PamConfiguration                    string                `name:"pam_configuration" default:"https://info.example.com/oauth2/tokeninfo?access_token= uid realm=/employees"`
+	TeamsAPIUrl                         string                `name:"teams_api_url" default:"https://teams.example.com/api/"`
+	OAuthTokenSecretName                spec.NamespacedName   `name:"oauth_token_secret_name" default:"postgresql-operator"`
+	InfrastructureRolesSecretName       spec.NamespacedName   `name:"infrastructure_roles_secret_name"`
+	InfrastructureRoles                 []*InfrastructureRole `name:"-"`
+	InfrastructureRolesDefs             string                `name:"infrastructure_roles_secrets"`
+	SuperUsername                       string                `name:"super_username" default:"postgres"`
+	ReplicationUsername                 string                `name:"replication_username" default:"standby"`
+	AdditionalOwnerRoles                []string              `name:"additional_owner_roles" default:""`
+	EnablePasswordRotation              bool                  `name:"enable_password_rotation" default:"false"`
+	PasswordRotationInterval            uint32                `name:"password_rotation_interval" default:"90"`
+	PasswordRotationUserRetention       uint32                `name:"password_rotation_user_retention" default:"180"`
+	ClientCertificateSecretNameTemplate StringTemplate        `name:"client_certificate_secret_name_template" default:"{username}.{cluster}.client-certificate.{tprkind}.{tprgroup}"`
+	ClientCASecretNameTemplate          StringTemplate        `name:"client_ca_secret_name_template" default:"{cluster}.client-ca.{tprkind}.{tprgroup}"`
+	ClientCertificateValidity           time.Duration         `name:"client_certificate_validity" default:"8760h"`
+	ClientCertificateRenewBefore        time.Duration         `name:"client_certificate_renew_before" default:"720h"`
+	EnableSecretConnectionKeys          bool                  `name:"enable_secret_connection_keys" default:"false"`
+	SecretConnectionKeyTemplate         StringTemplate        `name:"secret_connection_key_template" default:"{service}-{key}"`
+	APITokenSecretName                  spec.NamespacedName   `name:"api_token_secret_name"`
+	APITokenReviewUsers                 []string              `name:"api_token_review_users" default:""`
 }
 
 // Scalyr holds the configuration for the Scalyr Agent sidecar for log shipping:
@@ -144,9 +152,21 @@ type ConnectionPooler struct {
 	NumberOfInstances                    *int32 `name:"connection_pooler_number_of_instances" default:"2"`
 	Schema                               string `name:"connection_pooler_schema" default:"pooler"`
 	User                                 string `name:"connection_pooler_user" default:"pooler"`
+	Type                                 string `name:"connection_pooler_type" default:"pgbouncer"`
+	AuthMode                             string `name:"connection_pooler_auth_mode" default:"lookup"`
 	Image                                string `name:"connection_pooler_image" default:"registry.opensource.zalan.do/acid/pgbouncer"`
+	PgcatImage                           string `name:"connection_pooler_pgcat_image" default:"ghcr.io/postgresml/pgcat:v1.2.0"`
+	OdysseyImage                         string `name:"connection_pooler_odyssey_image"`
+	PgbouncerTemplatePath                string `name:"connection_pooler_pgbouncer_template_path" default:"/etc/pgbouncer/pgbouncer.ini.tmpl"`
+	PgbouncerAuthFile                    string `name:"connection_pooler_pgbouncer_auth_file" default:"/etc/pgbouncer/auth_file.txt"`
+	PgbouncerStatsUsersPrefix            string `name:"connection_pooler_pgbouncer_stats_users_prefix" default:"robot_"`
+	PgbouncerTLSCertificateFile          string `name:"connection_pooler_pgbouncer_tls_certificate_file" default:"/etc/ssl/certs/pgbouncer.crt"`
+	PgbouncerTLSPrivateKeyFile           string `name:"connection_pooler_pgbouncer_tls_private_key_file" default:"/etc/ssl/certs/pgbouncer.key"`
 	Mode                                 string `name:"connection_pooler_mode" default:"transaction"`
 	MaxDBConnections                     *int32 `name:"connection_pooler_max_db_connections" default:"60"`
+	AutoscalingMetric                    string `name:"connection_pooler_autoscaling_metric" default:"connection_pooler_client_connections"`
+	SpreadAcrossZones                    bool   `name:"connection_pooler_spread_across_zones" default:"false"`
+	SpreadAcrossNodes                    bool   `name:"connection_pooler_spread_across_nodes" default:"false"`
 	ConnectionPoolerDefaultCPURequest    string `name:"connection_pooler_default_cpu_request" default:"500m"`
 	ConnectionPoolerDefaultMemoryRequest string `name:"connection_pooler_default_memory_request" default:"100Mi"`
 	ConnectionPoolerDefaultCPULimit      string `name:"connection_pooler_default_cpu_limit" default:"1"`
@@ -185,8 +205,18 @@ type Config struct {
 	AdditionalSecretMountPath              string            `name:"additional_secret_mount_path" default:"/meta/credentials"`
 	EnableEBSGp3Migration                  bool              `name:"enable_ebs_gp3_migration" default:"false"`
 	EnableEBSGp3MigrationMaxSize           int64             `name:"enable_ebs_gp3_migration_max_size" default:"1000"`
+	EnableVolumeMigration                  bool              `name:"enable_volume_migration" default:"false"`
+	VolumeMigrationSourceType              string            `name:"volume_migration_source_type"`
+	VolumeMigrationTargetType              string            `name:"volume_migration_target_type"`
+	VolumeMigrationIops                    int64             `name:"volume_migration_iops" default:"0"`
+	VolumeMigrationThroughput              int64             `name:"volume_migration_throughput" default:"0"`
+	VolumeMigrationMaxSize                 int64             `name:"volume_migration_max_size" default:"0"`
+	VolumeMigrationMaxConcurrent           int               `name:"volume_migration_max_concurrent" default:"1"`
+	StorageResizeProvider                  string            `name:"storage_resize_provider" default:"aws"`
 	DebugLogging                           bool              `name:"debug_logging" default:"true"`
 	EnableDBAccess                         bool              `name:"enable_database_access" default:"true"`
+	TracingExporter                        string            `name:"tracing_exporter" default:""`
+	TracingOTLPEndpoint                    string            `name:"tracing_otlp_endpoint" default:""`
 	EnableTeamsAPI                         bool              `name:"enable_teams_api" default:"true"`
 	EnableTeamSuperuser                    bool              `name:"enable_team_superuser" default:"false"`
 	TeamAdminRole                          string            `name:"team_admin_role" default:"admin"`
@@ -204,6 +234,9 @@ type Config struct {
 	EnablePodAntiAffinity                  bool              `name:"enable_pod_antiaffinity" default:"false"`
 	PodAntiAffinityTopologyKey             string            `name:"pod_antiaffinity_topology_key" default:"kubernetes.io/hostname"`
 	StorageResizeMode                      string            `name:"storage_resize_mode" default:"pvc"`
+	StorageAutogrowTarget                  string            `name:"storage_autogrow_target" default:"status"`
+	EnableVolumeRebuild                    bool              `name:"enable_volume_rebuild" default:"false"`
+	PersistentVolumeClaimRetentionPolicy   map[string]string `name:"persistent_volume_claim_retention_policy" default:"when_deleted:Delete,when_scaled:Retain"`
 	EnableLoadBalancer                     *bool             `name:"enable_load_balancer"` // deprecated and kept for backward compatibility
 	ExternalTrafficPolicy                  string            `name:"external_traffic_policy" default:"Cluster"`
 	MasterDNSNameFormat                    StringTemplate    `name:"master_dns_name_format" default:"{cluster}.{namespace}.{hostedzone}"`
@@ -216,6 +249,9 @@ type Config struct {
 	APIPort                                int               `name:"api_port" default:"8080"`
 	RingLogLines                           int               `name:"ring_log_lines" default:"100"`
 	ClusterHistoryEntries                  int               `name:"cluster_history_entries" default:"1000"`
+	ClusterHistoryStorage                  string            `name:"cluster_history_storage" default:""`
+	ClusterHistoryStoragePath              string            `name:"cluster_history_storage_path" default:"/var/lib/postgres-operator/history"`
+	ClusterHistoryRetention                time.Duration     `name:"cluster_history_retention" default:"168h"`
 	TeamAPIRoleConfiguration               map[string]string `name:"team_api_role_configuration" default:"log_statement:all"`
 	PodTerminateGracePeriod                time.Duration     `name:"pod_terminate_grace_period" default:"5m"`
 	PodManagementPolicy                    string            `name:"pod_management_policy" default:"ordered_ready"`
@@ -305,5 +341,17 @@ func validate(cfg *Config) (err error) {
 		err = fmt.Errorf(msg, cfg.ConnectionPooler.User)
 	}
 
+	if cfg.ConnectionPooler.Type == "odyssey" && cfg.ConnectionPooler.OdysseyImage == "" {
+		err = fmt.Errorf("connection pooler type odyssey requires connection_pooler_odyssey_image to be set")
+	}
+
+	for key, policy := range cfg.PersistentVolumeClaimRetentionPolicy {
+		if key != "when_deleted" && key != "when_scaled" {
+			err = fmt.Errorf("unknown key %q in persistent_volume_claim_retention_policy", key)
+		} else if !strings.EqualFold(policy, "retain") && !strings.EqualFold(policy, "delete") {
+			err = fmt.Errorf("persistent_volume_claim_retention_policy %s must be Retain or Delete, got %q", key, policy)
+		}
+	}
+
 	return
 }
@@ -8,4 +8,5 @@ const (
 	KubeIAmAnnotation                  = "iam.amazonaws.com/role"
 	VolumeStorateProvisionerAnnotation = "pv.kubernetes.io/provisioned-by"
 	PostgresqlControllerAnnotationKey  = "acid.zalan.do/controller"
+	PostgresqlDebugLoggingAnnotation   = "acid.zalan.do/debug-logging"
 )
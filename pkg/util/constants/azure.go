@@ -0,0 +1,18 @@
+package constants
+
+import "time"
+
+// Azure specific constants used by other modules
+const (
+	// Azure Disk related constants
+	AzureDiskProvisioner = "kubernetes.io/azure-disk"
+	AzureDiskCSIDriver   = "disk.csi.azure.com"
+	AzureManagementURL   = "https://management.azure.com"
+	AzureDiskAPIVersion  = "2022-07-02"
+	AzureIMDSTokenURL    = "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https%3A%2F%2Fmanagement.azure.com%2F"
+	//https://learn.microsoft.com/en-us/rest/api/compute/disks/get
+	AzureDiskStateSucceeded       = "Succeeded"
+	AzureDiskStateFailed          = "Failed"
+	AzureVolumeResizeWaitInterval = 2 * time.Second
+	AzureVolumeResizeWaitTimeout  = 60 * time.Second
+)
@@ -0,0 +1,15 @@
+package constants
+
+import "time"
+
+// GCP specific constants used by other modules
+const (
+	// GCE persistent disk related constants
+	GCEPDProvisioner            = "kubernetes.io/gce-pd"
+	GCEPDCSIDriver              = "pd.csi.storage.gke.io"
+	GCEComputeAPIURL            = "https://compute.googleapis.com/compute/v1/"
+	GCEMetadataURL              = "http://metadata.google.internal/computeMetadata/v1/"
+	GCEOperationStatusDone      = "DONE"
+	GCEVolumeResizeWaitInterval = 2 * time.Second
+	GCEVolumeResizeWaitTimeout  = 60 * time.Second
+)
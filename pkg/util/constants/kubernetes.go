@@ -10,4 +10,13 @@ const (
 	QueueResyncPeriodPod  = 5 * time.Minute
 	QueueResyncPeriodTPR  = 5 * time.Minute
 	QueueResyncPeriodNode = 5 * time.Minute
+
+	RetainedVolumeClaimLabel = "retained-from-cluster"
+
+	VolumeSnapshotAPIGroup         = "snapshot.storage.k8s.io"
+	VolumeSnapshotKind             = "VolumeSnapshot"
+	VolumeSnapshotWaitInterval     = 2 * time.Second
+	VolumeSnapshotWaitTimeout      = 30 * time.Second
+	VolumeSnapshotDefaultInterval  = 24 * time.Hour
+	VolumeSnapshotDefaultRetention = 7
 )
@@ -5,14 +5,26 @@ const (
 	ConnectionPoolerUserName             = "pooler"
 	ConnectionPoolerSchemaName           = "pooler"
 	ConnectionPoolerDefaultType          = "pgbouncer"
+	ConnectionPoolerDefaultAuthMode      = "lookup"
+	ConnectionPoolerPgcatImage           = "ghcr.io/postgresml/pgcat:v1.2.0"
 	ConnectionPoolerDefaultMode          = "transaction"
 	ConnectionPoolerDefaultCpuRequest    = "500m"
 	ConnectionPoolerDefaultCpuLimit      = "1"
 	ConnectionPoolerDefaultMemoryRequest = "100Mi"
 	ConnectionPoolerDefaultMemoryLimit   = "100Mi"
 
+	// files and settings of the pgbouncer image kept in the rendered template
+	ConnectionPoolerPgbouncerTemplatePath       = "/etc/pgbouncer/pgbouncer.ini.tmpl"
+	ConnectionPoolerPgbouncerAuthFile           = "/etc/pgbouncer/auth_file.txt"
+	ConnectionPoolerPgbouncerStatsUsersPrefix   = "robot_"
+	ConnectionPoolerPgbouncerTLSCertificateFile = "/etc/ssl/certs/pgbouncer.crt"
+	ConnectionPoolerPgbouncerTLSPrivateKeyFile  = "/etc/ssl/certs/pgbouncer.key"
+
 	ConnectionPoolerContainer            = 0
 	ConnectionPoolerMaxDBConnections     = 60
 	ConnectionPoolerMaxClientConnections = 10000
 	ConnectionPoolerMinInstances         = 1
+
+	ConnectionPoolerAutoscalingMetric    = "connection_pooler_client_connections"
+	ConnectionPoolerTargetCPUUtilization = 80
 )
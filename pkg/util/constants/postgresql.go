@@ -8,11 +8,25 @@ const (
 	PostgresDataMount = "/home/postgres/pgdata"
 	PostgresDataPath  = PostgresDataMount + "/pgroot"
 
+	WALVolumeName    = "pgwal"
+	PostgresWALMount = "/home/postgres/pgwal"
+	PostgresWALPath  = PostgresWALMount + "/pg_wal"
+
+	TablespaceVolumeNamePrefix = "tablespace-"
+	PostgresTablespacesMount   = "/home/postgres/tablespaces"
+
 	PatroniPGParametersParameterName = "parameters"
+	// Patroni's default for maximum_lag_on_failover in bytes
+	PatroniDefaultMaximumLagOnFailover = 1048576
 
 	PostgresConnectRetryTimeout = 2 * time.Minute
 	PostgresConnectTimeout      = 15 * time.Second
 
 	ShmVolumeName = "dshm"
 	ShmVolumePath = "/dev/shm"
+
+	VolumeAutogrowDefaultThreshold = 80
+	VolumeAutogrowDefaultStep      = "10%"
+	// share of the volume size the filesystem may lose to its own metadata
+	VolumeAutogrowFilesystemOverheadPercent = 5
 )
@@ -5,3 +5,11 @@ type FilesystemResizer interface {
 	CanResizeFilesystem(fstype string) bool
 	ResizeFilesystem(deviceName string, commandExecutor func(string) (out string, err error)) error
 }
+
+// MountedFilesystemResizer is implemented by resizers which grow a filesystem
+// through its mount point instead of the device. Callers prefer it over
+// ResizeFilesystem when a resizer implements it.
+type MountedFilesystemResizer interface {
+	FilesystemResizer
+	ResizeMountedFilesystem(mountPoint string, commandExecutor func(string) (out string, err error)) error
+}
@@ -0,0 +1,40 @@
+package filesystems
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	xfs       = "xfs"
+	xfsGrowfs = "xfs_growfs"
+)
+
+// XFSResize implements the MountedFilesystemResizer interface for XFS.
+type XFSResize struct {
+}
+
+// CanResizeFilesystem checks whether XFSResize can resize this filesystem.
+func (c *XFSResize) CanResizeFilesystem(fstype string) bool {
+	return fstype == xfs
+}
+
+// ResizeFilesystem fails, since xfs_growfs only works on the mount point of a mounted filesystem.
+func (c *XFSResize) ResizeFilesystem(deviceName string, commandExecutor func(cmd string) (out string, err error)) error {
+	return fmt.Errorf("%s needs the mount point of the filesystem on %s", xfsGrowfs, deviceName)
+}
+
+// ResizeMountedFilesystem calls xfs_growfs to grow the filesystem to the size of the device. It does
+// nothing if there is no space to grow.
+func (c *XFSResize) ResizeMountedFilesystem(mountPoint string, commandExecutor func(cmd string) (out string, err error)) error {
+	command := fmt.Sprintf("%s %s 2>&1", xfsGrowfs, mountPoint)
+	out, err := commandExecutor(command)
+	if err != nil {
+		return err
+	}
+	// the geometry of the filesystem is printed on success, whether or not it grew
+	if strings.Contains(out, "meta-data=") {
+		return nil
+	}
+	return fmt.Errorf("unrecognized output: %q, assuming error", out)
+}
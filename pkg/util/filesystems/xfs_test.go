@@ -0,0 +1,56 @@
+package filesystems
+
+import (
+	"fmt"
+	"testing"
+)
+
+const xfsGrowfsOutput = `meta-data=/dev/nvme1n1           isize=512    agcount=4, agsize=655360 blks
+         =                       sectsz=512   attr=2, projid32bit=1
+data     =                       bsize=4096   blocks=2621440, imaxpct=25
+naming   =version 2              bsize=4096   ascii-ci=0, ftype=1
+log      =internal log           bsize=4096   blocks=2560, version=2
+realtime =none                   extsz=4096   blocks=0, rtextents=0
+data blocks changed from 2621440 to 5242880
+`
+
+func TestXFSResize(t *testing.T) {
+	resizer := &XFSResize{}
+
+	for fstype, expected := range map[string]bool{"xfs": true, "ext4": false, "btrfs": false} {
+		if resizer.CanResizeFilesystem(fstype) != expected {
+			t.Errorf("expected CanResizeFilesystem(%q) to be %t", fstype, expected)
+		}
+	}
+
+	if err := resizer.ResizeFilesystem("/dev/nvme1n1", func(cmd string) (string, error) {
+		t.Errorf("unexpected command %q without a mount point", cmd)
+		return "", nil
+	}); err == nil {
+		t.Errorf("expected an error resizing XFS by device")
+	}
+
+	tests := []struct {
+		output  string
+		execErr error
+		wantErr bool
+	}{
+		{xfsGrowfsOutput, nil, false},
+		{"xfs_growfs: /home/postgres/pgdata is not a mounted XFS filesystem\n", nil, true},
+		{"", fmt.Errorf("command terminated with exit code 1"), true},
+	}
+
+	for _, tt := range tests {
+		var command string
+		err := resizer.ResizeMountedFilesystem("/home/postgres/pgdata", func(cmd string) (string, error) {
+			command = cmd
+			return tt.output, tt.execErr
+		})
+		if command != "xfs_growfs /home/postgres/pgdata 2>&1" {
+			t.Errorf("unexpected command %q", command)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error for output %q: %v", tt.output, err)
+		}
+	}
+}
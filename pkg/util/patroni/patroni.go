@@ -25,8 +25,9 @@ const (
 	clusterPath  = "/cluster"
 	statusPath   = "/patroni"
 	restartPath  = "/restart"
+	reloadPath   = "/reload"
 	ApiPort      = 8008
-	timeout      = 30 * time.Second
+	Timeout      = 30 * time.Second
 )
 
 // Interface describe patroni methods
@@ -36,6 +37,8 @@ type Interface interface {
 	SetPostgresParameters(server *v1.Pod, options map[string]string) error
 	GetMemberData(server *v1.Pod) (MemberData, error)
 	Restart(server *v1.Pod) error
+	RestartNow(server *v1.Pod) error
+	Reload(server *v1.Pod) error
 	GetConfig(server *v1.Pod) (acidv1.Patroni, map[string]string, error)
 	SetConfig(server *v1.Pod, config map[string]interface{}) error
 }
@@ -51,7 +54,7 @@ func New(logger *logrus.Entry, client httpclient.HTTPClient) *Patroni {
 	if client == nil {
 
 		client = &http.Client{
-			Timeout: timeout,
+			Timeout: Timeout,
 		}
 
 	}
@@ -77,7 +80,16 @@ func apiURL(masterPod *v1.Pod) (string, error) {
 	return fmt.Sprintf("http://%s", net.JoinHostPort(ip.String(), strconv.Itoa(ApiPort))), nil
 }
 
-func (p *Patroni) httpPostOrPatch(method string, url string, body *bytes.Buffer) (err error) {
+func (p *Patroni) httpPostOrPatch(method string, url string, body *bytes.Buffer) error {
+	return p.httpRequest(method, url, body, http.StatusOK)
+}
+
+// httpPostScheduled accepts actions which Patroni only schedules, like restart and reload
+func (p *Patroni) httpPostScheduled(url string, body *bytes.Buffer) error {
+	return p.httpRequest(http.MethodPost, url, body, http.StatusOK, http.StatusAccepted)
+}
+
+func (p *Patroni) httpRequest(method string, url string, body *bytes.Buffer, expectedStatus ...int) (err error) {
 	request, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return fmt.Errorf("could not create request: %v", err)
@@ -102,15 +114,17 @@ func (p *Patroni) httpPostOrPatch(method string, url string, body *bytes.Buffer)
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("could not read response: %v", err)
+	for _, status := range expectedStatus {
+		if resp.StatusCode == status {
+			return nil
 		}
+	}
 
-		return fmt.Errorf("patroni returned '%s'", string(bodyBytes))
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response: %v", err)
 	}
-	return nil
+	return fmt.Errorf("patroni returned '%s'", string(bodyBytes))
 }
 
 func (p *Patroni) httpGet(url string) (string, error) {
@@ -221,6 +235,7 @@ type MemberData struct {
 	ServerVersion   int               `json:"server_version"`
 	PendingRestart  bool              `json:"pending_restart"`
 	ClusterUnlocked bool              `json:"cluster_unlocked"`
+	Pause           bool              `json:"pause"`
 	Patroni         MemberDataPatroni `json:"patroni"`
 }
 
@@ -271,7 +286,21 @@ func (p *Patroni) Restart(server *v1.Pod) error {
 	if err != nil {
 		return err
 	}
-	if err := p.httpPostOrPatch(http.MethodPost, apiURLString+restartPath, buf); err != nil {
+	if err := p.httpPostScheduled(apiURLString+restartPath, buf); err != nil {
+		return err
+	}
+	p.logger.Infof("Postgres server successfuly restarted in pod %s", server.Name)
+
+	return nil
+}
+
+// RestartNow method restarts instance via Patroni POST API call, even if no restart is pending.
+func (p *Patroni) RestartNow(server *v1.Pod) error {
+	apiURLString, err := apiURL(server)
+	if err != nil {
+		return err
+	}
+	if err := p.httpPostScheduled(apiURLString+restartPath, bytes.NewBufferString("{}")); err != nil {
 		return err
 	}
 	p.logger.Infof("Postgres server successfuly restarted in pod %s", server.Name)
@@ -279,6 +308,15 @@ func (p *Patroni) Restart(server *v1.Pod) error {
 	return nil
 }
 
+// Reload method reloads the Patroni and Postgres configuration of an instance via Patroni POST API call.
+func (p *Patroni) Reload(server *v1.Pod) error {
+	apiURLString, err := apiURL(server)
+	if err != nil {
+		return err
+	}
+	return p.httpPostScheduled(apiURLString+reloadPath, &bytes.Buffer{})
+}
+
 // GetClusterMembers read cluster data from patroni API
 func (p *Patroni) GetClusterMembers(server *v1.Pod) ([]ClusterMember, error) {
 
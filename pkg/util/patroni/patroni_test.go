@@ -283,3 +283,51 @@ func TestSetPostgresParameters(t *testing.T) {
 	}
 
 }
+
+func TestReload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r := ioutil.NopCloser(bytes.NewReader([]byte("reload scheduled")))
+
+	response := http.Response{
+		StatusCode: 202,
+		Body:       r,
+	}
+
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost || req.URL.String() != "http://192.168.100.1:8008/reload" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL.String())
+		}
+		return &response, nil
+	})
+
+	p := New(logger, mockClient)
+
+	err := p.Reload(newMockPod("192.168.100.1"))
+	if err != nil {
+		t.Errorf("could not call reload of Patroni: %v", err)
+	}
+}
+
+func TestSetConfigRejectsAccepted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := http.Response{
+		StatusCode: 202,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("accepted"))),
+	}
+
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	mockClient.EXPECT().Do(gomock.Any()).Return(&response, nil)
+
+	p := New(logger, mockClient)
+
+	// only restart and reload may be scheduled by Patroni
+	err := p.SetConfig(newMockPod("192.168.100.1"), map[string]interface{}{"ttl": 20})
+	if err == nil {
+		t.Errorf("expected an error for a config patch answered with 202")
+	}
+}
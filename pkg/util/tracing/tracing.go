@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the supported exporters
+const (
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// OTLP protocols, selected with the standard OpenTelemetry environment variables
+const (
+	protocolGRPC         = "grpc"
+	protocolHTTPProtobuf = "http/protobuf"
+)
+
+const (
+	instrumentationName = "github.com/zalando/postgres-operator"
+	shutdownTimeout     = 10 * time.Second
+)
+
+// Tracer creates spans with the OpenTelemetry SDK, which exports them in batches once they are finished.
+// A nil Tracer is valid and creates no spans, so callers do not need to check if tracing is enabled.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	logger   *logrus.Entry
+}
+
+// New creates a tracer for the given exporter, or no tracer at all if the exporter is empty.
+// Settings not given explicitly are read from the OTEL_* environment variables by the SDK.
+func New(exporter, endpoint, serviceName string, logger *logrus.Entry) (*Tracer, error) {
+	var (
+		spanExporter sdktrace.SpanExporter
+		err          error
+	)
+
+	switch exporter {
+	case "":
+		return nil, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		spanExporter, err = newOTLPExporter(endpoint)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s exporter: %v", exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over the default service name
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+		resource.WithFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("could not create tracing resource: %v", err)
+	}
+
+	return NewWithExporter(spanExporter, res, logger), nil
+}
+
+// NewWithExporter creates a tracer shipping spans to a custom exporter
+func NewWithExporter(exporter sdktrace.SpanExporter, res *resource.Resource, logger *logrus.Entry) *Tracer {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res))
+
+	return &Tracer{
+		provider: provider,
+		tracer:   provider.Tracer(instrumentationName),
+		logger:   logger,
+	}
+}
+
+// newOTLPExporter creates an OTLP exporter speaking the protocol set in OTEL_EXPORTER_OTLP_TRACES_PROTOCOL
+// or OTEL_EXPORTER_OTLP_PROTOCOL, which is HTTP with protobuf encoding by default
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+
+	var endpointURL *url.URL
+	if endpoint != "" {
+		var err error
+		if endpointURL, err = url.Parse(endpoint); err != nil || endpointURL.Host == "" {
+			return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+		}
+	}
+
+	switch protocol {
+	case "", protocolHTTPProtobuf:
+		options := []otlptracehttp.Option{}
+		if endpointURL != nil {
+			options = append(options, otlptracehttp.WithEndpoint(endpointURL.Host))
+			if endpointURL.Path != "" {
+				options = append(options, otlptracehttp.WithURLPath(endpointURL.Path))
+			}
+			if endpointURL.Scheme == "http" {
+				options = append(options, otlptracehttp.WithInsecure())
+			}
+		}
+		return otlptracehttp.New(context.Background(), options...)
+	case protocolGRPC:
+		options := []otlptracegrpc.Option{}
+		if endpointURL != nil {
+			options = append(options, otlptracegrpc.WithEndpoint(endpointURL.Host))
+			if endpointURL.Scheme == "http" {
+				options = append(options, otlptracegrpc.WithInsecure())
+			}
+		}
+		return otlptracegrpc.New(context.Background(), options...)
+	}
+
+	return nil, fmt.Errorf("unsupported OTLP protocol %q", protocol)
+}
+
+// Run waits until the stop channel is closed, then flushes the remaining spans and shuts the exporter down
+func (t *Tracer) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	<-stopCh
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := t.provider.Shutdown(ctx); err != nil {
+		t.logger.Warningf("could not flush remaining spans: %v", err)
+	}
+}
+
+// Start starts a new span as child of the span in the context, or a new trace if there is none
+func (t *Tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return t.tracer.Start(ctx, name, opts...)
+}
+
+// End finishes the span, setting its status from the error of the traced operation
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
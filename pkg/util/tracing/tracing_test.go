@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var logger = logrus.New().WithField("test", "tracing")
+
+// recordingExporter keeps the exported spans after the tracer is shut down
+type recordingExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (e recordingExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func stopTracer(tracer *Tracer) {
+	stopCh := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go tracer.Run(stopCh, wg)
+	close(stopCh)
+	wg.Wait()
+}
+
+func TestNilTracer(t *testing.T) {
+	tracer, err := New("", "", "postgres-operator", logger)
+	if err != nil || tracer != nil {
+		t.Fatalf("expected no tracer without exporter, got %v, %v", tracer, err)
+	}
+
+	// none of these must panic
+	ctx, span := tracer.Start(context.Background(), "sync")
+	_, child := tracer.Start(ctx, "sync secrets")
+	End(child, nil)
+	End(span, fmt.Errorf("failed"))
+	if span.IsRecording() {
+		t.Errorf("expected spans of a nil tracer not to be recorded")
+	}
+
+	if _, err := New("jaeger", "", "postgres-operator", logger); err == nil {
+		t.Errorf("expected error for unknown exporter")
+	}
+}
+
+func TestRunExportsSpans(t *testing.T) {
+	exporter := recordingExporter{tracetest.NewInMemoryExporter()}
+	tracer := NewWithExporter(exporter, resource.Empty(), logger)
+
+	ctx, root := tracer.Start(context.Background(), "sync")
+	_, child := tracer.Start(ctx, "sync secrets")
+	End(child, fmt.Errorf("could not sync secrets"))
+	End(root, nil)
+
+	stopTracer(tracer)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 exported spans, got %d", len(spans))
+	}
+	exportedChild, exportedRoot := spans[0], spans[1]
+	if exportedChild.SpanContext.TraceID() != exportedRoot.SpanContext.TraceID() {
+		t.Errorf("expected child to be part of the trace of its parent")
+	}
+	if exportedChild.Parent.SpanID() != exportedRoot.SpanContext.SpanID() {
+		t.Errorf("expected parent id %s, got %s", exportedRoot.SpanContext.SpanID(), exportedChild.Parent.SpanID())
+	}
+	if exportedRoot.Parent.IsValid() {
+		t.Errorf("expected root span without parent, got %s", exportedRoot.Parent.SpanID())
+	}
+	if exportedChild.Status.Code != codes.Error || exportedChild.Status.Description != "could not sync secrets" {
+		t.Errorf("expected error of the child span to be recorded, got %#v", exportedChild.Status)
+	}
+	if exportedRoot.Status.Code != codes.Ok {
+		t.Errorf("expected ok status of the root span, got %#v", exportedRoot.Status)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer server.Close()
+
+	tracer, err := New(ExporterOTLP, server.URL+"/custom/v1/traces", "postgres-operator", logger)
+	if err != nil {
+		t.Fatalf("could not create tracer: %v", err)
+	}
+	_, span := tracer.Start(context.Background(), "sync")
+	End(span, nil)
+	stopTracer(tracer)
+
+	select {
+	case r := <-requests:
+		if r.URL.Path != "/custom/v1/traces" {
+			t.Errorf("expected spans to be sent to the configured path, got %q", r.URL.Path)
+		}
+		if r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+	default:
+		t.Errorf("expected spans to be sent to the collector")
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", protocolGRPC)
+	if _, err := New(ExporterOTLP, "http://localhost:4317", "postgres-operator", logger); err != nil {
+		t.Errorf("could not create gRPC exporter: %v", err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "http/json")
+	if _, err := New(ExporterOTLP, "", "postgres-operator", logger); err == nil {
+		t.Errorf("expected error for unsupported protocol")
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "")
+	if _, err := New(ExporterOTLP, "localhost", "postgres-operator", logger); err == nil {
+		t.Errorf("expected error for endpoint without scheme")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/patroni" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewWithExporter(tracetest.NewInMemoryExporter(), resource.Empty(), logger)
+	tracer.provider.RegisterSpanProcessor(recorder)
+	var parent context.Context
+	client := &http.Client{Transport: NewTransport(nil, "patroni", func() context.Context { return parent })}
+
+	// not traced without a parent span
+	resp, err := client.Get(server.URL + "/patroni")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(recorder.Ended()) != 0 {
+		t.Errorf("expected no span without parent, got %d", len(recorder.Ended()))
+	}
+
+	parent, span := tracer.Start(context.Background(), "sync")
+	for _, path := range []string{"/patroni?token=secret", "/config"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 recorded spans, got %d", len(spans))
+	}
+	ok, failed := spans[0], spans[1]
+	if ok.Name() != "patroni GET /patroni" || ok.SpanKind() != trace.SpanKindClient || ok.Status().Code == codes.Error {
+		t.Errorf("unexpected span %q of kind %s with status %#v", ok.Name(), ok.SpanKind(), ok.Status())
+	}
+	if ok.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected request span to be a child of the active span")
+	}
+	statusCode := attribute.Int(string(semconv.HTTPStatusCodeKey), http.StatusOK)
+	found := false
+	for _, attr := range ok.Attributes() {
+		if attr == statusCode {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected status code attribute, got %v", ok.Attributes())
+	}
+	if failed.Name() != "patroni GET /config" || failed.Status().Code != codes.Error {
+		t.Errorf("expected server error to mark span %q as failed", failed.Name())
+	}
+}
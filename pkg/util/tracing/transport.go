@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport records a client span for every HTTP request, as child of the span in the context returned
+// by Parent. Requests made while there is no parent span are not traced.
+type Transport struct {
+	Base   http.RoundTripper
+	System string
+	Parent func() context.Context
+}
+
+// NewTransport wraps the given round tripper, using the default transport if it is nil
+func NewTransport(base http.RoundTripper, system string, parent func() context.Context) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		Base:   base,
+		System: system,
+		Parent: parent,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := t.Parent()
+	if ctx == nil {
+		return t.Base.RoundTrip(req)
+	}
+	parent := trace.SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return t.Base.RoundTrip(req)
+	}
+
+	// the query is left out on purpose, it can carry label selectors with personal data or tokens
+	_, span := parent.TracerProvider().Tracer(instrumentationName).Start(ctx,
+		fmt.Sprintf("%s %s %s", t.System, req.Method, req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String(t.System),
+			semconv.HTTPMethodKey.String(req.Method),
+			semconv.HTTPTargetKey.String(req.URL.Path),
+			semconv.NetPeerNameKey.String(req.URL.Host)))
+
+	resp, err := t.Base.RoundTrip(req)
+	spanErr := err
+	if err == nil {
+		span.SetAttributes(attribute.Int(string(semconv.HTTPStatusCodeKey), resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			spanErr = fmt.Errorf("%s returned %d", t.System, resp.StatusCode)
+		}
+	}
+	End(span, spanErr)
+
+	return resp, err
+}
@@ -0,0 +1,293 @@
+package volumes
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+	"github.com/zalando/postgres-operator/pkg/util/httpclient"
+	"github.com/zalando/postgres-operator/pkg/util/retryutil"
+)
+
+var azureDiskIDRegexp = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Compute/disks/[^/]+$`)
+
+// AzureDisk describes the properties of an Azure managed disk relevant for resizing.
+type AzureDisk struct {
+	ID     string
+	SKU    string
+	SizeGB int64
+	IOPS   int64
+	MBps   int64
+}
+
+// AzureDiskUpdate holds the properties of a managed disk to change, nil fields are left untouched.
+type AzureDiskUpdate struct {
+	SKU    *string
+	SizeGB *int64
+	IOPS   *int64
+	MBps   *int64
+}
+
+// AzureDisksClient defines the subset of the Azure compute API used by the AzureVolumeResizer.
+type AzureDisksClient interface {
+	GetDisk(diskID string) (*AzureDisk, error)
+	UpdateDisk(diskID string, update AzureDiskUpdate) error
+}
+
+// AzureVolumeResizer implements volume resizing interface for Azure managed disks.
+type AzureVolumeResizer struct {
+	Client AzureDisksClient
+}
+
+// ConnectToProvider creates a client for the Azure compute API authenticated with the
+// managed identity of the node the operator runs on.
+func (r *AzureVolumeResizer) ConnectToProvider() error {
+	r.Client = &azureRESTClient{httpClient: &http.Client{Timeout: 30 * time.Second}}
+	return nil
+}
+
+// IsConnectedToProvider checks if the Azure client is initialized.
+func (r *AzureVolumeResizer) IsConnectedToProvider() bool {
+	return r.Client != nil
+}
+
+// VolumeBelongsToProvider checks if the given persistent volume is backed by an Azure managed disk.
+func (r *AzureVolumeResizer) VolumeBelongsToProvider(pv *v1.PersistentVolume) bool {
+	if pv.Spec.AzureDisk != nil {
+		return pv.Annotations[constants.VolumeStorateProvisionerAnnotation] == constants.AzureDiskProvisioner
+	}
+	return pv.Spec.CSI != nil && pv.Spec.CSI.Driver == constants.AzureDiskCSIDriver
+}
+
+// ExtractVolumeID validates the resource id of a managed disk, e.g.
+// /subscriptions/s/resourceGroups/rg/providers/Microsoft.Compute/disks/d
+func (r *AzureVolumeResizer) ExtractVolumeID(volumeID string) (string, error) {
+	if !azureDiskIDRegexp.MatchString(volumeID) {
+		return "", fmt.Errorf("malformed Azure disk id %q", volumeID)
+	}
+	return volumeID, nil
+}
+
+// GetProviderVolumeID returns the resource id of the managed disk backing the volume
+func (r *AzureVolumeResizer) GetProviderVolumeID(pv *v1.PersistentVolume) (string, error) {
+	volumeID := ""
+	if pv.Spec.CSI != nil {
+		volumeID = pv.Spec.CSI.VolumeHandle
+	} else if pv.Spec.AzureDisk != nil {
+		volumeID = pv.Spec.AzureDisk.DataDiskURI
+	}
+	if volumeID == "" {
+		return "", fmt.Errorf("got empty volume id for volume %q", pv.Name)
+	}
+
+	return r.ExtractVolumeID(volumeID)
+}
+
+// DescribeVolumes returns the properties of the given managed disks.
+func (r *AzureVolumeResizer) DescribeVolumes(volumeIds []string) ([]VolumeProperties, error) {
+	if !r.IsConnectedToProvider() {
+		err := r.ConnectToProvider()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := []VolumeProperties{}
+	for _, volumeID := range volumeIds {
+		disk, err := r.Client.GetDisk(volumeID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get information about disk %q: %v", volumeID, err)
+		}
+		p = append(p, VolumeProperties{
+			VolumeID:   volumeID,
+			VolumeType: disk.SKU,
+			Size:       disk.SizeGB,
+			Iops:       disk.IOPS,
+			Throughput: disk.MBps,
+		})
+	}
+
+	return p, nil
+}
+
+// DescribeVolumeModifications returns no modifications, Azure applies them before UpdateDisk returns
+func (r *AzureVolumeResizer) DescribeVolumeModifications(volumeIds []string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+// ResizeVolume calls the Azure API to grow the managed disk if necessary. Managed disks cannot be shrunk.
+func (r *AzureVolumeResizer) ResizeVolume(volumeID string, newSize int64) error {
+	return r.ModifyVolume(volumeID, nil, &newSize, nil, nil)
+}
+
+// ModifyVolume changes size, SKU, IOPS and throughput of a managed disk. Ultra and Premium SSD v2
+// disks can neither be converted from nor to other SKUs, but are the only ones with configurable
+// IOPS and throughput.
+func (r *AzureVolumeResizer) ModifyVolume(volumeID string, newType *string, newSize *int64, iops *int64, throughput *int64) error {
+	disk, err := r.Client.GetDisk(volumeID)
+	if err != nil {
+		return fmt.Errorf("could not get information about the disk: %v", err)
+	}
+
+	update := AzureDiskUpdate{}
+	sku := disk.SKU
+	if newType != nil && *newType != "" && *newType != disk.SKU {
+		if azureDiskHasProvisionedPerformance(disk.SKU) || azureDiskHasProvisionedPerformance(*newType) {
+			return fmt.Errorf("could not change SKU of disk %q from %q to %q: not supported by Azure", volumeID, disk.SKU, *newType)
+		}
+		sku = *newType
+		update.SKU = &sku
+	}
+	if newSize != nil && *newSize != disk.SizeGB {
+		if *newSize < disk.SizeGB {
+			return fmt.Errorf("could not resize disk %q: shrinking from %dGi to %dGi is not supported", volumeID, disk.SizeGB, *newSize)
+		}
+		update.SizeGB = newSize
+	}
+	if iops != nil && *iops != disk.IOPS {
+		if !azureDiskHasProvisionedPerformance(sku) {
+			return fmt.Errorf("could not set IOPS of disk %q: not supported for SKU %q", volumeID, sku)
+		}
+		update.IOPS = iops
+	}
+	if throughput != nil && *throughput != disk.MBps {
+		if !azureDiskHasProvisionedPerformance(sku) {
+			return fmt.Errorf("could not set throughput of disk %q: not supported for SKU %q", volumeID, sku)
+		}
+		update.MBps = throughput
+	}
+
+	if update.SKU == nil && update.SizeGB == nil && update.IOPS == nil && update.MBps == nil {
+		// nothing to do
+		return nil
+	}
+	if err := r.Client.UpdateDisk(volumeID, update); err != nil {
+		return fmt.Errorf("could not modify persistent volume: %v", err)
+	}
+	return nil
+}
+
+// DisconnectFromProvider drops the Azure client
+func (r *AzureVolumeResizer) DisconnectFromProvider() error {
+	r.Client = nil
+	return nil
+}
+
+func azureDiskHasProvisionedPerformance(sku string) bool {
+	return sku == "UltraSSD_LRS" || sku == "PremiumV2_LRS"
+}
+
+// azureRESTClient talks to the Azure resource manager REST API using a token of the
+// managed identity obtained from the instance metadata service.
+type azureRESTClient struct {
+	httpClient httpclient.HTTPClient
+	token      accessToken
+}
+
+type azureDiskResource struct {
+	SKU *struct {
+		Name string `json:"name,omitempty"`
+	} `json:"sku,omitempty"`
+	Properties struct {
+		DiskSizeGB        *int64 `json:"diskSizeGB,omitempty"`
+		DiskIOPSReadWrite *int64 `json:"diskIOPSReadWrite,omitempty"`
+		DiskMBpsReadWrite *int64 `json:"diskMBpsReadWrite,omitempty"`
+		ProvisioningState string `json:"provisioningState,omitempty"`
+	} `json:"properties"`
+}
+
+func (c *azureRESTClient) header() (http.Header, error) {
+	if !c.token.valid() {
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   string `json:"expires_in"`
+		}
+		header := http.Header{"Metadata": []string{"true"}}
+		err := doJSONRequest(c.httpClient, http.MethodGet, constants.AzureIMDSTokenURL, header, nil, &token)
+		if err != nil {
+			return nil, fmt.Errorf("could not get access token: %v", err)
+		}
+		expiresIn, err := strconv.ParseInt(token.ExpiresIn, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse token expiry %q: %v", token.ExpiresIn, err)
+		}
+		c.token = accessToken{value: token.AccessToken, expires: time.Now().Add(time.Duration(expiresIn) * time.Second)}
+	}
+	return http.Header{"Authorization": []string{"Bearer " + c.token.value}}, nil
+}
+
+func (c *azureRESTClient) do(method, diskID string, body interface{}, result interface{}) error {
+	header, err := c.header()
+	if err != nil {
+		return err
+	}
+	url := constants.AzureManagementURL + diskID + "?api-version=" + constants.AzureDiskAPIVersion
+	return doJSONRequest(c.httpClient, method, url, header, body, result)
+}
+
+func (c *azureRESTClient) getDisk(diskID string) (*azureDiskResource, error) {
+	var disk azureDiskResource
+	if err := c.do(http.MethodGet, diskID, nil, &disk); err != nil {
+		return nil, err
+	}
+	return &disk, nil
+}
+
+// GetDisk fetches the managed disk resource
+func (c *azureRESTClient) GetDisk(diskID string) (*AzureDisk, error) {
+	resource, err := c.getDisk(diskID)
+	if err != nil {
+		return nil, err
+	}
+	disk := &AzureDisk{ID: diskID}
+	if resource.SKU != nil {
+		disk.SKU = resource.SKU.Name
+	}
+	if resource.Properties.DiskSizeGB != nil {
+		disk.SizeGB = *resource.Properties.DiskSizeGB
+	}
+	if resource.Properties.DiskIOPSReadWrite != nil {
+		disk.IOPS = *resource.Properties.DiskIOPSReadWrite
+	}
+	if resource.Properties.DiskMBpsReadWrite != nil {
+		disk.MBps = *resource.Properties.DiskMBpsReadWrite
+	}
+	return disk, nil
+}
+
+// UpdateDisk patches the managed disk and waits until the update is provisioned
+func (c *azureRESTClient) UpdateDisk(diskID string, update AzureDiskUpdate) error {
+	body := azureDiskResource{}
+	if update.SKU != nil {
+		body.SKU = &struct {
+			Name string `json:"name,omitempty"`
+		}{Name: *update.SKU}
+	}
+	body.Properties.DiskSizeGB = update.SizeGB
+	body.Properties.DiskIOPSReadWrite = update.IOPS
+	body.Properties.DiskMBpsReadWrite = update.MBps
+
+	if err := c.do(http.MethodPatch, diskID, body, nil); err != nil {
+		return err
+	}
+
+	return retryutil.Retry(constants.AzureVolumeResizeWaitInterval, constants.AzureVolumeResizeWaitTimeout,
+		func() (bool, error) {
+			disk, err := c.getDisk(diskID)
+			if err != nil {
+				return false, fmt.Errorf("could not get provisioning state: %v", err)
+			}
+			switch disk.Properties.ProvisioningState {
+			case constants.AzureDiskStateSucceeded:
+				return true, nil
+			case constants.AzureDiskStateFailed:
+				return false, fmt.Errorf("provisioning state of disk %q is failed", diskID)
+			}
+			return false, nil
+		})
+}
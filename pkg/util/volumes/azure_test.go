@@ -0,0 +1,181 @@
+package volumes
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+)
+
+type fakeAzureDisksClient struct {
+	disks   map[string]*AzureDisk
+	updates map[string]AzureDiskUpdate
+}
+
+func (c *fakeAzureDisksClient) GetDisk(diskID string) (*AzureDisk, error) {
+	disk, ok := c.disks[diskID]
+	if !ok {
+		return nil, fmt.Errorf("disk %q not found", diskID)
+	}
+	return disk, nil
+}
+
+func (c *fakeAzureDisksClient) UpdateDisk(diskID string, update AzureDiskUpdate) error {
+	c.updates[diskID] = update
+	return nil
+}
+
+func TestAzureGetProviderVolumeID(t *testing.T) {
+	resizer := &AzureVolumeResizer{}
+	diskID := "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Compute/disks/pvc-1"
+
+	csiPV := &v1.PersistentVolume{
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: constants.AzureDiskCSIDriver, VolumeHandle: diskID},
+			},
+		},
+	}
+	inTreePV := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{constants.VolumeStorateProvisionerAnnotation: constants.AzureDiskProvisioner},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				AzureDisk: &v1.AzureDiskVolumeSource{DiskName: "pvc-1", DataDiskURI: diskID},
+			},
+		},
+	}
+	ebsPV := &v1.PersistentVolume{
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				AWSElasticBlockStore: &v1.AWSElasticBlockStoreVolumeSource{VolumeID: "aws://eu-central-1b/vol-1"},
+			},
+		},
+	}
+
+	for _, pv := range []*v1.PersistentVolume{csiPV, inTreePV} {
+		if !resizer.VolumeBelongsToProvider(pv) {
+			t.Errorf("expected volume %v to belong to Azure", pv.Spec)
+		}
+		volumeID, err := resizer.GetProviderVolumeID(pv)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if volumeID != diskID {
+			t.Errorf("expected volume id %q, got %q", diskID, volumeID)
+		}
+	}
+
+	if resizer.VolumeBelongsToProvider(ebsPV) {
+		t.Errorf("expected EBS volume not to belong to Azure")
+	}
+	if _, err := resizer.ExtractVolumeID("vol-1"); err == nil {
+		t.Errorf("expected error for malformed disk id")
+	}
+}
+
+func TestAzureModifyVolume(t *testing.T) {
+	diskID := "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Compute/disks/d"
+	newSize := int64(20)
+	smallerSize := int64(5)
+	iops := int64(5000)
+	throughput := int64(300)
+	premium := "Premium_LRS"
+	ultra := "UltraSSD_LRS"
+
+	tests := []struct {
+		subTest    string
+		disk       AzureDisk
+		newType    *string
+		newSize    *int64
+		iops       *int64
+		throughput *int64
+		expected   *AzureDiskUpdate
+		err        bool
+	}{
+		{
+			subTest:  "grow disk and change sku",
+			disk:     AzureDisk{ID: diskID, SKU: "StandardSSD_LRS", SizeGB: 10},
+			newType:  &premium,
+			newSize:  &newSize,
+			expected: &AzureDiskUpdate{SKU: &premium, SizeGB: &newSize},
+		},
+		{
+			subTest: "shrinking is rejected",
+			disk:    AzureDisk{ID: diskID, SKU: "Premium_LRS", SizeGB: 10},
+			newSize: &smallerSize,
+			err:     true,
+		},
+		{
+			subTest: "conversion to ultra disk is rejected",
+			disk:    AzureDisk{ID: diskID, SKU: "Premium_LRS", SizeGB: 10},
+			newType: &ultra,
+			err:     true,
+		},
+		{
+			subTest: "iops for premium disk are rejected",
+			disk:    AzureDisk{ID: diskID, SKU: "Premium_LRS", SizeGB: 10},
+			iops:    &iops,
+			err:     true,
+		},
+		{
+			subTest:    "iops and throughput for ultra disk",
+			disk:       AzureDisk{ID: diskID, SKU: "UltraSSD_LRS", SizeGB: 10, IOPS: 3000, MBps: 125},
+			iops:       &iops,
+			throughput: &throughput,
+			expected:   &AzureDiskUpdate{IOPS: &iops, MBps: &throughput},
+		},
+		{
+			subTest:    "unchanged values are skipped",
+			disk:       AzureDisk{ID: diskID, SKU: "UltraSSD_LRS", SizeGB: 20, IOPS: 5000, MBps: 300},
+			newType:    &ultra,
+			newSize:    &newSize,
+			iops:       &iops,
+			throughput: &throughput,
+		},
+	}
+
+	for _, tt := range tests {
+		disk := tt.disk
+		client := &fakeAzureDisksClient{
+			disks:   map[string]*AzureDisk{diskID: &disk},
+			updates: make(map[string]AzureDiskUpdate),
+		}
+		resizer := &AzureVolumeResizer{Client: client}
+
+		err := resizer.ModifyVolume(diskID, tt.newType, tt.newSize, tt.iops, tt.throughput)
+		if tt.err != (err != nil) {
+			t.Errorf("%s: expected error %t, got %v", tt.subTest, tt.err, err)
+		}
+		update, updated := client.updates[diskID]
+		if (tt.expected != nil) != updated {
+			t.Errorf("%s: expected disk updated %t, got %t", tt.subTest, tt.expected != nil, updated)
+			continue
+		}
+		if tt.expected == nil {
+			continue
+		}
+		if fmt.Sprint(derefString(update.SKU), derefInt64(update.SizeGB), derefInt64(update.IOPS), derefInt64(update.MBps)) !=
+			fmt.Sprint(derefString(tt.expected.SKU), derefInt64(tt.expected.SizeGB), derefInt64(tt.expected.IOPS), derefInt64(tt.expected.MBps)) {
+			t.Errorf("%s: expected update %+v, got %+v", tt.subTest, *tt.expected, update)
+		}
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
+
+func derefInt64(i *int64) string {
+	if i == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(*i)
+}
@@ -3,8 +3,10 @@ package volumes
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	v1 "k8s.io/api/core/v1"
@@ -78,18 +80,45 @@ func (r *EBSVolumeResizer) DescribeVolumes(volumeIds []string) ([]VolumeProperti
 	}
 
 	for _, v := range volumeOutput.Volumes {
-		if *v.VolumeType == "gp3" {
-			p = append(p, VolumeProperties{VolumeID: *v.VolumeId, Size: *v.Size, VolumeType: *v.VolumeType, Iops: *v.Iops, Throughput: *v.Throughput})
-		} else if *v.VolumeType == "gp2" {
-			p = append(p, VolumeProperties{VolumeID: *v.VolumeId, Size: *v.Size, VolumeType: *v.VolumeType})
-		} else {
-			return nil, fmt.Errorf("Discovered unexpected volume type %s %s", *v.VolumeId, *v.VolumeType)
-		}
+		// iops and throughput are only reported for volume types supporting them
+		p = append(p, VolumeProperties{VolumeID: *v.VolumeId, Size: *v.Size, VolumeType: *v.VolumeType, Iops: aws.Int64Value(v.Iops), Throughput: aws.Int64Value(v.Throughput)})
 	}
 
 	return p, nil
 }
 
+// DescribeVolumeModifications returns the state of the latest modification of the given volumes, volumes
+// never modified are left out. EBS reports the new type already while a modification is optimizing.
+func (r *EBSVolumeResizer) DescribeVolumeModifications(volumeIds []string) (map[string]string, error) {
+	if !r.IsConnectedToProvider() {
+		err := r.ConnectToProvider()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	states := make(map[string]string)
+	startTimes := make(map[string]time.Time)
+	out, err := r.connection.DescribeVolumesModifications(&ec2.DescribeVolumesModificationsInput{VolumeIds: aws.StringSlice(volumeIds)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidVolumeModification.NotFound" {
+			return states, nil
+		}
+		return nil, fmt.Errorf("could not describe volume modifications: %v", err)
+	}
+	for _, modification := range out.VolumesModifications {
+		volumeID := aws.StringValue(modification.VolumeId)
+		startTime := aws.TimeValue(modification.StartTime)
+		if _, ok := states[volumeID]; ok && startTime.Before(startTimes[volumeID]) {
+			continue
+		}
+		states[volumeID] = aws.StringValue(modification.ModificationState)
+		startTimes[volumeID] = startTime
+	}
+
+	return states, nil
+}
+
 // ResizeVolume actually calls AWS API to resize the EBS volume if necessary.
 func (r *EBSVolumeResizer) ResizeVolume(volumeID string, newSize int64) error {
 	/* first check if the volume is already of a requested size */
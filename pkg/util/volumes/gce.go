@@ -0,0 +1,345 @@
+package volumes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+	"github.com/zalando/postgres-operator/pkg/util/httpclient"
+	"github.com/zalando/postgres-operator/pkg/util/retryutil"
+)
+
+var gceDiskIDRegexp = regexp.MustCompile(`^projects/[^/]+/(zones|regions)/[^/]+/disks/[^/]+$`)
+
+// GCEDisk describes the properties of a GCE persistent disk relevant for resizing.
+type GCEDisk struct {
+	ID                    string
+	Type                  string
+	SizeGb                int64
+	ProvisionedIops       int64
+	ProvisionedThroughput int64
+}
+
+// GCEDisksClient defines the subset of the GCE compute API used by the GCEVolumeResizer.
+type GCEDisksClient interface {
+	GetDisk(diskID string) (*GCEDisk, error)
+	ResizeDisk(diskID string, sizeGb int64) error
+	UpdateDisk(diskID string, iops *int64, throughput *int64) error
+}
+
+// GCEVolumeResizer implements volume resizing interface for GCE persistent disks.
+type GCEVolumeResizer struct {
+	Client  GCEDisksClient
+	Project string
+}
+
+// ConnectToProvider creates a client for the GCE compute API authenticated with the
+// service account of the node the operator runs on.
+func (r *GCEVolumeResizer) ConnectToProvider() error {
+	client := &gceRESTClient{httpClient: &http.Client{Timeout: 30 * time.Second}}
+	if r.Project == "" {
+		project, err := client.metadata("project/project-id")
+		if err != nil {
+			return fmt.Errorf("could not determine GCP project: %v", err)
+		}
+		r.Project = project
+	}
+	r.Client = client
+	return nil
+}
+
+// IsConnectedToProvider checks if the GCE client is initialized.
+func (r *GCEVolumeResizer) IsConnectedToProvider() bool {
+	return r.Client != nil
+}
+
+// VolumeBelongsToProvider checks if the given persistent volume is backed by a GCE persistent disk.
+func (r *GCEVolumeResizer) VolumeBelongsToProvider(pv *v1.PersistentVolume) bool {
+	if pv.Spec.GCEPersistentDisk != nil {
+		return pv.Annotations[constants.VolumeStorateProvisionerAnnotation] == constants.GCEPDProvisioner
+	}
+	return pv.Spec.CSI != nil && pv.Spec.CSI.Driver == constants.GCEPDCSIDriver
+}
+
+// ExtractVolumeID strips the compute API prefix from the disk URL, e.g.
+// https://www.googleapis.com/compute/v1/projects/p/zones/z/disks/d becomes projects/p/zones/z/disks/d
+func (r *GCEVolumeResizer) ExtractVolumeID(volumeID string) (string, error) {
+	idx := strings.Index(volumeID, "projects/")
+	if idx >= 0 {
+		volumeID = volumeID[idx:]
+	}
+	if !gceDiskIDRegexp.MatchString(volumeID) {
+		return "", fmt.Errorf("malformed GCE disk id %q", volumeID)
+	}
+	return volumeID, nil
+}
+
+// GetProviderVolumeID returns the disk path in the form projects/<project>/zones/<zone>/disks/<name>
+func (r *GCEVolumeResizer) GetProviderVolumeID(pv *v1.PersistentVolume) (string, error) {
+	if pv.Spec.CSI != nil {
+		if pv.Spec.CSI.VolumeHandle == "" {
+			return "", fmt.Errorf("got empty volume handle for volume %q", pv.Name)
+		}
+		return r.ExtractVolumeID(pv.Spec.CSI.VolumeHandle)
+	}
+	if pv.Spec.GCEPersistentDisk == nil || pv.Spec.GCEPersistentDisk.PDName == "" {
+		return "", fmt.Errorf("got empty disk name for volume %q", pv.Name)
+	}
+
+	zone := pv.Labels[v1.LabelTopologyZone]
+	if zone == "" {
+		zone = pv.Labels[v1.LabelFailureDomainBetaZone]
+	}
+	if zone == "" {
+		return "", fmt.Errorf("could not determine zone of volume %q", pv.Name)
+	}
+	// regional disks are labeled with all zones they are replicated to, separated by "__"
+	if zones := strings.Split(zone, "__"); len(zones) > 1 {
+		separator := strings.LastIndex(zones[0], "-")
+		if separator <= 0 {
+			return "", fmt.Errorf("could not determine region from zone %q of volume %q", zones[0], pv.Name)
+		}
+		region := zones[0][:separator]
+		return fmt.Sprintf("projects/%s/regions/%s/disks/%s", r.Project, region, pv.Spec.GCEPersistentDisk.PDName), nil
+	}
+
+	return fmt.Sprintf("projects/%s/zones/%s/disks/%s", r.Project, zone, pv.Spec.GCEPersistentDisk.PDName), nil
+}
+
+// DescribeVolumes returns the properties of the given GCE persistent disks.
+func (r *GCEVolumeResizer) DescribeVolumes(volumeIds []string) ([]VolumeProperties, error) {
+	if !r.IsConnectedToProvider() {
+		err := r.ConnectToProvider()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := []VolumeProperties{}
+	for _, volumeID := range volumeIds {
+		disk, err := r.Client.GetDisk(volumeID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get information about disk %q: %v", volumeID, err)
+		}
+		p = append(p, VolumeProperties{
+			VolumeID:   volumeID,
+			VolumeType: disk.Type,
+			Size:       disk.SizeGb,
+			Iops:       disk.ProvisionedIops,
+			Throughput: disk.ProvisionedThroughput,
+		})
+	}
+
+	return p, nil
+}
+
+// DescribeVolumeModifications returns no modifications, the type of a GCE disk is never changed
+func (r *GCEVolumeResizer) DescribeVolumeModifications(volumeIds []string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+// ResizeVolume calls the compute API to grow the disk if necessary. GCE disks cannot be shrunk.
+func (r *GCEVolumeResizer) ResizeVolume(volumeID string, newSize int64) error {
+	disk, err := r.Client.GetDisk(volumeID)
+	if err != nil {
+		return fmt.Errorf("could not get information about the disk: %v", err)
+	}
+	if disk.SizeGb == newSize {
+		// nothing to do
+		return nil
+	}
+	if disk.SizeGb > newSize {
+		return fmt.Errorf("could not resize disk %q: shrinking from %dGi to %dGi is not supported", volumeID, disk.SizeGb, newSize)
+	}
+	if err := r.Client.ResizeDisk(volumeID, newSize); err != nil {
+		return fmt.Errorf("could not resize persistent volume: %v", err)
+	}
+	return nil
+}
+
+// ModifyVolume resizes the disk and changes provisioned IOPS and throughput. The type of
+// an existing GCE disk cannot be changed, IOPS can only be provisioned for pd-extreme and
+// hyperdisk types, throughput only for hyperdisk types.
+func (r *GCEVolumeResizer) ModifyVolume(volumeID string, newType *string, newSize *int64, iops *int64, throughput *int64) error {
+	disk, err := r.Client.GetDisk(volumeID)
+	if err != nil {
+		return fmt.Errorf("could not get information about the disk: %v", err)
+	}
+	if newType != nil && *newType != "" && *newType != disk.Type {
+		return fmt.Errorf("could not change type of disk %q from %q to %q: not supported by GCE", volumeID, disk.Type, *newType)
+	}
+	if iops != nil && *iops == disk.ProvisionedIops {
+		iops = nil
+	}
+	if throughput != nil && *throughput == disk.ProvisionedThroughput {
+		throughput = nil
+	}
+	if iops != nil && disk.Type != "pd-extreme" && !strings.HasPrefix(disk.Type, "hyperdisk-") {
+		return fmt.Errorf("could not set IOPS of disk %q: not supported for disk type %q", volumeID, disk.Type)
+	}
+	if throughput != nil && !strings.HasPrefix(disk.Type, "hyperdisk-") {
+		return fmt.Errorf("could not set throughput of disk %q: not supported for disk type %q", volumeID, disk.Type)
+	}
+
+	if newSize != nil && *newSize != disk.SizeGb {
+		if err := r.ResizeVolume(volumeID, *newSize); err != nil {
+			return err
+		}
+	}
+	if iops != nil || throughput != nil {
+		if err := r.Client.UpdateDisk(volumeID, iops, throughput); err != nil {
+			return fmt.Errorf("could not modify persistent volume: %v", err)
+		}
+	}
+	return nil
+}
+
+// DisconnectFromProvider drops the GCE client
+func (r *GCEVolumeResizer) DisconnectFromProvider() error {
+	r.Client = nil
+	return nil
+}
+
+// gceRESTClient talks to the GCE compute REST API using the token of the default
+// service account obtained from the metadata server.
+type gceRESTClient struct {
+	httpClient httpclient.HTTPClient
+	token      accessToken
+}
+
+type gceDiskResource struct {
+	Name                  string `json:"name"`
+	Type                  string `json:"type"`
+	SizeGb                int64  `json:"sizeGb,string"`
+	ProvisionedIops       int64  `json:"provisionedIops,string,omitempty"`
+	ProvisionedThroughput int64  `json:"provisionedThroughput,string,omitempty"`
+}
+
+type gceOperation struct {
+	SelfLink string `json:"selfLink"`
+	Status   string `json:"status"`
+	Error    *struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error,omitempty"`
+}
+
+func (c *gceRESTClient) metadata(path string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, constants.GCEMetadataURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned status %d: %s", resp.StatusCode, string(data))
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (c *gceRESTClient) header() (http.Header, error) {
+	if !c.token.valid() {
+		var token struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int64  `json:"expires_in"`
+		}
+		header := http.Header{"Metadata-Flavor": []string{"Google"}}
+		err := doJSONRequest(c.httpClient, http.MethodGet, constants.GCEMetadataURL+"instance/service-accounts/default/token", header, nil, &token)
+		if err != nil {
+			return nil, fmt.Errorf("could not get access token: %v", err)
+		}
+		c.token = accessToken{value: token.AccessToken, expires: time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)}
+	}
+	return http.Header{"Authorization": []string{"Bearer " + c.token.value}}, nil
+}
+
+func (c *gceRESTClient) do(method, url string, body interface{}, result interface{}) error {
+	header, err := c.header()
+	if err != nil {
+		return err
+	}
+	return doJSONRequest(c.httpClient, method, url, header, body, result)
+}
+
+// GetDisk fetches the disk resource from the compute API
+func (c *gceRESTClient) GetDisk(diskID string) (*GCEDisk, error) {
+	var disk gceDiskResource
+	if err := c.do(http.MethodGet, constants.GCEComputeAPIURL+diskID, nil, &disk); err != nil {
+		return nil, err
+	}
+	return &GCEDisk{
+		ID:                    diskID,
+		Type:                  disk.Type[strings.LastIndex(disk.Type, "/")+1:],
+		SizeGb:                disk.SizeGb,
+		ProvisionedIops:       disk.ProvisionedIops,
+		ProvisionedThroughput: disk.ProvisionedThroughput,
+	}, nil
+}
+
+// ResizeDisk grows the disk and waits for the operation to finish
+func (c *gceRESTClient) ResizeDisk(diskID string, sizeGb int64) error {
+	body := map[string]string{"sizeGb": fmt.Sprintf("%d", sizeGb)}
+	var op gceOperation
+	if err := c.do(http.MethodPost, constants.GCEComputeAPIURL+diskID+"/resize", body, &op); err != nil {
+		return err
+	}
+	return c.waitForOperation(op)
+}
+
+// UpdateDisk changes provisioned IOPS and throughput and waits for the operation to finish
+func (c *gceRESTClient) UpdateDisk(diskID string, iops *int64, throughput *int64) error {
+	body := map[string]string{}
+	paths := []string{}
+	if iops != nil {
+		body["provisionedIops"] = fmt.Sprintf("%d", *iops)
+		paths = append(paths, "paths=provisionedIops")
+	}
+	if throughput != nil {
+		body["provisionedThroughput"] = fmt.Sprintf("%d", *throughput)
+		paths = append(paths, "paths=provisionedThroughput")
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	var op gceOperation
+	url := constants.GCEComputeAPIURL + diskID + "?" + strings.Join(paths, "&")
+	if err := c.do(http.MethodPatch, url, body, &op); err != nil {
+		return err
+	}
+	return c.waitForOperation(op)
+}
+
+func (c *gceRESTClient) waitForOperation(op gceOperation) error {
+	return retryutil.Retry(constants.GCEVolumeResizeWaitInterval, constants.GCEVolumeResizeWaitTimeout,
+		func() (bool, error) {
+			if op.Status == constants.GCEOperationStatusDone {
+				if op.Error != nil && len(op.Error.Errors) > 0 {
+					return false, fmt.Errorf("operation failed: %s", op.Error.Errors[0].Message)
+				}
+				return true, nil
+			}
+			if op.SelfLink == "" {
+				return false, fmt.Errorf("operation has no self link")
+			}
+			if err := c.do(http.MethodGet, op.SelfLink, nil, &op); err != nil {
+				return false, fmt.Errorf("could not get operation status: %v", err)
+			}
+			return false, nil
+		})
+}
@@ -0,0 +1,229 @@
+package volumes
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zalando/postgres-operator/pkg/util/constants"
+)
+
+type fakeGCEDisksClient struct {
+	disks   map[string]*GCEDisk
+	resized map[string]int64
+	updated map[string][2]*int64
+}
+
+func newFakeGCEDisksClient(disks ...*GCEDisk) *fakeGCEDisksClient {
+	client := &fakeGCEDisksClient{
+		disks:   make(map[string]*GCEDisk),
+		resized: make(map[string]int64),
+		updated: make(map[string][2]*int64),
+	}
+	for _, disk := range disks {
+		client.disks[disk.ID] = disk
+	}
+	return client
+}
+
+func (c *fakeGCEDisksClient) GetDisk(diskID string) (*GCEDisk, error) {
+	disk, ok := c.disks[diskID]
+	if !ok {
+		return nil, fmt.Errorf("disk %q not found", diskID)
+	}
+	return disk, nil
+}
+
+func (c *fakeGCEDisksClient) ResizeDisk(diskID string, sizeGb int64) error {
+	c.resized[diskID] = sizeGb
+	return nil
+}
+
+func (c *fakeGCEDisksClient) UpdateDisk(diskID string, iops *int64, throughput *int64) error {
+	c.updated[diskID] = [2]*int64{iops, throughput}
+	return nil
+}
+
+func TestGCEGetProviderVolumeID(t *testing.T) {
+	resizer := &GCEVolumeResizer{Project: "my-project"}
+
+	tests := []struct {
+		subTest  string
+		pv       *v1.PersistentVolume
+		expected string
+		err      bool
+	}{
+		{
+			subTest: "csi volume",
+			pv: &v1.PersistentVolume{
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						CSI: &v1.CSIPersistentVolumeSource{
+							Driver:       constants.GCEPDCSIDriver,
+							VolumeHandle: "projects/other/zones/europe-west1-b/disks/pvc-1",
+						},
+					},
+				},
+			},
+			expected: "projects/other/zones/europe-west1-b/disks/pvc-1",
+		},
+		{
+			subTest: "in-tree zonal volume",
+			pv: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1.LabelTopologyZone: "europe-west1-b"},
+				},
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						GCEPersistentDisk: &v1.GCEPersistentDiskVolumeSource{PDName: "pvc-2"},
+					},
+				},
+			},
+			expected: "projects/my-project/zones/europe-west1-b/disks/pvc-2",
+		},
+		{
+			subTest: "in-tree regional volume",
+			pv: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1.LabelTopologyZone: "europe-west1-b__europe-west1-c"},
+				},
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						GCEPersistentDisk: &v1.GCEPersistentDiskVolumeSource{PDName: "pvc-3"},
+					},
+				},
+			},
+			expected: "projects/my-project/regions/europe-west1/disks/pvc-3",
+		},
+		{
+			subTest: "in-tree regional volume with malformed zone",
+			pv: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1.LabelTopologyZone: "zone__other"},
+				},
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						GCEPersistentDisk: &v1.GCEPersistentDiskVolumeSource{PDName: "pvc-5"},
+					},
+				},
+			},
+			err: true,
+		},
+		{
+			subTest: "in-tree volume without zone",
+			pv: &v1.PersistentVolume{
+				Spec: v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						GCEPersistentDisk: &v1.GCEPersistentDiskVolumeSource{PDName: "pvc-4"},
+					},
+				},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		volumeID, err := resizer.GetProviderVolumeID(tt.pv)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected error, got volume id %q", tt.subTest, volumeID)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.subTest, err)
+		}
+		if volumeID != tt.expected {
+			t.Errorf("%s: expected volume id %q, got %q", tt.subTest, tt.expected, volumeID)
+		}
+	}
+}
+
+func TestGCEModifyVolume(t *testing.T) {
+	diskID := "projects/p/zones/z/disks/d"
+	newSize := int64(20)
+	smallerSize := int64(5)
+	iops := int64(5000)
+	throughput := int64(300)
+	pdSSD := "pd-ssd"
+	pdBalanced := "pd-balanced"
+
+	tests := []struct {
+		subTest    string
+		disk       GCEDisk
+		newType    *string
+		newSize    *int64
+		iops       *int64
+		throughput *int64
+		resized    bool
+		updated    bool
+		err        bool
+	}{
+		{
+			subTest: "grow disk",
+			disk:    GCEDisk{ID: diskID, Type: "pd-ssd", SizeGb: 10},
+			newType: &pdSSD,
+			newSize: &newSize,
+			resized: true,
+		},
+		{
+			subTest: "shrinking is rejected",
+			disk:    GCEDisk{ID: diskID, Type: "pd-ssd", SizeGb: 10},
+			newSize: &smallerSize,
+			err:     true,
+		},
+		{
+			subTest: "type change is rejected",
+			disk:    GCEDisk{ID: diskID, Type: "pd-ssd", SizeGb: 10},
+			newType: &pdBalanced,
+			err:     true,
+		},
+		{
+			subTest: "iops for pd-ssd are rejected",
+			disk:    GCEDisk{ID: diskID, Type: "pd-ssd", SizeGb: 10},
+			iops:    &iops,
+			err:     true,
+		},
+		{
+			subTest:    "throughput for pd-extreme is rejected",
+			disk:       GCEDisk{ID: diskID, Type: "pd-extreme", SizeGb: 10},
+			throughput: &throughput,
+			err:        true,
+		},
+		{
+			subTest:    "iops and throughput for hyperdisk",
+			disk:       GCEDisk{ID: diskID, Type: "hyperdisk-balanced", SizeGb: 10, ProvisionedIops: 3000, ProvisionedThroughput: 140},
+			newSize:    &newSize,
+			iops:       &iops,
+			throughput: &throughput,
+			resized:    true,
+			updated:    true,
+		},
+		{
+			subTest:    "unchanged values are skipped",
+			disk:       GCEDisk{ID: diskID, Type: "hyperdisk-balanced", SizeGb: 20, ProvisionedIops: 5000, ProvisionedThroughput: 300},
+			newSize:    &newSize,
+			iops:       &iops,
+			throughput: &throughput,
+		},
+	}
+
+	for _, tt := range tests {
+		disk := tt.disk
+		client := newFakeGCEDisksClient(&disk)
+		resizer := &GCEVolumeResizer{Client: client, Project: "p"}
+
+		err := resizer.ModifyVolume(diskID, tt.newType, tt.newSize, tt.iops, tt.throughput)
+		if tt.err != (err != nil) {
+			t.Errorf("%s: expected error %t, got %v", tt.subTest, tt.err, err)
+		}
+		if _, resized := client.resized[diskID]; resized != tt.resized {
+			t.Errorf("%s: expected disk resized %t, got %t", tt.subTest, tt.resized, resized)
+		}
+		if _, updated := client.updated[diskID]; updated != tt.updated {
+			t.Errorf("%s: expected disk updated %t, got %t", tt.subTest, tt.updated, updated)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package volumes
+
+import "sync"
+
+// MigrationLimiter bounds the number of volumes migrating to another type at the same time. It is shared
+// by all clusters of an operator, a nil limiter or a limit below one does not restrict migrations.
+type MigrationLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	volumes map[string]bool
+}
+
+// NewMigrationLimiter creates a limiter allowing the given number of concurrent migrations
+func NewMigrationLimiter(limit int) *MigrationLimiter {
+	return &MigrationLimiter{
+		limit:   limit,
+		volumes: make(map[string]bool),
+	}
+}
+
+// Acquire registers the migration of a volume. It returns false when the limit is reached,
+// volumes already registered are always accepted.
+func (l *MigrationLimiter) Acquire(volumeID string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.volumes[volumeID] {
+		return true
+	}
+	if l.limit > 0 && len(l.volumes) >= l.limit {
+		return false
+	}
+	l.volumes[volumeID] = true
+	return true
+}
+
+// Register takes over the migration of a volume started before the operator was restarted. It ignores
+// the limit, as the migration is already running.
+func (l *MigrationLimiter) Register(volumeID string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.volumes[volumeID] = true
+}
+
+// Release removes a volume whose migration finished or was abandoned
+func (l *MigrationLimiter) Release(volumeID string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.volumes, volumeID)
+}
+
+// InProgress returns the number of volumes currently migrating
+func (l *MigrationLimiter) InProgress() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.volumes)
+}
@@ -0,0 +1,71 @@
+package volumes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/zalando/postgres-operator/pkg/util/httpclient"
+)
+
+// accessToken caches an OAuth token obtained from the metadata service of the
+// cloud provider the operator is running in.
+type accessToken struct {
+	value   string
+	expires time.Time
+}
+
+func (t *accessToken) valid() bool {
+	return t.value != "" && time.Now().Add(time.Minute).Before(t.expires)
+}
+
+// doJSONRequest sends the request with an optional JSON body and decodes the
+// JSON response into result unless it is nil.
+func doJSONRequest(client httpclient.HTTPClient, method, url string, header http.Header, body interface{}, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("could not marshal request body: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("could not create request: %v", err)
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send %s request to %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read response: %v", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s request to %s failed with status %d: %s", method, url, resp.StatusCode, string(data))
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err = json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("could not unmarshal response: %v", err)
+	}
+
+	return nil
+}
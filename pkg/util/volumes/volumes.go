@@ -24,4 +24,5 @@ type VolumeResizer interface {
 	ModifyVolume(providerVolumeID string, newType *string, newSize *int64, iops *int64, throughput *int64) error
 	DisconnectFromProvider() error
 	DescribeVolumes(providerVolumesID []string) ([]VolumeProperties, error)
+	DescribeVolumeModifications(providerVolumesID []string) (map[string]string, error)
 }